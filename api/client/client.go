@@ -5,11 +5,15 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/auth"
 	"github.com/quilt/quilt/db"
 
 	"golang.org/x/net/context"
@@ -48,13 +52,102 @@ type Client interface {
 	// QueryClusters retrieves cluster information tracked by the Quilt daemon.
 	QueryClusters() ([]db.Cluster, error)
 
+	// QueryTraffic retrieves the traffic sent over each connection, as tracked by
+	// the Quilt daemon.
+	QueryTraffic() ([]db.Traffic, error)
+
+	// QueryPortTraffic retrieves the traffic sent and received by each container,
+	// as tracked by the Quilt daemon.
+	QueryPortTraffic() ([]db.PortTraffic, error)
+
+	// QueryStats retrieves the latest resource usage samples of the server's
+	// machine and containers.
+	QueryStats() ([]db.Stats, error)
+
+	// QuerySecrets retrieves the names of the secrets stored by the Quilt daemon.
+	// Their values are never sent back.
+	QuerySecrets() ([]db.Secret, error)
+
+	// QueryHistory retrieves the deployments recorded by the Quilt daemon, oldest
+	// first.
+	QueryHistory() ([]db.Revision, error)
+
+	// QueryStatus retrieves what the server is still waiting on before the
+	// deployment converges.
+	QueryStatus() (Status, error)
+
 	// Deploy makes a request to the Quilt daemon to deploy the given deployment.
 	Deploy(deployment string) error
 
+	// SetSecret stores a secret in the Quilt daemon.
+	SetSecret(name, value string) error
+
+	// RemoveSecret deletes a secret from the Quilt daemon.
+	RemoveSecret(name string) error
+
+	// Exec runs a command in a container on the minion the client is connected
+	// to, and returns its exit code.
+	Exec(container string, opts ExecOptions) (int, error)
+
+	// Logs writes the logs of a container on the minion the client is connected
+	// to, or of the minion itself if `container` is empty.
+	Logs(container string, opts LogsOptions) error
+
+	// Upload extracts a tar archive into a directory of a container on the minion
+	// the client is connected to.
+	Upload(container, dir string, tarball io.Reader) error
+
+	// Download writes a tar archive of a path in a container on the minion the
+	// client is connected to.
+	Download(container, path string, tarball io.Writer) error
+
+	// Events calls `handle` with the events recorded by the server, oldest first.
+	// If `follow` is set, `handle` is then called with the events recorded or
+	// repeated since, until it returns an error.
+	Events(follow bool, handle func([]db.Event) error) error
+
 	// Host returns the server address the Client is connected to.
 	Host() string
 }
 
+// Status describes how far a server is from converging to a deployment.
+type Status struct {
+	// The hash of the deployment the server is converging to, if any.
+	SpecHash string
+
+	// Descriptions of the parts of the deployment that haven't converged.
+	Pending []string
+}
+
+// ExecOptions describes a command run by Exec.
+type ExecOptions struct {
+	Cmd []string
+	Tty bool
+
+	Stdin  io.Reader // Not attached if nil.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// The size of the terminal, sent each time it changes.
+	Resize <-chan TTYSize
+}
+
+// LogsOptions describes the logs fetched by Logs.
+type LogsOptions struct {
+	Since      time.Time // All logs are shown if zero.
+	Tail       string    // The number of lines to show from the end, or "all".
+	Follow     bool
+	Timestamps bool
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// TTYSize is the size of a terminal, in characters.
+type TTYSize struct {
+	Height, Width int
+}
+
 // Getter provides methods for obtaining Quilt clients connected to various servers.
 type Getter interface {
 	// Client obtains a client connected to the given address.
@@ -74,7 +167,8 @@ type clientImpl struct {
 	serverHost string
 }
 
-// New creates a new Quilt client connected to `lAddr`.
+// New creates a new Quilt client connected to `lAddr`.  Servers listening over TCP
+// are authenticated with, and must accept, the credentials in auth.DefaultDir.
 func New(lAddr string) (Client, error) {
 	proto, addr, err := api.ParseListenAddress(lAddr)
 	if err != nil {
@@ -84,8 +178,17 @@ func New(lAddr string) (Client, error) {
 	dialer := func(dialAddr string, t time.Duration) (net.Conn, error) {
 		return net.DialTimeout(proto, dialAddr, t)
 	}
-	cc, err := grpc.Dial(addr, grpc.WithDialer(dialer), grpc.WithInsecure(),
-		grpc.WithBlock(), grpc.WithTimeout(connectTimeout))
+	opts := []grpc.DialOption{grpc.WithDialer(dialer), grpc.WithBlock(),
+		grpc.WithTimeout(connectTimeout)}
+
+	credsOpt := grpc.WithInsecure()
+	if proto == "tcp" {
+		if credsOpt, err = dialCredentials(); err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials: %s", err)
+		}
+	}
+
+	cc, err := grpc.Dial(addr, append(opts, credsOpt)...)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +202,14 @@ func New(lAddr string) (Client, error) {
 	}, err
 }
 
+func dialCredentials() (grpc.DialOption, error) {
+	creds, err := auth.Load(auth.DefaultDir)
+	if err != nil {
+		return nil, err
+	}
+	return creds.DialOption()
+}
+
 func query(pbClient pb.APIClient, table db.TableType) (interface{}, error) {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
 	reply, err := pbClient.Query(ctx, &pb.DBQuery{Table: string(table)})
@@ -144,6 +255,30 @@ func query(pbClient pb.APIClient, table db.TableType) (interface{}, error) {
 			return nil, err
 		}
 		return clusters, nil
+	case db.TrafficTable:
+		var traffic []db.Traffic
+		if err := json.Unmarshal(replyBytes, &traffic); err != nil {
+			return nil, err
+		}
+		return traffic, nil
+	case db.PortTrafficTable:
+		var ports []db.PortTraffic
+		if err := json.Unmarshal(replyBytes, &ports); err != nil {
+			return nil, err
+		}
+		return ports, nil
+	case db.StatsTable:
+		var stats []db.Stats
+		if err := json.Unmarshal(replyBytes, &stats); err != nil {
+			return nil, err
+		}
+		return stats, nil
+	case db.SecretTable:
+		var secrets []db.Secret
+		if err := json.Unmarshal(replyBytes, &secrets); err != nil {
+			return nil, err
+		}
+		return secrets, nil
 	default:
 		panic(fmt.Sprintf("unsupported table type: %s", table))
 	}
@@ -214,13 +349,322 @@ func (c clientImpl) QueryClusters() ([]db.Cluster, error) {
 	return rows.([]db.Cluster), nil
 }
 
-// Deploy makes a request to the Quilt daemon to deploy the given deployment.
+// QueryTraffic retrieves the traffic sent over each connection, as tracked by the
+// Quilt daemon.
+func (c clientImpl) QueryTraffic() ([]db.Traffic, error) {
+	rows, err := query(c.pbClient, db.TrafficTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Traffic), nil
+}
+
+// QueryPortTraffic retrieves the traffic sent and received by each container, as
+// tracked by the Quilt daemon.
+func (c clientImpl) QueryPortTraffic() ([]db.PortTraffic, error) {
+	rows, err := query(c.pbClient, db.PortTrafficTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.PortTraffic), nil
+}
+
+// QueryStats retrieves the latest resource usage samples of the server's machine
+// and containers.
+func (c clientImpl) QueryStats() ([]db.Stats, error) {
+	rows, err := query(c.pbClient, db.StatsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Stats), nil
+}
+
+// QueryHistory retrieves the deployments recorded by the Quilt daemon, oldest
+// first.
+func (c clientImpl) QueryHistory() ([]db.Revision, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.History(ctx, &pb.HistoryRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var revs []db.Revision
+	if err := json.Unmarshal([]byte(reply.Revisions), &revs); err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+// QueryStatus retrieves what the server is still waiting on before the deployment
+// converges.
+func (c clientImpl) QueryStatus() (Status, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.Status(ctx, &pb.StatusRequest{})
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{SpecHash: reply.SpecHash, Pending: reply.Pending}, nil
+}
+
+// Deploy makes a request to the Quilt daemon to deploy the given deployment.  The
+// local hostname is recorded as the deployment's author.
 func (c clientImpl) Deploy(deployment string) error {
+	author, _ := os.Hostname()
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.Deploy(ctx, &pb.DeployRequest{
+		Deployment: deployment,
+		Author:     author,
+	})
+	return err
+}
+
+// QuerySecrets retrieves the names of the secrets stored by the Quilt daemon.
+func (c clientImpl) QuerySecrets() ([]db.Secret, error) {
+	rows, err := query(c.pbClient, db.SecretTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Secret), nil
+}
+
+// SetSecret stores a secret in the Quilt daemon.
+func (c clientImpl) SetSecret(name, value string) error {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.SetSecret(ctx, &pb.SecretRequest{Name: name, Value: value})
+	return err
+}
+
+// RemoveSecret deletes a secret from the Quilt daemon.
+func (c clientImpl) RemoveSecret(name string) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
-	_, err := c.pbClient.Deploy(ctx, &pb.DeployRequest{Deployment: deployment})
+	_, err := c.pbClient.RemoveSecret(ctx, &pb.SecretRequest{Name: name})
 	return err
 }
 
+// Exec runs a command in a container on the minion the client is connected to,
+// and returns its exit code.
+func (c clientImpl) Exec(container string, opts ExecOptions) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.pbClient.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Input is forwarded concurrently, but gRPC streams don't support concurrent
+	// sends.
+	var sendLock sync.Mutex
+	send := func(req *pb.ExecRequest) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return stream.Send(req)
+	}
+
+	err = send(&pb.ExecRequest{
+		Container:   container,
+		Cmd:         opts.Cmd,
+		Tty:         opts.Tty,
+		AttachStdin: opts.Stdin != nil,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Stdin != nil {
+		go forwardStdin(opts.Stdin, send)
+	}
+
+	if opts.Resize != nil {
+		go func() {
+			for size := range opts.Resize {
+				err := send(&pb.ExecRequest{
+					Height: int32(size.Height),
+					Width:  int32(size.Width),
+				})
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		if reply.Exited {
+			return int(reply.ExitCode), nil
+		}
+
+		if len(reply.Stdout) > 0 && opts.Stdout != nil {
+			opts.Stdout.Write(reply.Stdout)
+		}
+		if len(reply.Stderr) > 0 && opts.Stderr != nil {
+			opts.Stderr.Write(reply.Stderr)
+		}
+	}
+}
+
+func forwardStdin(stdin io.Reader, send func(*pb.ExecRequest) error) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			data := append([]byte{}, buf[:n]...)
+			if send(&pb.ExecRequest{Stdin: data}) != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			send(&pb.ExecRequest{CloseStdin: true})
+			return
+		}
+	}
+}
+
+// Logs writes the logs of a container on the minion the client is connected to, or
+// of the minion itself if `container` is empty.
+func (c clientImpl) Logs(container string, opts LogsOptions) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := &pb.LogsRequest{
+		Container:  container,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+		Tail:       opts.Tail,
+	}
+	if !opts.Since.IsZero() {
+		req.Since = opts.Since.Unix()
+	}
+
+	stream, err := c.pbClient.Logs(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if len(reply.Stdout) > 0 && opts.Stdout != nil {
+			opts.Stdout.Write(reply.Stdout)
+		}
+		if len(reply.Stderr) > 0 && opts.Stderr != nil {
+			opts.Stderr.Write(reply.Stderr)
+		}
+	}
+}
+
+// Upload extracts a tar archive into a directory of a container on the minion the
+// client is connected to.
+func (c clientImpl) Upload(container, dir string, tarball io.Reader) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.pbClient.Upload(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = stream.Send(&pb.UploadRequest{Container: container, Path: dir})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := tarball.Read(buf)
+		if n > 0 {
+			data := append([]byte{}, buf[:n]...)
+			if err := stream.Send(&pb.UploadRequest{Tar: data}); err != nil {
+				// The server's error is returned by CloseAndRecv.
+				break
+			}
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// Download writes a tar archive of a path in a container on the minion the client
+// is connected to.
+func (c clientImpl) Download(container, path string, tarball io.Writer) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.pbClient.Download(ctx, &pb.DownloadRequest{
+		Container: container,
+		Path:      path,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := tarball.Write(reply.Tar); err != nil {
+			return err
+		}
+	}
+}
+
+// Events calls `handle` with the events recorded by the server, oldest first.  If
+// `follow` is set, `handle` is then called with the events recorded or repeated
+// since, until it returns an error.
+func (c clientImpl) Events(follow bool, handle func([]db.Event) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.pbClient.Events(ctx, &pb.EventsRequest{Follow: follow})
+	if err != nil {
+		return err
+	}
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var events []db.Event
+		if err := json.Unmarshal([]byte(reply.Events), &events); err != nil {
+			return err
+		}
+
+		if err := handle(events); err != nil {
+			return err
+		}
+	}
+}
+
 func (c clientImpl) Host() string {
 	return c.serverHost
 }
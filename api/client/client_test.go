@@ -1,8 +1,12 @@
 package client
 
 import (
+	"bytes"
 	"errors"
+	"io"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -10,11 +14,20 @@ import (
 
 	"github.com/quilt/quilt/api/pb"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+
+	"github.com/spf13/afero"
 )
 
 type mockAPIClient struct {
 	mockResponse string
 	mockError    error
+
+	// The last secret request is copied into secretReq if it's set.
+	secretReq *pb.SecretRequest
+
+	// The last deploy request is copied into deployReq if it's set.
+	deployReq *pb.DeployRequest
 }
 
 func (c mockAPIClient) Query(ctx context.Context, in *pb.DBQuery,
@@ -26,9 +39,224 @@ func (c mockAPIClient) Query(ctx context.Context, in *pb.DBQuery,
 func (c mockAPIClient) Deploy(ctx context.Context, in *pb.DeployRequest,
 	opts ...grpc.CallOption) (*pb.DeployReply, error) {
 
+	if c.deployReq != nil {
+		*c.deployReq = *in
+	}
 	return &pb.DeployReply{}, nil
 }
 
+func (c mockAPIClient) History(ctx context.Context, in *pb.HistoryRequest,
+	opts ...grpc.CallOption) (*pb.HistoryReply, error) {
+
+	return &pb.HistoryReply{Revisions: c.mockResponse}, c.mockError
+}
+
+func (c mockAPIClient) Status(ctx context.Context, in *pb.StatusRequest,
+	opts ...grpc.CallOption) (*pb.StatusReply, error) {
+
+	return &pb.StatusReply{SpecHash: c.mockResponse, Pending: []string{"pending"}},
+		c.mockError
+}
+
+func (c mockAPIClient) Exec(ctx context.Context, opts ...grpc.CallOption) (
+	pb.API_ExecClient, error) {
+
+	if c.mockError != nil {
+		return nil, c.mockError
+	}
+	return &mockExecClient{reqs: make(chan *pb.ExecRequest, 16)}, nil
+}
+
+// mockExecClient runs `cat`: once stdin is closed, it replies with the input as
+// stdout, and the number of resizes as the exit code.
+type mockExecClient struct {
+	grpc.ClientStream
+
+	reqs    chan *pb.ExecRequest
+	first   *pb.ExecRequest
+	replies []*pb.ExecReply
+}
+
+func (c *mockExecClient) Send(req *pb.ExecRequest) error {
+	c.reqs <- req
+	return nil
+}
+
+func (c *mockExecClient) Recv() (*pb.ExecReply, error) {
+	if c.replies == nil {
+		var stdin []byte
+		var resizes int32
+		for req := range c.reqs {
+			if c.first == nil {
+				c.first = req
+			}
+			stdin = append(stdin, req.Stdin...)
+			if req.Height > 0 {
+				resizes++
+			}
+			if req.CloseStdin || !c.first.AttachStdin {
+				break
+			}
+		}
+		c.replies = []*pb.ExecReply{
+			{Stdout: stdin},
+			{Stderr: []byte(strings.Join(c.first.Cmd, " "))},
+			{Exited: true, ExitCode: resizes},
+		}
+	}
+
+	if len(c.replies) == 0 {
+		return nil, io.EOF
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func (c mockAPIClient) Logs(ctx context.Context, in *pb.LogsRequest,
+	opts ...grpc.CallOption) (pb.API_LogsClient, error) {
+
+	if c.mockError != nil {
+		return nil, c.mockError
+	}
+	return &mockLogsClient{replies: []*pb.LogsReply{
+		{Stdout: []byte(c.mockResponse)},
+		{Stderr: []byte(in.Container)},
+	}}, nil
+}
+
+type mockLogsClient struct {
+	grpc.ClientStream
+	replies []*pb.LogsReply
+}
+
+func (c *mockLogsClient) Recv() (*pb.LogsReply, error) {
+	if len(c.replies) == 0 {
+		return nil, io.EOF
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func (c mockAPIClient) Upload(ctx context.Context, opts ...grpc.CallOption) (
+	pb.API_UploadClient, error) {
+
+	if c.mockError != nil {
+		return nil, c.mockError
+	}
+	return &mockUploadClient{}, nil
+}
+
+// mockUploadClient fails unless the archive it receives is "archive".
+type mockUploadClient struct {
+	grpc.ClientStream
+	reqs []pb.UploadRequest
+}
+
+func (c *mockUploadClient) Send(req *pb.UploadRequest) error {
+	c.reqs = append(c.reqs, *req)
+	return nil
+}
+
+func (c *mockUploadClient) CloseAndRecv() (*pb.UploadReply, error) {
+	var tarball string
+	for _, req := range c.reqs {
+		tarball += string(req.Tar)
+	}
+
+	if len(c.reqs) == 0 || c.reqs[0].Container != "container" ||
+		c.reqs[0].Path != "/dir" || tarball != "archive" {
+		return nil, errors.New("bad upload")
+	}
+	return &pb.UploadReply{}, nil
+}
+
+func (c mockAPIClient) Download(ctx context.Context, in *pb.DownloadRequest,
+	opts ...grpc.CallOption) (pb.API_DownloadClient, error) {
+
+	if c.mockError != nil {
+		return nil, c.mockError
+	}
+	return &mockDownloadClient{replies: []*pb.DownloadReply{
+		{Tar: []byte(in.Container)},
+		{Tar: []byte(in.Path)},
+	}}, nil
+}
+
+type mockDownloadClient struct {
+	grpc.ClientStream
+	replies []*pb.DownloadReply
+}
+
+func (c *mockDownloadClient) Recv() (*pb.DownloadReply, error) {
+	if len(c.replies) == 0 {
+		return nil, io.EOF
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func (c mockAPIClient) Events(ctx context.Context, in *pb.EventsRequest,
+	opts ...grpc.CallOption) (pb.API_EventsClient, error) {
+
+	if c.mockError != nil {
+		return nil, c.mockError
+	}
+
+	replies := []*pb.EventsReply{{Events: c.mockResponse}}
+	if in.Follow {
+		replies = append(replies, &pb.EventsReply{Events: c.mockResponse})
+	}
+	return &mockEventsClient{replies: replies}, nil
+}
+
+type mockEventsClient struct {
+	grpc.ClientStream
+	replies []*pb.EventsReply
+}
+
+func (c *mockEventsClient) Recv() (*pb.EventsReply, error) {
+	if len(c.replies) == 0 {
+		return nil, io.EOF
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func (c mockAPIClient) SetSecret(ctx context.Context, in *pb.SecretRequest,
+	opts ...grpc.CallOption) (*pb.SecretReply, error) {
+
+	if c.secretReq != nil {
+		*c.secretReq = *in
+	}
+	return &pb.SecretReply{}, c.mockError
+}
+
+func (c mockAPIClient) RemoveSecret(ctx context.Context, in *pb.SecretRequest,
+	opts ...grpc.CallOption) (*pb.SecretReply, error) {
+
+	if c.secretReq != nil {
+		*c.secretReq = *in
+	}
+	return &pb.SecretReply{}, c.mockError
+}
+
+func TestNewCredentials(t *testing.T) {
+	oldFs := util.AppFs
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { util.AppFs = oldFs }()
+
+	// Servers listening over TCP can't be reached without the credentials.
+	_, err := New("tcp://127.0.0.1:9000")
+	if err == nil || !strings.HasPrefix(err.Error(),
+		"failed to load TLS credentials: ") {
+		t.Errorf("Expected a credentials error, but got %v", err)
+	}
+}
+
 func TestUnmarshalMachine(t *testing.T) {
 	t.Parallel()
 
@@ -92,6 +320,109 @@ func TestUnmarshalContainer(t *testing.T) {
 	}
 }
 
+func TestUnmarshalTraffic(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"From":"a","To":"b","Packets":1,"Bytes":2}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryTraffic()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := []db.Traffic{{From: "a", To: "b", Packets: 1, Bytes: 2}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad unmarshalling of traffic: expected %v, got %v.",
+			exp, res)
+	}
+}
+
+func TestUnmarshalPortTraffic(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"StitchID":"1","Minion":"10.0.0.1","RxBytes":2}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryPortTraffic()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := []db.PortTraffic{{StitchID: "1", Minion: "10.0.0.1", RxBytes: 2}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad unmarshalling of port traffic: expected %v, got %v.",
+			exp, res)
+	}
+}
+
+func TestUnmarshalStats(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"StitchID":"a","Minion":"1.2.3.4","CPUPercent":1.5,` +
+			`"Load1":0.5}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryStats()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := []db.Stats{{StitchID: "a", Minion: "1.2.3.4", CPUPercent: 1.5,
+		Load1: 0.5}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad unmarshalling of stats: expected %v, got %v.",
+			exp, res)
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"Name":"a"}]`,
+		secretReq:    &pb.SecretRequest{},
+	}
+	c := clientImpl{pbClient: apiClient}
+
+	res, err := c.QuerySecrets()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := []db.Secret{{Name: "a"}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad unmarshalling of secrets: expected %v, got %v.", exp, res)
+	}
+
+	if err := c.SetSecret("a", "value"); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	expReq := &pb.SecretRequest{Name: "a", Value: "value"}
+	if !reflect.DeepEqual(expReq, apiClient.secretReq) {
+		t.Errorf("Bad SetSecret request: expected %v, got %v.", expReq,
+			apiClient.secretReq)
+	}
+
+	apiClient.mockError = errors.New("timeout")
+	c = clientImpl{pbClient: apiClient}
+	if err := c.RemoveSecret("a"); err == nil || err.Error() != "timeout" {
+		t.Errorf("RemoveSecret should return grpc errors, but got %v", err)
+	}
+	expReq = &pb.SecretRequest{Name: "a"}
+	if !reflect.DeepEqual(expReq, apiClient.secretReq) {
+		t.Errorf("Bad RemoveSecret request: expected %v, got %v.", expReq,
+			apiClient.secretReq)
+	}
+}
+
 func TestUnmarshalError(t *testing.T) {
 	t.Parallel()
 
@@ -130,3 +461,191 @@ func TestGrpcError(t *testing.T) {
 			exp.Error(), err.Error())
 	}
 }
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"Number":1,"Hash":"abc","Author":"host","Spec":"{}"}]`,
+		deployReq:    &pb.DeployRequest{},
+	}
+	c := clientImpl{pbClient: apiClient}
+
+	res, err := c.QueryHistory()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := []db.Revision{{Number: 1, Hash: "abc", Author: "host", Spec: "{}"}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad unmarshalling of history: expected %v, got %v.", exp, res)
+	}
+
+	if err := c.Deploy("{}"); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	hostname, _ := os.Hostname()
+	expReq := &pb.DeployRequest{Deployment: "{}", Author: hostname}
+	if !reflect.DeepEqual(expReq, apiClient.deployReq) {
+		t.Errorf("Bad Deploy request: expected %v, got %v.", expReq,
+			apiClient.deployReq)
+	}
+
+	apiClient.mockError = errors.New("timeout")
+	c = clientImpl{pbClient: apiClient}
+	if _, err := c.QueryHistory(); err == nil || err.Error() != "timeout" {
+		t.Errorf("QueryHistory should return grpc errors, but got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{mockResponse: "hash"}
+	c := clientImpl{pbClient: apiClient}
+
+	res, err := c.QueryStatus()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := Status{SpecHash: "hash", Pending: []string{"pending"}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad status: expected %v, got %v.", exp, res)
+	}
+
+	apiClient.mockError = errors.New("timeout")
+	c = clientImpl{pbClient: apiClient}
+	if _, err := c.QueryStatus(); err == nil || err.Error() != "timeout" {
+		t.Errorf("QueryStatus should return grpc errors, but got %v", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{}}
+
+	var stdout, stderr bytes.Buffer
+	code, err := c.Exec("container", ExecOptions{
+		Cmd:    []string{"cat", "-"},
+		Stdin:  strings.NewReader("input"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	if code != 0 || stdout.String() != "input" || stderr.String() != "cat -" {
+		t.Errorf("Bad exec: got exit code %d, stdout %q and stderr %q.",
+			code, stdout.String(), stderr.String())
+	}
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: errors.New("timeout")}}
+	if _, err := c.Exec("container", ExecOptions{}); err == nil ||
+		err.Error() != "timeout" {
+		t.Errorf("Exec should return grpc errors, but got %v", err)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{mockResponse: "logs"}}
+
+	var stdout, stderr bytes.Buffer
+	err := c.Logs("container", LogsOptions{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	if stdout.String() != "logs" || stderr.String() != "container" {
+		t.Errorf("Bad logs: got stdout %q and stderr %q.", stdout.String(),
+			stderr.String())
+	}
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: errors.New("timeout")}}
+	if err := c.Logs("container", LogsOptions{}); err == nil ||
+		err.Error() != "timeout" {
+		t.Errorf("Logs should return grpc errors, but got %v", err)
+	}
+}
+
+func TestUploadDownload(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{}}
+	err := c.Upload("container", "/dir", strings.NewReader("archive"))
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	var tarball bytes.Buffer
+	if err := c.Download("container", "/path", &tarball); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if tarball.String() != "container/path" {
+		t.Errorf("Bad download: got %q.", tarball.String())
+	}
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: errors.New("timeout")}}
+	if err := c.Upload("container", "/dir", &tarball); err == nil ||
+		err.Error() != "timeout" {
+		t.Errorf("Upload should return grpc errors, but got %v", err)
+	}
+	if err := c.Download("container", "/path", &tarball); err == nil ||
+		err.Error() != "timeout" {
+		t.Errorf("Download should return grpc errors, but got %v", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{
+		mockResponse: `[{"Type":"Warning","Reason":"FailedPull","Count":2}]`,
+	}}
+
+	var batches [][]db.Event
+	handle := func(events []db.Event) error {
+		batches = append(batches, events)
+		return nil
+	}
+
+	exp := []db.Event{{Type: db.EventWarning, Reason: "FailedPull", Count: 2}}
+	if err := c.Events(false, handle); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(batches, [][]db.Event{exp}) {
+		t.Errorf("Bad events: expected %v, got %v.", exp, batches)
+	}
+
+	batches = nil
+	if err := c.Events(true, handle); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(batches, [][]db.Event{exp, exp}) {
+		t.Errorf("Bad followed events: got %v.", batches)
+	}
+
+	stop := errors.New("stop")
+	err := c.Events(true, func(events []db.Event) error { return stop })
+	if err != stop {
+		t.Errorf("Events should return the handler's error, but got %v", err)
+	}
+
+	c = clientImpl{pbClient: mockAPIClient{mockResponse: "malformed"}}
+	if err := c.Events(false, handle); err == nil {
+		t.Error("Events should fail to parse malformed events")
+	}
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: errors.New("timeout")}}
+	if err := c.Events(false, handle); err == nil || err.Error() != "timeout" {
+		t.Errorf("Events should return grpc errors, but got %v", err)
+	}
+}
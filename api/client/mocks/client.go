@@ -1,20 +1,57 @@
 package mocks
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/quilt/quilt/api/client"
 	"github.com/quilt/quilt/db"
 )
 
 // Client implements a mocked version of a Quilt client.
 type Client struct {
-	MachineReturn   []db.Machine
-	ContainerReturn []db.Container
-	EtcdReturn      []db.Etcd
-	ClusterReturn   []db.Cluster
-	HostReturn      string
-	DeployArg       string
+	MachineReturn     []db.Machine
+	ContainerReturn   []db.Container
+	EtcdReturn        []db.Etcd
+	ClusterReturn     []db.Cluster
+	ConnectionReturn  []db.Connection
+	LabelReturn       []db.Label
+	TrafficReturn     []db.Traffic
+	PortTrafficReturn []db.PortTraffic
+	StatsReturn       []db.Stats
+	EventsReturn      [][]db.Event
+	SecretReturn      []db.Secret
+	HistoryReturn     []db.Revision
+	StatusReturn      client.Status
+	ExecReturn        int
+	LogsReturn        string
+	HostReturn        string
+	DeployArg         string
+
+	// ExecContainer and ExecArg record the last command executed.
+	ExecContainer string
+	ExecArg       client.ExecOptions
+
+	// Uploads records the archives uploaded, by container and directory, and
+	// Downloads holds the archives downloaded, by container and path.
+	Uploads   map[string]string
+	Downloads map[string]string
+
+	// LogsContainer and LogsArg record the last logs fetched.
+	LogsContainer string
+	LogsArg       client.LogsOptions
+
+	// Secrets records the secrets set and removed through the client.
+	Secrets map[string]string
 
 	MachineErr, ContainerErr, EtcdErr, ClusterErr, HostErr error
-	DeployErr, ConnectionErr                               error
+	DeployErr, ConnectionErr, TrafficErr, SecretErr        error
+	HistoryErr, StatusErr, ExecErr, LogsErr, CopyErr       error
+	StatsErr, EventsErr, PortTrafficErr                    error
+
+	// EventsFollow records whether the last events fetched were followed.
+	EventsFollow bool
 }
 
 // QueryMachines retrieves the machines tracked by the Quilt daemon.
@@ -47,12 +84,12 @@ func (c *Client) QueryConnections() ([]db.Connection, error) {
 	if c.ConnectionErr != nil {
 		return nil, c.ConnectionErr
 	}
-	return nil, nil
+	return c.ConnectionReturn, nil
 }
 
 // QueryLabels retrieves the label information tracked by the Quilt daemon.
 func (c *Client) QueryLabels() ([]db.Label, error) {
-	return nil, nil
+	return c.LabelReturn, nil
 }
 
 // QueryClusters retrieves cluster information tracked by the Quilt daemon.
@@ -63,11 +100,162 @@ func (c *Client) QueryClusters() ([]db.Cluster, error) {
 	return c.ClusterReturn, nil
 }
 
+// QueryTraffic retrieves the traffic sent over each connection, as tracked by the
+// Quilt daemon.
+func (c *Client) QueryTraffic() ([]db.Traffic, error) {
+	if c.TrafficErr != nil {
+		return nil, c.TrafficErr
+	}
+	return c.TrafficReturn, nil
+}
+
+// QueryPortTraffic retrieves the traffic sent and received by each container, as
+// tracked by the Quilt daemon.
+func (c *Client) QueryPortTraffic() ([]db.PortTraffic, error) {
+	if c.PortTrafficErr != nil {
+		return nil, c.PortTrafficErr
+	}
+	return c.PortTrafficReturn, nil
+}
+
+// QueryStats retrieves the latest resource usage samples of the server's machine
+// and containers.
+func (c *Client) QueryStats() ([]db.Stats, error) {
+	if c.StatsErr != nil {
+		return nil, c.StatsErr
+	}
+	return c.StatsReturn, nil
+}
+
+// QuerySecrets retrieves the names of the secrets stored by the Quilt daemon.
+func (c *Client) QuerySecrets() ([]db.Secret, error) {
+	if c.SecretErr != nil {
+		return nil, c.SecretErr
+	}
+	return c.SecretReturn, nil
+}
+
+// SetSecret stores a secret in the Quilt daemon.
+func (c *Client) SetSecret(name, value string) error {
+	if c.SecretErr != nil {
+		return c.SecretErr
+	}
+
+	if c.Secrets == nil {
+		c.Secrets = map[string]string{}
+	}
+	c.Secrets[name] = value
+	return nil
+}
+
+// RemoveSecret deletes a secret from the Quilt daemon.
+func (c *Client) RemoveSecret(name string) error {
+	if c.SecretErr != nil {
+		return c.SecretErr
+	}
+	delete(c.Secrets, name)
+	return nil
+}
+
 // Close the grpc connection.
 func (c *Client) Close() error {
 	return nil
 }
 
+// QueryHistory retrieves the deployments recorded by the Quilt daemon.
+func (c *Client) QueryHistory() ([]db.Revision, error) {
+	if c.HistoryErr != nil {
+		return nil, c.HistoryErr
+	}
+	return c.HistoryReturn, nil
+}
+
+// QueryStatus retrieves what the server is still waiting on before the deployment
+// converges.
+func (c *Client) QueryStatus() (client.Status, error) {
+	if c.StatusErr != nil {
+		return client.Status{}, c.StatusErr
+	}
+	return c.StatusReturn, nil
+}
+
+// Exec runs a command in a container on the minion the client is connected to,
+// and returns its exit code.
+func (c *Client) Exec(container string, opts client.ExecOptions) (int, error) {
+	c.ExecContainer = container
+	c.ExecArg = opts
+	if c.ExecErr != nil {
+		return 0, c.ExecErr
+	}
+	return c.ExecReturn, nil
+}
+
+// Logs writes LogsReturn to the standard output of the logs.
+func (c *Client) Logs(container string, opts client.LogsOptions) error {
+	c.LogsContainer = container
+	c.LogsArg = opts
+	if c.LogsErr != nil {
+		return c.LogsErr
+	}
+
+	if opts.Stdout != nil {
+		io.WriteString(opts.Stdout, c.LogsReturn)
+	}
+	return nil
+}
+
+// Upload records the tar archive in Uploads, keyed by "<container>:<dir>".
+func (c *Client) Upload(container, dir string, tarball io.Reader) error {
+	if c.CopyErr != nil {
+		return c.CopyErr
+	}
+
+	content, err := ioutil.ReadAll(tarball)
+	if err != nil {
+		return err
+	}
+
+	if c.Uploads == nil {
+		c.Uploads = map[string]string{}
+	}
+	c.Uploads[container+":"+dir] = string(content)
+	return nil
+}
+
+// Download writes the archive in Downloads keyed by "<container>:<path>".
+func (c *Client) Download(container, path string, tarball io.Writer) error {
+	if c.CopyErr != nil {
+		return c.CopyErr
+	}
+
+	content, ok := c.Downloads[container+":"+path]
+	if !ok {
+		return fmt.Errorf("no such file: %s", path)
+	}
+	_, err := io.WriteString(tarball, content)
+	return err
+}
+
+// Events calls `handle` with each batch in EventsReturn.  Only the first batch is
+// sent if `follow` isn't set.
+func (c *Client) Events(follow bool, handle func([]db.Event) error) error {
+	c.EventsFollow = follow
+	if c.EventsErr != nil {
+		return c.EventsErr
+	}
+
+	for i, events := range c.EventsReturn {
+		if i > 0 && !follow {
+			break
+		}
+
+		if err := handle(events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Deploy makes a request to the Quilt daemon to deploy the given deployment.
 func (c *Client) Deploy(depl string) error {
 	if c.DeployErr != nil {
@@ -1,18 +1,34 @@
 // Code generated by protoc-gen-go.
-// source: pb/pb.proto
+// source: api/pb/pb.proto
 // DO NOT EDIT!
 
 /*
 Package pb is a generated protocol buffer package.
 
 It is generated from these files:
-	pb/pb.proto
+	api/pb/pb.proto
 
 It has these top-level messages:
 	DBQuery
 	QueryReply
 	DeployRequest
 	DeployReply
+	SecretRequest
+	SecretReply
+	HistoryRequest
+	HistoryReply
+	StatusRequest
+	StatusReply
+	ExecRequest
+	ExecReply
+	LogsRequest
+	LogsReply
+	UploadRequest
+	UploadReply
+	DownloadRequest
+	DownloadReply
+	EventsRequest
+	EventsReply
 */
 package pb
 
@@ -70,6 +86,7 @@ func (m *QueryReply) GetTableContents() string {
 
 type DeployRequest struct {
 	Deployment string `protobuf:"bytes,1,opt,name=Deployment,json=deployment" json:"Deployment,omitempty"`
+	Author     string `protobuf:"bytes,2,opt,name=Author,json=author" json:"Author,omitempty"`
 }
 
 func (m *DeployRequest) Reset()                    { *m = DeployRequest{} }
@@ -84,6 +101,13 @@ func (m *DeployRequest) GetDeployment() string {
 	return ""
 }
 
+func (m *DeployRequest) GetAuthor() string {
+	if m != nil {
+		return m.Author
+	}
+	return ""
+}
+
 type DeployReply struct {
 }
 
@@ -92,11 +116,411 @@ func (m *DeployReply) String() string            { return proto.CompactTextStrin
 func (*DeployReply) ProtoMessage()               {}
 func (*DeployReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
+type SecretRequest struct {
+	Name  string `protobuf:"bytes,1,opt,name=Name,json=name" json:"Name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=Value,json=value" json:"Value,omitempty"`
+}
+
+func (m *SecretRequest) Reset()                    { *m = SecretRequest{} }
+func (m *SecretRequest) String() string            { return proto.CompactTextString(m) }
+func (*SecretRequest) ProtoMessage()               {}
+func (*SecretRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *SecretRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *SecretRequest) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+type SecretReply struct {
+}
+
+func (m *SecretReply) Reset()                    { *m = SecretReply{} }
+func (m *SecretReply) String() string            { return proto.CompactTextString(m) }
+func (*SecretReply) ProtoMessage()               {}
+func (*SecretReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type HistoryRequest struct {
+}
+
+func (m *HistoryRequest) Reset()                    { *m = HistoryRequest{} }
+func (m *HistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*HistoryRequest) ProtoMessage()               {}
+func (*HistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+type HistoryReply struct {
+	Revisions string `protobuf:"bytes,1,opt,name=Revisions,json=revisions" json:"Revisions,omitempty"`
+}
+
+func (m *HistoryReply) Reset()                    { *m = HistoryReply{} }
+func (m *HistoryReply) String() string            { return proto.CompactTextString(m) }
+func (*HistoryReply) ProtoMessage()               {}
+func (*HistoryReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *HistoryReply) GetRevisions() string {
+	if m != nil {
+		return m.Revisions
+	}
+	return ""
+}
+
+type StatusRequest struct {
+}
+
+func (m *StatusRequest) Reset()                    { *m = StatusRequest{} }
+func (m *StatusRequest) String() string            { return proto.CompactTextString(m) }
+func (*StatusRequest) ProtoMessage()               {}
+func (*StatusRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type StatusReply struct {
+	SpecHash string   `protobuf:"bytes,1,opt,name=SpecHash,json=specHash" json:"SpecHash,omitempty"`
+	Pending  []string `protobuf:"bytes,2,rep,name=Pending,json=pending" json:"Pending,omitempty"`
+}
+
+func (m *StatusReply) Reset()                    { *m = StatusReply{} }
+func (m *StatusReply) String() string            { return proto.CompactTextString(m) }
+func (*StatusReply) ProtoMessage()               {}
+func (*StatusReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *StatusReply) GetSpecHash() string {
+	if m != nil {
+		return m.SpecHash
+	}
+	return ""
+}
+
+func (m *StatusReply) GetPending() []string {
+	if m != nil {
+		return m.Pending
+	}
+	return nil
+}
+
+type ExecRequest struct {
+	Container   string   `protobuf:"bytes,1,opt,name=Container,json=container" json:"Container,omitempty"`
+	Cmd         []string `protobuf:"bytes,2,rep,name=Cmd,json=cmd" json:"Cmd,omitempty"`
+	Tty         bool     `protobuf:"varint,3,opt,name=Tty,json=tty" json:"Tty,omitempty"`
+	AttachStdin bool     `protobuf:"varint,4,opt,name=AttachStdin,json=attachStdin" json:"AttachStdin,omitempty"`
+	Stdin       []byte   `protobuf:"bytes,5,opt,name=Stdin,json=stdin,proto3" json:"Stdin,omitempty"`
+	CloseStdin  bool     `protobuf:"varint,6,opt,name=CloseStdin,json=closeStdin" json:"CloseStdin,omitempty"`
+	Height      int32    `protobuf:"varint,7,opt,name=Height,json=height" json:"Height,omitempty"`
+	Width       int32    `protobuf:"varint,8,opt,name=Width,json=width" json:"Width,omitempty"`
+}
+
+func (m *ExecRequest) Reset()                    { *m = ExecRequest{} }
+func (m *ExecRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExecRequest) ProtoMessage()               {}
+func (*ExecRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ExecRequest) GetContainer() string {
+	if m != nil {
+		return m.Container
+	}
+	return ""
+}
+
+func (m *ExecRequest) GetCmd() []string {
+	if m != nil {
+		return m.Cmd
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetTty() bool {
+	if m != nil {
+		return m.Tty
+	}
+	return false
+}
+
+func (m *ExecRequest) GetAttachStdin() bool {
+	if m != nil {
+		return m.AttachStdin
+	}
+	return false
+}
+
+func (m *ExecRequest) GetStdin() []byte {
+	if m != nil {
+		return m.Stdin
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetCloseStdin() bool {
+	if m != nil {
+		return m.CloseStdin
+	}
+	return false
+}
+
+func (m *ExecRequest) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *ExecRequest) GetWidth() int32 {
+	if m != nil {
+		return m.Width
+	}
+	return 0
+}
+
+type ExecReply struct {
+	Stdout   []byte `protobuf:"bytes,1,opt,name=Stdout,json=stdout,proto3" json:"Stdout,omitempty"`
+	Stderr   []byte `protobuf:"bytes,2,opt,name=Stderr,json=stderr,proto3" json:"Stderr,omitempty"`
+	Exited   bool   `protobuf:"varint,3,opt,name=Exited,json=exited" json:"Exited,omitempty"`
+	ExitCode int32  `protobuf:"varint,4,opt,name=ExitCode,json=exitCode" json:"ExitCode,omitempty"`
+}
+
+func (m *ExecReply) Reset()                    { *m = ExecReply{} }
+func (m *ExecReply) String() string            { return proto.CompactTextString(m) }
+func (*ExecReply) ProtoMessage()               {}
+func (*ExecReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *ExecReply) GetStdout() []byte {
+	if m != nil {
+		return m.Stdout
+	}
+	return nil
+}
+
+func (m *ExecReply) GetStderr() []byte {
+	if m != nil {
+		return m.Stderr
+	}
+	return nil
+}
+
+func (m *ExecReply) GetExited() bool {
+	if m != nil {
+		return m.Exited
+	}
+	return false
+}
+
+func (m *ExecReply) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
+type LogsRequest struct {
+	Container  string `protobuf:"bytes,1,opt,name=Container,json=container" json:"Container,omitempty"`
+	Since      int64  `protobuf:"varint,2,opt,name=Since,json=since" json:"Since,omitempty"`
+	Follow     bool   `protobuf:"varint,3,opt,name=Follow,json=follow" json:"Follow,omitempty"`
+	Timestamps bool   `protobuf:"varint,4,opt,name=Timestamps,json=timestamps" json:"Timestamps,omitempty"`
+	Tail       string `protobuf:"bytes,5,opt,name=Tail,json=tail" json:"Tail,omitempty"`
+}
+
+func (m *LogsRequest) Reset()                    { *m = LogsRequest{} }
+func (m *LogsRequest) String() string            { return proto.CompactTextString(m) }
+func (*LogsRequest) ProtoMessage()               {}
+func (*LogsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *LogsRequest) GetContainer() string {
+	if m != nil {
+		return m.Container
+	}
+	return ""
+}
+
+func (m *LogsRequest) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *LogsRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+func (m *LogsRequest) GetTimestamps() bool {
+	if m != nil {
+		return m.Timestamps
+	}
+	return false
+}
+
+func (m *LogsRequest) GetTail() string {
+	if m != nil {
+		return m.Tail
+	}
+	return ""
+}
+
+type LogsReply struct {
+	Stdout []byte `protobuf:"bytes,1,opt,name=Stdout,json=stdout,proto3" json:"Stdout,omitempty"`
+	Stderr []byte `protobuf:"bytes,2,opt,name=Stderr,json=stderr,proto3" json:"Stderr,omitempty"`
+}
+
+func (m *LogsReply) Reset()                    { *m = LogsReply{} }
+func (m *LogsReply) String() string            { return proto.CompactTextString(m) }
+func (*LogsReply) ProtoMessage()               {}
+func (*LogsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *LogsReply) GetStdout() []byte {
+	if m != nil {
+		return m.Stdout
+	}
+	return nil
+}
+
+func (m *LogsReply) GetStderr() []byte {
+	if m != nil {
+		return m.Stderr
+	}
+	return nil
+}
+
+type UploadRequest struct {
+	Container string `protobuf:"bytes,1,opt,name=Container,json=container" json:"Container,omitempty"`
+	Path      string `protobuf:"bytes,2,opt,name=Path,json=path" json:"Path,omitempty"`
+	Tar       []byte `protobuf:"bytes,3,opt,name=Tar,json=tar,proto3" json:"Tar,omitempty"`
+}
+
+func (m *UploadRequest) Reset()                    { *m = UploadRequest{} }
+func (m *UploadRequest) String() string            { return proto.CompactTextString(m) }
+func (*UploadRequest) ProtoMessage()               {}
+func (*UploadRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *UploadRequest) GetContainer() string {
+	if m != nil {
+		return m.Container
+	}
+	return ""
+}
+
+func (m *UploadRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *UploadRequest) GetTar() []byte {
+	if m != nil {
+		return m.Tar
+	}
+	return nil
+}
+
+type UploadReply struct {
+}
+
+func (m *UploadReply) Reset()                    { *m = UploadReply{} }
+func (m *UploadReply) String() string            { return proto.CompactTextString(m) }
+func (*UploadReply) ProtoMessage()               {}
+func (*UploadReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+type DownloadRequest struct {
+	Container string `protobuf:"bytes,1,opt,name=Container,json=container" json:"Container,omitempty"`
+	Path      string `protobuf:"bytes,2,opt,name=Path,json=path" json:"Path,omitempty"`
+}
+
+func (m *DownloadRequest) Reset()                    { *m = DownloadRequest{} }
+func (m *DownloadRequest) String() string            { return proto.CompactTextString(m) }
+func (*DownloadRequest) ProtoMessage()               {}
+func (*DownloadRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *DownloadRequest) GetContainer() string {
+	if m != nil {
+		return m.Container
+	}
+	return ""
+}
+
+func (m *DownloadRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+type DownloadReply struct {
+	Tar []byte `protobuf:"bytes,1,opt,name=Tar,json=tar,proto3" json:"Tar,omitempty"`
+}
+
+func (m *DownloadReply) Reset()                    { *m = DownloadReply{} }
+func (m *DownloadReply) String() string            { return proto.CompactTextString(m) }
+func (*DownloadReply) ProtoMessage()               {}
+func (*DownloadReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *DownloadReply) GetTar() []byte {
+	if m != nil {
+		return m.Tar
+	}
+	return nil
+}
+
+type EventsRequest struct {
+	Follow bool `protobuf:"varint,1,opt,name=Follow,json=follow" json:"Follow,omitempty"`
+}
+
+func (m *EventsRequest) Reset()                    { *m = EventsRequest{} }
+func (m *EventsRequest) String() string            { return proto.CompactTextString(m) }
+func (*EventsRequest) ProtoMessage()               {}
+func (*EventsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *EventsRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+type EventsReply struct {
+	Events string `protobuf:"bytes,1,opt,name=Events,json=events" json:"Events,omitempty"`
+}
+
+func (m *EventsReply) Reset()                    { *m = EventsReply{} }
+func (m *EventsReply) String() string            { return proto.CompactTextString(m) }
+func (*EventsReply) ProtoMessage()               {}
+func (*EventsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *EventsReply) GetEvents() string {
+	if m != nil {
+		return m.Events
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*DBQuery)(nil), "DBQuery")
 	proto.RegisterType((*QueryReply)(nil), "QueryReply")
 	proto.RegisterType((*DeployRequest)(nil), "DeployRequest")
 	proto.RegisterType((*DeployReply)(nil), "DeployReply")
+	proto.RegisterType((*SecretRequest)(nil), "SecretRequest")
+	proto.RegisterType((*SecretReply)(nil), "SecretReply")
+	proto.RegisterType((*HistoryRequest)(nil), "HistoryRequest")
+	proto.RegisterType((*HistoryReply)(nil), "HistoryReply")
+	proto.RegisterType((*StatusRequest)(nil), "StatusRequest")
+	proto.RegisterType((*StatusReply)(nil), "StatusReply")
+	proto.RegisterType((*ExecRequest)(nil), "ExecRequest")
+	proto.RegisterType((*ExecReply)(nil), "ExecReply")
+	proto.RegisterType((*LogsRequest)(nil), "LogsRequest")
+	proto.RegisterType((*LogsReply)(nil), "LogsReply")
+	proto.RegisterType((*UploadRequest)(nil), "UploadRequest")
+	proto.RegisterType((*UploadReply)(nil), "UploadReply")
+	proto.RegisterType((*DownloadRequest)(nil), "DownloadRequest")
+	proto.RegisterType((*DownloadReply)(nil), "DownloadReply")
+	proto.RegisterType((*EventsRequest)(nil), "EventsRequest")
+	proto.RegisterType((*EventsReply)(nil), "EventsReply")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -112,6 +536,15 @@ const _ = grpc.SupportPackageIsVersion4
 type APIClient interface {
 	Query(ctx context.Context, in *DBQuery, opts ...grpc.CallOption) (*QueryReply, error)
 	Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error)
+	SetSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*SecretReply, error)
+	RemoveSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*SecretReply, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryReply, error)
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error)
+	Exec(ctx context.Context, opts ...grpc.CallOption) (API_ExecClient, error)
+	Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (API_LogsClient, error)
+	Upload(ctx context.Context, opts ...grpc.CallOption) (API_UploadClient, error)
+	Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (API_DownloadClient, error)
+	Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (API_EventsClient, error)
 }
 
 type aPIClient struct {
@@ -140,11 +573,217 @@ func (c *aPIClient) Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.
 	return out, nil
 }
 
+func (c *aPIClient) SetSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*SecretReply, error) {
+	out := new(SecretReply)
+	err := grpc.Invoke(ctx, "/API/SetSecret", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) RemoveSecret(ctx context.Context, in *SecretRequest, opts ...grpc.CallOption) (*SecretReply, error) {
+	out := new(SecretReply)
+	err := grpc.Invoke(ctx, "/API/RemoveSecret", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryReply, error) {
+	out := new(HistoryReply)
+	err := grpc.Invoke(ctx, "/API/History", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error) {
+	out := new(StatusReply)
+	err := grpc.Invoke(ctx, "/API/Status", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Exec(ctx context.Context, opts ...grpc.CallOption) (API_ExecClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[0], c.cc, "/API/Exec", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIExecClient{stream}
+	return x, nil
+}
+
+type API_ExecClient interface {
+	Send(*ExecRequest) error
+	Recv() (*ExecReply, error)
+	grpc.ClientStream
+}
+
+type aPIExecClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIExecClient) Send(m *ExecRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *aPIExecClient) Recv() (*ExecReply, error) {
+	m := new(ExecReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (API_LogsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[1], c.cc, "/API/Logs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPILogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_LogsClient interface {
+	Recv() (*LogsReply, error)
+	grpc.ClientStream
+}
+
+type aPILogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPILogsClient) Recv() (*LogsReply, error) {
+	m := new(LogsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) Upload(ctx context.Context, opts ...grpc.CallOption) (API_UploadClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[2], c.cc, "/API/Upload", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIUploadClient{stream}
+	return x, nil
+}
+
+type API_UploadClient interface {
+	Send(*UploadRequest) error
+	CloseAndRecv() (*UploadReply, error)
+	grpc.ClientStream
+}
+
+type aPIUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIUploadClient) Send(m *UploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *aPIUploadClient) CloseAndRecv() (*UploadReply, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (API_DownloadClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[3], c.cc, "/API/Download", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIDownloadClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_DownloadClient interface {
+	Recv() (*DownloadReply, error)
+	grpc.ClientStream
+}
+
+type aPIDownloadClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIDownloadClient) Recv() (*DownloadReply, error) {
+	m := new(DownloadReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (API_EventsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[4], c.cc, "/API/Events", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_EventsClient interface {
+	Recv() (*EventsReply, error)
+	grpc.ClientStream
+}
+
+type aPIEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIEventsClient) Recv() (*EventsReply, error) {
+	m := new(EventsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for API service
 
 type APIServer interface {
 	Query(context.Context, *DBQuery) (*QueryReply, error)
 	Deploy(context.Context, *DeployRequest) (*DeployReply, error)
+	SetSecret(context.Context, *SecretRequest) (*SecretReply, error)
+	RemoveSecret(context.Context, *SecretRequest) (*SecretReply, error)
+	History(context.Context, *HistoryRequest) (*HistoryReply, error)
+	Status(context.Context, *StatusRequest) (*StatusReply, error)
+	Exec(API_ExecServer) error
+	Logs(*LogsRequest, API_LogsServer) error
+	Upload(API_UploadServer) error
+	Download(*DownloadRequest, API_DownloadServer) error
+	Events(*EventsRequest, API_EventsServer) error
 }
 
 func RegisterAPIServer(s *grpc.Server, srv APIServer) {
@@ -187,6 +826,193 @@ func _API_Deploy_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_SetSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).SetSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/SetSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).SetSecret(ctx, req.(*SecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_RemoveSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).RemoveSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/RemoveSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).RemoveSecret(ctx, req.(*SecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/History",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Exec_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(APIServer).Exec(&aPIExecServer{stream})
+}
+
+type API_ExecServer interface {
+	Send(*ExecReply) error
+	Recv() (*ExecRequest, error)
+	grpc.ServerStream
+}
+
+type aPIExecServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIExecServer) Send(m *ExecReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *aPIExecServer) Recv() (*ExecRequest, error) {
+	m := new(ExecRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _API_Logs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Logs(m, &aPILogsServer{stream})
+}
+
+type API_LogsServer interface {
+	Send(*LogsReply) error
+	grpc.ServerStream
+}
+
+type aPILogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPILogsServer) Send(m *LogsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _API_Upload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(APIServer).Upload(&aPIUploadServer{stream})
+}
+
+type API_UploadServer interface {
+	SendAndClose(*UploadReply) error
+	Recv() (*UploadRequest, error)
+	grpc.ServerStream
+}
+
+type aPIUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIUploadServer) SendAndClose(m *UploadReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *aPIUploadServer) Recv() (*UploadRequest, error) {
+	m := new(UploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _API_Download_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Download(m, &aPIDownloadServer{stream})
+}
+
+type API_DownloadServer interface {
+	Send(*DownloadReply) error
+	grpc.ServerStream
+}
+
+type aPIDownloadServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIDownloadServer) Send(m *DownloadReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _API_Events_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Events(m, &aPIEventsServer{stream})
+}
+
+type API_EventsServer interface {
+	Send(*EventsReply) error
+	grpc.ServerStream
+}
+
+type aPIEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIEventsServer) Send(m *EventsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _API_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "API",
 	HandlerType: (*APIServer)(nil),
@@ -199,25 +1025,106 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Deploy",
 			Handler:    _API_Deploy_Handler,
 		},
+		{
+			MethodName: "SetSecret",
+			Handler:    _API_SetSecret_Handler,
+		},
+		{
+			MethodName: "RemoveSecret",
+			Handler:    _API_RemoveSecret_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _API_History_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _API_Status_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Exec",
+			Handler:       _API_Exec_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Logs",
+			Handler:       _API_Logs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Upload",
+			Handler:       _API_Upload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Download",
+			Handler:       _API_Download_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Events",
+			Handler:       _API_Events_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "pb/pb.proto",
+	Metadata: "api/pb/pb.proto",
 }
 
-func init() { proto.RegisterFile("pb/pb.proto", fileDescriptor0) }
+func init() { proto.RegisterFile("api/pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 189 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0x48, 0xd2, 0x2f,
-	0x48, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0x92, 0xe7, 0x62, 0x77, 0x71, 0x0a, 0x2c, 0x4d,
-	0x2d, 0xaa, 0x14, 0x12, 0xe1, 0x62, 0x0d, 0x49, 0x4c, 0xca, 0x49, 0x95, 0x60, 0x54, 0x60, 0xd4,
-	0xe0, 0x0c, 0x62, 0x2d, 0x01, 0x71, 0x94, 0x8c, 0xb8, 0xb8, 0xc0, 0xd2, 0x41, 0xa9, 0x05, 0x39,
-	0x95, 0x42, 0x2a, 0x5c, 0xbc, 0x60, 0x35, 0xce, 0xf9, 0x79, 0x25, 0xa9, 0x79, 0x25, 0xc5, 0x50,
-	0xb5, 0xbc, 0x25, 0xc8, 0x82, 0x4a, 0xfa, 0x5c, 0xbc, 0x2e, 0xa9, 0x05, 0x39, 0xf9, 0x95, 0x41,
-	0xa9, 0x85, 0xa5, 0xa9, 0xc5, 0x25, 0x42, 0x72, 0x5c, 0x5c, 0x10, 0x81, 0xdc, 0xd4, 0xbc, 0x12,
-	0xa8, 0x1e, 0xae, 0x14, 0xb8, 0x88, 0x12, 0x2f, 0x17, 0x37, 0x4c, 0x43, 0x41, 0x4e, 0xa5, 0x51,
-	0x20, 0x17, 0xb3, 0x63, 0x80, 0xa7, 0x90, 0x02, 0x17, 0x2b, 0xc4, 0x65, 0x1c, 0x7a, 0x50, 0x37,
-	0x4a, 0x71, 0xeb, 0x21, 0x1c, 0xa3, 0xc4, 0x20, 0xa4, 0xc1, 0xc5, 0x06, 0xd1, 0x27, 0xc4, 0xa7,
-	0x87, 0x62, 0xa3, 0x14, 0x8f, 0x1e, 0x92, 0x81, 0x4a, 0x0c, 0x49, 0x6c, 0x60, 0xef, 0x1a, 0x03,
-	0x02, 0x00, 0x00, 0xff, 0xff, 0x52, 0x06, 0x87, 0x98, 0xfd, 0x00, 0x00, 0x00,
+	// 792 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0x5d, 0x6f, 0xe3, 0x44,
+	0x14, 0x8d, 0xd7, 0xb1, 0x13, 0x5f, 0xdb, 0x69, 0x35, 0x42, 0x2b, 0xcb, 0x5a, 0x2d, 0x61, 0xb4,
+	0x80, 0xc5, 0xa2, 0xd9, 0x6a, 0x79, 0x42, 0x3c, 0x95, 0xb4, 0x50, 0x24, 0x84, 0x8a, 0x53, 0xe0,
+	0x79, 0x6a, 0x0f, 0xb5, 0x25, 0xdb, 0x63, 0xec, 0x49, 0xbb, 0xf9, 0x11, 0xfc, 0x3c, 0x1e, 0xf9,
+	0x2f, 0x68, 0xbe, 0x12, 0x87, 0xa7, 0x0a, 0x1e, 0xcf, 0x99, 0x99, 0x3b, 0xf7, 0x1e, 0x9f, 0x39,
+	0x86, 0x33, 0xda, 0xd7, 0xef, 0xfa, 0xfb, 0x77, 0xfd, 0x3d, 0xe9, 0x07, 0x2e, 0x38, 0xfe, 0x18,
+	0x16, 0x57, 0xdf, 0xfe, 0xbc, 0x63, 0xc3, 0x1e, 0x7d, 0x04, 0xde, 0x1d, 0xbd, 0x6f, 0x58, 0xe2,
+	0xac, 0x9d, 0x2c, 0xc8, 0x3d, 0x21, 0x01, 0x7e, 0x0f, 0xa0, 0x96, 0x73, 0xd6, 0x37, 0x7b, 0xf4,
+	0x06, 0x62, 0xb5, 0x67, 0xc3, 0x3b, 0xc1, 0x3a, 0x31, 0x9a, 0xbd, 0xb1, 0x98, 0x92, 0xf8, 0x7b,
+	0x88, 0xaf, 0x58, 0xdf, 0xf0, 0x7d, 0xce, 0xfe, 0xd8, 0xb1, 0x51, 0xa0, 0xd7, 0x00, 0x9a, 0x68,
+	0x59, 0x27, 0xcc, 0x19, 0x28, 0x0f, 0x0c, 0x7a, 0x09, 0xfe, 0xe5, 0x4e, 0x54, 0x7c, 0x48, 0x5e,
+	0xa8, 0x35, 0x9f, 0x2a, 0x84, 0x63, 0x08, 0x6d, 0xa1, 0xbe, 0xd9, 0xe3, 0xaf, 0x21, 0xde, 0xb2,
+	0x62, 0x60, 0xc2, 0xd6, 0x45, 0x30, 0xff, 0x89, 0xb6, 0xb6, 0xe3, 0x79, 0x47, 0x5b, 0x26, 0xc7,
+	0xf8, 0x95, 0x36, 0x3b, 0x66, 0x4a, 0x79, 0x8f, 0x12, 0xc8, 0x4a, 0xf6, 0xa8, 0xac, 0x74, 0x0e,
+	0xab, 0x9b, 0x7a, 0x14, 0x7c, 0xb0, 0x2d, 0xe2, 0x2f, 0x21, 0x3a, 0x30, 0x72, 0xd2, 0x57, 0x10,
+	0xe4, 0xec, 0xb1, 0x1e, 0x6b, 0xde, 0xd9, 0x29, 0x83, 0xc1, 0x12, 0xf8, 0x0c, 0xe2, 0xad, 0xa0,
+	0x62, 0x37, 0xda, 0xe3, 0x1b, 0x08, 0x2d, 0x21, 0x4f, 0xa7, 0xb0, 0xdc, 0xf6, 0xac, 0xb8, 0xa1,
+	0x63, 0x65, 0x0e, 0x2f, 0x47, 0x83, 0x51, 0x02, 0x8b, 0x5b, 0xd6, 0x95, 0x75, 0xf7, 0x90, 0xbc,
+	0x58, 0xbb, 0x59, 0x90, 0x2f, 0x7a, 0x0d, 0xf1, 0x5f, 0x0e, 0x84, 0xd7, 0x1f, 0x58, 0x61, 0xc7,
+	0x7b, 0x05, 0x81, 0xd4, 0x94, 0xd6, 0x1d, 0x1b, 0x6c, 0x0f, 0x85, 0x25, 0xd0, 0x39, 0xb8, 0x9b,
+	0xb6, 0x34, 0x35, 0xdc, 0xa2, 0x2d, 0x25, 0x73, 0x27, 0xf6, 0x89, 0xbb, 0x76, 0xb2, 0x65, 0xee,
+	0x0a, 0xb1, 0x47, 0x6b, 0x08, 0x2f, 0x85, 0xa0, 0x45, 0xb5, 0x15, 0x65, 0xdd, 0x25, 0x73, 0xb5,
+	0x12, 0xd2, 0x23, 0x25, 0xe5, 0xd2, 0x6b, 0xde, 0xda, 0xc9, 0xa2, 0xdc, 0x1b, 0x15, 0xfb, 0x1a,
+	0x60, 0xd3, 0xf0, 0x91, 0xe9, 0x25, 0x5f, 0x1d, 0x83, 0xe2, 0xc0, 0xc8, 0x0f, 0x76, 0xc3, 0xea,
+	0x87, 0x4a, 0x24, 0x8b, 0xb5, 0x93, 0x79, 0xb9, 0x5f, 0x29, 0x24, 0xab, 0xfd, 0x56, 0x97, 0xa2,
+	0x4a, 0x96, 0x8a, 0xf6, 0x9e, 0x24, 0xc0, 0x1c, 0x02, 0x3d, 0x96, 0x94, 0xe6, 0x25, 0xf8, 0x5b,
+	0x51, 0xf2, 0x9d, 0xf6, 0x41, 0x94, 0xfb, 0xa3, 0x42, 0x86, 0x67, 0x83, 0xf6, 0x80, 0xe6, 0xd9,
+	0x30, 0x48, 0xfe, 0xfa, 0x43, 0x2d, 0x58, 0x69, 0xe6, 0xf2, 0x99, 0x42, 0x52, 0x62, 0xc9, 0x6f,
+	0x78, 0xc9, 0xd4, 0x5c, 0x5e, 0xbe, 0x64, 0x06, 0xe3, 0x3f, 0x1d, 0x08, 0x7f, 0xe4, 0x0f, 0xe3,
+	0xf3, 0x84, 0x94, 0x12, 0xd4, 0x5d, 0xa1, 0x1d, 0xe3, 0xe6, 0xde, 0x28, 0x81, 0xbc, 0xf7, 0x3b,
+	0xde, 0x34, 0xfc, 0xc9, 0xde, 0xfb, 0xbb, 0x42, 0x52, 0x9a, 0xbb, 0xba, 0x65, 0xa3, 0xa0, 0x6d,
+	0x3f, 0x1a, 0x45, 0x41, 0x1c, 0x18, 0xe9, 0xc9, 0x3b, 0x5a, 0x37, 0x4a, 0xcf, 0x20, 0x9f, 0x0b,
+	0x5a, 0x37, 0xf8, 0x1b, 0x08, 0x74, 0x3b, 0xff, 0x41, 0x00, 0xbc, 0x85, 0xf8, 0x97, 0xbe, 0xe1,
+	0xb4, 0x7c, 0xde, 0x34, 0x08, 0xe6, 0xb7, 0x54, 0x54, 0xc6, 0xfe, 0xf3, 0x9e, 0x8a, 0x4a, 0x19,
+	0x83, 0x0e, 0x6a, 0x90, 0x28, 0x77, 0x05, 0x55, 0x2f, 0xcb, 0x16, 0x95, 0xef, 0x61, 0x03, 0x67,
+	0x57, 0xfc, 0xa9, 0xfb, 0x5f, 0xb7, 0xe0, 0x4f, 0x20, 0x3e, 0x16, 0x91, 0x93, 0x9a, 0x6b, 0x9d,
+	0xe3, 0xb5, 0x9f, 0x43, 0x7c, 0xfd, 0x28, 0x33, 0xc2, 0xde, 0x72, 0x54, 0xd9, 0x99, 0xaa, 0x8c,
+	0x3f, 0x85, 0xd0, 0x6e, 0x34, 0x9a, 0x69, 0x68, 0x3a, 0xf1, 0x99, 0x42, 0xef, 0xff, 0x76, 0xc1,
+	0xbd, 0xbc, 0xfd, 0x01, 0xad, 0xc1, 0xd3, 0x21, 0xb6, 0x24, 0x26, 0xce, 0xd2, 0x90, 0x1c, 0x73,
+	0x0b, 0xcf, 0x50, 0x06, 0xbe, 0x8e, 0x12, 0xb4, 0x22, 0x27, 0xe1, 0x94, 0x46, 0x64, 0x9a, 0x31,
+	0x33, 0xf4, 0x16, 0x82, 0x2d, 0x13, 0x3a, 0x2d, 0xd0, 0x8a, 0x9c, 0x24, 0x4e, 0x1a, 0x91, 0x69,
+	0x8c, 0xcc, 0x10, 0x81, 0x28, 0x67, 0x2d, 0x7f, 0x64, 0xcf, 0xdc, 0xff, 0x16, 0x16, 0x26, 0x66,
+	0xd0, 0x19, 0x39, 0x8d, 0xa0, 0x34, 0x26, 0xd3, 0x04, 0xd2, 0x3d, 0xeb, 0x50, 0x91, 0x65, 0xa7,
+	0x71, 0x93, 0x46, 0x07, 0xac, 0x77, 0x7e, 0x06, 0x73, 0xf9, 0xc2, 0x50, 0x44, 0x26, 0xf9, 0x91,
+	0x02, 0x39, 0x3c, 0x3b, 0x3c, 0xcb, 0x9c, 0x0b, 0x07, 0xbd, 0x81, 0xb9, 0x34, 0x22, 0x8a, 0xc8,
+	0xe4, 0x79, 0xa4, 0x40, 0x0e, 0xee, 0xc4, 0xb3, 0x0b, 0x07, 0x7d, 0x01, 0xbe, 0x36, 0x07, 0x5a,
+	0x91, 0x13, 0xeb, 0xa5, 0x11, 0x99, 0xba, 0x66, 0x96, 0x39, 0xe8, 0x02, 0x96, 0xf6, 0xa3, 0xa3,
+	0x73, 0xf2, 0x2f, 0x13, 0xa5, 0x2b, 0x72, 0xe2, 0x08, 0x5b, 0x5d, 0x7f, 0x4b, 0xb4, 0x22, 0x27,
+	0x66, 0x48, 0x23, 0x32, 0xf9, 0xe6, 0x72, 0xef, 0xbd, 0xaf, 0xfe, 0x52, 0x5f, 0xfd, 0x33, 0x00,
+	0x3a, 0xc3, 0xe7, 0xdb, 0xb8, 0x06, 0x00, 0x00,
 }
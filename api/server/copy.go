@@ -0,0 +1,95 @@
+package server
+
+import (
+	"errors"
+	"io"
+
+	"github.com/quilt/quilt/api/pb"
+)
+
+// Upload extracts a tar archive into a directory of one of the containers on this
+// minion.  The first request in the stream names the container and directory, and
+// the rest carry the archive.
+func (s server) Upload(stream pb.API_UploadServer) error {
+	if s.dk == nil {
+		return errors.New("files can only be copied by minions")
+	}
+
+	if err := s.authenticated(stream.Context()); err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	dockerID, err := s.dockerID(req.Container)
+	if err != nil {
+		return err
+	}
+
+	tarReader, tarWriter := io.Pipe()
+	uploadErr := make(chan error, 1)
+	go func() {
+		err := s.dk.Upload(dockerID, req.Path, tarReader)
+		// Unblock the writes below if Docker stopped reading early.
+		tarReader.CloseWithError(err)
+		uploadErr <- err
+	}()
+
+	data := req.Tar
+	for {
+		if _, err := tarWriter.Write(data); err != nil {
+			break
+		}
+
+		next, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			tarWriter.CloseWithError(err)
+			<-uploadErr
+			return err
+		}
+		data = next.Tar
+	}
+	tarWriter.Close()
+
+	if err := <-uploadErr; err != nil {
+		return err
+	}
+	return stream.SendAndClose(&pb.UploadReply{})
+}
+
+// Download streams a tar archive of a path in one of the containers on this minion.
+func (s server) Download(req *pb.DownloadRequest, stream pb.API_DownloadServer) error {
+	if s.dk == nil {
+		return errors.New("files can only be copied by minions")
+	}
+
+	if err := s.authenticated(stream.Context()); err != nil {
+		return err
+	}
+
+	dockerID, err := s.dockerID(req.Container)
+	if err != nil {
+		return err
+	}
+
+	return s.dk.Download(dockerID, req.Path, downloadWriter{stream})
+}
+
+// A downloadWriter sends a tar archive to the client.
+type downloadWriter struct {
+	stream pb.API_DownloadServer
+}
+
+func (w downloadWriter) Write(p []byte) (int, error) {
+	// The caller may reuse `p` once Write returns.
+	data := append([]byte{}, p...)
+	if err := w.stream.Send(&pb.DownloadReply{Tar: data}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
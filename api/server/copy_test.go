@@ -0,0 +1,150 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockUploadStream struct {
+	grpc.ServerStream
+	ctx    context.Context // Defaults to the background context if nil.
+	reqs   []*pb.UploadRequest
+	closed bool
+}
+
+func (s *mockUploadStream) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *mockUploadStream) Recv() (*pb.UploadRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *mockUploadStream) SendAndClose(*pb.UploadReply) error {
+	s.closed = true
+	return nil
+}
+
+type mockDownloadStream struct {
+	grpc.ServerStream
+	ctx context.Context // Defaults to the background context if nil.
+	tar bytes.Buffer
+}
+
+func (s *mockDownloadStream) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *mockDownloadStream) Send(reply *pb.DownloadReply) error {
+	s.tar.Write(reply.Tar)
+	return nil
+}
+
+func TestUploadDownload(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	dockerID, err := dk.Run(docker.RunOptions{Name: "a"})
+	assert.NoError(t, err)
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.PrivateIP = "1.2.3.4"
+		view.Commit(self)
+
+		dbc := view.InsertContainer()
+		dbc.StitchID = "stitch"
+		dbc.DockerID = dockerID
+		dbc.Minion = "1.2.3.4"
+		view.Commit(dbc)
+		return nil
+	})
+	s := server{conn: conn, dk: &dk}
+
+	tarball, err := util.ToTar("file", 0644, "content")
+	assert.NoError(t, err)
+	tarBytes, err := ioutil.ReadAll(tarball)
+	assert.NoError(t, err)
+
+	// Split the archive across requests.
+	stream := &mockUploadStream{reqs: []*pb.UploadRequest{
+		{Container: "stitch", Path: "/tmp", Tar: tarBytes[:100]},
+		{Tar: tarBytes[100:]},
+	}}
+	assert.NoError(t, s.Upload(stream))
+	assert.True(t, stream.closed)
+	assert.Equal(t, "content", md.Containers[dockerID].Files["/tmp/file"])
+
+	download := &mockDownloadStream{}
+	err = s.Download(&pb.DownloadRequest{Container: "stitch", Path: "/tmp/file"},
+		download)
+	assert.NoError(t, err)
+
+	archive := tar.NewReader(&download.tar)
+	header, err := archive.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "file", header.Name)
+
+	err = s.Download(&pb.DownloadRequest{Container: "missing", Path: "/"},
+		&mockDownloadStream{})
+	assert.EqualError(t, err, "no running container with ID missing")
+
+	// Over TCP, only authenticated clients may copy files.
+	s.requireAuth = true
+	stream = &mockUploadStream{reqs: []*pb.UploadRequest{
+		{Container: "stitch", Path: "/tmp", Tar: tarBytes}}}
+	assert.EqualError(t, s.Upload(stream), "client is not authenticated")
+	assert.False(t, stream.closed)
+
+	stream.ctx = authenticatedContext()
+	assert.NoError(t, s.Upload(stream))
+	assert.True(t, stream.closed)
+
+	download = &mockDownloadStream{}
+	err = s.Download(&pb.DownloadRequest{Container: "stitch", Path: "/tmp/file"},
+		download)
+	assert.EqualError(t, err, "client is not authenticated")
+	assert.Zero(t, download.tar.Len())
+
+	download.ctx = authenticatedContext()
+	err = s.Download(&pb.DownloadRequest{Container: "stitch", Path: "/tmp/file"},
+		download)
+	assert.NoError(t, err)
+	assert.NotZero(t, download.tar.Len())
+	s.requireAuth = false
+
+	md.UploadError = true
+	stream = &mockUploadStream{reqs: []*pb.UploadRequest{
+		{Container: "stitch", Path: "/tmp", Tar: tarBytes}}}
+	assert.EqualError(t, s.Upload(stream), "upload error")
+	assert.False(t, stream.closed)
+
+	s = server{conn: conn}
+	assert.EqualError(t, s.Upload(&mockUploadStream{}),
+		"files can only be copied by minions")
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+)
+
+// Events streams the events recorded in this server's database, oldest first.  If
+// the request follows, events are then streamed as they're recorded or repeated,
+// until the client disconnects.
+func (s server) Events(req *pb.EventsRequest, stream pb.API_EventsServer) error {
+	trigg := s.conn.Trigger(db.EventTable)
+	defer trigg.Stop()
+
+	sent := map[int]time.Time{}
+	for {
+		events := unsentEvents(s.conn.SelectFromEvent(nil), sent)
+		if len(events) > 0 {
+			js, err := json.Marshal(events)
+			if err != nil {
+				return err
+			}
+
+			err = stream.Send(&pb.EventsReply{Events: string(js)})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !req.Follow {
+			return nil
+		}
+
+		select {
+		case <-trigg.C:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// unsentEvents returns the events that are new or were repeated since they were
+// recorded in `sent`, oldest first.  `sent` is updated to reflect that they were
+// sent, and to forget events that have been removed.
+func unsentEvents(events []db.Event, sent map[int]time.Time) []db.Event {
+	sort.Sort(db.EventSlice(events))
+
+	current := map[int]struct{}{}
+	var unsent []db.Event
+	for _, e := range events {
+		current[e.ID] = struct{}{}
+		if lastSeen, ok := sent[e.ID]; !ok || !lastSeen.Equal(e.LastSeen) {
+			unsent = append(unsent, e)
+			sent[e.ID] = e.LastSeen
+		}
+	}
+
+	for id := range sent {
+		if _, ok := current[id]; !ok {
+			delete(sent, id)
+		}
+	}
+	return unsent
+}
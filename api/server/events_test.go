@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockEventsStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	sync.Mutex
+	replies [][]db.Event
+}
+
+func (s *mockEventsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockEventsStream) Send(reply *pb.EventsReply) error {
+	var events []db.Event
+	if err := json.Unmarshal([]byte(reply.Events), &events); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.replies = append(s.replies, events)
+	return nil
+}
+
+func (s *mockEventsStream) objects() [][]string {
+	s.Lock()
+	defer s.Unlock()
+
+	var objects [][]string
+	for _, events := range s.replies {
+		var reply []string
+		for _, e := range events {
+			reply = append(reply, e.Object)
+		}
+		objects = append(objects, reply)
+	}
+	return objects
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn}
+	conn.RecordEvent(db.Event{Object: "machine/a"})
+	conn.RecordEvent(db.Event{Object: "machine/b"})
+
+	stream := &mockEventsStream{ctx: context.Background()}
+	assert.NoError(t, s.Events(&pb.EventsRequest{}, stream))
+	assert.Equal(t, [][]string{{"machine/a", "machine/b"}}, stream.objects())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream = &mockEventsStream{ctx: ctx}
+	done := make(chan error)
+	go func() {
+		done <- s.Events(&pb.EventsRequest{Follow: true}, stream)
+	}()
+
+	waitForReplies := func(n int) {
+		for i := 0; i < 100 && len(stream.objects()) < n; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitForReplies(1)
+	conn.RecordEvent(db.Event{Object: "machine/a"})
+	waitForReplies(2)
+	conn.RecordEvent(db.Event{Object: "machine/c"})
+	waitForReplies(3)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, [][]string{{"machine/a", "machine/b"}, {"machine/a"},
+		{"machine/c"}}, stream.objects())
+}
+
+func TestUnsentEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	sent := map[int]time.Time{}
+	events := []db.Event{{ID: 2, LastSeen: now}, {ID: 1, LastSeen: now}}
+	assert.Equal(t, []db.Event{{ID: 1, LastSeen: now}, {ID: 2, LastSeen: now}},
+		unsentEvents(events, sent))
+	assert.Equal(t, map[int]time.Time{1: now, 2: now}, sent)
+
+	later := now.Add(time.Second)
+	events = []db.Event{{ID: 2, LastSeen: later}, {ID: 3, LastSeen: now}}
+	assert.Equal(t, []db.Event{{ID: 3, LastSeen: now}, {ID: 2, LastSeen: later}},
+		unsentEvents(events, sent))
+	assert.Equal(t, map[int]time.Time{2: later, 3: now}, sent)
+
+	assert.Empty(t, unsentEvents(events, sent))
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+)
+
+// Exec runs a command in one of the containers on this minion.  The first request
+// in the stream names the container and command, and the rest carry its input and
+// terminal size.  Its output is streamed back, followed by its exit code.
+func (s server) Exec(stream pb.API_ExecServer) error {
+	if s.dk == nil {
+		return errors.New("commands can only be executed by minions")
+	}
+
+	if err := s.authenticated(stream.Context()); err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	dockerID, err := s.dockerID(req.Container)
+	if err != nil {
+		return err
+	}
+
+	stdinReader, stdinWriter := io.Pipe()
+	resize := make(chan docker.TTYSize)
+	go forwardExecInput(stream, stdinWriter, resize)
+
+	// Closing stdin unblocks forwardExecInput if the command exits without
+	// reading all of its input.
+	defer stdinReader.Close()
+
+	var sendLock sync.Mutex
+	send := func(stdout, stderr []byte) error {
+		return stream.Send(&pb.ExecReply{Stdout: stdout, Stderr: stderr})
+	}
+	opts := docker.ExecOptions{
+		Cmd:    req.Cmd,
+		Tty:    req.Tty,
+		Stdout: streamWriter{send, &sendLock, false},
+		Stderr: streamWriter{send, &sendLock, true},
+		Resize: resize,
+	}
+	if req.AttachStdin {
+		opts.Stdin = stdinReader
+	}
+
+	code, err := s.dk.Exec(dockerID, opts)
+	if err != nil {
+		return err
+	}
+
+	sendLock.Lock()
+	defer sendLock.Unlock()
+	return stream.Send(&pb.ExecReply{Exited: true, ExitCode: int32(code)})
+}
+
+// dockerID returns the Docker ID of the running container with the given Stitch ID.
+// The leader also tracks the Docker IDs of the containers on the other workers, so
+// only those scheduled on this machine are considered.
+func (s server) dockerID(stitchID string) (string, error) {
+	var dbcs []db.Container
+	s.conn.Txn(db.ContainerTable, db.MinionTable).Run(func(view db.Database) error {
+		self, err := view.MinionSelf()
+		if err != nil {
+			return err
+		}
+
+		dbcs = view.SelectFromContainer(func(dbc db.Container) bool {
+			return dbc.StitchID == stitchID && dbc.DockerID != "" &&
+				dbc.Minion == self.PrivateIP
+		})
+		return nil
+	})
+	if len(dbcs) == 0 {
+		return "", fmt.Errorf("no running container with ID %s", stitchID)
+	}
+	return dbcs[0].DockerID, nil
+}
+
+// forwardExecInput copies the input and terminal sizes sent by the client to a
+// running command, until the stream ends.
+func forwardExecInput(stream pb.API_ExecServer, stdin *io.PipeWriter,
+	resize chan<- docker.TTYSize) {
+
+	defer close(resize)
+	defer stdin.Close()
+
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return
+		}
+
+		if req.Height > 0 && req.Width > 0 {
+			size := docker.TTYSize{Height: int(req.Height), Width: int(req.Width)}
+			select {
+			case resize <- size:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+
+		if len(req.Stdin) > 0 {
+			if _, err := stdin.Write(req.Stdin); err != nil {
+				return
+			}
+		}
+
+		if req.CloseStdin {
+			stdin.Close()
+		}
+	}
+}
+
+// A streamWriter sends the output of a container to the client, as either stdout
+// or stderr.
+type streamWriter struct {
+	send   func(stdout, stderr []byte) error
+	lock   *sync.Mutex
+	stderr bool
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	// The caller may reuse `p` once Write returns.
+	data := append([]byte{}, p...)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var err error
+	if w.stderr {
+		err = w.send(nil, data)
+	} else {
+		err = w.send(data, nil)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
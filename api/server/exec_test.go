@@ -0,0 +1,144 @@
+package server
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockExecStream struct {
+	grpc.ServerStream
+
+	ctx  context.Context
+	reqs chan *pb.ExecRequest
+
+	sync.Mutex
+	replies []pb.ExecReply
+}
+
+func newMockExecStream(reqs ...*pb.ExecRequest) *mockExecStream {
+	stream := &mockExecStream{
+		ctx:  context.Background(),
+		reqs: make(chan *pb.ExecRequest, len(reqs)),
+	}
+	for _, req := range reqs {
+		stream.reqs <- req
+	}
+	close(stream.reqs)
+	return stream
+}
+
+func (s *mockExecStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockExecStream) Recv() (*pb.ExecRequest, error) {
+	req, ok := <-s.reqs
+	if !ok {
+		return nil, io.EOF
+	}
+	return req, nil
+}
+
+func (s *mockExecStream) Send(reply *pb.ExecReply) error {
+	s.Lock()
+	defer s.Unlock()
+	s.replies = append(s.replies, *reply)
+	return nil
+}
+
+func (s *mockExecStream) stdout() string {
+	s.Lock()
+	defer s.Unlock()
+
+	var out string
+	for _, reply := range s.replies {
+		out += string(reply.Stdout)
+	}
+	return out
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	dockerID, err := dk.Run(docker.RunOptions{Name: "a"})
+	assert.NoError(t, err)
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.PrivateIP = "1.2.3.4"
+		view.Commit(self)
+
+		dbc := view.InsertContainer()
+		dbc.StitchID = "stitch"
+		dbc.DockerID = dockerID
+		dbc.Minion = "1.2.3.4"
+		view.Commit(dbc)
+
+		// The leader tracks the containers on other workers too.
+		dbc = view.InsertContainer()
+		dbc.StitchID = "remote"
+		dbc.DockerID = dockerID
+		dbc.Minion = "5.6.7.8"
+		view.Commit(dbc)
+		return nil
+	})
+	s := server{conn: conn, dk: &dk}
+
+	md.ExecExitCode = 2
+	stream := newMockExecStream(
+		&pb.ExecRequest{Container: "stitch", Cmd: []string{"cat"},
+			AttachStdin: true},
+		&pb.ExecRequest{Stdin: []byte("hello ")},
+		&pb.ExecRequest{Stdin: []byte("world"), CloseStdin: true})
+	assert.NoError(t, s.Exec(stream))
+	assert.Equal(t, "hello world", stream.stdout())
+	assert.Equal(t, pb.ExecReply{Exited: true, ExitCode: 2},
+		stream.replies[len(stream.replies)-1])
+	assert.Equal(t, []string{"cat"}, md.Executions[dockerID])
+
+	// Commands run without input as well.
+	stream = newMockExecStream(&pb.ExecRequest{Container: "stitch",
+		Cmd: []string{"ls"}})
+	assert.NoError(t, s.Exec(stream))
+	assert.Equal(t, []pb.ExecReply{{Exited: true, ExitCode: 2}}, stream.replies)
+
+	stream = newMockExecStream(&pb.ExecRequest{Container: "missing",
+		Cmd: []string{"ls"}})
+	assert.EqualError(t, s.Exec(stream), "no running container with ID missing")
+
+	stream = newMockExecStream(&pb.ExecRequest{Container: "remote",
+		Cmd: []string{"ls"}})
+	assert.EqualError(t, s.Exec(stream), "no running container with ID remote")
+
+	// Over TCP, only authenticated clients may run commands.
+	s.requireAuth = true
+	stream = newMockExecStream(&pb.ExecRequest{Container: "stitch",
+		Cmd: []string{"ls"}})
+	assert.EqualError(t, s.Exec(stream), "client is not authenticated")
+	assert.Empty(t, stream.replies)
+
+	stream.ctx = authenticatedContext()
+	assert.NoError(t, s.Exec(stream))
+	s.requireAuth = false
+
+	md.CreateExecError = true
+	stream = newMockExecStream(&pb.ExecRequest{Container: "stitch",
+		Cmd: []string{"ls"}}, &pb.ExecRequest{Height: 24, Width: 80})
+	assert.EqualError(t, s.Exec(stream), "create exec error")
+
+	s = server{conn: conn}
+	assert.EqualError(t, s.Exec(newMockExecStream()),
+		"commands can only be executed by minions")
+}
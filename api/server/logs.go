@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/minion/docker"
+)
+
+// The name of the minion's own container.
+const minionContainer = "minion"
+
+// Logs streams the logs of one of the containers on this minion, or of the minion
+// itself if no container is given.  Either may contain secrets, so clients must be
+// authenticated.
+func (s server) Logs(req *pb.LogsRequest, stream pb.API_LogsServer) error {
+	if s.dk == nil {
+		return errors.New("logs can only be fetched from minions")
+	}
+
+	if err := s.authenticated(stream.Context()); err != nil {
+		return err
+	}
+
+	dockerID := minionContainer
+	if req.Container != "" {
+		var err error
+		if dockerID, err = s.dockerID(req.Container); err != nil {
+			return err
+		}
+	}
+
+	opts := docker.LogsOptions{
+		Tail:       req.Tail,
+		Follow:     req.Follow,
+		Timestamps: req.Timestamps,
+		Context:    stream.Context(),
+	}
+	if req.Since != 0 {
+		opts.Since = time.Unix(req.Since, 0)
+	}
+
+	var sendLock sync.Mutex
+	send := func(stdout, stderr []byte) error {
+		return stream.Send(&pb.LogsReply{Stdout: stdout, Stderr: stderr})
+	}
+	opts.Stdout = streamWriter{send, &sendLock, false}
+	opts.Stderr = streamWriter{send, &sendLock, true}
+	return s.dk.Logs(dockerID, opts)
+}
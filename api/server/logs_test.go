@@ -0,0 +1,98 @@
+package server
+
+import (
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockLogsStream struct {
+	grpc.ServerStream
+
+	ctx context.Context // Defaults to the background context if nil.
+
+	sync.Mutex
+	stdout string
+}
+
+func (s *mockLogsStream) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *mockLogsStream) Send(reply *pb.LogsReply) error {
+	s.Lock()
+	defer s.Unlock()
+	s.stdout += string(reply.Stdout)
+	return nil
+}
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	dockerID, err := dk.Run(docker.RunOptions{Name: "a"})
+	assert.NoError(t, err)
+	md.ContainerLogs[dockerID] = "container logs\n"
+	md.ContainerLogs["minion"] = "minion logs\n"
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.PrivateIP = "1.2.3.4"
+		view.Commit(self)
+
+		dbc := view.InsertContainer()
+		dbc.StitchID = "stitch"
+		dbc.DockerID = dockerID
+		dbc.Minion = "1.2.3.4"
+		view.Commit(dbc)
+		return nil
+	})
+	s := server{conn: conn, dk: &dk}
+
+	stream := &mockLogsStream{}
+	err = s.Logs(&pb.LogsRequest{Container: "stitch", Tail: "10"}, stream)
+	assert.NoError(t, err)
+	assert.Equal(t, "container logs\n", stream.stdout)
+
+	stream = &mockLogsStream{}
+	assert.NoError(t, s.Logs(&pb.LogsRequest{Since: 1}, stream))
+	assert.Equal(t, "minion logs\n", stream.stdout)
+
+	err = s.Logs(&pb.LogsRequest{Container: "missing"}, &mockLogsStream{})
+	assert.EqualError(t, err, "no running container with ID missing")
+
+	// Over TCP, only authenticated clients may fetch the logs of the containers or
+	// of the minion.
+	s.requireAuth = true
+	stream = &mockLogsStream{}
+	err = s.Logs(&pb.LogsRequest{Container: "stitch"}, stream)
+	assert.EqualError(t, err, "client is not authenticated")
+	assert.EqualError(t, s.Logs(&pb.LogsRequest{}, stream),
+		"client is not authenticated")
+	assert.Empty(t, stream.stdout)
+
+	stream = &mockLogsStream{ctx: authenticatedContext()}
+	assert.NoError(t, s.Logs(&pb.LogsRequest{}, stream))
+	assert.Equal(t, "minion logs\n", stream.stdout)
+	s.requireAuth = false
+
+	md.LogsError = true
+	err = s.Logs(&pb.LogsRequest{Container: "stitch"}, &mockLogsStream{})
+	assert.EqualError(t, err, "logs error")
+
+	s = server{conn: conn}
+	err = s.Logs(&pb.LogsRequest{}, &mockLogsStream{})
+	assert.EqualError(t, err, "logs can only be fetched from minions")
+}
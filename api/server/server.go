@@ -7,34 +7,71 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
 	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/auth"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/stitch"
 
 	"github.com/docker/distribution/reference"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 
 	log "github.com/Sirupsen/logrus"
 )
 
 type server struct {
 	conn db.Conn
+
+	// The Docker daemon that commands are executed with.  Only set on minions.
+	dk *docker.Client
+
+	// Whether clients must authenticate before accessing containers, which they
+	// must when the server listens over TCP.
+	requireAuth bool
+}
+
+// The number of deployments kept in the history.
+const maxRevisions = 20
+
+// Run accepts incoming `quiltctl` connections and responds to them.  Clients that
+// connect over TCP must authenticate with `creds`.
+func Run(conn db.Conn, listenAddr string, creds auth.Credentials) error {
+	return run(server{conn: conn}, listenAddr, creds)
+}
+
+// RunMinion is like Run, but also executes commands in the containers managed by
+// `dk` on behalf of `quilt exec`.
+func RunMinion(conn db.Conn, listenAddr string, dk docker.Client,
+	creds auth.Credentials) error {
+	return run(server{conn: conn, dk: &dk}, listenAddr, creds)
 }
 
-// Run accepts incoming `quiltctl` connections and responds to them.
-func Run(conn db.Conn, listenAddr string) error {
+func run(apiServer server, listenAddr string, creds auth.Credentials) error {
 	proto, addr, err := api.ParseListenAddress(listenAddr)
 	if err != nil {
 		return err
 	}
 
+	// Only local users can reach the Unix socket, but anyone can reach a TCP one.
+	var opts []grpc.ServerOption
+	if proto == "tcp" {
+		credsOpt, err := creds.ServerOption()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, credsOpt)
+		apiServer.requireAuth = true
+	}
+
 	var sock net.Listener
-	apiServer := server{conn}
 	for {
 		sock, err = net.Listen(proto, addr)
 
@@ -56,13 +93,29 @@ func Run(conn db.Conn, listenAddr string) error {
 		os.Exit(0)
 	}(sigc)
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(opts...)
 	pb.RegisterAPIServer(s, apiServer)
 	s.Serve(sock)
 
 	return nil
 }
 
+// authenticated returns an error if the server requires authentication, and the
+// client of the request `ctx` didn't present a verified certificate.
+func (s server) authenticated(ctx context.Context) error {
+	if !s.requireAuth {
+		return nil
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		if ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			return nil
+		}
+	}
+	return errors.New("client is not authenticated")
+}
+
 func (s server) Query(cts context.Context, query *pb.DBQuery) (*pb.QueryReply, error) {
 	var rows interface{}
 	switch db.TableType(query.Table) {
@@ -78,6 +131,15 @@ func (s server) Query(cts context.Context, query *pb.DBQuery) (*pb.QueryReply, e
 		rows = s.conn.SelectFromLabel(nil)
 	case db.ClusterTable:
 		rows = s.conn.SelectFromCluster(nil)
+	case db.TrafficTable:
+		rows = s.conn.SelectFromTraffic(nil)
+	case db.PortTrafficTable:
+		rows = s.conn.SelectFromPortTraffic(nil)
+	case db.StatsTable:
+		rows = s.conn.SelectFromStats(nil)
+	case db.SecretTable:
+		// The values of secrets are omitted from their JSON.
+		rows = s.conn.SelectFromSecret(nil)
 	default:
 		return nil, fmt.Errorf("unrecognized table: %s", query.Table)
 	}
@@ -105,7 +167,13 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 		}
 	}
 
-	err = s.conn.Txn(db.ClusterTable).Run(func(view db.Database) error {
+	author := deployReq.Author
+	if p, ok := peer.FromContext(cts); ok && author == "" && p.Addr != nil {
+		author = p.Addr.String()
+	}
+
+	err = s.conn.Txn(db.ClusterTable,
+		db.RevisionTable).Run(func(view db.Database) error {
 		cluster, err := view.GetCluster()
 		if err != nil {
 			cluster = view.InsertCluster()
@@ -113,6 +181,7 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 
 		cluster.Spec = stitch.String()
 		view.Commit(cluster)
+		recordRevision(view, cluster.Spec, author)
 		return nil
 	})
 	if err != nil {
@@ -131,3 +200,94 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 
 	return &pb.DeployReply{}, nil
 }
+
+// recordRevision adds `spec` to the deployment history, unless it's already the
+// latest revision.  Only the most recent maxRevisions are kept.
+func recordRevision(view db.Database, spec, author string) {
+	revs := db.RevisionSlice(view.SelectFromRevision(nil))
+	sort.Sort(revs)
+
+	hash := specHash(spec)
+	number := 1
+	if len(revs) > 0 {
+		latest := revs[len(revs)-1]
+		if latest.Hash == hash {
+			return
+		}
+		number = latest.Number + 1
+	}
+
+	rev := view.InsertRevision()
+	rev.Number = number
+	rev.Hash = hash
+	rev.Author = author
+	rev.Time = time.Now()
+	rev.Spec = spec
+	view.Commit(rev)
+
+	for i := 0; i < len(revs)+1-maxRevisions; i++ {
+		view.Remove(revs[i])
+	}
+}
+
+// History returns the deployments recorded by Deploy, oldest first.
+func (s server) History(cts context.Context, req *pb.HistoryRequest) (
+	*pb.HistoryReply, error) {
+
+	revs := db.RevisionSlice(s.conn.SelectFromRevision(nil))
+	sort.Sort(revs)
+
+	js, err := json.Marshal(revs)
+	if err != nil {
+		return &pb.HistoryReply{}, err
+	}
+	return &pb.HistoryReply{Revisions: string(js)}, nil
+}
+
+// SetSecret stores a secret, replacing any secret of the same name.
+func (s server) SetSecret(cts context.Context, req *pb.SecretRequest) (
+	*pb.SecretReply, error) {
+
+	if req.Name == "" {
+		return &pb.SecretReply{}, errors.New("secret name must not be empty")
+	}
+
+	err := s.conn.Txn(db.SecretTable).Run(func(view db.Database) error {
+		secrets := view.SelectFromSecret(func(secret db.Secret) bool {
+			return secret.Name == req.Name
+		})
+
+		var secret db.Secret
+		if len(secrets) > 0 {
+			secret = secrets[0]
+		} else {
+			secret = view.InsertSecret()
+		}
+
+		secret.Name = req.Name
+		secret.Value = req.Value
+		view.Commit(secret)
+		return nil
+	})
+	return &pb.SecretReply{}, err
+}
+
+// RemoveSecret deletes a secret.
+func (s server) RemoveSecret(cts context.Context, req *pb.SecretRequest) (
+	*pb.SecretReply, error) {
+
+	err := s.conn.Txn(db.SecretTable).Run(func(view db.Database) error {
+		secrets := view.SelectFromSecret(func(secret db.Secret) bool {
+			return secret.Name == req.Name
+		})
+		if len(secrets) == 0 {
+			return fmt.Errorf("no such secret: %s", req.Name)
+		}
+
+		for _, secret := range secrets {
+			view.Remove(secret)
+		}
+		return nil
+	})
+	return &pb.SecretReply{}, err
+}
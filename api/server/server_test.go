@@ -1,10 +1,16 @@
 package server
 
 import (
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 
 	"github.com/quilt/quilt/api/pb"
 	"github.com/quilt/quilt/db"
@@ -20,6 +26,34 @@ func checkQuery(t *testing.T, s server, table db.TableType, exp string) {
 	assert.Equal(t, exp, reply.TableContents, "Wrong query response")
 }
 
+// authenticatedContext returns the context of a request from a client that presented
+// a verified certificate.
+func authenticatedContext() context.Context {
+	state := tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{&x509.Certificate{}}},
+	}
+	return peer.NewContext(context.Background(),
+		&peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestAuthenticated(t *testing.T) {
+	t.Parallel()
+
+	s := server{requireAuth: true}
+	assert.NoError(t, s.authenticated(authenticatedContext()))
+
+	unverified := peer.NewContext(context.Background(),
+		&peer.Peer{AuthInfo: credentials.TLSInfo{}})
+	assert.EqualError(t, s.authenticated(unverified),
+		"client is not authenticated")
+	assert.EqualError(t, s.authenticated(context.Background()),
+		"client is not authenticated")
+
+	// Clients of the Unix socket don't authenticate.
+	s = server{}
+	assert.NoError(t, s.authenticated(context.Background()))
+}
+
 func TestMachineResponse(t *testing.T) {
 	t.Parallel()
 
@@ -41,7 +75,7 @@ func TestMachineResponse(t *testing.T) {
 		`"CloudID":"","PublicIP":"8.8.8.8","PrivateIP":"9.9.9.9",` +
 		`"Connected":false}]`
 
-	checkQuery(t, server{conn}, db.MachineTable, exp)
+	checkQuery(t, server{conn: conn}, db.MachineTable, exp)
 }
 
 func TestContainerResponse(t *testing.T) {
@@ -62,7 +96,96 @@ func TestContainerResponse(t *testing.T) {
 	exp := `[{"DockerID":"docker-id","Image":"image","Command":["cmd","arg"],` +
 		`"Labels":["labelA","labelB"],"Created":"0001-01-01T00:00:00Z"}]`
 
-	checkQuery(t, server{conn}, db.ContainerTable, exp)
+	checkQuery(t, server{conn: conn}, db.ContainerTable, exp)
+}
+
+func TestTrafficResponse(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		tr := view.InsertTraffic()
+		tr.From = "a"
+		tr.To = "b"
+		tr.Packets = 1
+		tr.Bytes = 2
+		view.Commit(tr)
+
+		return nil
+	})
+
+	exp := `[{"From":"a","To":"b","MinPort":0,"MaxPort":0,"Packets":1,"Bytes":2}]`
+	checkQuery(t, server{conn: conn}, db.TrafficTable, exp)
+}
+
+func TestPortTrafficResponse(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		port := view.InsertPortTraffic()
+		port.StitchID = "1"
+		port.Minion = "10.0.0.1"
+		port.RxBytes = 2
+		view.Commit(port)
+
+		return nil
+	})
+
+	exp := `[{"StitchID":"1","Minion":"10.0.0.1","Port":"","RxPackets":0,` +
+		`"RxBytes":2,"TxPackets":0,"TxBytes":0}]`
+	checkQuery(t, server{conn: conn}, db.PortTrafficTable, exp)
+}
+
+func TestStatsResponse(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		stats := view.InsertStats()
+		stats.StitchID = "a"
+		stats.Minion = "1.2.3.4"
+		stats.CPUPercent = 1.5
+		stats.MemoryUsage = 2
+		view.Commit(stats)
+
+		return nil
+	})
+
+	exp := `[{"StitchID":"a","Minion":"1.2.3.4","CPUPercent":1.5,` +
+		`"MemoryUsage":2,"MemoryLimit":0,"NetworkRx":0,"NetworkTx":0,` +
+		`"Time":"0001-01-01T00:00:00Z"}]`
+	checkQuery(t, server{conn: conn}, db.StatsTable, exp)
+}
+
+func TestSecrets(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn}
+	ctx := context.Background()
+
+	_, err := s.SetSecret(ctx, &pb.SecretRequest{Value: "value"})
+	assert.EqualError(t, err, "secret name must not be empty")
+
+	_, err = s.SetSecret(ctx, &pb.SecretRequest{Name: "a", Value: "1"})
+	assert.NoError(t, err)
+	_, err = s.SetSecret(ctx, &pb.SecretRequest{Name: "a", Value: "2"})
+	assert.NoError(t, err)
+
+	secrets := conn.SelectFromSecret(nil)
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, "2", secrets[0].Value)
+
+	// Values are never sent back.
+	checkQuery(t, s, db.SecretTable, `[{"Name":"a"}]`)
+
+	_, err = s.RemoveSecret(ctx, &pb.SecretRequest{Name: "b"})
+	assert.EqualError(t, err, "no such secret: b")
+
+	_, err = s.RemoveSecret(ctx, &pb.SecretRequest{Name: "a"})
+	assert.NoError(t, err)
+	assert.Empty(t, conn.SelectFromSecret(nil))
 }
 
 func TestBadDeployment(t *testing.T) {
@@ -146,6 +269,61 @@ func TestDeploy(t *testing.T) {
 	assert.Equal(t, exp, actual)
 }
 
+func TestHistory(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn}
+	ctx := context.Background()
+
+	deploy := func(namespace, author string) {
+		_, err := s.Deploy(ctx, &pb.DeployRequest{
+			Deployment: fmt.Sprintf(`{"Namespace": "%s"}`, namespace),
+			Author:     author,
+		})
+		assert.NoError(t, err)
+	}
+
+	history := func() []db.Revision {
+		reply, err := s.History(ctx, &pb.HistoryRequest{})
+		assert.NoError(t, err)
+
+		var revs []db.Revision
+		assert.NoError(t, json.Unmarshal([]byte(reply.Revisions), &revs))
+		return revs
+	}
+
+	assert.Empty(t, history())
+
+	deploy("a", "laptop")
+	deploy("b", "desktop")
+
+	revs := history()
+	assert.Len(t, revs, 2)
+	assert.Equal(t, 1, revs[0].Number)
+	assert.Equal(t, "laptop", revs[0].Author)
+	assert.Equal(t, 2, revs[1].Number)
+	assert.Equal(t, "desktop", revs[1].Author)
+	assert.False(t, revs[1].Time.IsZero())
+
+	spec, err := stitch.FromJSON(revs[1].Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", spec.Namespace)
+	assert.Equal(t, fmt.Sprintf("%x", sha1.Sum([]byte(revs[1].Spec))),
+		revs[1].Hash)
+
+	// Redeploying the current spec doesn't add a revision.
+	deploy("b", "laptop")
+	assert.Len(t, history(), 2)
+
+	// Only the most recent revisions are kept.
+	for i := 0; i < maxRevisions; i++ {
+		deploy(fmt.Sprintf("ns%d", i), "laptop")
+	}
+	revs = history()
+	assert.Len(t, revs, maxRevisions)
+	assert.Equal(t, 3, revs[0].Number)
+	assert.Equal(t, maxRevisions+2, revs[maxRevisions-1].Number)
+}
+
 func TestVagrantDeployment(t *testing.T) {
 	conn := db.New()
 	s := server{conn: conn}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/stitch"
+	"github.com/quilt/quilt/util"
+
+	"golang.org/x/net/context"
+)
+
+// Status reports what this server's database is still waiting on before the
+// deployment converges.  The daemon tracks the machines, and the lead minion
+// tracks the containers, so clients must ask both.
+func (s server) Status(cts context.Context, req *pb.StatusRequest) (
+	*pb.StatusReply, error) {
+
+	reply := &pb.StatusReply{}
+	err := s.conn.Txn(db.ClusterTable, db.MachineTable, db.MinionTable,
+		db.EtcdTable, db.ContainerTable).Run(func(view db.Database) error {
+
+		if cluster, err := view.GetCluster(); err == nil {
+			reply.SpecHash = specHash(cluster.Spec)
+			spec, err := stitch.FromJSON(cluster.Spec)
+			if err != nil {
+				return err
+			}
+			reply.Pending = machinesPending(spec,
+				view.SelectFromMachine(nil))
+			return nil
+		}
+
+		self, err := view.MinionSelf()
+		switch {
+		case err != nil:
+			reply.Pending = []string{"nothing has been deployed"}
+		case self.Role != db.Master || !view.EtcdLeader():
+			reply.Pending = []string{"not the lead minion"}
+		case self.Spec == "":
+			reply.Pending = []string{"waiting for the deployment"}
+		default:
+			reply.SpecHash = specHash(self.Spec)
+			spec, err := stitch.FromJSON(self.Spec)
+			if err != nil {
+				return err
+			}
+			reply.Pending = containersPending(spec,
+				view.SelectFromContainer(nil))
+		}
+		return nil
+	})
+	if err != nil {
+		return &pb.StatusReply{}, err
+	}
+	return reply, nil
+}
+
+// machinesPending describes the machines in `spec` that aren't yet booted and
+// connected to the daemon.
+func machinesPending(spec stitch.Stitch, dbms []db.Machine) []string {
+	byID := map[string]db.Machine{}
+	for _, dbm := range dbms {
+		byID[dbm.StitchID] = dbm
+	}
+
+	var pending []string
+	for _, m := range spec.Machines {
+		var problem string
+		dbm, ok := byID[m.ID]
+		switch {
+		case !ok || dbm.CloudID == "":
+			problem = "booting"
+		case dbm.PublicIP == "":
+			problem = "waiting for a public IP"
+		case !dbm.Connected:
+			problem = "connecting"
+		default:
+			continue
+		}
+		pending = append(pending, fmt.Sprintf("machine %s (%s): %s",
+			util.ShortUUID(m.ID), m.Role, problem))
+	}
+	return pending
+}
+
+// containersPending describes the containers in `spec` that aren't yet scheduled
+// and running with the latest configuration.
+func containersPending(spec stitch.Stitch, dbcs []db.Container) []string {
+	byID := map[string]db.Container{}
+	for _, dbc := range dbcs {
+		byID[dbc.StitchID] = dbc
+	}
+
+	var pending []string
+	for _, c := range spec.Containers {
+		var problem string
+		dbc, ok := byID[c.ID]
+		switch {
+		case !ok:
+			problem = "not created yet"
+		case dbc.Outdated:
+			problem = "waiting to be updated"
+		case dbc.Minion == "":
+			problem = "not scheduled"
+		case dbc.IP == "":
+			problem = "waiting for an IP"
+		case dbc.DockerID == "" || dbc.Status != "running":
+			problem = "not running"
+		default:
+			continue
+		}
+		pending = append(pending, fmt.Sprintf("container %s (%s): %s",
+			util.ShortUUID(c.ID), c.Image, problem))
+	}
+	return pending
+}
+
+func specHash(spec string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(spec)))
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/quilt/quilt/api/pb"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/stitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusDaemon(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn}
+
+	reply, err := s.Status(context.Background(), &pb.StatusRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nothing has been deployed"}, reply.Pending)
+	assert.Empty(t, reply.SpecHash)
+
+	spec := `{"Machines": [{"ID": "1", "Role": "Master"},
+		{"ID": "2", "Role": "Worker"}]}`
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		cluster := view.InsertCluster()
+		cluster.Spec = spec
+		view.Commit(cluster)
+
+		m := view.InsertMachine()
+		m.StitchID = "1"
+		m.CloudID = "cloud"
+		m.PublicIP = "1.2.3.4"
+		m.Connected = true
+		view.Commit(m)
+		return nil
+	})
+
+	reply, err = s.Status(context.Background(), &pb.StatusRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, specHash(spec), reply.SpecHash)
+	assert.Equal(t, []string{"machine 2 (Worker): booting"}, reply.Pending)
+}
+
+func TestStatusMinion(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn}
+
+	spec := `{"Containers": [{"ID": "1", "Image": "a"}]}`
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.Role = db.Worker
+		view.Commit(self)
+		return nil
+	})
+
+	pending := func() []string {
+		reply, err := s.Status(context.Background(), &pb.StatusRequest{})
+		assert.NoError(t, err)
+		return reply.Pending
+	}
+	assert.Equal(t, []string{"not the lead minion"}, pending())
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self, _ := view.MinionSelf()
+		self.Role = db.Master
+		view.Commit(self)
+
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+	assert.Equal(t, []string{"waiting for the deployment"}, pending())
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self, _ := view.MinionSelf()
+		self.Spec = spec
+		view.Commit(self)
+		return nil
+	})
+	assert.Equal(t, []string{"container 1 (a): not created yet"}, pending())
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertContainer()
+		dbc.StitchID = "1"
+		dbc.Minion = "10.0.0.1"
+		dbc.IP = "10.1.0.1"
+		dbc.DockerID = "docker"
+		dbc.Status = "running"
+		view.Commit(dbc)
+		return nil
+	})
+	assert.Empty(t, pending())
+}
+
+func TestMachinesPending(t *testing.T) {
+	t.Parallel()
+
+	spec := stitch.Stitch{Machines: []stitch.Machine{{ID: "1", Role: "Worker"}}}
+	check := func(dbm db.Machine, exp string) {
+		dbm.StitchID = "1"
+		pending := machinesPending(spec, []db.Machine{dbm})
+		if exp == "" {
+			assert.Empty(t, pending)
+		} else {
+			assert.Equal(t, []string{"machine 1 (Worker): " + exp}, pending)
+		}
+	}
+
+	check(db.Machine{}, "booting")
+	check(db.Machine{CloudID: "c"}, "waiting for a public IP")
+	check(db.Machine{CloudID: "c", PublicIP: "ip"}, "connecting")
+	check(db.Machine{CloudID: "c", PublicIP: "ip", Connected: true}, "")
+}
+
+func TestContainersPending(t *testing.T) {
+	t.Parallel()
+
+	spec := stitch.Stitch{Containers: []stitch.Container{{ID: "1", Image: "a"}}}
+	check := func(dbc db.Container, exp string) {
+		dbc.StitchID = "1"
+		pending := containersPending(spec, []db.Container{dbc})
+		if exp == "" {
+			assert.Empty(t, pending)
+		} else {
+			assert.Equal(t, []string{"container 1 (a): " + exp}, pending)
+		}
+	}
+
+	running := db.Container{Minion: "m", IP: "ip", DockerID: "d", Status: "running"}
+	check(running, "")
+
+	outdated := running
+	outdated.Outdated = true
+	check(outdated, "waiting to be updated")
+
+	pending := containersPending(spec, nil)
+	assert.Equal(t, []string{"container 1 (a): not created yet"}, pending)
+
+	check(db.Container{}, "not scheduled")
+	check(db.Container{Minion: "m"}, "waiting for an IP")
+	check(db.Container{Minion: "m", IP: "ip", Status: "running"}, "not running")
+	check(db.Container{Minion: "m", IP: "ip", DockerID: "d", Status: "exited"},
+		"not running")
+}
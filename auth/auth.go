@@ -0,0 +1,287 @@
+// Package auth creates and loads the TLS credentials that the daemon, quiltctl, and
+// the minions use to authenticate each other.  The daemon keeps a certificate
+// authority, and issues each machine it boots its own certificate signed by it.
+// Machines may only authenticate as servers, so that only holders of the daemon's
+// credentials may configure minions or access their containers.
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/quilt/quilt/util"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// The files in a credentials directory.  The certificate authority's key is only
+// kept by the daemon.
+const (
+	CAFile    = "ca.pem"
+	CAKeyFile = "ca_key.pem"
+	CertFile  = "cert.pem"
+	KeyFile   = "key.pem"
+)
+
+// MinionDir is where the credentials are installed on the machines.
+const MinionDir = "/etc/quilt/tls"
+
+// DefaultDir is where the daemon and quiltctl keep the credentials.
+var DefaultDir = filepath.Join(os.Getenv("HOME"), ".quilt", "tls")
+
+// The name in the certificates, which peers are verified against instead of their
+// addresses.
+const serverName = "quilt"
+
+// How long generated certificates are valid for.
+const certLifetime = 10 * 365 * 24 * time.Hour
+
+// Credentials hold a PEM encoded certificate and its private key, and the
+// certificate of the authority that peers must be signed by.
+type Credentials struct {
+	CA   string
+	Cert string
+	Key  string
+}
+
+// Load reads the credentials in `dir`.
+func Load(dir string) (Credentials, error) {
+	var creds Credentials
+	for _, file := range []struct {
+		name string
+		dst  *string
+	}{{CAFile, &creds.CA}, {CertFile, &creds.Cert}, {KeyFile, &creds.Key}} {
+		contents, err := util.ReadFile(filepath.Join(dir, file.name))
+		if err != nil {
+			return Credentials{}, err
+		}
+		*file.dst = contents
+	}
+	return creds, nil
+}
+
+// LoadOrCreate reads the daemon's credentials in `dir`.  If they don't exist, it
+// first generates a certificate authority, and credentials signed by it that
+// authenticate both clients and servers.
+func LoadOrCreate(dir string) (Credentials, error) {
+	creds, err := Load(dir)
+	if err == nil || !os.IsNotExist(err) {
+		return creds, err
+	}
+
+	ca, caKey, err := generateCA()
+	if err != nil {
+		return Credentials{}, fmt.Errorf(
+			"failed to generate certificate authority: %s", err)
+	}
+
+	creds, err = issue(ca, caKey, x509.ExtKeyUsageServerAuth,
+		x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return Credentials{}, fmt.Errorf(
+			"failed to generate credentials: %s", err)
+	}
+
+	caKeyPEM, err := encodeKey(caKey)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	if err := util.AppFs.MkdirAll(dir, 0700); err != nil {
+		return Credentials{}, err
+	}
+
+	for _, file := range []struct {
+		name     string
+		contents string
+		perm     os.FileMode
+	}{
+		{CAFile, creds.CA, 0644},
+		{CAKeyFile, caKeyPEM, 0600},
+		{CertFile, creds.Cert, 0644},
+		{KeyFile, creds.Key, 0600},
+	} {
+		path := filepath.Join(dir, file.name)
+		err := util.WriteFile(path, []byte(file.contents), file.perm)
+		if err != nil {
+			return Credentials{}, err
+		}
+	}
+	return creds, nil
+}
+
+// IssueMinion returns new credentials for a minion, signed by the certificate
+// authority in `dir`.  They only authenticate servers, so that a minion's
+// credentials can't be used to access the others.
+func IssueMinion(dir string) (Credentials, error) {
+	caPEM, err := util.ReadFile(filepath.Join(dir, CAFile))
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	caKeyPEM, err := util.ReadFile(filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	ca, err := tls.X509KeyPair([]byte(caPEM), []byte(caKeyPEM))
+	if err != nil {
+		return Credentials{}, fmt.Errorf("bad certificate authority: %s", err)
+	}
+
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return Credentials{}, fmt.Errorf("bad certificate authority: %s", err)
+	}
+
+	caKey, ok := ca.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return Credentials{}, errors.New(
+			"bad certificate authority: unsupported key type")
+	}
+
+	return issue(caCert, caKey, x509.ExtKeyUsageServerAuth)
+}
+
+func generateCA() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := certTemplate("quilt-ca")
+	if err != nil {
+		return nil, nil, err
+	}
+	template.KeyUsage = x509.KeyUsageCertSign
+	template.BasicConstraintsValid = true
+	template.IsCA = true
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	return cert, key, err
+}
+
+// issue generates credentials signed by `ca` for the given uses.
+func issue(ca *x509.Certificate, caKey *ecdsa.PrivateKey,
+	usage ...x509.ExtKeyUsage) (Credentials, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	template, err := certTemplate(serverName)
+	if err != nil {
+		return Credentials{}, err
+	}
+	template.DNSNames = []string{serverName}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = usage
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey,
+		caKey)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return Credentials{
+		CA:   encodeCert(ca.Raw),
+		Cert: encodeCert(der),
+		Key:  keyPEM,
+	}, nil
+}
+
+func certTemplate(name string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certLifetime),
+	}, nil
+}
+
+func encodeCert(der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func encodeKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	block := pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	return string(pem.EncodeToMemory(&block)), nil
+}
+
+// TLSConfig returns a configuration that presents the credentials' certificate, and
+// only accepts peers whose certificates are signed by the credentials' authority.
+// It's used by both clients and servers.
+func (creds Credentials) TLSConfig() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair([]byte(creds.Cert), []byte(creds.Key))
+	if err != nil {
+		return nil, fmt.Errorf("bad credentials: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(creds.CA)) {
+		return nil, errors.New("bad credentials: no certificate authority")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerOption returns a gRPC server option that requires clients to authenticate
+// with credentials signed by the same authority.
+func (creds Credentials) ServerOption() (grpc.ServerOption, error) {
+	config, err := creds.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Creds(credentials.NewTLS(config)), nil
+}
+
+// DialOption returns a gRPC dial option that authenticates with the credentials,
+// and only accepts servers signed by the same authority.
+func (creds Credentials) DialOption() (grpc.DialOption, error) {
+	config, err := creds.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/quilt/quilt/util"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrCreate(t *testing.T) {
+	oldFs := util.AppFs
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { util.AppFs = oldFs }()
+
+	_, err := Load("/tls")
+	assert.True(t, os.IsNotExist(err))
+
+	creds, err := LoadOrCreate("/tls")
+	assert.NoError(t, err)
+	assert.Contains(t, creds.CA, "BEGIN CERTIFICATE")
+	assert.Contains(t, creds.Cert, "BEGIN CERTIFICATE")
+	assert.Contains(t, creds.Key, "BEGIN EC PRIVATE KEY")
+	assert.NotEqual(t, creds.CA, creds.Cert)
+
+	caKey, err := util.ReadFile("/tls/ca_key.pem")
+	assert.NoError(t, err)
+	assert.Contains(t, caKey, "BEGIN EC PRIVATE KEY")
+	assert.NotEqual(t, creds.Key, caKey)
+
+	// Once created, the credentials are reused.
+	loaded, err := LoadOrCreate("/tls")
+	assert.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+
+	loaded, err = Load("/tls")
+	assert.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+
+	util.AppFs.Remove("/tls/key.pem")
+	_, err = Load("/tls")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestIssueMinion(t *testing.T) {
+	oldFs := util.AppFs
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { util.AppFs = oldFs }()
+
+	_, err := IssueMinion("/tls")
+	assert.True(t, os.IsNotExist(err))
+
+	daemon, err := LoadOrCreate("/tls")
+	assert.NoError(t, err)
+
+	minion, err := IssueMinion("/tls")
+	assert.NoError(t, err)
+	assert.Equal(t, daemon.CA, minion.CA)
+	assert.NotEqual(t, daemon.Cert, minion.Cert)
+
+	other, err := IssueMinion("/tls")
+	assert.NoError(t, err)
+	assert.NotEqual(t, minion.Key, other.Key)
+
+	assert.NoError(t, handshake(t, daemon, minion))
+
+	util.WriteFile("/tls/ca_key.pem", []byte("bad"), 0600)
+	_, err = IssueMinion("/tls")
+	assert.Error(t, err)
+}
+
+func TestTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	ca, caKey, err := generateCA()
+	assert.NoError(t, err)
+	daemon, err := issue(ca, caKey, x509.ExtKeyUsageServerAuth,
+		x509.ExtKeyUsageClientAuth)
+	assert.NoError(t, err)
+	minion, err := issue(ca, caKey, x509.ExtKeyUsageServerAuth)
+	assert.NoError(t, err)
+
+	otherCA, otherCAKey, err := generateCA()
+	assert.NoError(t, err)
+	other, err := issue(otherCA, otherCAKey, x509.ExtKeyUsageServerAuth,
+		x509.ExtKeyUsageClientAuth)
+	assert.NoError(t, err)
+
+	assert.NoError(t, handshake(t, daemon, minion))
+	assert.NoError(t, handshake(t, daemon, daemon))
+
+	// Minions can't authenticate as clients.
+	assert.Error(t, handshake(t, minion, minion))
+	assert.Error(t, handshake(t, minion, daemon))
+
+	// Peers must be signed by the same authority.
+	assert.Error(t, handshake(t, daemon, other))
+	assert.Error(t, handshake(t, other, minion))
+
+	_, err = Credentials{CA: daemon.CA, Cert: daemon.Cert,
+		Key: minion.Key}.TLSConfig()
+	assert.Error(t, err)
+
+	_, err = Credentials{Cert: daemon.Cert, Key: daemon.Key}.TLSConfig()
+	assert.Error(t, err)
+
+	_, err = Credentials{}.ServerOption()
+	assert.Error(t, err)
+
+	_, err = Credentials{}.DialOption()
+	assert.Error(t, err)
+}
+
+// handshake returns the error of a client with `clientCreds` connecting to a server
+// with `serverCreds`.
+func handshake(t *testing.T, clientCreds, serverCreds Credentials) error {
+	clientConfig, err := clientCreds.TLSConfig()
+	assert.NoError(t, err)
+	serverConfig, err := serverCreds.TLSConfig()
+	assert.NoError(t, err)
+
+	// Unlike net.Pipe, a socket buffers writes, so neither side blocks sending an
+	// alert while the other is still sending its handshake.
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sock.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := sock.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		server := tls.Server(conn, serverConfig)
+		serverErr <- server.Handshake()
+		conn.Close()
+	}()
+
+	conn, err := net.Dial("tcp", sock.Addr().String())
+	assert.NoError(t, err)
+	client := tls.Client(conn, clientConfig)
+	err = client.Handshake()
+	conn.Close()
+
+	if sErr := <-serverErr; err == nil {
+		err = sErr
+	}
+	return err
+}
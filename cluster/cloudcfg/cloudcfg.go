@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"strings"
 	"text/template"
+
+	"github.com/quilt/quilt/auth"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 const (
@@ -11,19 +15,34 @@ const (
 )
 
 // Ubuntu generates a cloud config file for the Ubuntu operating system with the
-// corresponding `version`.
+// corresponding `version`.  Each call issues new credentials for the minion, so that
+// it and the daemon can authenticate each other.
 func Ubuntu(keys []string, version string) string {
 	t := template.Must(template.New("cloudConfig").Parse(cfgTemplate))
 
+	creds, err := auth.IssueMinion(auth.DefaultDir)
+	if err != nil {
+		log.WithError(err).Error("Failed to issue TLS credentials, the minion " +
+			"won't be able to authenticate the daemon")
+	}
+
 	var cloudConfigBytes bytes.Buffer
-	err := t.Execute(&cloudConfigBytes, struct {
+	err = t.Execute(&cloudConfigBytes, struct {
 		QuiltImage    string
 		UbuntuVersion string
 		SSHKeys       string
+		TLSDir        string
+		TLSCA         string
+		TLSCert       string
+		TLSKey        string
 	}{
 		QuiltImage:    quiltImage,
 		UbuntuVersion: version,
 		SSHKeys:       strings.Join(keys, "\n"),
+		TLSDir:        auth.MinionDir,
+		TLSCA:         strings.TrimSpace(creds.CA),
+		TLSCert:       strings.TrimSpace(creds.Cert),
+		TLSKey:        strings.TrimSpace(creds.Key),
 	})
 	if err != nil {
 		panic(err)
@@ -1,13 +1,50 @@
 package cloudcfg
 
-import "testing"
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quilt/quilt/auth"
+	"github.com/quilt/quilt/util"
+
+	"github.com/spf13/afero"
+)
 
 func TestCloudConfig(t *testing.T) {
-	cfgTemplate = "({{.QuiltImage}}) ({{.SSHKeys}}) ({{.UbuntuVersion}})"
+	oldFs := util.AppFs
+	util.AppFs = afero.NewMemMapFs()
+	defer func() { util.AppFs = oldFs }()
+
+	cfgTemplate = "({{.QuiltImage}}) ({{.SSHKeys}}) ({{.UbuntuVersion}}) " +
+		"({{.TLSDir}}) ({{.TLSCA}}) ({{.TLSCert}}) ({{.TLSKey}})"
 
 	res := Ubuntu([]string{"a", "b"}, "1")
-	exp := "(quilt/quilt:latest) (a\nb) (1)"
+	exp := "(quilt/quilt:latest) (a\nb) (1) (/etc/quilt/tls) () () ()"
 	if res != exp {
 		t.Errorf("res: %s\nexp: %s", res, exp)
 	}
+
+	daemon, err := auth.LoadOrCreate(auth.DefaultDir)
+	if err != nil {
+		t.Fatalf("Failed to create credentials: %s", err)
+	}
+	caKey, _ := util.ReadFile(filepath.Join(auth.DefaultDir, auth.CAKeyFile))
+
+	res = Ubuntu([]string{"a", "b"}, "1")
+	exp = "(quilt/quilt:latest) (a\nb) (1) (/etc/quilt/tls) (" +
+		strings.TrimSpace(daemon.CA) + ") ("
+	if !strings.HasPrefix(res, exp) {
+		t.Errorf("res: %s\nexp prefix: %s", res, exp)
+	}
+
+	// Each machine gets its own credentials, and never the authority's key.
+	if strings.Contains(res, strings.TrimSpace(daemon.Key)) ||
+		strings.Contains(res, strings.TrimSpace(caKey)) {
+		t.Errorf("res contains a private key of the daemon: %s", res)
+	}
+
+	if other := Ubuntu([]string{"a", "b"}, "1"); other == res {
+		t.Errorf("Machines were issued the same credentials: %s", res)
+	}
 }
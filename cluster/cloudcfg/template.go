@@ -42,6 +42,23 @@ initialize_docker() {
 	EOF
 }
 
+install_tls_credentials() {
+	install -d -m 700 {{.TLSDir}}
+
+	cat <<- 'EOF' > {{.TLSDir}}/ca.pem
+	{{.TLSCA}}
+	EOF
+
+	cat <<- 'EOF' > {{.TLSDir}}/cert.pem
+	{{.TLSCert}}
+	EOF
+
+	install -m 600 /dev/null {{.TLSDir}}/key.pem
+	cat <<- 'EOF' >> {{.TLSDir}}/key.pem
+	{{.TLSKey}}
+	EOF
+}
+
 initialize_minion() {
 	cat <<- EOF > /etc/systemd/system/minion.service
 	[Unit]
@@ -58,6 +75,7 @@ initialize_minion() {
 	-v /var/run/docker.sock:/var/run/docker.sock \
 	-v /etc/ssl/certs/ca-certificates.crt:/etc/ssl/certs/ca-certificates.crt \
 	-v /home/quilt/.ssh:/home/quilt/.ssh:rw \
+	-v {{.TLSDir}}:{{.TLSDir}}:ro \
 	-v /run/docker:/run/docker:rw {{.QuiltImage}} \
 	quilt minion
 	Restart=on-failure
@@ -103,6 +121,7 @@ sudo mkdir /run/docker/plugins
 sudo chmod -R /run/docker/plugins 0755
 
 install_docker
+install_tls_credentials
 initialize_ovs
 initialize_docker
 initialize_minion
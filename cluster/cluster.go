@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/quilt/quilt/cluster/acl"
@@ -12,6 +13,7 @@ import (
 	"github.com/quilt/quilt/cluster/vagrant"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/metrics"
 	"github.com/quilt/quilt/util"
 	log "github.com/Sirupsen/logrus"
 )
@@ -45,6 +47,10 @@ type cluster struct {
 var myIP = util.MyIP
 var sleep = time.Sleep
 
+var providerErrors = metrics.NewCounter("quilt_provider_errors_total",
+	"The number of failed cloud provider API calls.",
+	"provider", "region", "action")
+
 // action is an enum for provider actions.
 type action int
 
@@ -57,9 +63,16 @@ const (
 // Run continually checks 'conn' for cluster changes and recreates the cluster as
 // needed.
 func Run(conn db.Conn) {
+	metrics.NewGaugeFunc("quilt_machines",
+		"The number of machines with each role in each state.",
+		[]string{"role", "state"}, machineStates(conn))
+
 	var clst *cluster
+	loopLog := util.NewEventTimer("Cluster")
 	for range conn.TriggerTick(30, db.ClusterTable, db.MachineTable, db.ACLTable).C {
+		loopLog.LogStart()
 		clst = updateCluster(conn, clst)
+		loopLog.LogEnd()
 
 		// Somewhat of a crude rate-limit of once every five seconds to avoid
 		// stressing out the cloud providers with too many API calls.
@@ -184,18 +197,34 @@ func (clst cluster) updateCloud(machines []machine.Machine, act action) {
 
 		if err != nil {
 			noFailures = false
+			providerErrors.Inc(string(i.provider), i.region,
+				metricAction(act))
+
+			reason := ""
 			switch act {
 			case boot:
+				reason = "FailedBoot"
 				log.WithError(err).Warnf(
 					"Unable to boot machines on %s.", i.provider)
 			case stop:
+				reason = "FailedStop"
 				log.WithError(err).Warnf(
 					"Unable to stop machines on %s", i.provider)
 			case updateIPs:
+				reason = "FailedUpdateFloatingIPs"
 				log.WithError(err).Warnf(
 					"Unable to update floating IPs on %s",
 					i.provider)
 			}
+
+			object := fmt.Sprintf("provider/%s-%s", i.provider, i.region)
+			clst.conn.RecordEvent(db.Event{
+				Type:    db.EventWarning,
+				Reason:  reason,
+				Object:  object,
+				Message: err.Error(),
+				Source:  "cluster",
+			})
 		}
 	}
 
@@ -214,6 +243,52 @@ func (clst cluster) updateCloud(machines []machine.Machine, act action) {
 	}
 }
 
+// metricAction returns the name of `act` in the provider errors metric.
+func metricAction(act action) string {
+	switch act {
+	case boot:
+		return "boot"
+	case stop:
+		return "stop"
+	case updateIPs:
+		return "update_floating_ips"
+	default:
+		panic("Not Reached")
+	}
+}
+
+// machineStates reports the number of machines with each role that are booting,
+// waiting for their minion to connect, and connected.
+func machineStates(conn db.Conn) func(set func(float64, ...string)) {
+	return func(set func(float64, ...string)) {
+		counts := map[db.Role]map[string]int{}
+		for _, role := range []db.Role{db.Master, db.Worker} {
+			counts[role] = map[string]int{
+				"booting": 0, "connecting": 0, "connected": 0}
+		}
+
+		for _, m := range conn.SelectFromMachine(nil) {
+			state := "connected"
+			if m.PublicIP == "" {
+				state = "booting"
+			} else if !m.Connected {
+				state = "connecting"
+			}
+
+			if counts[m.Role] == nil {
+				counts[m.Role] = map[string]int{}
+			}
+			counts[m.Role][state]++
+		}
+
+		for role, states := range counts {
+			for state, n := range states {
+				set(float64(n), string(role), state)
+			}
+		}
+	}
+}
+
 type joinResult struct {
 	machines []db.Machine
 	acl      db.ACL
@@ -232,7 +307,7 @@ func (clst cluster) join() (joinResult, error) {
 		return res, err
 	}
 
-	err = clst.conn.Txn(db.ACLTable, db.ClusterTable,
+	err = clst.conn.Txn(db.ACLTable, db.ClusterTable, db.EventTable,
 		db.MachineTable).Run(func(view db.Database) error {
 		namespace, err := view.GetClusterNamespace()
 		if err != nil {
@@ -262,6 +337,10 @@ func (clst cluster) join() (joinResult, error) {
 			dbm := pair.L.(db.Machine)
 			m := pair.R.(machine.Machine)
 
+			if dbm.PublicIP == "" && m.PublicIP != "" {
+				view.RecordEvent(bootedEvent(dbm.StitchID, m))
+			}
+
 			dbm.CloudID = m.ID
 			dbm.PublicIP = m.PublicIP
 			dbm.PrivateIP = m.PrivateIP
@@ -287,6 +366,19 @@ func (clst cluster) join() (joinResult, error) {
 	return res, err
 }
 
+// bootedEvent describes the machine `m`, whose StitchID is `stitchID`, receiving a
+// public IP.
+func bootedEvent(stitchID string, m machine.Machine) db.Event {
+	return db.Event{
+		Type:   db.EventNormal,
+		Reason: "Booted",
+		Object: "machine/" + stitchID,
+		Message: fmt.Sprintf("%s %s in %s is up at %s", m.Provider, m.Size,
+			m.Region, m.PublicIP),
+		Source: "cluster",
+	}
+}
+
 func (clst cluster) syncACLs(adminACLs []string, appACLs []db.PortRange,
 	machines []db.Machine) {
 
@@ -337,6 +429,8 @@ func (clst cluster) syncACLs(adminACLs []string, appACLs []db.PortRange,
 		}
 
 		if err := prvdr.SetACLs(setACLs); err != nil {
+			providerErrors.Inc(string(inst.provider), inst.region,
+				"set_acls")
 			log.WithError(err).Warnf("Could not update ACLs on %s in %s.",
 				inst.provider, inst.region)
 		}
@@ -418,9 +512,10 @@ func syncDB(cms []machine.Machine, dbms []db.Machine) syncDBResult {
 
 func (clst cluster) get() ([]machine.Machine, error) {
 	var cloudMachines []machine.Machine
-	for _, p := range clst.providers {
+	for inst, p := range clst.providers {
 		providerMachines, err := p.List()
 		if err != nil {
+			providerErrors.Inc(string(inst.provider), inst.region, "list")
 			return []machine.Machine{}, err
 		}
 		cloudMachines = append(cloudMachines, providerMachines...)
@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"errors"
 	"reflect"
 	"strconv"
 	"testing"
@@ -46,6 +47,8 @@ type fakeProvider struct {
 	stopRequests []string
 	updateIPs    []ipRequest
 	aclRequests  []acl.ACL
+
+	bootErr error
 }
 
 func fakeValidRegions(p db.Provider) []string {
@@ -86,6 +89,10 @@ func (p *fakeProvider) List() ([]machine.Machine, error) {
 }
 
 func (p *fakeProvider) Boot(bootSet []machine.Machine) error {
+	if p.bootErr != nil {
+		return p.bootErr
+	}
+
 	for _, bootSet := range bootSet {
 		p.idCounter++
 		idStr := strconv.Itoa(p.idCounter)
@@ -609,6 +616,104 @@ func setNamespace(conn db.Conn, ns string) {
 	})
 }
 
+func TestClusterEvents(t *testing.T) {
+	clst := newTestCluster("ns")
+	setNamespace(clst.conn, "ns")
+	clst.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.StitchID = "1"
+		m.Role = db.Master
+		m.Provider = FakeAmazon
+		m.Region = testRegion
+		m.Size = "m4.large"
+		view.Commit(m)
+		return nil
+	})
+
+	eventReasons := func() map[string]db.Event {
+		events := map[string]db.Event{}
+		for _, e := range clst.conn.SelectFromEvent(nil) {
+			events[e.Reason] = e
+		}
+		return events
+	}
+
+	bootErrors := func() float64 {
+		return providerErrors.Get(string(FakeAmazon), testRegion, "boot")
+	}
+	oldBootErrors := bootErrors()
+
+	prvdr := clst.providers[instance{FakeAmazon, testRegion}].(*fakeProvider)
+	prvdr.bootErr = errors.New("quota exceeded")
+	clst.runOnce()
+	assert.Equal(t, float64(2), bootErrors()-oldBootErrors)
+
+	events := eventReasons()
+	assert.Len(t, events, 1)
+	assert.Equal(t, db.Event{
+		ID:        events["FailedBoot"].ID,
+		Type:      db.EventWarning,
+		Reason:    "FailedBoot",
+		Object:    "provider/FakeAmazon-Fake region",
+		Message:   "quota exceeded",
+		Source:    "cluster",
+		Count:     2,
+		FirstSeen: events["FailedBoot"].FirstSeen,
+		LastSeen:  events["FailedBoot"].LastSeen,
+	}, events["FailedBoot"])
+
+	prvdr.bootErr = nil
+	clst.runOnce()
+	assert.Len(t, eventReasons(), 1)
+
+	// The machine is booted once it has a public IP.
+	for id, m := range prvdr.machines {
+		m.PublicIP = "1.2.3.4"
+		prvdr.machines[id] = m
+	}
+	clst.runOnce()
+	clst.runOnce()
+
+	booted := eventReasons()["Booted"]
+	assert.Equal(t, db.EventNormal, booted.Type)
+	assert.Equal(t, "machine/1", booted.Object)
+	assert.Equal(t, "FakeAmazon m4.large in Fake region is up at 1.2.3.4",
+		booted.Message)
+	assert.Equal(t, 1, booted.Count)
+}
+
+func TestMachineStates(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, m := range []db.Machine{
+			{Role: db.Master, PublicIP: "1.2.3.4", Connected: true},
+			{Role: db.Worker, PublicIP: "1.2.3.5", Connected: true},
+			{Role: db.Worker, PublicIP: "1.2.3.6"},
+			{Role: db.Worker},
+			{Role: db.None},
+		} {
+			m.ID = view.InsertMachine().ID
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	states := map[string]float64{}
+	machineStates(conn)(func(v float64, labels ...string) {
+		states[labels[0]+"/"+labels[1]] = v
+	})
+
+	assert.Equal(t, map[string]float64{
+		"Master/booting":    0,
+		"Master/connecting": 0,
+		"Master/connected":  1,
+		"Worker/booting":    1,
+		"Worker/connecting": 1,
+		"Worker/connected":  1,
+		"/booting":          1,
+	}, states)
+}
+
 func mock() {
 	newProvider = newFakeProvider
 	validRegions = fakeValidRegions
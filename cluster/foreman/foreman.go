@@ -1,6 +1,8 @@
 package foreman
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
@@ -9,8 +11,10 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/quilt/quilt/auth"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/pb"
+	"github.com/quilt/quilt/stitch"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -20,6 +24,7 @@ var minions map[string]*minion
 type client interface {
 	setMinion(pb.MinionConfig) error
 	getMinion() (pb.MinionConfig, error)
+	getEvents(since time.Time) ([]db.Event, error)
 	Close()
 }
 
@@ -35,6 +40,10 @@ type minion struct {
 	machine db.Machine
 	config  pb.MinionConfig
 
+	// When the latest event replicated from the minion was last seen, according
+	// to the minion's clock.
+	lastEvent time.Time
+
 	mark bool /* Mark and sweep garbage collection. */
 }
 
@@ -70,8 +79,9 @@ func Init(conn db.Conn) {
 func RunOnce(conn db.Conn) {
 	var spec string
 	var machines []db.Machine
-	conn.Txn(db.ClusterTable,
-		db.MachineTable).Run(func(view db.Database) error {
+	var dbSecrets []db.Secret
+	conn.Txn(db.ClusterTable, db.MachineTable,
+		db.SecretTable).Run(func(view db.Database) error {
 
 		machines = view.SelectFromMachine(func(m db.Machine) bool {
 			return m.PublicIP != "" && m.PrivateIP != "" && m.CloudID != ""
@@ -80,21 +90,35 @@ func RunOnce(conn db.Conn) {
 		clst, _ := view.GetCluster()
 		spec = clst.Spec
 
+		dbSecrets = view.SelectFromSecret(nil)
 		return nil
 	})
 
+	// The spec was validated when it was deployed, so the subnets are known good.
+	var subnet, subnet6 string
+	var secrets map[string]string
+	if stc, err := stitch.FromJSON(spec); err == nil {
+		subnet, subnet6 = stc.Subnet, stc.IPv6Subnet
+		secrets = referencedSecrets(stc, dbSecrets)
+	}
+
 	updateMinionMap(machines)
 
 	forEachMinion(updateConfig)
 	forEachMinion(func(m *minion) {
 		if m.connected != m.machine.Connected {
-			tr := conn.Txn(db.MachineTable)
+			tr := conn.Txn(db.MachineTable, db.EventTable)
 			tr.Run(func(view db.Database) error {
 				m.machine.Connected = m.connected
 				view.Commit(m.machine)
+				view.RecordEvent(connectionEvent(m.machine))
 				return nil
 			})
 		}
+
+		if m.connected {
+			replicateEvents(conn, m)
+		}
 	})
 
 	var etcdIPs []string
@@ -119,9 +143,22 @@ func RunOnce(conn db.Conn) {
 			Region:         m.machine.Region,
 			EtcdMembers:    etcdIPs,
 			AuthorizedKeys: m.machine.SSHKeys,
+			Subnet:         subnet,
+			IPv6Subnet:     subnet6,
 		}
 
-		if reflect.DeepEqual(newConfig, m.config) {
+		// Only the workers run containers, so the masters never see secrets.
+		// The containers are placed by the leader rather than the daemon, so
+		// every worker gets all of the secrets the deployment references.
+		if m.machine.Role == db.Worker {
+			newConfig.Secrets = secrets
+			newConfig.SecretsDigest = db.SecretsDigest(secrets)
+		}
+
+		// Minions report a digest of their secrets instead of the secrets.
+		reported := newConfig
+		reported.Secrets = nil
+		if reflect.DeepEqual(reported, m.config) {
 			return
 		}
 
@@ -188,8 +225,89 @@ func updateConfig(m *minion) {
 	m.connected = connected
 }
 
+// connectionEvent describes `m`'s minion connecting, or losing its connection.
+func connectionEvent(m db.Machine) db.Event {
+	e := db.Event{Object: "machine/" + m.StitchID, Source: "foreman"}
+	if m.Connected {
+		e.Type = db.EventNormal
+		e.Reason = "Connected"
+		e.Message = fmt.Sprintf("minion on %s connected", m.PublicIP)
+	} else {
+		e.Type = db.EventWarning
+		e.Reason = "Disconnected"
+		e.Message = fmt.Sprintf("lost connection to minion on %s", m.PublicIP)
+	}
+	return e
+}
+
+// replicateEvents copies the events recorded by `m`'s minion since they were last
+// replicated into the Event table.
+func replicateEvents(conn db.Conn, m *minion) {
+	events, err := m.client.getEvents(m.lastEvent)
+	if err != nil {
+		log.WithError(err).Debug("Failed to get minion events")
+		return
+	}
+
+	if len(events) == 0 {
+		return
+	}
+
+	conn.Txn(db.EventTable).Run(func(view db.Database) error {
+		for _, e := range events {
+			e.Machine = m.machine.StitchID
+			view.MergeEvent(e)
+
+			if e.LastSeen.After(m.lastEvent) {
+				m.lastEvent = e.LastSeen
+			}
+		}
+		return nil
+	})
+}
+
+// referencedSecrets returns the values of the secrets referenced by the containers in
+// `stc`, as environment variables or files.  Containers that reference unknown
+// secrets won't be started by the workers until the secrets are set.
+func referencedSecrets(stc stitch.Stitch, dbSecrets []db.Secret) map[string]string {
+	names := map[string]struct{}{}
+	for _, c := range stc.Containers {
+		for _, name := range c.Secrets {
+			names[name] = struct{}{}
+		}
+		for _, name := range c.SecretFiles {
+			names[name] = struct{}{}
+		}
+	}
+
+	var secrets map[string]string
+	for _, secret := range dbSecrets {
+		if _, ok := names[secret.Name]; !ok {
+			continue
+		}
+
+		if secrets == nil {
+			secrets = map[string]string{}
+		}
+		secrets[secret.Name] = secret.Value
+	}
+	return secrets
+}
+
+// newClientImpl connects to the minion at `ip`, which must authenticate with
+// credentials issued by the daemon.
 func newClientImpl(ip string) (client, error) {
-	cc, err := grpc.Dial(ip+":9999", grpc.WithInsecure())
+	creds, err := auth.Load(auth.DefaultDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dialCreds, err := creds.DialOption()
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := grpc.Dial(ip+":9999", dialCreds)
 	if err != nil {
 		return nil, err
 	}
@@ -216,6 +334,23 @@ func (c clientImpl) setMinion(cfg pb.MinionConfig) error {
 	return err
 }
 
+func (c clientImpl) getEvents(since time.Time) ([]db.Event, error) {
+	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+	req := &pb.MinionEventsRequest{}
+	if !since.IsZero() {
+		req.Since = since.UnixNano()
+	}
+
+	reply, err := c.GetEvents(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []db.Event
+	err = json.Unmarshal([]byte(reply.Events), &events)
+	return events, err
+}
+
 func (c clientImpl) Close() {
 	c.cc.Close()
 }
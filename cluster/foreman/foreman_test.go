@@ -2,6 +2,7 @@ package foreman
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -147,6 +148,79 @@ func TestBootEtcd(t *testing.T) {
 		clients.clients["w1-pub"].mc.EtcdMembers)
 }
 
+func TestSubnets(t *testing.T) {
+	conn, clients := startTest()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		clst := view.InsertCluster()
+		clst.Spec = `{"Subnet": "172.16.0.0/12", "IPv6Subnet": "fd00::/64"}`
+		view.Commit(clst)
+
+		m := view.InsertMachine()
+		m.Role = db.Worker
+		m.PublicIP = "w1-pub"
+		m.PrivateIP = "w1-priv"
+		m.CloudID = "ignored"
+		view.Commit(m)
+		return nil
+	})
+	RunOnce(conn)
+
+	mc := clients.clients["w1-pub"].mc
+	assert.Equal(t, "172.16.0.0/12", mc.Subnet)
+	assert.Equal(t, "fd00::/64", mc.IPv6Subnet)
+}
+
+func TestSecrets(t *testing.T) {
+	conn, clients := startTest()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		clst := view.InsertCluster()
+		clst.Spec = `{"Containers": [{"ID": "1",
+			"Secrets": {"PASS": "db-pass", "KEY": "missing"}}]}`
+		view.Commit(clst)
+
+		for _, name := range []string{"db-pass", "unused"} {
+			secret := view.InsertSecret()
+			secret.Name = name
+			secret.Value = name + "-value"
+			view.Commit(secret)
+		}
+
+		for _, role := range []db.Role{db.Master, db.Worker} {
+			m := view.InsertMachine()
+			m.Role = role
+			m.PublicIP = string(role) + "-pub"
+			m.PrivateIP = string(role) + "-priv"
+			m.CloudID = "ignored"
+			view.Commit(m)
+		}
+		return nil
+	})
+	RunOnce(conn)
+
+	worker := clients.clients["Worker-pub"]
+	assert.Equal(t, map[string]string{"db-pass": "db-pass-value"},
+		worker.mc.Secrets)
+	assert.Equal(t, db.SecretsDigest(worker.mc.Secrets), worker.mc.SecretsDigest)
+	assert.Nil(t, clients.clients["Master-pub"].mc.Secrets)
+	assert.Empty(t, clients.clients["Master-pub"].mc.SecretsDigest)
+
+	// The config isn't sent again while the minion's digest matches.
+	RunOnce(conn)
+	assert.Equal(t, 1, worker.setCalls)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		secret := view.SelectFromSecret(func(s db.Secret) bool {
+			return s.Name == "db-pass"
+		})[0]
+		secret.Value = "changed"
+		view.Commit(secret)
+		return nil
+	})
+	RunOnce(conn)
+	assert.Equal(t, 2, worker.setCalls)
+	assert.Equal(t, map[string]string{"db-pass": "changed"}, worker.mc.Secrets)
+}
+
 func TestInitForeman(t *testing.T) {
 	conn := startTestWithRole(pb.MinionConfig_WORKER)
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
@@ -213,10 +287,10 @@ func TestConfigConsistency(t *testing.T) {
 
 	// Insert the clients into the client list to simulate fetching
 	// from the remote cluster
-	clients.clients["1.1.1.1"] = &fakeClient{clients, "1.1.1.1",
-		pb.MinionConfig{Role: masterRole}}
-	clients.clients["2.2.2.2"] = &fakeClient{clients, "2.2.2.2",
-		pb.MinionConfig{Role: workerRole}}
+	clients.clients["1.1.1.1"] = &fakeClient{clients: clients, ip: "1.1.1.1",
+		mc: pb.MinionConfig{Role: masterRole}}
+	clients.clients["2.2.2.2"] = &fakeClient{clients: clients, ip: "2.2.2.2",
+		mc: pb.MinionConfig{Role: workerRole}}
 
 	Init(conn)
 	RunOnce(conn)
@@ -243,6 +317,89 @@ func TestConfigConsistency(t *testing.T) {
 	})
 }
 
+func TestConnectionEvents(t *testing.T) {
+	conn, _ := startTest()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.StitchID = "1"
+		m.PublicIP = "1.1.1.1"
+		m.PrivateIP = "10.0.0.1"
+		m.CloudID = "ID"
+		view.Commit(m)
+		return nil
+	})
+
+	RunOnce(conn)
+	RunOnce(conn)
+
+	events := conn.SelectFromEvent(nil)
+	assert.Len(t, events, 1)
+	assert.Equal(t, db.EventNormal, events[0].Type)
+	assert.Equal(t, "Connected", events[0].Reason)
+	assert.Equal(t, "machine/1", events[0].Object)
+	assert.Equal(t, "minion on 1.1.1.1 connected", events[0].Message)
+	assert.Equal(t, "foreman", events[0].Source)
+	assert.Equal(t, 1, events[0].Count)
+
+	e := connectionEvent(db.Machine{StitchID: "1", PublicIP: "1.1.1.1"})
+	assert.Equal(t, db.Event{
+		Type:    db.EventWarning,
+		Reason:  "Disconnected",
+		Object:  "machine/1",
+		Message: "lost connection to minion on 1.1.1.1",
+		Source:  "foreman",
+	}, e)
+}
+
+func TestReplicateEvents(t *testing.T) {
+	conn, clients := startTest()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.StitchID = "1"
+		m.PublicIP = "1.1.1.1"
+		m.PrivateIP = "10.0.0.1"
+		m.CloudID = "ID"
+		m.Connected = true
+		view.Commit(m)
+		return nil
+	})
+
+	now := time.Now()
+	pulled := db.Event{Type: db.EventWarning, Reason: "FailedPull",
+		Object: "container/a", Source: "scheduler", Count: 1, FirstSeen: now,
+		LastSeen: now}
+
+	RunOnce(conn)
+	clients.clients["1.1.1.1"].events = []db.Event{pulled}
+	RunOnce(conn)
+
+	replicated := func() []db.Event {
+		events := conn.SelectFromEvent(nil)
+		for i := range events {
+			events[i].ID = 0
+		}
+		return events
+	}
+
+	exp := pulled
+	exp.Machine = "1"
+	assert.Equal(t, []db.Event{exp}, replicated())
+	assert.Equal(t, now, minions["1.1.1.1"].lastEvent)
+
+	// Only events seen since the last replication are fetched, and repeated events
+	// replace their earlier counts.
+	later := now.Add(time.Minute)
+	pulled.Count = 2
+	pulled.LastSeen = later
+	clients.clients["1.1.1.1"].events[0] = pulled
+	RunOnce(conn)
+
+	exp.Count = 2
+	exp.LastSeen = later
+	assert.Equal(t, []db.Event{exp}, replicated())
+	assert.Equal(t, later, minions["1.1.1.1"].lastEvent)
+}
+
 func startTest() (db.Conn, *clients) {
 	conn := db.New()
 	minions = map[string]*minion{}
@@ -251,7 +408,7 @@ func startTest() (db.Conn, *clients) {
 		if fc, ok := clients.clients[ip]; ok {
 			return fc, nil
 		}
-		fc := &fakeClient{clients, ip, pb.MinionConfig{}}
+		fc := &fakeClient{clients: clients, ip: ip}
 		clients.clients[ip] = fc
 		clients.newCalls++
 		return fc, nil
@@ -262,7 +419,8 @@ func startTest() (db.Conn, *clients) {
 func startTestWithRole(role pb.MinionConfig_Role) db.Conn {
 	clientInst := &clients{make(map[string]*fakeClient), 0}
 	newClient = func(ip string) (client, error) {
-		fc := &fakeClient{clientInst, ip, pb.MinionConfig{Role: role}}
+		fc := &fakeClient{clients: clientInst, ip: ip,
+			mc: pb.MinionConfig{Role: role}}
 		clientInst.clients[ip] = fc
 		clientInst.newCalls++
 		return fc, nil
@@ -271,18 +429,34 @@ func startTestWithRole(role pb.MinionConfig_Role) db.Conn {
 }
 
 type fakeClient struct {
-	clients *clients
-	ip      string
-	mc      pb.MinionConfig
+	clients  *clients
+	ip       string
+	mc       pb.MinionConfig
+	events   []db.Event
+	setCalls int
 }
 
 func (fc *fakeClient) setMinion(mc pb.MinionConfig) error {
 	fc.mc = mc
+	fc.setCalls++
 	return nil
 }
 
+// getMinion reports the config like a minion does, without the secrets.
 func (fc *fakeClient) getMinion() (pb.MinionConfig, error) {
-	return fc.mc, nil
+	mc := fc.mc
+	mc.Secrets = nil
+	return mc, nil
+}
+
+func (fc *fakeClient) getEvents(since time.Time) ([]db.Event, error) {
+	var events []db.Event
+	for _, e := range fc.events {
+		if e.LastSeen.After(since) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
 }
 
 func (fc *fakeClient) Close() {
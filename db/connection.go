@@ -5,14 +5,16 @@ import (
 )
 
 // A Connection allows the members of two labels to speak to each other on the port
-// range [MinPort, MaxPort] inclusive.
+// range [MinPort, MaxPort] inclusive.  If Bandwidth is non-zero, it limits the rate,
+// in kbit/s, at which each member of To receives traffic from the members of From.
 type Connection struct {
 	ID int `json:"-"`
 
-	From    string
-	To      string
-	MinPort int
-	MaxPort int
+	From      string
+	To        string
+	MinPort   int
+	MaxPort   int
+	Bandwidth int `json:",omitempty"`
 }
 
 // InsertConnection creates a new connection row and inserts it into the database.
@@ -56,6 +58,10 @@ func (c Connection) String() string {
 		port += fmt.Sprintf("-%d", c.MaxPort)
 	}
 
+	if c.Bandwidth != 0 {
+		port += fmt.Sprintf(" %dkbit/s", c.Bandwidth)
+	}
+
 	return fmt.Sprintf("Connection-%d{%s->%s:%s}", c.ID, c.From, c.To, port)
 }
 
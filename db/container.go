@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,17 +15,27 @@ import (
 type Container struct {
 	ID int `json:"-"`
 
-	IP         string            `json:",omitempty"`
-	Minion     string            `json:",omitempty"`
-	EndpointID string            `json:",omitempty"`
-	StitchID   string            `json:",omitempty"`
-	DockerID   string            `json:",omitempty"`
-	Image      string            `json:",omitempty"`
-	Status     string            `json:",omitempty"`
-	Command    []string          `json:",omitempty"`
-	Labels     []string          `json:",omitempty"`
-	Env        map[string]string `json:",omitempty"`
-	Created    time.Time         `json:","`
+	IP          string            `json:",omitempty"`
+	IPv6        string            `json:",omitempty"`
+	Minion      string            `json:",omitempty"`
+	EndpointID  string            `json:",omitempty"`
+	StitchID    string            `json:",omitempty"`
+	DockerID    string            `json:",omitempty"`
+	Image       string            `json:",omitempty"`
+	Status      string            `json:",omitempty"`
+	Command     []string          `json:",omitempty"`
+	Labels      []string          `json:",omitempty"`
+	Env         map[string]string `json:",omitempty"`
+	Secrets     map[string]string `json:",omitempty"` // Env var to secret name.
+	Files       map[string]string `json:",omitempty"` // Path to contents.
+	SecretFiles map[string]string `json:",omitempty"` // Path to secret name.
+	Bandwidth   int               `json:",omitempty"` // kbit/s, or 0 if unlimited.
+	StopTimeout int               `json:",omitempty"` // Seconds, or 0 for default.
+	Created     time.Time         `json:","`
+
+	// Outdated containers were removed from the deployment, but are kept running
+	// until a rolling update of their labels replaces them.
+	Outdated bool `json:",omitempty"`
 }
 
 // ContainerSlice is an alias for []Container to allow for joins
@@ -93,10 +104,40 @@ func (c Container) String() string {
 		tags = append(tags, fmt.Sprintf("Env: %s", c.Env))
 	}
 
+	if len(c.Secrets) > 0 {
+		tags = append(tags, fmt.Sprintf("Secrets: %s", c.Secrets))
+	}
+
+	// The contents of the files are omitted, as they may be large.
+	if len(c.Files) > 0 {
+		var paths []string
+		for path := range c.Files {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		tags = append(tags, fmt.Sprintf("Files: %s", paths))
+	}
+
+	if len(c.SecretFiles) > 0 {
+		tags = append(tags, fmt.Sprintf("SecretFiles: %s", c.SecretFiles))
+	}
+
+	if c.Bandwidth != 0 {
+		tags = append(tags, fmt.Sprintf("Bandwidth: %dkbit/s", c.Bandwidth))
+	}
+
+	if c.StopTimeout != 0 {
+		tags = append(tags, fmt.Sprintf("StopTimeout: %ds", c.StopTimeout))
+	}
+
 	if len(c.Status) > 0 {
 		tags = append(tags, fmt.Sprintf("Status: %s", c.Status))
 	}
 
+	if c.Outdated {
+		tags = append(tags, "Outdated")
+	}
+
 	if !c.Created.IsZero() {
 		tags = append(tags, fmt.Sprintf("Created: %s", c.Created.String()))
 	}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/quilt/quilt/metrics"
 )
 
 // The Database is the central storage location for all state in the system.  The policy
@@ -39,6 +41,10 @@ type Transaction struct {
 	db Database
 }
 
+var triggerCount = metrics.NewCounter("quilt_db_triggers_total",
+	"The number of transactions that changed each table, notifying its triggers.",
+	"table")
+
 // An idCounter is a wrapper around the global DB id providing concurrency safe use
 type idCounter struct {
 	sync.Mutex
@@ -79,10 +85,11 @@ func (tr Transaction) Run(do func(db Database) error) error {
 
 	err := do(tr.db)
 	var alertTables []*table
-	for _, table := range tr.db.tables {
+	for tt, table := range tr.db.tables {
 		if table.shouldAlert {
 			alertTables = append(alertTables, table)
 			table.shouldAlert = false
+			triggerCount.Inc(string(tt))
 		}
 	}
 
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"reflect"
@@ -138,27 +139,43 @@ func TestContainerString(t *testing.T) {
 	fakeTimeString := fakeTime.String()
 
 	c = Container{
-		ID:         1,
-		IP:         "1.2.3.4",
-		Minion:     "Test",
-		EndpointID: "TestEndpoint",
-		StitchID:   "1",
-		DockerID:   "DockerID",
-		Image:      "test/test",
-		Status:     "testing",
-		Command:    []string{"run", "/bin/sh"},
-		Labels:     []string{"label1"},
-		Env:        fakeMap,
-		Created:    fakeTime,
+		ID:          1,
+		IP:          "1.2.3.4",
+		Minion:      "Test",
+		EndpointID:  "TestEndpoint",
+		StitchID:    "1",
+		DockerID:    "DockerID",
+		Image:       "test/test",
+		Status:      "testing",
+		Command:     []string{"run", "/bin/sh"},
+		Labels:      []string{"label1"},
+		Env:         fakeMap,
+		Files:       map[string]string{"/b": "b", "/a": "a"},
+		Bandwidth:   100,
+		StopTimeout: 30,
+		Created:     fakeTime,
+		Outdated:    true,
 	}
 
 	exp = "Container-1{run test/test run /bin/sh, DockerID: DockerID, " +
 		"Minion: Test, StitchID: 1, IP: 1.2.3.4, Labels: [label1], " +
-		"Env: map[test:tester], Status: testing, Created: " + fakeTimeString + "}"
+		"Env: map[test:tester], Files: [/a /b], Bandwidth: 100kbit/s, " +
+		"StopTimeout: 30s, Status: testing, Outdated, " +
+		"Created: " + fakeTimeString + "}"
 
 	assert.Equal(t, exp, c.String())
 }
 
+func TestEtcdString(t *testing.T) {
+	e := Etcd{ID: 1, EtcdIPs: []string{"1.2.3.4"}, OverlayKey: "secret"}
+	assert.NotContains(t, e.String(), "secret")
+
+	// The key isn't sent to API clients either.
+	b, err := json.Marshal(e)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "secret")
+}
+
 func TestTxnBasic(t *testing.T) {
 	conn := New()
 	conn.Txn(AllTables...).Run(func(view Database) error {
@@ -203,6 +220,12 @@ func TestAllTablesNoPanic(t *testing.T) {
 		view.InsertContainer()
 		view.InsertConnection()
 		view.InsertACL()
+		view.InsertTraffic()
+		view.InsertPortTraffic()
+		view.InsertSecret()
+		view.InsertRevision()
+		view.InsertStats()
+		view.InsertEvent()
 
 		return nil
 	})
@@ -449,6 +472,29 @@ func TestTrigger(t *testing.T) {
 	triggerRecv(t, fast)
 }
 
+func TestTriggerCount(t *testing.T) {
+	conn := New()
+	machines := triggerCount.Get(string(MachineTable))
+	clusters := triggerCount.Get(string(ClusterTable))
+
+	conn.Txn(AllTables...).Run(func(db Database) error {
+		db.InsertMachine()
+		db.InsertMachine()
+		return nil
+	})
+	conn.Txn(AllTables...).Run(func(db Database) error {
+		db.SelectFromMachine(nil)
+		return nil
+	})
+
+	if n := triggerCount.Get(string(MachineTable)) - machines; n != 1 {
+		t.Errorf("Counted %v machine table triggers, expected 1", n)
+	}
+	if n := triggerCount.Get(string(ClusterTable)) - clusters; n != 0 {
+		t.Errorf("Counted %v cluster table triggers, expected 0", n)
+	}
+}
+
 func TestTriggerTickStop(t *testing.T) {
 	conn := New()
 
@@ -590,6 +636,278 @@ func TestSliceHelpers(t *testing.T) {
 	sort.Sort(ConnectionSlice(conns))
 	assert.Equal(t, expConns, conns)
 	assert.Equal(t, conns[0], ConnectionSlice(conns).Get(0))
+
+	traffic := []Traffic{{From: "b", To: "a"}, {From: "a", To: "b", MinPort: 80},
+		{From: "a", To: "b", MinPort: 22}, {From: "a", To: "a"}}
+	expTraffic := []Traffic{{From: "a", To: "a"}, {From: "a", To: "b", MinPort: 22},
+		{From: "a", To: "b", MinPort: 80}, {From: "b", To: "a"}}
+	sort.Sort(TrafficSlice(traffic))
+	assert.Equal(t, expTraffic, traffic)
+	assert.Equal(t, traffic[0], TrafficSlice(traffic).Get(0))
+	assert.Equal(t, 4, TrafficSlice(traffic).Len())
+
+	portTraffic := []PortTraffic{{Minion: "b"}, {Minion: "a", StitchID: "2"},
+		{Minion: "a", StitchID: "1"}}
+	expPortTraffic := []PortTraffic{{Minion: "a", StitchID: "1"},
+		{Minion: "a", StitchID: "2"}, {Minion: "b"}}
+	sort.Sort(PortTrafficSlice(portTraffic))
+	assert.Equal(t, expPortTraffic, portTraffic)
+	assert.Equal(t, portTraffic[0], PortTrafficSlice(portTraffic).Get(0))
+	assert.Equal(t, 3, PortTrafficSlice(portTraffic).Len())
+
+	stats := []Stats{{Minion: "b"}, {Minion: "a", StitchID: "2"},
+		{Minion: "a", StitchID: "1"}}
+	expStats := []Stats{{Minion: "a", StitchID: "1"}, {Minion: "a", StitchID: "2"},
+		{Minion: "b"}}
+	sort.Sort(StatsSlice(stats))
+	assert.Equal(t, expStats, stats)
+	assert.Equal(t, stats[0], StatsSlice(stats).Get(0))
+	assert.Equal(t, 3, StatsSlice(stats).Len())
+
+	now := time.Now()
+	events := []Event{{ID: 1, LastSeen: now},
+		{ID: 3, LastSeen: now.Add(-time.Second)}, {ID: 2, LastSeen: now}}
+	expEvents := []Event{{ID: 3, LastSeen: now.Add(-time.Second)},
+		{ID: 1, LastSeen: now}, {ID: 2, LastSeen: now}}
+	sort.Sort(EventSlice(events))
+	assert.Equal(t, expEvents, events)
+	assert.Equal(t, events[0], EventSlice(events).Get(0))
+	assert.Equal(t, 3, EventSlice(events).Len())
+}
+
+func TestTraffic(t *testing.T) {
+	conn := New()
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		traffic := view.InsertTraffic()
+		traffic.From = "a"
+		traffic.To = "b"
+		traffic.MinPort = 80
+		traffic.MaxPort = 90
+		traffic.Packets = 2
+		traffic.Bytes = 100
+		view.Commit(traffic)
+		return nil
+	})
+
+	traffic := conn.SelectFromTraffic(nil)
+	assert.Len(t, traffic, 1)
+	assert.Equal(t, fmt.Sprintf("Traffic-%d{a->b:80-90: 2 packets, 100 bytes}",
+		traffic[0].ID), traffic[0].String())
+	assert.Empty(t, conn.SelectFromTraffic(func(t Traffic) bool {
+		return t.From == "b"
+	}))
+}
+
+func TestPortTraffic(t *testing.T) {
+	conn := New()
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		pt := view.InsertPortTraffic()
+		pt.StitchID = "1"
+		pt.Minion = "10.0.0.1"
+		pt.RxBytes = 100
+		view.Commit(pt)
+		return nil
+	})
+
+	portTraffic := conn.SelectFromPortTraffic(nil)
+	assert.Len(t, portTraffic, 1)
+	assert.Equal(t, fmt.Sprintf("PortTraffic-%d{StitchID=1, Minion=10.0.0.1, "+
+		"RxBytes=100}", portTraffic[0].ID), portTraffic[0].String())
+	assert.Empty(t, conn.SelectFromPortTraffic(func(pt PortTraffic) bool {
+		return pt.Minion == "10.0.0.2"
+	}))
+}
+
+func TestStats(t *testing.T) {
+	conn := New()
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		stats := view.InsertStats()
+		stats.Minion = "1.2.3.4"
+		stats.StitchID = "a"
+		stats.CPUPercent = 12.34
+		stats.MemoryUsage = 10
+		stats.MemoryLimit = 100
+		view.Commit(stats)
+		return nil
+	})
+
+	stats := conn.SelectFromStats(nil)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, fmt.Sprintf("Stats-%d{1.2.3.4/a: 12.3%% CPU, 10/100 bytes}",
+		stats[0].ID), stats[0].String())
+	assert.Empty(t, conn.SelectFromStats(func(s Stats) bool {
+		return s.StitchID == ""
+	}))
+}
+
+func TestRecordEvent(t *testing.T) {
+	conn := New()
+
+	placed := Event{Type: EventNormal, Reason: "Placed", Object: "container/a",
+		Message: "placed on 10.0.0.2", Source: "scheduler"}
+	conn.RecordEvent(placed)
+
+	events := conn.SelectFromEvent(nil)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 1, events[0].Count)
+	assert.Equal(t, events[0].FirstSeen, events[0].LastSeen)
+	firstSeen := events[0].FirstSeen
+
+	// Identical events are merged.
+	conn.RecordEvent(placed)
+	events = conn.SelectFromEvent(nil)
+	assert.Len(t, events, 1)
+	assert.Equal(t, 2, events[0].Count)
+	assert.Equal(t, firstSeen, events[0].FirstSeen)
+	assert.False(t, events[0].LastSeen.Before(firstSeen))
+
+	events[0].ID = 0
+	events[0].Count = 0
+	events[0].FirstSeen = time.Time{}
+	events[0].LastSeen = time.Time{}
+	assert.Equal(t, placed, events[0])
+
+	placed.Message = "placed on 10.0.0.3"
+	conn.RecordEvent(placed)
+	assert.Len(t, conn.SelectFromEvent(nil), 2)
+	assert.Len(t, conn.SelectFromEvent(func(e Event) bool {
+		return e.Count == 1
+	}), 1)
+}
+
+func TestMergeEvent(t *testing.T) {
+	conn := New()
+
+	now := time.Now()
+	pulled := Event{Type: EventWarning, Reason: "FailedPull", Object: "container/a",
+		Source: "scheduler", Machine: "1", Count: 1, FirstSeen: now,
+		LastSeen: now}
+	merge := func(e Event) []Event {
+		conn.Txn(EventTable).Run(func(view Database) error {
+			view.MergeEvent(e)
+			return nil
+		})
+
+		events := conn.SelectFromEvent(nil)
+		for i := range events {
+			events[i].ID = 0
+		}
+		return events
+	}
+
+	assert.Equal(t, []Event{pulled}, merge(pulled))
+
+	pulled.Count = 3
+	pulled.LastSeen = now.Add(time.Minute)
+	assert.Equal(t, []Event{pulled}, merge(pulled))
+
+	// The same event on another machine is distinct.
+	other := pulled
+	other.Machine = "2"
+	assert.Len(t, merge(other), 2)
+}
+
+func TestInsertEventFull(t *testing.T) {
+	conn := New()
+	start := time.Now()
+	conn.Txn(EventTable).Run(func(view Database) error {
+		for i := 0; i < maxEvents; i++ {
+			e := view.InsertEvent()
+			e.Object = fmt.Sprintf("container/%d", i)
+			e.LastSeen = start.Add(time.Duration(i) * time.Second)
+			view.Commit(e)
+		}
+
+		// The first event was the least recently seen, until it was repeated.
+		e := view.SelectFromEvent(func(e Event) bool {
+			return e.Object == "container/0"
+		})[0]
+		e.LastSeen = start.Add(time.Hour)
+		view.Commit(e)
+
+		view.InsertEvent()
+		return nil
+	})
+
+	events := conn.SelectFromEvent(nil)
+	assert.Len(t, events, maxEvents)
+	assert.Empty(t, conn.SelectFromEvent(func(e Event) bool {
+		return e.Object == "container/1"
+	}))
+	assert.Len(t, conn.SelectFromEvent(func(e Event) bool {
+		return e.Object == "container/0"
+	}), 1)
+}
+
+func TestSecret(t *testing.T) {
+	conn := New()
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		secret := view.InsertSecret()
+		secret.Name = "password"
+		secret.Value = "hunter2"
+		view.Commit(secret)
+		return nil
+	})
+
+	secrets := conn.SelectFromSecret(nil)
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, "hunter2", secrets[0].Value)
+	assert.Equal(t, fmt.Sprintf("Secret-%d{Name=password}", secrets[0].ID),
+		secrets[0].String())
+
+	// The value isn't sent to API clients.
+	b, err := json.Marshal(secrets[0])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":"password"}`, string(b))
+
+	assert.Empty(t, conn.SelectFromSecret(func(s Secret) bool {
+		return s.Name == "other"
+	}))
+
+	ss := []Secret{{Name: "b"}, {Name: "a"}}
+	sort.Sort(SecretSlice(ss))
+	assert.Equal(t, []Secret{{Name: "a"}, {Name: "b"}}, ss)
+	assert.Equal(t, ss[0], SecretSlice(ss).Get(0))
+	assert.Equal(t, 2, SecretSlice(ss).Len())
+}
+
+func TestSecretsDigest(t *testing.T) {
+	assert.Empty(t, SecretsDigest(nil))
+
+	digest := SecretsDigest(map[string]string{"a": "1", "b": "2"})
+	assert.Equal(t, digest, SecretsDigest(map[string]string{"b": "2", "a": "1"}))
+	assert.NotEqual(t, digest, SecretsDigest(map[string]string{"a": "1", "b": "3"}))
+	assert.NotEqual(t, SecretsDigest(map[string]string{"a": "12"}),
+		SecretsDigest(map[string]string{"a1": "2"}))
+}
+
+func TestRevision(t *testing.T) {
+	conn := New()
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		for _, number := range []int{2, 1} {
+			rev := view.InsertRevision()
+			rev.Number = number
+			rev.Hash = fmt.Sprintf("hash%d", number)
+			rev.Spec = "spec"
+			view.Commit(rev)
+		}
+		return nil
+	})
+
+	revs := RevisionSlice(conn.SelectFromRevision(nil))
+	assert.Len(t, revs, 2)
+	sort.Sort(revs)
+	assert.Equal(t, 1, revs[0].Number)
+	assert.Equal(t, revs[0], revs.Get(0))
+	assert.Equal(t, 2, revs.Len())
+
+	// The spec is omitted, as it's large.
+	assert.Equal(t, fmt.Sprintf("Revision-%d{Number=1, Hash=hash1, "+
+		"Time=0001-01-01 00:00:00 +0000 UTC}", revs[0].ID), revs[0].String())
+
+	assert.Len(t, conn.SelectFromRevision(func(r Revision) bool {
+		return r.Number == 2
+	}), 1)
 }
 
 func TestGetClusterNamespace(t *testing.T) {
@@ -13,8 +13,16 @@ type Etcd struct {
 
 	Leader   bool   // True if this Minion is the leader.
 	LeaderIP string // IP address of the current leader, or ""
+
+	// The hex encoded master key used to encrypt traffic between workers, or ""
+	// if the overlay isn't encrypted.
+	OverlayKey string `json:"-" rowStringer:"omit"`
 }
 
+// OverlayKeyLen is the length of the decoded OverlayKey: 32 bytes of AES-256 key
+// material followed by a 4 byte salt, as expected by rfc4106(gcm(aes)).
+const OverlayKeyLen = 36
+
 func (e Etcd) String() string {
 	return defaultString(e)
 }
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"time"
+)
+
+// EventType is the severity of an Event.
+type EventType string
+
+const (
+	// EventNormal events are expected transitions, such as a machine booting.
+	EventNormal EventType = "Normal"
+
+	// EventWarning events are failures, such as an image that couldn't be pulled.
+	EventWarning EventType = "Warning"
+)
+
+// The number of events kept in the Event table.  Once it's full, the least recently
+// seen event is removed for each new one.
+const maxEvents = 1000
+
+// An Event is a notable transition in the cluster, such as a machine booting or a
+// container failing to be placed.  Repeated events are merged into one row, whose
+// Count is the number of times the event occurred.
+type Event struct {
+	ID int `json:"-"`
+
+	Type    EventType
+	Reason  string // A short CamelCase reason, such as "Booted".
+	Object  string // What the event is about, such as "machine/<stitch id>".
+	Message string
+	Source  string // The component that recorded the event, such as "scheduler".
+
+	// The StitchID of the machine whose minion recorded the event.  Only set in the
+	// daemon, and empty for the daemon's own events.
+	Machine string `json:",omitempty"`
+
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// EventSlice is an alias for []Event to allow for joins
+type EventSlice []Event
+
+// InsertEvent creates a new event row and inserts it into the database.  If the
+// table is full, the least recently seen event is removed to make room.
+func (db Database) InsertEvent() Event {
+	events := db.SelectFromEvent(nil)
+	if len(events) >= maxEvents {
+		oldest := events[0]
+		for _, e := range events {
+			if e.LastSeen.Before(oldest.LastSeen) {
+				oldest = e
+			}
+		}
+		db.Remove(oldest)
+	}
+
+	result := Event{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// RecordEvent records an occurrence of `e`.  If an identical event has already been
+// recorded, its Count is incremented instead of inserting a new row.
+func (db Database) RecordEvent(e Event) {
+	now := time.Now()
+	events := db.SelectFromEvent(func(dbe Event) bool {
+		return sameEvent(dbe, e)
+	})
+
+	if len(events) > 0 {
+		e = events[0]
+	} else {
+		e.ID = db.InsertEvent().ID
+		e.Count = 0
+		e.FirstSeen = now
+	}
+
+	e.Count++
+	e.LastSeen = now
+	db.Commit(e)
+}
+
+// MergeEvent stores a copy of an event recorded elsewhere, such as by a minion.  If
+// an identical event is already stored, its counts are replaced by those of `e`.
+func (db Database) MergeEvent(e Event) {
+	events := db.SelectFromEvent(func(dbe Event) bool {
+		return sameEvent(dbe, e)
+	})
+
+	if len(events) > 0 {
+		e.ID = events[0].ID
+	} else {
+		e.ID = db.InsertEvent().ID
+	}
+	db.Commit(e)
+}
+
+// sameEvent returns whether `a` and `b` are occurrences of the same event.
+func sameEvent(a, b Event) bool {
+	return a.Type == b.Type && a.Reason == b.Reason && a.Object == b.Object &&
+		a.Message == b.Message && a.Source == b.Source && a.Machine == b.Machine
+}
+
+// RecordEvent records an occurrence of `e` in its own transaction.
+func (conn Conn) RecordEvent(e Event) {
+	conn.Txn(EventTable).Run(func(view Database) error {
+		view.RecordEvent(e)
+		return nil
+	})
+}
+
+// SelectFromEvent gets all events in the database that satisfy 'check'.
+func (db Database) SelectFromEvent(check func(Event) bool) []Event {
+	eventTable := db.accessTable(EventTable)
+	var result []Event
+	for _, row := range eventTable.rows {
+		if check == nil || check(row.(Event)) {
+			result = append(result, row.(Event))
+		}
+	}
+
+	return result
+}
+
+// SelectFromEvent gets all events in the database connection that satisfy 'check'.
+func (conn Conn) SelectFromEvent(check func(Event) bool) []Event {
+	var result []Event
+	conn.Txn(EventTable).Run(func(view Database) error {
+		result = view.SelectFromEvent(check)
+		return nil
+	})
+	return result
+}
+
+func (e Event) getID() int {
+	return e.ID
+}
+
+func (e Event) String() string {
+	return defaultString(e)
+}
+
+// Events are ordered by when they were last seen.
+func (e Event) less(r row) bool {
+	o := r.(Event)
+
+	switch {
+	case !e.LastSeen.Equal(o.LastSeen):
+		return e.LastSeen.Before(o.LastSeen)
+	default:
+		return e.ID < o.ID
+	}
+}
+
+// Get returns the value contained at the given index
+func (es EventSlice) Get(i int) interface{} {
+	return es[i]
+}
+
+// Len returns the number of items in the slice
+func (es EventSlice) Len() int {
+	return len(es)
+}
+
+// Less implements less than for sort.Interface.
+func (es EventSlice) Less(i, j int) bool {
+	return es[i].less(es[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (es EventSlice) Swap(i, j int) {
+	es[i], es[j] = es[j], es[i]
+}
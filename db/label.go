@@ -7,6 +7,11 @@ type Label struct {
 	Label        string
 	IP           string
 	ContainerIPs []string
+
+	// The IPv6 addresses are only set for dual stack deployments.  ContainerIPv6s
+	// is in the same order as ContainerIPs.
+	IPv6           string   `json:",omitempty"`
+	ContainerIPv6s []string `json:",omitempty"`
 }
 
 // LabelSlice is an alias for []Label to allow for joins
@@ -11,6 +11,8 @@ type Minion struct {
 	Spec           string `json:"-" rowStringer:"omit"`
 	AuthorizedKeys string `json:"-" rowStringer:"omit"`
 	SupervisorInit bool   `json:"-"`
+	Subnet         string `json:"-"`
+	IPv6Subnet     string `json:"-"`
 
 	// Below fields are included in the JSON encoding.
 	Role       Role
@@ -19,6 +21,7 @@ type Minion struct {
 	Size       string
 	Region     string
 	FloatingIP string
+	MTU        int
 }
 
 // InsertMinion creates a new Minion and inserts it into 'db'.
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"time"
+)
+
+// A Revision is a deployment that was deployed to the cluster.  The daemon keeps a
+// bounded history of them, so that the cluster can be rolled back.
+type Revision struct {
+	ID int `json:"-"`
+
+	Number int       // Increases with each deployment.
+	Hash   string    // The SHA-1 of the deployment.
+	Author string    // The host the deployment was sent from.
+	Time   time.Time // When the deployment was received.
+	Spec   string    `rowStringer:"omit"`
+}
+
+// RevisionSlice is an alias for []Revision to allow for joins
+type RevisionSlice []Revision
+
+// InsertRevision creates a new revision row and inserts it into the database.
+func (db Database) InsertRevision() Revision {
+	result := Revision{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromRevision gets all revisions in the database that satisfy 'check'.
+func (db Database) SelectFromRevision(check func(Revision) bool) []Revision {
+	revisionTable := db.accessTable(RevisionTable)
+	var result []Revision
+	for _, row := range revisionTable.rows {
+		if check == nil || check(row.(Revision)) {
+			result = append(result, row.(Revision))
+		}
+	}
+
+	return result
+}
+
+// SelectFromRevision gets all revisions in the database connection that satisfy
+// 'check'.
+func (conn Conn) SelectFromRevision(check func(Revision) bool) []Revision {
+	var result []Revision
+	conn.Txn(RevisionTable).Run(func(view Database) error {
+		result = view.SelectFromRevision(check)
+		return nil
+	})
+	return result
+}
+
+func (r Revision) getID() int {
+	return r.ID
+}
+
+func (r Revision) String() string {
+	return defaultString(r)
+}
+
+func (r Revision) less(row row) bool {
+	return r.Number < row.(Revision).Number
+}
+
+// Get returns the value contained at the given index
+func (rs RevisionSlice) Get(i int) interface{} {
+	return rs[i]
+}
+
+// Len returns the number of items in the slice
+func (rs RevisionSlice) Len() int {
+	return len(rs)
+}
+
+// Less implements less than for sort.Interface.
+func (rs RevisionSlice) Less(i, j int) bool {
+	return rs[i].less(rs[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (rs RevisionSlice) Swap(i, j int) {
+	rs[i], rs[j] = rs[j], rs[i]
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// A Secret row holds a value that containers may reference by name, such as a
+// database password, without it appearing in the deployment.  The daemon holds the
+// secrets set by the user, and delivers those referenced by the deployment to the
+// workers.
+//
+// The value is omitted from the logs and the API.  Like the rest of the database, it
+// only lives in memory, so secrets must be set again after the daemon restarts.
+type Secret struct {
+	ID int `json:"-"`
+
+	Name  string
+	Value string `json:"-" rowStringer:"omit"`
+}
+
+// SecretSlice is an alias for []Secret to allow for joins
+type SecretSlice []Secret
+
+// InsertSecret creates a new secret row and inserts it into the database.
+func (db Database) InsertSecret() Secret {
+	result := Secret{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromSecret gets all secrets in the database that satisfy 'check'.
+func (db Database) SelectFromSecret(check func(Secret) bool) []Secret {
+	secretTable := db.accessTable(SecretTable)
+	var result []Secret
+	for _, row := range secretTable.rows {
+		if check == nil || check(row.(Secret)) {
+			result = append(result, row.(Secret))
+		}
+	}
+
+	return result
+}
+
+// SelectFromSecret gets all secrets in the database connection that satisfy 'check'.
+func (conn Conn) SelectFromSecret(check func(Secret) bool) []Secret {
+	var result []Secret
+	conn.Txn(SecretTable).Run(func(view Database) error {
+		result = view.SelectFromSecret(check)
+		return nil
+	})
+	return result
+}
+
+// SecretsDigest returns a digest of the names and values of `secrets`, or the empty
+// string if there are none, so that the secrets held by a minion can be compared
+// with the daemon's without being sent back.
+func SecretsDigest(secrets map[string]string) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%d:%s%d:%s", len(name), name,
+			len(secrets[name]), secrets[name])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func (s Secret) getID() int {
+	return s.ID
+}
+
+func (s Secret) String() string {
+	return defaultString(s)
+}
+
+func (s Secret) less(r row) bool {
+	return s.Name < r.(Secret).Name
+}
+
+// Get returns the value contained at the given index
+func (ss SecretSlice) Get(i int) interface{} {
+	return ss[i]
+}
+
+// Len returns the number of items in the slice
+func (ss SecretSlice) Len() int {
+	return len(ss)
+}
+
+// Less implements less than for sort.Interface.
+func (ss SecretSlice) Less(i, j int) bool {
+	return ss[i].less(ss[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (ss SecretSlice) Swap(i, j int) {
+	ss[i], ss[j] = ss[j], ss[i]
+}
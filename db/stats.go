@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// A Stats row is a sample of the resource usage of a container, or of the machine
+// itself if StitchID is empty.  Each worker samples the containers it runs.
+type Stats struct {
+	ID int `json:"-"`
+
+	StitchID string `json:",omitempty"` // Empty for the machine's own usage.
+	Minion   string // The private IP of the machine.
+
+	CPUPercent  float64 // Relative to a single core, so it may exceed 100.
+	MemoryUsage uint64  // Bytes.
+	MemoryLimit uint64  // Bytes.
+	NetworkRx   uint64  // Bytes received since the container started.
+	NetworkTx   uint64  // Bytes sent since the container started.
+
+	// The load averages over the last 1, 5, and 15 minutes.  Only machines have
+	// them.
+	Load1, Load5, Load15 float64 `json:",omitempty"`
+
+	Time time.Time // When the sample was taken.
+}
+
+// StatsSlice is an alias for []Stats to allow for joins
+type StatsSlice []Stats
+
+// InsertStats creates a new stats row and inserts it into the database.
+func (db Database) InsertStats() Stats {
+	result := Stats{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromStats gets all stats rows in the database that satisfy 'check'.
+func (db Database) SelectFromStats(check func(Stats) bool) []Stats {
+	statsTable := db.accessTable(StatsTable)
+	var result []Stats
+	for _, row := range statsTable.rows {
+		if check == nil || check(row.(Stats)) {
+			result = append(result, row.(Stats))
+		}
+	}
+
+	return result
+}
+
+// SelectFromStats gets all stats rows in the database connection that satisfy
+// 'check'.
+func (conn Conn) SelectFromStats(check func(Stats) bool) []Stats {
+	var result []Stats
+	conn.Txn(StatsTable).Run(func(view Database) error {
+		result = view.SelectFromStats(check)
+		return nil
+	})
+	return result
+}
+
+func (s Stats) getID() int {
+	return s.ID
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("Stats-%d{%s/%s: %.1f%% CPU, %d/%d bytes}", s.ID, s.Minion,
+		s.StitchID, s.CPUPercent, s.MemoryUsage, s.MemoryLimit)
+}
+
+func (s Stats) less(r row) bool {
+	o := r.(Stats)
+
+	switch {
+	case s.Minion != o.Minion:
+		return s.Minion < o.Minion
+	case s.StitchID != o.StitchID:
+		return s.StitchID < o.StitchID
+	default:
+		return s.ID < o.ID
+	}
+}
+
+// Get returns the value contained at the given index
+func (ss StatsSlice) Get(i int) interface{} {
+	return ss[i]
+}
+
+// Len returns the number of items in the slice
+func (ss StatsSlice) Len() int {
+	return len(ss)
+}
+
+// Less implements less than for sort.Interface.
+func (ss StatsSlice) Less(i, j int) bool {
+	return ss[i].less(ss[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (ss StatsSlice) Swap(i, j int) {
+	ss[i], ss[j] = ss[j], ss[i]
+}
@@ -35,11 +35,30 @@ var PlacementTable = TableType(reflect.TypeOf(Placement{}).String())
 // ACLTable is the type of the ACL table.
 var ACLTable = TableType(reflect.TypeOf(ACL{}).String())
 
+// TrafficTable is the type of the traffic table.
+var TrafficTable = TableType(reflect.TypeOf(Traffic{}).String())
+
+// PortTrafficTable is the type of the port traffic table.
+var PortTrafficTable = TableType(reflect.TypeOf(PortTraffic{}).String())
+
+// SecretTable is the type of the secret table.
+var SecretTable = TableType(reflect.TypeOf(Secret{}).String())
+
+// RevisionTable is the type of the revision table.
+var RevisionTable = TableType(reflect.TypeOf(Revision{}).String())
+
+// StatsTable is the type of the stats table.
+var StatsTable = TableType(reflect.TypeOf(Stats{}).String())
+
+// EventTable is the type of the event table.
+var EventTable = TableType(reflect.TypeOf(Event{}).String())
+
 // AllTables is a slice of all the db TableTypes. It is used primarily for tests,
 // where there is no reason to put lots of thought into which tables a Transaction
 // should use.
 var AllTables = []TableType{ClusterTable, MachineTable, ContainerTable, MinionTable,
-	ConnectionTable, LabelTable, EtcdTable, PlacementTable, ACLTable}
+	ConnectionTable, LabelTable, EtcdTable, PlacementTable, ACLTable, TrafficTable,
+	PortTrafficTable, SecretTable, RevisionTable, StatsTable, EventTable}
 
 type table struct {
 	rows map[int]row
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"fmt"
+)
+
+// A Traffic row counts the traffic sent over a connection, i.e. the TCP and UDP
+// traffic the containers implementing the To label have received from the
+// containers implementing the From label on ports MinPort through MaxPort.  Each
+// worker counts the traffic received by its own containers, which the leader totals
+// for the cluster.
+type Traffic struct {
+	ID int `json:"-"`
+
+	From    string
+	To      string
+	MinPort int
+	MaxPort int
+	Packets uint64
+	Bytes   uint64
+}
+
+// TrafficSlice is an alias for []Traffic to allow for joins
+type TrafficSlice []Traffic
+
+// InsertTraffic creates a new traffic row and inserts it into the database.
+func (db Database) InsertTraffic() Traffic {
+	result := Traffic{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromTraffic gets all traffic rows in the database that satisfy 'check'.
+func (db Database) SelectFromTraffic(check func(Traffic) bool) []Traffic {
+	trafficTable := db.accessTable(TrafficTable)
+	var result []Traffic
+	for _, row := range trafficTable.rows {
+		if check == nil || check(row.(Traffic)) {
+			result = append(result, row.(Traffic))
+		}
+	}
+
+	return result
+}
+
+// SelectFromTraffic gets all traffic rows in the database connection that satisfy
+// 'check'.
+func (conn Conn) SelectFromTraffic(check func(Traffic) bool) []Traffic {
+	var result []Traffic
+	conn.Txn(TrafficTable).Run(func(view Database) error {
+		result = view.SelectFromTraffic(check)
+		return nil
+	})
+	return result
+}
+
+func (t Traffic) getID() int {
+	return t.ID
+}
+
+func (t Traffic) String() string {
+	ports := PortRange{MinPort: t.MinPort, MaxPort: t.MaxPort}
+	return fmt.Sprintf("Traffic-%d{%s->%s:%s: %d packets, %d bytes}", t.ID,
+		t.From, t.To, ports, t.Packets, t.Bytes)
+}
+
+func (t Traffic) less(r row) bool {
+	o := r.(Traffic)
+
+	switch {
+	case t.From != o.From:
+		return t.From < o.From
+	case t.To != o.To:
+		return t.To < o.To
+	case t.MinPort != o.MinPort:
+		return t.MinPort < o.MinPort
+	case t.MaxPort != o.MaxPort:
+		return t.MaxPort < o.MaxPort
+	default:
+		return t.ID < o.ID
+	}
+}
+
+// Get returns the value contained at the given index
+func (ts TrafficSlice) Get(i int) interface{} {
+	return ts[i]
+}
+
+// Len returns the number of items in the slice
+func (ts TrafficSlice) Len() int {
+	return len(ts)
+}
+
+// Less implements less than for sort.Interface.
+func (ts TrafficSlice) Less(i, j int) bool {
+	return ts[i].less(ts[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (ts TrafficSlice) Swap(i, j int) {
+	ts[i], ts[j] = ts[j], ts[i]
+}
+
+// A PortTraffic row counts the traffic through the OVS port of a container, from the
+// container's point of view.  Unlike Traffic, it includes traffic that isn't sent
+// over a connection, such as to the public internet.  Each worker counts the ports
+// of its own containers.
+type PortTraffic struct {
+	ID int `json:"-"`
+
+	StitchID string
+	Minion   string // The private IP of the container's machine.
+	Port     string // The name of the container's veth.
+
+	RxPackets uint64
+	RxBytes   uint64
+	TxPackets uint64
+	TxBytes   uint64
+}
+
+// PortTrafficSlice is an alias for []PortTraffic to allow for joins
+type PortTrafficSlice []PortTraffic
+
+// InsertPortTraffic creates a new port traffic row and inserts it into the database.
+func (db Database) InsertPortTraffic() PortTraffic {
+	result := PortTraffic{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromPortTraffic gets all port traffic rows in the database that satisfy
+// 'check'.
+func (db Database) SelectFromPortTraffic(check func(PortTraffic) bool) []PortTraffic {
+	portTrafficTable := db.accessTable(PortTrafficTable)
+	var result []PortTraffic
+	for _, row := range portTrafficTable.rows {
+		if check == nil || check(row.(PortTraffic)) {
+			result = append(result, row.(PortTraffic))
+		}
+	}
+
+	return result
+}
+
+// SelectFromPortTraffic gets all port traffic rows in the database connection that
+// satisfy 'check'.
+func (conn Conn) SelectFromPortTraffic(check func(PortTraffic) bool) []PortTraffic {
+	var result []PortTraffic
+	conn.Txn(PortTrafficTable).Run(func(view Database) error {
+		result = view.SelectFromPortTraffic(check)
+		return nil
+	})
+	return result
+}
+
+func (pt PortTraffic) getID() int {
+	return pt.ID
+}
+
+func (pt PortTraffic) String() string {
+	return defaultString(pt)
+}
+
+func (pt PortTraffic) less(r row) bool {
+	o := r.(PortTraffic)
+
+	switch {
+	case pt.Minion != o.Minion:
+		return pt.Minion < o.Minion
+	case pt.StitchID != o.StitchID:
+		return pt.StitchID < o.StitchID
+	default:
+		return pt.ID < o.ID
+	}
+}
+
+// Get returns the value contained at the given index
+func (pts PortTrafficSlice) Get(i int) interface{} {
+	return pts[i]
+}
+
+// Len returns the number of items in the slice
+func (pts PortTrafficSlice) Len() int {
+	return len(pts)
+}
+
+// Less implements less than for sort.Interface.
+func (pts PortTrafficSlice) Less(i, j int) bool {
+	return pts[i].less(pts[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (pts PortTrafficSlice) Swap(i, j int) {
+	pts[i], pts[j] = pts[j], pts[i]
+}
@@ -0,0 +1,369 @@
+// Package metrics records counters, gauges, and histograms about the daemon and
+// minions, and serves them over HTTP in the Prometheus text format so that they can
+// be scraped.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// DefaultPort is the port on which minions serve their metrics.
+const DefaultPort = 9001
+
+// DefaultDaemonAddress is the address on which the daemon serves its metrics.
+const DefaultDaemonAddress = "localhost:9001"
+
+// DefBuckets are the default histogram buckets, in seconds.  They cover loops that
+// take anywhere from a few milliseconds to a minute.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Label values are joined with a byte that can't appear in UTF-8 to key samples.
+const labelSep = "\xff"
+
+type metric interface {
+	getName() string
+	write(w io.Writer)
+}
+
+var registry = struct {
+	sync.Mutex
+	metrics map[string]metric
+}{metrics: map[string]metric{}}
+
+// register adds `m` to the metrics that are served, replacing any metric with the
+// same name.
+func register(m metric) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.metrics[m.getName()] = m
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) getName() string {
+	return d.name
+}
+
+func (d desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d",
+			d.name, len(d.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelSep)
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	help := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help)
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.typ)
+}
+
+// writeSample writes one line of the exposition.  `key` holds the label values of
+// the sample, and `extra` any additional label pairs, such as a histogram's "le".
+func (d desc) writeSample(w io.Writer, suffix, key string, value float64,
+	extra ...string) {
+
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, v := range strings.Split(key, labelSep) {
+			pairs = append(pairs, labelPair(d.labels[i], v))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, labelPair(extra[i], extra[i+1]))
+	}
+
+	labels := ""
+	if len(pairs) > 0 {
+		labels = "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s%s%s %s\n", d.name, suffix, labels, formatFloat(value))
+}
+
+func labelPair(name, value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return fmt.Sprintf(`%s="%s"`, name, escaped)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func sortedKeys(values map[string]float64) []string {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// A Counter is a metric that only increases, such as the number of API errors.
+type Counter struct {
+	desc
+
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates and registers a counter partitioned by `labels`.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, typ: "counter", labels: labels},
+		values: map[string]float64{},
+	}
+	register(c)
+	return c
+}
+
+// Inc increments the counter with the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds `v`, which must not be negative, to the counter with the given label
+// values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("counters can't decrease")
+	}
+
+	key := c.key(labelValues)
+	c.lock.Lock()
+	c.values[key] += v
+	c.lock.Unlock()
+}
+
+// Get returns the value of the counter with the given label values.
+func (c *Counter) Get(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.values) == 0 {
+		return
+	}
+
+	c.writeHeader(w)
+	for _, key := range sortedKeys(c.values) {
+		c.writeSample(w, "", key, c.values[key])
+	}
+}
+
+// A GaugeFunc is a metric whose values are computed each time the metrics are
+// scraped, such as the number of machines in each state.
+type GaugeFunc struct {
+	desc
+	collect func(set func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc creates and registers a gauge partitioned by `labels`.  When scraped,
+// `collect` is called, and must call `set` once for each combination of label
+// values.
+func NewGaugeFunc(name, help string, labels []string,
+	collect func(set func(value float64, labelValues ...string))) *GaugeFunc {
+
+	g := &GaugeFunc{
+		desc:    desc{name: name, help: help, typ: "gauge", labels: labels},
+		collect: collect,
+	}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	values := map[string]float64{}
+	g.collect(func(value float64, labelValues ...string) {
+		values[g.key(labelValues)] = value
+	})
+
+	if len(values) == 0 {
+		return
+	}
+
+	g.writeHeader(w)
+	for _, key := range sortedKeys(values) {
+		g.writeSample(w, "", key, values[key])
+	}
+}
+
+// A Histogram counts observations, such as loop durations, in buckets.
+type Histogram struct {
+	desc
+	buckets []float64
+
+	lock   sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64 // The number of observations in each bucket, not cumulative.
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates and registers a histogram partitioned by `labels`.  `buckets`
+// are the sorted upper bounds of the buckets; an implicit +Inf bucket is added.
+func NewHistogram(name, help string, buckets []float64,
+	labels ...string) *Histogram {
+
+	h := &Histogram{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: buckets,
+		values:  map[string]*histogramValue{},
+	}
+	register(h)
+	return h
+}
+
+// Observe adds an observation to the histogram with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// ObserveSince observes the seconds elapsed since `start`.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns the number of observations of the histogram with the given label
+// values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if hv, ok := h.values[key]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.values) == 0 {
+		return
+	}
+
+	var keys []string
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h.writeHeader(w)
+	for _, key := range keys {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hv.counts[i]
+			h.writeSample(w, "_bucket", key, float64(cumulative),
+				"le", formatFloat(bound))
+		}
+		h.writeSample(w, "_bucket", key, float64(hv.count), "le", "+Inf")
+		h.writeSample(w, "_sum", key, hv.sum)
+		h.writeSample(w, "_count", key, float64(hv.count))
+	}
+}
+
+// Write writes every metric that has at least one sample to `w`, in the Prometheus
+// text format.
+func Write(w io.Writer) {
+	registry.Lock()
+	var metrics []metric
+	for _, m := range registry.metrics {
+		metrics = append(metrics, m)
+	}
+	registry.Unlock()
+
+	sort.Sort(metricSlice(metrics))
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler returns an http.Handler that serves the metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w)
+	})
+}
+
+// Run serves the metrics at /metrics on `addr`.  It blocks, retrying if the server
+// fails.
+func Run(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	for {
+		err := listenAndServe(addr, mux)
+		log.WithError(err).WithField("address", addr).Error(
+			"Failed to serve metrics.")
+		sleep(30 * time.Second)
+	}
+}
+
+var listenAndServe = http.ListenAndServe
+var sleep = time.Sleep
+
+type metricSlice []metric
+
+func (ms metricSlice) Len() int {
+	return len(ms)
+}
+
+func (ms metricSlice) Less(i, j int) bool {
+	return ms[i].getName() < ms[j].getName()
+}
+
+func (ms metricSlice) Swap(i, j int) {
+	ms[i], ms[j] = ms[j], ms[i]
+}
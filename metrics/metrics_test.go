@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// output returns the exposition of the metrics whose name starts with `prefix`.
+func output(prefix string) string {
+	var b bytes.Buffer
+	Write(&b)
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := fields[0]
+		if name == "#" && len(fields) > 2 {
+			name = fields[2]
+		}
+		if strings.HasPrefix(name, prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestCounter(t *testing.T) {
+	t.Parallel()
+
+	c := NewCounter("test_counter_total", "A test counter.", "a", "b")
+	assert.Empty(t, output("test_counter"))
+
+	c.Inc("x", "y")
+	c.Add(2, "x", "y")
+	c.Inc("x", `"z"`)
+	assert.Equal(t, float64(3), c.Get("x", "y"))
+	assert.Equal(t, float64(0), c.Get("x", "w"))
+
+	exp := `# HELP test_counter_total A test counter.
+# TYPE test_counter_total counter
+test_counter_total{a="x",b="\"z\""} 1
+test_counter_total{a="x",b="y"} 3`
+	assert.Equal(t, exp, output("test_counter"))
+
+	assert.Panics(t, func() { c.Inc("x") })
+	assert.Panics(t, func() { c.Add(-1, "x", "y") })
+}
+
+func TestCounterNoLabels(t *testing.T) {
+	t.Parallel()
+
+	c := NewCounter("test_nolabels_total", "Line one\nline two.")
+	c.Inc()
+
+	exp := `# HELP test_nolabels_total Line one\nline two.
+# TYPE test_nolabels_total counter
+test_nolabels_total 1`
+	assert.Equal(t, exp, output("test_nolabels"))
+}
+
+func TestGaugeFunc(t *testing.T) {
+	t.Parallel()
+
+	var values map[string]float64
+	NewGaugeFunc("test_gauge", "A test gauge.", []string{"state"},
+		func(set func(float64, ...string)) {
+			for state, v := range values {
+				set(v, state)
+			}
+		})
+	assert.Empty(t, output("test_gauge"))
+
+	values = map[string]float64{"up": 2, "down": 0.5}
+	exp := `# HELP test_gauge A test gauge.
+# TYPE test_gauge gauge
+test_gauge{state="down"} 0.5
+test_gauge{state="up"} 2`
+	assert.Equal(t, exp, output("test_gauge"))
+}
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+
+	h := NewHistogram("test_histogram_seconds", "A test histogram.",
+		[]float64{0.5, 1}, "loop")
+	assert.Empty(t, output("test_histogram"))
+
+	h.Observe(0.25, "a")
+	h.Observe(1, "a")
+	h.Observe(5, "a")
+	assert.Equal(t, uint64(3), h.Count("a"))
+	assert.Equal(t, uint64(0), h.Count("b"))
+
+	exp := `# HELP test_histogram_seconds A test histogram.
+# TYPE test_histogram_seconds histogram
+test_histogram_seconds_bucket{loop="a",le="0.5"} 1
+test_histogram_seconds_bucket{loop="a",le="1"} 2
+test_histogram_seconds_bucket{loop="a",le="+Inf"} 3
+test_histogram_seconds_sum{loop="a"} 6.25
+test_histogram_seconds_count{loop="a"} 3`
+	assert.Equal(t, exp, output("test_histogram"))
+}
+
+func TestFormatFloat(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "+Inf", formatFloat(math.Inf(1)))
+	assert.Equal(t, "-Inf", formatFloat(math.Inf(-1)))
+	assert.Equal(t, "NaN", formatFloat(math.NaN()))
+	assert.Equal(t, "0.005", formatFloat(0.005))
+	assert.Equal(t, "12", formatFloat(12))
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	NewCounter("test_handler_total", "A test counter.").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "\ntest_handler_total 1\n")
+}
@@ -2,11 +2,17 @@ package docker
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/net/context"
+
 	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/util"
 
 	log "github.com/Sirupsen/logrus"
 	dkc "github.com/fsouza/go-dockerclient"
@@ -14,11 +20,22 @@ import (
 
 var pullCacheTimeout = time.Minute
 var networkTimeout = time.Minute
+var statsTimeout = 30 * time.Second
 
 // ErrNoSuchContainer is the error returned when an operation is requested on a
 // non-existent container.
 var ErrNoSuchContainer = errors.New("container does not exist")
 
+// A PullError is returned by Run when the container's image couldn't be pulled.
+type PullError struct {
+	Image string
+	Err   error
+}
+
+func (err PullError) Error() string {
+	return fmt.Sprintf("failed to pull %s: %s", err.Image, err.Err)
+}
+
 // A Container as returned by the docker client API.
 type Container struct {
 	ID      string
@@ -26,6 +43,7 @@ type Container struct {
 	Name    string
 	Image   string
 	IP      string
+	IPv6    string
 	Mac     string
 	Path    string
 	Status  string
@@ -46,6 +64,38 @@ type Client struct {
 	imageCache map[string]*cacheEntry
 }
 
+// ExecOptions changes the behavior of the Exec function.
+type ExecOptions struct {
+	Cmd []string
+	Tty bool
+
+	Stdin  io.Reader // Not attached if nil.
+	Stdout io.Writer
+	Stderr io.Writer // Unused with a TTY, whose output all goes to Stdout.
+
+	// The size of the TTY is changed to each size received until Resize is closed.
+	Resize <-chan TTYSize
+}
+
+// LogsOptions changes the behavior of the Logs function.
+type LogsOptions struct {
+	Since      time.Time // All logs are shown if zero.
+	Tail       string    // The number of lines to show from the end, or "all".
+	Follow     bool
+	Timestamps bool
+
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Following the logs stops once Context is done.
+	Context context.Context
+}
+
+// TTYSize is the size of a terminal, in characters.
+type TTYSize struct {
+	Height, Width int
+}
+
 type cacheEntry struct {
 	sync.Mutex
 	expiration time.Time
@@ -58,8 +108,10 @@ type RunOptions struct {
 	Args   []string
 	Labels map[string]string
 	Env    map[string]string
+	Files  map[string]string // File contents, by absolute path.
 
 	IP          string
+	IPv6        string
 	NetworkMode string
 	DNS         []string
 	DNSSearch   []string
@@ -71,6 +123,13 @@ type RunOptions struct {
 
 type client interface {
 	StartContainer(id string, hostConfig *dkc.HostConfig) error
+	StopContainer(id string, timeout uint) error
+	CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error)
+	StartExec(id string, opts dkc.StartExecOptions) error
+	ResizeExecTTY(id string, height, width int) error
+	InspectExec(id string) (*dkc.ExecInspect, error)
+	Logs(opts dkc.LogsOptions) error
+	Stats(opts dkc.StatsOptions) error
 	UploadToContainer(id string, opts dkc.UploadToContainerOptions) error
 	DownloadFromContainer(id string, opts dkc.DownloadFromContainerOptions) error
 	RemoveContainer(opts dkc.RemoveContainerOptions) error
@@ -122,6 +181,7 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 				"quilt": {
 					IPAMConfig: &dkc.EndpointIPAMConfig{
 						IPv4Address: opts.IP,
+						IPv6Address: opts.IPv6,
 					},
 				},
 			},
@@ -133,6 +193,15 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 		return "", err
 	}
 
+	// The files are uploaded before the container starts, so that they're in
+	// place when its process reads them.
+	for path, content := range opts.Files {
+		if err = dk.uploadFile(id, path, content); err != nil {
+			dk.RemoveID(id) // Remove the container to avoid a zombie.
+			return "", fmt.Errorf("failed to upload %s: %s", path, err)
+		}
+	}
+
 	if err = dk.StartContainer(id, hc); err != nil {
 		dk.RemoveID(id) // Remove the container to avoid a zombie.
 		return "", err
@@ -141,6 +210,36 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 	return id, nil
 }
 
+// uploadFile writes `content` to the absolute `path` in the container.  Docker
+// creates any missing parent directories.
+func (dk Client) uploadFile(id, path, content string) error {
+	name := strings.TrimPrefix(filepath.Clean(path), "/")
+	tarball, err := util.ToTar(name, 0644, content)
+	if err != nil {
+		return err
+	}
+
+	return dk.Upload(id, "/", tarball)
+}
+
+// Upload extracts the tar archive `tarball` into the directory `dir` of the
+// container with the given ID.
+func (dk Client) Upload(id, dir string, tarball io.Reader) error {
+	return dk.UploadToContainer(id, dkc.UploadToContainerOptions{
+		InputStream: tarball,
+		Path:        dir,
+	})
+}
+
+// Download writes a tar archive of `path` in the container with the given ID to
+// `tarball`.  The archive is rooted at the base name of `path`.
+func (dk Client) Download(id, path string, tarball io.Writer) error {
+	return dk.DownloadFromContainer(id, dkc.DownloadFromContainerOptions{
+		OutputStream: tarball,
+		Path:         path,
+	})
+}
+
 // ConfigureNetwork makes a request to docker to create a network running on driver.
 func (dk Client) ConfigureNetwork(driver string) error {
 	networks, err := dk.ListNetworks()
@@ -152,15 +251,22 @@ func (dk Client) ConfigureNetwork(driver string) error {
 		}
 	}
 
+	ipam := []dkc.IPAMConfig{{
+		Subnet:  ipdef.QuiltSubnet.String(),
+		Gateway: ipdef.GatewayIP.String(),
+	}}
+	if ipdef.QuiltSubnet6 != nil {
+		ipam = append(ipam, dkc.IPAMConfig{
+			Subnet:  ipdef.QuiltSubnet6.String(),
+			Gateway: ipdef.GatewayIP6.String(),
+		})
+	}
+
 	_, err = dk.CreateNetwork(dkc.CreateNetworkOptions{
-		Name:   driver,
-		Driver: driver,
-		IPAM: dkc.IPAMOptions{
-			Config: []dkc.IPAMConfig{{
-				Subnet:  ipdef.QuiltSubnet.String(),
-				Gateway: ipdef.GatewayIP.String(),
-			}},
-		},
+		Name:       driver,
+		Driver:     driver,
+		EnableIPv6: ipdef.QuiltSubnet6 != nil,
+		IPAM:       dkc.IPAMOptions{Config: ipam},
 	})
 
 	return err
@@ -176,6 +282,145 @@ func (dk Client) Remove(name string) error {
 	return dk.RemoveID(id)
 }
 
+// Stop sends SIGTERM to the container with the given ID, and kills it if it hasn't
+// exited after `timeout`.  Stopping a container that isn't running is not an error.
+func (dk Client) Stop(id string, timeout time.Duration) error {
+	err := dk.StopContainer(id, uint(timeout/time.Second))
+	if _, ok := err.(*dkc.ContainerNotRunning); ok {
+		return nil
+	}
+	return err
+}
+
+// Exec runs a command in the container with the given ID, streaming its standard
+// streams, and returns its exit code once it exits.
+func (dk Client) Exec(id string, opts ExecOptions) (int, error) {
+	exec, err := dk.CreateExec(dkc.CreateExecOptions{
+		Container:    id,
+		Cmd:          opts.Cmd,
+		Tty:          opts.Tty,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Resize != nil {
+		go func() {
+			for size := range opts.Resize {
+				// Resizing fails if the command hasn't started yet, or has
+				// already exited, neither of which matter.
+				dk.ResizeExecTTY(exec.ID, size.Height, size.Width)
+			}
+		}()
+	}
+
+	err = dk.StartExec(exec.ID, dkc.StartExecOptions{
+		InputStream:  opts.Stdin,
+		OutputStream: opts.Stdout,
+		ErrorStream:  opts.Stderr,
+		Tty:          opts.Tty,
+		RawTerminal:  opts.Tty,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	inspect, err := dk.InspectExec(exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
+// Logs writes the logs of the container with the given name or ID.  If following
+// them, it blocks until the container exits or the context is done.
+func (dk Client) Logs(id string, opts LogsOptions) error {
+	var since int64
+	if !opts.Since.IsZero() {
+		since = opts.Since.Unix()
+	}
+
+	tail := opts.Tail
+	if tail == "" {
+		tail = "all"
+	}
+
+	return dk.client.Logs(dkc.LogsOptions{
+		Container:    id,
+		OutputStream: opts.Stdout,
+		ErrorStream:  opts.Stderr,
+		Follow:       opts.Follow,
+		Stdout:       true,
+		Stderr:       true,
+		Since:        since,
+		Timestamps:   opts.Timestamps,
+		Tail:         tail,
+		Context:      opts.Context,
+	})
+}
+
+// ContainerStats is a sample of the resource usage of a container.
+type ContainerStats struct {
+	CPUPercent  float64 // Relative to a single core, so it may exceed 100.
+	MemoryUsage uint64  // Bytes.
+	MemoryLimit uint64  // Bytes.
+	NetworkRx   uint64  // Bytes received since the container started.
+	NetworkTx   uint64  // Bytes sent since the container started.
+}
+
+// Stats samples the resource usage of the container with the given ID.
+func (dk Client) Stats(id string) (ContainerStats, error) {
+	statsChan := make(chan *dkc.Stats, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- dk.client.Stats(dkc.StatsOptions{
+			ID:      id,
+			Stats:   statsChan,
+			Stream:  false,
+			Timeout: statsTimeout,
+		})
+	}()
+
+	var sample *dkc.Stats
+	for s := range statsChan {
+		sample = s
+	}
+
+	if err := <-errChan; err != nil {
+		return ContainerStats{}, err
+	}
+
+	if sample == nil {
+		return ContainerStats{}, fmt.Errorf("no stats for container %s", id)
+	}
+
+	stats := ContainerStats{
+		MemoryUsage: sample.MemoryStats.Usage,
+		MemoryLimit: sample.MemoryStats.Limit,
+	}
+
+	// The CPU usage is the container's share of the system's CPU time since the
+	// previous sample, as calculated by `docker stats`.
+	cpu := sample.CPUStats.CPUUsage.TotalUsage
+	preCPU := sample.PreCPUStats.CPUUsage.TotalUsage
+	system := sample.CPUStats.SystemCPUUsage
+	preSystem := sample.PreCPUStats.SystemCPUUsage
+	if cpu > preCPU && system > preSystem {
+		cores := float64(len(sample.CPUStats.CPUUsage.PercpuUsage))
+		stats.CPUPercent = float64(cpu-preCPU) / float64(system-preSystem) *
+			cores * 100
+	}
+
+	for _, network := range sample.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+	return stats, nil
+}
+
 // RemoveID stops and deletes the container with the given ID.
 func (dk Client) RemoveID(id string) error {
 	err := dk.RemoveContainer(dkc.RemoveContainerOptions{ID: id, Force: true})
@@ -279,6 +524,7 @@ func (dk Client) Get(id string) (Container, error) {
 		Name:    dkc.Name,
 		ID:      dkc.ID,
 		IP:      dkc.NetworkSettings.IPAddress,
+		IPv6:    dkc.NetworkSettings.GlobalIPv6Address,
 		Mac:     dkc.NetworkSettings.MacAddress,
 		EID:     dkc.NetworkSettings.EndpointID,
 		Image:   dkc.Config.Image,
@@ -295,6 +541,7 @@ func (dk Client) Get(id string) (Container, error) {
 	if len(networks) == 1 {
 		config := dkc.NetworkSettings.Networks[networks[0]]
 		c.IP = config.IPAddress
+		c.IPv6 = config.GlobalIPv6Address
 		c.Mac = config.MacAddress
 		c.EID = config.EndpointID
 	} else if len(networks) > 1 {
@@ -328,7 +575,7 @@ func (dk Client) create(name, image string, args []string, labels map[string]str
 	error) {
 
 	if err := dk.Pull(image); err != nil {
-		return "", err
+		return "", PullError{image, err}
 	}
 
 	container, err := dk.CreateContainer(dkc.CreateContainerOptions{
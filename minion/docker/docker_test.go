@@ -1,11 +1,17 @@
 package docker
 
 import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/quilt/quilt/minion/ipdef"
 	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -110,7 +116,8 @@ func TestCreateGet(t *testing.T) {
 
 	md.PullError = true
 	_, err := dk.create("name", "image", nil, nil, nil, nil, nil)
-	assert.NotNil(t, err)
+	assert.Equal(t, PullError{"image", errors.New("pull error")}, err)
+	assert.EqualError(t, err, "failed to pull image: pull error")
 	md.PullError = false
 
 	md.CreateError = true
@@ -175,7 +182,7 @@ func TestRun(t *testing.T) {
 	id2, err := dk.Run(RunOptions{Name: "name2"})
 	assert.Nil(t, err)
 
-	md.StopContainer(id2)
+	assert.Nil(t, md.StopContainer(id2, 0))
 
 	containers, err = dk.List(nil)
 	assert.Nil(t, err)
@@ -222,6 +229,185 @@ func TestRunEnv(t *testing.T) {
 	assert.Equal(t, env, container.Env)
 }
 
+func TestRunFiles(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	files := map[string]string{
+		"/etc/haproxy.cfg":  "haproxy",
+		"/etc/nginx/../foo": "foo",
+	}
+	id, err := dk.Run(RunOptions{Name: "name1", Files: files})
+	assert.Nil(t, err)
+
+	exp := map[string]string{
+		"/etc/haproxy.cfg": "haproxy",
+		"/etc/foo":         "foo",
+	}
+	assert.Equal(t, exp, md.Containers[id].Files)
+	assert.True(t, md.Containers[id].Running)
+
+	md.UploadError = true
+	_, err = dk.Run(RunOptions{Name: "name2", Files: files})
+	assert.NotNil(t, err)
+	md.UploadError = false
+
+	// The container that couldn't be set up is removed.
+	containers, err := dk.List(nil)
+	assert.Nil(t, err)
+	assert.Len(t, containers, 1)
+}
+
+func TestStop(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	id, err := dk.Run(RunOptions{Name: "name1"})
+	assert.Nil(t, err)
+
+	md.StopError = true
+	assert.NotNil(t, dk.Stop(id, 30*time.Second))
+	md.StopError = false
+	assert.True(t, md.Containers[id].Running)
+
+	assert.Nil(t, dk.Stop(id, 30*time.Second))
+	assert.False(t, md.Containers[id].Running)
+	assert.Equal(t, uint(30), md.Stopped[id])
+
+	// Stopping a container that has already exited succeeds.
+	assert.Nil(t, dk.Stop(id, time.Second))
+	assert.Equal(t, uint(30), md.Stopped[id])
+
+	assert.NotNil(t, dk.Stop("missing", time.Second))
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	id, err := dk.Run(RunOptions{Name: "name1"})
+	assert.Nil(t, err)
+
+	var stdout bytes.Buffer
+	resize := make(chan TTYSize, 1)
+	resize <- TTYSize{Height: 24, Width: 80}
+	close(resize)
+
+	md.ExecExitCode = 3
+	code, err := dk.Exec(id, ExecOptions{
+		Cmd:    []string{"cat", "-"},
+		Tty:    true,
+		Stdin:  strings.NewReader("input"),
+		Stdout: &stdout,
+		Resize: resize,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "input", stdout.String())
+	assert.Equal(t, []string{"cat -"}, md.Executions[id])
+
+	var sizes []TTYSize
+	for i := 0; i < 100 && len(sizes) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		md.Lock()
+		for _, s := range md.ExecSizes {
+			sizes = s
+		}
+		md.Unlock()
+	}
+	assert.Equal(t, []TTYSize{{Height: 24, Width: 80}}, sizes)
+
+	_, err = dk.Exec("missing", ExecOptions{Cmd: []string{"ls"}})
+	assert.NotNil(t, err)
+
+	md.StartExecError = true
+	_, err = dk.Exec(id, ExecOptions{Cmd: []string{"ls"}})
+	assert.NotNil(t, err)
+}
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	md.ContainerLogs["minion"] = "line1\nline2\n"
+
+	var stdout bytes.Buffer
+	err := dk.Logs("minion", LogsOptions{Tail: "10", Stdout: &stdout})
+	assert.Nil(t, err)
+	assert.Equal(t, "line1\nline2\n", stdout.String())
+
+	assert.NotNil(t, dk.Logs("missing", LogsOptions{Stdout: &stdout}))
+
+	md.LogsError = true
+	assert.NotNil(t, dk.Logs("minion", LogsOptions{Stdout: &stdout}))
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	sample := &dkc.Stats{}
+	sample.CPUStats.CPUUsage.TotalUsage = 300
+	sample.CPUStats.CPUUsage.PercpuUsage = []uint64{100, 200}
+	sample.CPUStats.SystemCPUUsage = 2000
+	sample.PreCPUStats.CPUUsage.TotalUsage = 100
+	sample.PreCPUStats.SystemCPUUsage = 1000
+	sample.MemoryStats.Usage = 10
+	sample.MemoryStats.Limit = 100
+	sample.Networks = map[string]dkc.NetworkStats{
+		"eth0": {RxBytes: 1, TxBytes: 2},
+		"eth1": {RxBytes: 3, TxBytes: 4},
+	}
+	md.ContainerStats["id"] = sample
+
+	stats, err := dk.Stats("id")
+	assert.Nil(t, err)
+	assert.Equal(t, ContainerStats{
+		CPUPercent:  40,
+		MemoryUsage: 10,
+		MemoryLimit: 100,
+		NetworkRx:   4,
+		NetworkTx:   6,
+	}, stats)
+
+	// Without a previous sample, the CPU usage is unknown.
+	md.ContainerStats["id"] = &dkc.Stats{}
+	stats, err = dk.Stats("id")
+	assert.Nil(t, err)
+	assert.Equal(t, ContainerStats{}, stats)
+
+	_, err = dk.Stats("missing")
+	assert.NotNil(t, err)
+}
+
+func TestUploadDownload(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	id, err := dk.Run(RunOptions{Name: "name1"})
+	assert.Nil(t, err)
+
+	tarball, err := util.ToTar("dir/file", 0644, "content")
+	assert.Nil(t, err)
+	assert.Nil(t, dk.Upload(id, "/root", tarball))
+	assert.Equal(t, map[string]string{"/root/dir/file": "content"},
+		md.Containers[id].Files)
+
+	var out bytes.Buffer
+	assert.Nil(t, dk.Download(id, "/root/dir", &out))
+
+	archive := tar.NewReader(&out)
+	header, err := archive.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "dir/file", header.Name)
+	content, err := ioutil.ReadAll(archive)
+	assert.Nil(t, err)
+	assert.Equal(t, "content", string(content))
+
+	assert.NotNil(t, dk.Download(id, "/missing", &out))
+	assert.NotNil(t, dk.Upload("missing", "/", &out))
+}
+
 func TestConfigureNetwork(t *testing.T) {
 	md, dk := NewMock()
 
@@ -242,6 +428,28 @@ func TestConfigureNetwork(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestConfigureNetwork6(t *testing.T) {
+	assert.NoError(t, ipdef.SetSubnets("172.16.0.0/12", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	md, dk := NewMock()
+	assert.NoError(t, dk.ConfigureNetwork("quilt"))
+
+	exp := &dkc.Network{
+		Name:       "quilt",
+		Driver:     "quilt",
+		EnableIPv6: true,
+		IPAM: dkc.IPAMOptions{
+			Config: []dkc.IPAMConfig{{
+				Subnet:  "172.16.0.0/12",
+				Gateway: "172.16.0.1",
+			}, {
+				Subnet:  "fd00::/64",
+				Gateway: "fd00::1",
+			}}}}
+	assert.Equal(t, exp, md.Networks["quilt"])
+}
+
 func TestRemove(t *testing.T) {
 	t.Parallel()
 	md, dk := NewMock()
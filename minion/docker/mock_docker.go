@@ -1,7 +1,13 @@
 package docker
 
 import (
+	"archive/tar"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -12,6 +18,7 @@ import (
 type mockContainer struct {
 	*dkc.Container
 	Running bool
+	Files   map[string]string // Uploaded file contents, by absolute path.
 }
 
 // MockClient gives unit testers access to the internals of the mock docker client
@@ -24,6 +31,15 @@ type MockClient struct {
 
 	createdExecs map[string]dkc.CreateExecOptions
 	Executions   map[string][]string
+	ExecSizes    map[string][]TTYSize // The TTY sizes of executions, by exec ID.
+	ExecExitCode int
+	Stopped      map[string]uint // The timeout each container was stopped with.
+
+	// ContainerLogs holds the output returned by Logs, by container name or ID.
+	ContainerLogs map[string]string
+
+	// ContainerStats holds the sample returned by Stats, by container ID.
+	ContainerStats map[string]*dkc.Stats
 
 	CreateError        bool
 	CreateNetworkError bool
@@ -31,10 +47,13 @@ type MockClient struct {
 	CreateExecError    bool
 	InspectError       bool
 	ListError          bool
+	LogsError          bool
 	PullError          bool
 	RemoveError        bool
 	StartError         bool
 	StartExecError     bool
+	StopError          bool
+	UploadError        bool
 }
 
 // NewMock creates a mock docker client suitable for use in unit tests, and a MockClient
@@ -47,6 +66,11 @@ func NewMock() (*MockClient, Client) {
 		Networks:     map[string]*dkc.Network{},
 		createdExecs: map[string]dkc.CreateExecOptions{},
 		Executions:   map[string][]string{},
+		ExecSizes:    map[string][]TTYSize{},
+		Stopped:      map[string]uint{},
+
+		ContainerLogs:  map[string]string{},
+		ContainerStats: map[string]*dkc.Stats{},
 	}
 	return md, Client{md, &sync.Mutex{}, map[string]*cacheEntry{}}
 }
@@ -68,12 +92,27 @@ func (dk MockClient) StartContainer(id string, hostConfig *dkc.HostConfig) error
 }
 
 // StopContainer stops the given docker container.
-func (dk MockClient) StopContainer(id string) {
+func (dk MockClient) StopContainer(id string, timeout uint) error {
 	dk.Lock()
 	defer dk.Unlock()
-	container := dk.Containers[id]
+
+	if dk.StopError {
+		return errors.New("stop error")
+	}
+
+	container, ok := dk.Containers[id]
+	if !ok {
+		return &dkc.NoSuchContainer{ID: id}
+	}
+
+	if !container.Running {
+		return &dkc.ContainerNotRunning{ID: id}
+	}
+
 	container.Running = false
 	dk.Containers[id] = container
+	dk.Stopped[id] = timeout
+	return nil
 }
 
 // RemoveContainer removes the given docker container.
@@ -146,9 +185,10 @@ func (dk MockClient) CreateNetwork(opts dkc.CreateNetworkOptions) (*dkc.Network,
 	}
 
 	network := &dkc.Network{
-		Name:   opts.Name,
-		Driver: opts.Driver,
-		IPAM:   opts.IPAM,
+		Name:       opts.Name,
+		Driver:     opts.Driver,
+		EnableIPv6: opts.EnableIPv6,
+		IPAM:       opts.IPAM,
 	}
 	dk.Networks[opts.Driver] = network
 	return network, nil
@@ -216,7 +256,7 @@ func (dk *MockClient) CreateContainer(opts dkc.CreateContainerOptions) (*dkc.Con
 		HostConfig:      opts.HostConfig,
 		NetworkSettings: &dkc.NetworkSettings{},
 	}
-	dk.Containers[id] = mockContainer{container, false}
+	dk.Containers[id] = mockContainer{Container: container}
 	return container, nil
 }
 
@@ -238,18 +278,86 @@ func (dk MockClient) CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error) {
 	return &dkc.Exec{ID: id}, nil
 }
 
-// StartExec starts the supplied execution object.
+// StartExec starts the supplied execution object.  Commands echo their input to
+// their output, and exit with ExecExitCode.
 func (dk MockClient) StartExec(id string, opts dkc.StartExecOptions) error {
 	dk.Lock()
-	defer dk.Unlock()
 
 	if dk.StartExecError {
+		dk.Unlock()
 		return errors.New("start exec error")
 	}
 
-	exec, _ := dk.createdExecs[id]
+	exec, ok := dk.createdExecs[id]
+	if !ok {
+		dk.Unlock()
+		return &dkc.NoSuchExec{ID: id}
+	}
 	dk.Executions[exec.Container] = append(dk.Executions[exec.Container],
 		strings.Join(exec.Cmd, " "))
+	dk.Unlock()
+
+	if opts.InputStream != nil && opts.OutputStream != nil {
+		if _, err := io.Copy(opts.OutputStream, opts.InputStream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResizeExecTTY records the size of the TTY of the given execution.
+func (dk MockClient) ResizeExecTTY(id string, height, width int) error {
+	dk.Lock()
+	defer dk.Unlock()
+
+	if _, ok := dk.createdExecs[id]; !ok {
+		return &dkc.NoSuchExec{ID: id}
+	}
+	dk.ExecSizes[id] = append(dk.ExecSizes[id], TTYSize{height, width})
+	return nil
+}
+
+// InspectExec returns the exit code of the given execution.
+func (dk MockClient) InspectExec(id string) (*dkc.ExecInspect, error) {
+	dk.Lock()
+	defer dk.Unlock()
+
+	if _, ok := dk.createdExecs[id]; !ok {
+		return nil, &dkc.NoSuchExec{ID: id}
+	}
+	return &dkc.ExecInspect{ID: id, ExitCode: dk.ExecExitCode}, nil
+}
+
+// Logs writes the ContainerLogs of the given container to its output stream.
+func (dk MockClient) Logs(opts dkc.LogsOptions) error {
+	dk.Lock()
+	defer dk.Unlock()
+
+	if dk.LogsError {
+		return errors.New("logs error")
+	}
+
+	logs, ok := dk.ContainerLogs[opts.Container]
+	if !ok {
+		return &dkc.NoSuchContainer{ID: opts.Container}
+	}
+
+	_, err := io.WriteString(opts.OutputStream, logs)
+	return err
+}
+
+// Stats sends the container's sample from ContainerStats, and closes the channel.
+func (dk MockClient) Stats(opts dkc.StatsOptions) error {
+	dk.Lock()
+	defer dk.Unlock()
+	defer close(opts.Stats)
+
+	stats, ok := dk.ContainerStats[opts.ID]
+	if !ok {
+		return &dkc.NoSuchContainer{ID: opts.ID}
+	}
+
+	opts.Stats <- stats
 	return nil
 }
 
@@ -261,16 +369,94 @@ func (dk *MockClient) ResetExec() {
 
 	dk.createdExecs = map[string]dkc.CreateExecOptions{}
 	dk.Executions = map[string][]string{}
+	dk.ExecSizes = map[string][]TTYSize{}
 }
 
-// UploadToContainer is not implemented.
+// UploadToContainer extracts the files in the tar archive `opts.InputStream` into
+// the container's Files.
 func (dk MockClient) UploadToContainer(id string,
 	opts dkc.UploadToContainerOptions) error {
-	panic("MockClient Not Implemented")
+	dk.Lock()
+	defer dk.Unlock()
+
+	if dk.UploadError {
+		return errors.New("upload error")
+	}
+
+	container, ok := dk.Containers[id]
+	if !ok {
+		return ErrNoSuchContainer
+	}
+
+	files := map[string]string{}
+	for path, content := range container.Files {
+		files[path] = content
+	}
+
+	archive := tar.NewReader(opts.InputStream)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+		files[filepath.Join("/", opts.Path, header.Name)] = string(content)
+	}
+
+	container.Files = files
+	dk.Containers[id] = container
+	return nil
 }
 
-// DownloadFromContainer is not implemented.
+// DownloadFromContainer writes a tar archive of the container's Files at or below
+// `opts.Path`.
 func (dk MockClient) DownloadFromContainer(id string,
 	opts dkc.DownloadFromContainerOptions) error {
-	panic("MockClient Not Implemented")
+	dk.Lock()
+	defer dk.Unlock()
+
+	container, ok := dk.Containers[id]
+	if !ok {
+		return ErrNoSuchContainer
+	}
+
+	root := filepath.Clean(opts.Path)
+	var paths []string
+	for path := range container.Files {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no such file: %s", root)
+	}
+	sort.Strings(paths)
+
+	archive := tar.NewWriter(opts.OutputStream)
+	for _, path := range paths {
+		content := container.Files[path]
+		name := filepath.Join(filepath.Base(root), strings.TrimPrefix(path, root))
+		err := archive.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(archive, content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
 }
@@ -64,10 +64,11 @@ func updateConnections(view db.Database, spec stitch.Stitch) {
 	dbcKey := func(val interface{}) interface{} {
 		c := val.(db.Connection)
 		return stitch.Connection{
-			From:    c.From,
-			To:      c.To,
-			MinPort: c.MinPort,
-			MaxPort: c.MaxPort,
+			From:      c.From,
+			To:        c.To,
+			MinPort:   c.MinPort,
+			MaxPort:   c.MaxPort,
+			Bandwidth: c.Bandwidth,
 		}
 	}
 
@@ -89,6 +90,7 @@ func updateConnections(view db.Database, spec stitch.Stitch) {
 		dbc.To = stitchc.To
 		dbc.MinPort = stitchc.MinPort
 		dbc.MaxPort = stitchc.MaxPort
+		dbc.Bandwidth = stitchc.Bandwidth
 		view.Commit(dbc)
 	}
 }
@@ -97,10 +99,15 @@ func queryContainers(spec stitch.Stitch) []db.Container {
 	containers := map[string]*db.Container{}
 	for _, c := range spec.Containers {
 		containers[c.ID] = &db.Container{
-			StitchID: c.ID,
-			Command:  c.Command,
-			Image:    c.Image,
-			Env:      c.Env,
+			StitchID:    c.ID,
+			Command:     c.Command,
+			Image:       c.Image,
+			Env:         c.Env,
+			Secrets:     c.Secrets,
+			Files:       c.Files,
+			SecretFiles: c.SecretFiles,
+			Bandwidth:   c.Bandwidth,
+			StopTimeout: c.StopTimeout,
 		}
 	}
 
@@ -123,14 +130,38 @@ func updateContainers(view db.Database, spec stitch.Stitch) {
 		return val.(db.Container).StitchID
 	}
 
-	pairs, news, dbcs := join.HashJoin(db.ContainerSlice(queryContainers(spec)),
+	pairs, newIs, oldIs := join.HashJoin(db.ContainerSlice(queryContainers(spec)),
 		db.ContainerSlice(view.SelectFromContainer(nil)), key, key)
 
-	for _, dbc := range dbcs {
-		view.Remove(dbc.(db.Container))
+	var current, news, olds []db.Container
+	for _, pair := range pairs {
+		dbc := pair.R.(db.Container)
+		dbc.Labels = pair.L.(db.Container).Labels
+		current = append(current, dbc)
+	}
+	for _, new := range newIs {
+		news = append(news, new.(db.Container))
+	}
+	for _, old := range oldIs {
+		olds = append(olds, old.(db.Container))
+	}
+
+	boot, stop := planRollout(spec.Labels, current, news, olds)
+
+	stopIDs := map[int]struct{}{}
+	for _, dbc := range stop {
+		stopIDs[dbc.ID] = struct{}{}
+		view.Remove(dbc)
+	}
+
+	for _, dbc := range olds {
+		if _, ok := stopIDs[dbc.ID]; !ok {
+			dbc.Outdated = true
+			view.Commit(dbc)
+		}
 	}
 
-	for _, new := range news {
+	for _, new := range boot {
 		pairs = append(pairs, join.Pair{L: new, R: view.InsertContainer()})
 	}
 
@@ -146,7 +177,13 @@ func updateContainers(view db.Database, spec stitch.Stitch) {
 		dbc.Command = newc.Command
 		dbc.Image = newc.Image
 		dbc.Env = newc.Env
+		dbc.Secrets = newc.Secrets
+		dbc.Files = newc.Files
+		dbc.SecretFiles = newc.SecretFiles
+		dbc.Bandwidth = newc.Bandwidth
+		dbc.StopTimeout = newc.StopTimeout
 		dbc.StitchID = newc.StitchID
+		dbc.Outdated = false
 		view.Commit(dbc)
 	}
 }
@@ -81,6 +81,108 @@ func TestContainerTxn(t *testing.T) {
 
 	testContainerTxn(t, conn, spec)
 	assert.False(t, fired(trigg))
+
+	spec = `deployment.deploy(new Service("a", [
+		new Container("alpine").withEnv({"pass": secret("db-pass")})
+	]))`
+	testContainerTxn(t, conn, spec)
+	assert.True(t, fired(trigg))
+
+	dbcs := conn.SelectFromContainer(nil)
+	assert.Len(t, dbcs, 1)
+	assert.Equal(t, map[string]string{"pass": "db-pass"}, dbcs[0].Secrets)
+	assert.Empty(t, dbcs[0].Env)
+
+	spec = `deployment.deploy(new Service("a", [
+		new Container("alpine").withFiles({"/etc/a.cfg": "a"})
+	]))`
+	testContainerTxn(t, conn, spec)
+	assert.True(t, fired(trigg))
+
+	dbcs = conn.SelectFromContainer(nil)
+	assert.Len(t, dbcs, 1)
+	assert.Equal(t, map[string]string{"/etc/a.cfg": "a"}, dbcs[0].Files)
+
+	spec = `deployment.deploy(new Service("a", [
+		new Container("alpine").withFiles({"/etc/key.pem": secret("key")})
+	]))`
+	testContainerTxn(t, conn, spec)
+	assert.True(t, fired(trigg))
+
+	dbcs = conn.SelectFromContainer(nil)
+	assert.Len(t, dbcs, 1)
+	assert.Equal(t, map[string]string{"/etc/key.pem": "key"}, dbcs[0].SecretFiles)
+	assert.Empty(t, dbcs[0].Files)
+
+	spec = `deployment.deploy(new Service("a", [
+		new Container("alpine").withStopTimeout(30)
+	]))`
+	testContainerTxn(t, conn, spec)
+	assert.True(t, fired(trigg))
+
+	dbcs = conn.SelectFromContainer(nil)
+	assert.Len(t, dbcs, 1)
+	assert.Equal(t, 30, dbcs[0].StopTimeout)
+}
+
+func TestContainerTxnRollout(t *testing.T) {
+	conn := db.New()
+
+	deploy := func(image string) []db.Container {
+		spec := `var web = new Service("web",
+			new Container("` + image + `").replicate(2));
+		web.setUpdateStrategy({maxUnavailable: 1, maxSurge: 0});
+		deployment.deploy(web);`
+		compiled, err := stitch.FromJavascript(spec, stitch.DefaultImportGetter)
+		assert.NoError(t, err)
+
+		var dbcs []db.Container
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			updatePolicy(view, compiled.String())
+			dbcs = view.SelectFromContainer(nil)
+			return nil
+		})
+		return dbcs
+	}
+
+	setRunning := func() {
+		conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+			for _, dbc := range view.SelectFromContainer(nil) {
+				dbc.Status = "running"
+				view.Commit(dbc)
+			}
+			return nil
+		})
+	}
+
+	images := func(dbcs []db.Container) map[string]int {
+		result := map[string]int{}
+		for _, dbc := range dbcs {
+			if dbc.Outdated {
+				result[dbc.Image+" (outdated)"]++
+			} else {
+				result[dbc.Image]++
+			}
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string]int{"alpine": 2}, images(deploy("alpine")))
+	setRunning()
+
+	// One of the old containers is stopped to make room for a new one.
+	assert.Equal(t, map[string]int{"alpine (outdated)": 1},
+		images(deploy("ubuntu")))
+	assert.Equal(t, map[string]int{"alpine (outdated)": 1, "ubuntu": 1},
+		images(deploy("ubuntu")))
+
+	// The update waits for the new container to run.
+	assert.Equal(t, map[string]int{"alpine (outdated)": 1, "ubuntu": 1},
+		images(deploy("ubuntu")))
+
+	setRunning()
+	assert.Equal(t, map[string]int{"ubuntu": 1}, images(deploy("ubuntu")))
+	assert.Equal(t, map[string]int{"ubuntu": 2}, images(deploy("ubuntu")))
 }
 
 func testContainerTxn(t *testing.T, conn db.Conn, spec string) {
@@ -137,6 +239,13 @@ func TestConnectionTxn(t *testing.T) {
 	testConnectionTxn(t, conn, spec)
 	assert.False(t, fired(trigg))
 
+	spec = pre + `a.connect(90, a, {bandwidth: 100});`
+	testConnectionTxn(t, conn, spec)
+	assert.True(t, fired(trigg))
+
+	testConnectionTxn(t, conn, spec)
+	assert.False(t, fired(trigg))
+
 	spec = pre + `b.connect(90, a);
 	b.connect(90, c);
 	b.connect(100, b);
@@ -171,7 +280,7 @@ func testConnectionTxn(t *testing.T, conn db.Conn, spec string) {
 		found := false
 		for i, c := range connections {
 			if e.From == c.From && e.To == c.To && e.MinPort == c.MinPort &&
-				e.MaxPort == c.MaxPort {
+				e.MaxPort == c.MaxPort && e.Bandwidth == c.Bandwidth {
 				connections = append(
 					connections[:i], connections[i+1:]...)
 				found = true
@@ -24,6 +24,8 @@ func runConnection(conn db.Conn, store Store) {
 }
 
 func runConnectionOnce(conn db.Conn, store Store) error {
+	defer syncDuration.ObserveSince(time.Now(), "connections")
+
 	etcdStr, err := readEtcdNode(store, connectionPath)
 	if err != nil {
 		return fmt.Errorf("etcd read error: %s", err)
@@ -12,6 +12,7 @@ func TestRunConnectionOnce(t *testing.T) {
 
 	store := newTestMock()
 	conn := db.New()
+	syncs := syncDuration.Count("connections")
 
 	err := runConnectionOnce(conn, store)
 	assert.Error(t, err)
@@ -71,4 +72,6 @@ func TestRunConnectionOnce(t *testing.T) {
 	conns[0].ID = 0
 	assert.Equal(t, db.Connection{From: "a", To: "b", MinPort: 80, MaxPort: 8080},
 		conns[0])
+
+	assert.Equal(t, syncs+3, syncDuration.Count("connections"))
 }
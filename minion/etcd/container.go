@@ -25,6 +25,8 @@ func runContainer(conn db.Conn, store Store) {
 }
 
 func runContainerOnce(conn db.Conn, store Store) error {
+	defer syncDuration.ObserveSince(time.Now(), "containers")
+
 	etcdStr, err := readEtcdNode(store, containerPath)
 	if err != nil {
 		return fmt.Errorf("etcd read error: %s", err)
@@ -78,24 +80,49 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 
 		// The environment variables must be sorted to ensure they're consistent
 		// in the join key.
-		var env []string
+		var env, secrets, files, secretFiles []string
 		for k, v := range dbc.Env {
 			env = append(env, fmt.Sprintf("%s=%s", k, v))
 		}
 		sort.Sort(sort.StringSlice(env))
 
+		for k, v := range dbc.Secrets {
+			secrets = append(secrets, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Sort(sort.StringSlice(secrets))
+
+		for k, v := range dbc.Files {
+			files = append(files, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Sort(sort.StringSlice(files))
+
+		for k, v := range dbc.SecretFiles {
+			secretFiles = append(secretFiles, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Sort(sort.StringSlice(secretFiles))
+
 		return struct {
-			IP       string
-			StitchID string
-			Image    string
-			Command  string
-			Env      string
+			IP          string
+			IPv6        string
+			StitchID    string
+			Image       string
+			Command     string
+			Env         string
+			Secrets     string
+			Files       string
+			SecretFiles string
+			Bandwidth   int
 		}{
-			IP:       dbc.IP,
-			StitchID: dbc.StitchID,
-			Image:    dbc.Image,
-			Command:  fmt.Sprintf("%v", dbc.Command),
-			Env:      fmt.Sprintf("%v", env),
+			IP:          dbc.IP,
+			IPv6:        dbc.IPv6,
+			StitchID:    dbc.StitchID,
+			Image:       dbc.Image,
+			Command:     fmt.Sprintf("%v", dbc.Command),
+			Env:         fmt.Sprintf("%v", env),
+			Secrets:     fmt.Sprintf("%v", secrets),
+			Files:       fmt.Sprintf("%v", files),
+			SecretFiles: fmt.Sprintf("%v", secretFiles),
+			Bandwidth:   dbc.Bandwidth,
 		}
 	}
 
@@ -117,12 +144,19 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 		edbc := pair.R.(db.Container)
 
 		dbc.IP = edbc.IP
+		dbc.IPv6 = edbc.IPv6
 		dbc.Minion = edbc.Minion
 		dbc.StitchID = edbc.StitchID
 		dbc.Image = edbc.Image
 		dbc.Command = edbc.Command
 		dbc.Labels = edbc.Labels
 		dbc.Env = edbc.Env
+		dbc.Secrets = edbc.Secrets
+		dbc.Files = edbc.Files
+		dbc.SecretFiles = edbc.SecretFiles
+		dbc.Bandwidth = edbc.Bandwidth
+		dbc.StopTimeout = edbc.StopTimeout
+		dbc.Outdated = edbc.Outdated
 		view.Commit(dbc)
 	}
 }
@@ -31,11 +31,16 @@ func TestRunContainerOnce(t *testing.T) {
 
 		dbc := view.InsertContainer()
 		dbc.IP = "10.0.0.2"
+		dbc.IPv6 = "fd00::2"
 		dbc.Minion = "1.2.3.4"
 		dbc.StitchID = "12"
 		dbc.Image = "ubuntu"
 		dbc.Command = []string{"1", "2", "3"}
 		dbc.Env = map[string]string{"red": "pill", "blue": "pill"}
+		dbc.Secrets = map[string]string{"pass": "db-pass"}
+		dbc.Files = map[string]string{"/etc/a.cfg": "a"}
+		dbc.SecretFiles = map[string]string{"/etc/key.pem": "key"}
+		dbc.StopTimeout = 30
 		view.Commit(dbc)
 		return nil
 	})
@@ -49,6 +54,7 @@ func TestRunContainerOnce(t *testing.T) {
 	expStr := `[
     {
         "IP": "10.0.0.2",
+        "IPv6": "fd00::2",
         "Minion": "1.2.3.4",
         "StitchID": "12",
         "Image": "ubuntu",
@@ -61,6 +67,16 @@ func TestRunContainerOnce(t *testing.T) {
             "blue": "pill",
             "red": "pill"
         },
+        "Secrets": {
+            "pass": "db-pass"
+        },
+        "Files": {
+            "/etc/a.cfg": "a"
+        },
+        "SecretFiles": {
+            "/etc/key.pem": "key"
+        },
+        "StopTimeout": 30,
         "Created": "0001-01-01T00:00:00Z"
     }
 ]`
@@ -73,6 +89,9 @@ func TestRunContainerOnce(t *testing.T) {
 
 		dbc := view.SelectFromContainer(nil)[0]
 		dbc.Env = map[string]string{"red": "fish", "blue": "fish"}
+		dbc.Secrets = map[string]string{"pass": "other-pass"}
+		dbc.Files = map[string]string{"/etc/a.cfg": "b"}
+		dbc.SecretFiles = map[string]string{"/etc/key.pem": "other-key"}
 		view.Commit(dbc)
 		return nil
 	})
@@ -81,12 +100,17 @@ func TestRunContainerOnce(t *testing.T) {
 	assert.NoError(t, err)
 
 	expDBC := db.Container{
-		IP:       "10.0.0.2",
-		StitchID: "12",
-		Minion:   "1.2.3.4",
-		Image:    "ubuntu",
-		Command:  []string{"1", "2", "3"},
-		Env:      map[string]string{"red": "pill", "blue": "pill"},
+		IP:          "10.0.0.2",
+		IPv6:        "fd00::2",
+		StitchID:    "12",
+		Minion:      "1.2.3.4",
+		Image:       "ubuntu",
+		Command:     []string{"1", "2", "3"},
+		Env:         map[string]string{"red": "pill", "blue": "pill"},
+		Secrets:     map[string]string{"pass": "db-pass"},
+		Files:       map[string]string{"/etc/a.cfg": "a"},
+		SecretFiles: map[string]string{"/etc/key.pem": "key"},
+		StopTimeout: 30,
 	}
 	dbcs := conn.SelectFromContainer(nil)
 	assert.Len(t, dbcs, 1)
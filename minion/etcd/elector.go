@@ -98,9 +98,19 @@ func commitLeader(conn db.Conn, leader bool, ip ...string) {
 		panic("Not Reached")
 	}
 
-	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+	conn.Txn(db.EtcdTable, db.EventTable).Run(func(view db.Database) error {
 		etcdRows := view.SelectFromEtcd(nil)
 		if len(etcdRows) == 1 {
+			if leader && !etcdRows[0].Leader && len(ip) == 1 {
+				view.RecordEvent(db.Event{
+					Type:    db.EventNormal,
+					Reason:  "LeaderElected",
+					Object:  "minion/" + ip[0],
+					Message: ip[0] + " became the leader",
+					Source:  "etcd",
+				})
+			}
+
 			etcdRows[0].Leader = leader
 
 			if len(ip) == 1 {
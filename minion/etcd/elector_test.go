@@ -0,0 +1,39 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/db"
+)
+
+func TestCommitLeader(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.InsertEtcd()
+		return nil
+	})
+
+	commitLeader(conn, true, "10.0.0.1")
+	etcdRows := conn.SelectFromEtcd(nil)
+	assert.True(t, etcdRows[0].Leader)
+	assert.Equal(t, "10.0.0.1", etcdRows[0].LeaderIP)
+
+	events := conn.SelectFromEvent(nil)
+	assert.Len(t, events, 1)
+	assert.Equal(t, db.EventNormal, events[0].Type)
+	assert.Equal(t, "LeaderElected", events[0].Reason)
+	assert.Equal(t, "minion/10.0.0.1", events[0].Object)
+	assert.Equal(t, "10.0.0.1 became the leader", events[0].Message)
+
+	// Refreshing the leadership isn't an event.
+	commitLeader(conn, true, "10.0.0.1")
+	assert.Equal(t, 1, conn.SelectFromEvent(nil)[0].Count)
+
+	commitLeader(conn, false)
+	assert.False(t, conn.SelectFromEtcd(nil)[0].Leader)
+	assert.Len(t, conn.SelectFromEvent(nil), 1)
+}
@@ -24,6 +24,8 @@ func runLabel(conn db.Conn, store Store) {
 }
 
 func runLabelOnce(conn db.Conn, store Store) error {
+	defer syncDuration.ObserveSince(time.Now(), "labels")
+
 	etcdStr, err := readEtcdNode(store, labelPath)
 	if err != nil {
 		return fmt.Errorf("etcd read error: %s", err)
@@ -54,13 +56,17 @@ func joinLabels(view db.Database, etcdLabels []db.Label) {
 	key := func(iface interface{}) interface{} {
 		label := iface.(db.Label)
 		return struct {
-			Label        string
-			IP           string
-			ContainerIPs string
+			Label          string
+			IP             string
+			ContainerIPs   string
+			IPv6           string
+			ContainerIPv6s string
 		}{
-			Label:        label.Label,
-			IP:           label.IP,
-			ContainerIPs: fmt.Sprintf("%v", label.ContainerIPs),
+			Label:          label.Label,
+			IP:             label.IP,
+			ContainerIPs:   fmt.Sprintf("%v", label.ContainerIPs),
+			IPv6:           label.IPv6,
+			ContainerIPv6s: fmt.Sprintf("%v", label.ContainerIPv6s),
 		}
 	}
 
@@ -27,6 +27,8 @@ func TestRunLabelOnce(t *testing.T) {
 		label := view.InsertLabel()
 		label.Label = "Robot"
 		label.IP = "1.2.3.5"
+		label.IPv6 = "fd00::5"
+		label.ContainerIPv6s = []string{"fd00::5"}
 		view.Commit(label)
 		return nil
 	})
@@ -41,7 +43,11 @@ func TestRunLabelOnce(t *testing.T) {
     {
         "Label": "Robot",
         "IP": "1.2.3.5",
-        "ContainerIPs": null
+        "ContainerIPs": null,
+        "IPv6": "fd00::5",
+        "ContainerIPv6s": [
+            "fd00::5"
+        ]
     }
 ]`
 	assert.Equal(t, expStr, str)
@@ -61,8 +67,10 @@ func TestRunLabelOnce(t *testing.T) {
 	assert.NoError(t, err)
 
 	explabel := db.Label{
-		Label: "Robot",
-		IP:    "1.2.3.5",
+		Label:          "Robot",
+		IP:             "1.2.3.5",
+		IPv6:           "fd00::5",
+		ContainerIPv6s: []string{"fd00::5"},
 	}
 	labels := conn.SelectFromLabel(nil)
 	assert.Len(t, labels, 1)
@@ -21,8 +21,10 @@ func runMinionSync(conn db.Conn, store Store) {
 	loopLog := util.NewEventTimer("Etcd")
 	for range conn.TriggerTick(minionTimeout/2, db.MinionTable).C {
 		loopLog.LogStart()
+		start := time.Now()
 		writeMinion(conn, store)
 		readMinion(conn, store)
+		syncDuration.ObserveSince(start, "minions")
 		loopLog.LogEnd()
 	}
 }
@@ -31,6 +31,7 @@ func TestWriteMinion(t *testing.T) {
 		m.Provider = "Amazon"
 		m.Size = "Big"
 		m.Region = "Somewhere"
+		m.MTU = 1428
 		view.Commit(m)
 		return nil
 	})
@@ -56,7 +57,8 @@ func TestWriteMinion(t *testing.T) {
     "Provider": "Amazon",
     "Size": "Big",
     "Region": "Somewhere",
-    "FloatingIP": ""
+    "FloatingIP": "",
+    "MTU": 1428
 }`
 	assert.Equal(t, expVal, val)
 }
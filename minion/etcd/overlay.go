@@ -0,0 +1,96 @@
+package etcd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/stitch"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const overlayKeyPath = "/overlay-key"
+
+func runOverlayKey(conn db.Conn, store Store) {
+	etcdWatch := store.Watch(overlayKeyPath, 1*time.Second)
+	trigg := conn.TriggerTick(60, db.MinionTable, db.EtcdTable)
+	for range joinNotifiers(trigg.C, etcdWatch) {
+		if err := runOverlayKeyOnce(conn, store); err != nil {
+			log.WithError(err).Warn("Failed to sync overlay key with Etcd.")
+		}
+	}
+}
+
+func runOverlayKeyOnce(conn db.Conn, store Store) error {
+	defer syncDuration.ObserveSince(time.Now(), "overlay_key")
+
+	key, err := readEtcdNode(store, overlayKeyPath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	if conn.EtcdLeader() {
+		if key, err = updateOverlayKey(conn, store, key); err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+	}
+
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcdRows := view.SelectFromEtcd(nil)
+		if len(etcdRows) == 1 && etcdRows[0].OverlayKey != key {
+			etcdRows[0].OverlayKey = key
+			view.Commit(etcdRows[0])
+		}
+		return nil
+	})
+	return nil
+}
+
+// updateOverlayKey creates or deletes the key stored in etcd depending on whether
+// the spec asks for an encrypted overlay, and returns the resulting key.
+func updateOverlayKey(conn db.Conn, store Store, key string) (string, error) {
+	self, err := conn.MinionSelf()
+	if err != nil {
+		return key, nil
+	}
+
+	spec, err := stitch.FromJSON(self.Spec)
+	if err != nil {
+		// Without a spec we don't know what the user wants, so leave the key
+		// alone rather than tear down encryption that may be in use.
+		return key, nil
+	}
+
+	switch {
+	case spec.EncryptOverlay && key == "":
+		newKey, err := newOverlayKey()
+		if err != nil {
+			return key, err
+		}
+
+		if err := store.Set(overlayKeyPath, newKey, 0); err != nil {
+			return key, err
+		}
+		return newKey, nil
+	case !spec.EncryptOverlay && key != "":
+		if err := store.Delete(overlayKeyPath); err != nil {
+			return key, err
+		}
+		return "", nil
+	}
+	return key, nil
+}
+
+func newOverlayKeyImpl() (string, error) {
+	key := make([]byte, db.OverlayKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// newOverlayKey is a variable so that it can be mocked out by the unit tests.
+var newOverlayKey = newOverlayKeyImpl
@@ -0,0 +1,102 @@
+package etcd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunOverlayKeyOnce(t *testing.T) {
+	store := newTestMock()
+	conn := db.New()
+
+	newOverlayKey = func() (string, error) { return "key", nil }
+	defer func() { newOverlayKey = newOverlayKeyImpl }()
+
+	err := runOverlayKeyOnce(conn, store)
+	assert.Error(t, err)
+
+	err = store.Set(overlayKeyPath, "", 0)
+	assert.NoError(t, err)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+
+		self := view.InsertMinion()
+		self.Self = true
+		self.Spec = `{"EncryptOverlay": true}`
+		view.Commit(self)
+		return nil
+	})
+
+	// The leader generates a key when the spec asks for one.
+	assert.NoError(t, runOverlayKeyOnce(conn, store))
+	str, err := store.Get(overlayKeyPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "key", str)
+	assert.Equal(t, "key", overlayKey(conn))
+
+	// An existing key is never replaced.
+	newOverlayKey = func() (string, error) { return "other", nil }
+	assert.NoError(t, runOverlayKeyOnce(conn, store))
+	str, _ = store.Get(overlayKeyPath)
+	assert.Equal(t, "key", str)
+
+	// A missing spec leaves the key alone.
+	setSpec(conn, "")
+	assert.NoError(t, runOverlayKeyOnce(conn, store))
+	str, _ = store.Get(overlayKeyPath)
+	assert.Equal(t, "key", str)
+
+	// Disabling encryption deletes the key.
+	setSpec(conn, "{}")
+	assert.NoError(t, runOverlayKeyOnce(conn, store))
+	_, err = store.Get(overlayKeyPath)
+	assert.Error(t, err)
+	assert.Equal(t, "", overlayKey(conn))
+
+	// Non-leaders only copy the key out of etcd.
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.Leader = false
+		view.Commit(etcd)
+		return nil
+	})
+	assert.NoError(t, store.Set(overlayKeyPath, "leaderKey", 0))
+	assert.NoError(t, runOverlayKeyOnce(conn, store))
+	str, _ = store.Get(overlayKeyPath)
+	assert.Equal(t, "leaderKey", str)
+	assert.Equal(t, "leaderKey", overlayKey(conn))
+}
+
+func TestNewOverlayKey(t *testing.T) {
+	t.Parallel()
+
+	a, err := newOverlayKeyImpl()
+	assert.NoError(t, err)
+
+	b, err := newOverlayKeyImpl()
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+
+	bytes, err := hex.DecodeString(a)
+	assert.NoError(t, err)
+	assert.Len(t, bytes, db.OverlayKeyLen)
+}
+
+func setSpec(conn db.Conn, spec string) {
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self, _ := view.MinionSelf()
+		self.Spec = spec
+		view.Commit(self)
+		return nil
+	})
+}
+
+func overlayKey(conn db.Conn) string {
+	return conn.SelectFromEtcd(nil)[0].OverlayKey
+}
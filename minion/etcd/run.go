@@ -4,20 +4,30 @@ import (
 	"time"
 
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/metrics"
 	"github.com/coreos/etcd/client"
 
 	log "github.com/Sirupsen/logrus"
 )
 
+var syncDuration = metrics.NewHistogram("quilt_etcd_sync_duration_seconds",
+	"How long it takes to synchronize each kind of state with Etcd.",
+	metrics.DefBuckets, "kind")
+
 // Run synchronizes state in `conn` with the Etcd cluster.
 func Run(conn db.Conn) {
 	store := NewStore()
 	makeEtcdDir(minionPath, store, 0)
+	makeEtcdDir(trafficPath, store, 0)
+	makeEtcdDir(statusPath, store, 0)
 
 	go runElection(conn, store)
 	go runConnection(conn, store)
 	go runContainer(conn, store)
 	go runLabel(conn, store)
+	go runOverlayKey(conn, store)
+	go runTraffic(conn, store)
+	go runStatus(conn, store)
 	runMinionSync(conn, store)
 }
 
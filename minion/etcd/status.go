@@ -0,0 +1,107 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/quilt/quilt/db"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	statusPath = "/status"
+
+	// Workers that stop reporting the status of their containers are forgotten
+	// after statusTTL seconds.
+	statusTTL = 30
+)
+
+// The status of a container, as reported by the worker running it.
+type containerStatus struct {
+	DockerID string    `json:",omitempty"`
+	Status   string    `json:",omitempty"`
+	Created  time.Time `json:","`
+}
+
+func runStatus(conn db.Conn, store Store) {
+	trigg := conn.TriggerTick(statusTTL/2, db.ContainerTable, db.EtcdTable)
+	for range trigg.C {
+		if err := runStatusOnce(conn, store); err != nil {
+			log.WithError(err).Warn("Failed to sync container status with Etcd.")
+		}
+	}
+}
+
+// runStatusOnce publishes the status of the containers running on workers, and
+// records it in the container table of the leader, which uses it to pace rolling
+// updates and to report whether the deployment has converged.
+func runStatusOnce(conn db.Conn, store Store) error {
+	defer syncDuration.ObserveSince(time.Now(), "status")
+
+	self, err := conn.MinionSelf()
+	if err != nil {
+		return nil
+	}
+
+	if self.Role == db.Worker && self.PrivateIP != "" {
+		statuses := map[string]containerStatus{}
+		for _, dbc := range conn.SelectFromContainer(nil) {
+			if dbc.StitchID != "" && dbc.DockerID != "" {
+				statuses[dbc.StitchID] = containerStatus{
+					DockerID: dbc.DockerID,
+					Status:   dbc.Status,
+					Created:  dbc.Created,
+				}
+			}
+		}
+
+		js, err := jsonMarshal(statuses)
+		if err != nil {
+			return err
+		}
+
+		key := path.Join(statusPath, self.PrivateIP)
+		if err := store.Set(key, string(js), statusTTL*time.Second); err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+		return nil
+	}
+
+	if !conn.EtcdLeader() {
+		return nil
+	}
+
+	tree, err := store.GetTree(statusPath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	// Maps worker IPs to the status of their containers, by Stitch ID.
+	workers := map[string]map[string]containerStatus{}
+	for ip, worker := range tree.Children {
+		var statuses map[string]containerStatus
+		if err := json.Unmarshal([]byte(worker.Value), &statuses); err != nil {
+			log.WithField("json", worker.Value).Warning(
+				"Failed to parse container status.")
+			continue
+		}
+		workers[ip] = statuses
+	}
+
+	conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			// Containers that were just moved may still be reported by
+			// their old workers, so only the assigned worker is trusted.
+			status := workers[dbc.Minion][dbc.StitchID]
+			dbc.DockerID = status.DockerID
+			dbc.Status = status.Status
+			dbc.Created = status.Created
+			view.Commit(dbc)
+		}
+		return nil
+	})
+	return nil
+}
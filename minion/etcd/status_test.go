@@ -0,0 +1,90 @@
+package etcd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunStatusOnce(t *testing.T) {
+	store := newTestMock()
+	conn := db.New()
+
+	// Without a self minion there's nothing to do.
+	assert.NoError(t, runStatusOnce(conn, store))
+
+	created := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.Role = db.Worker
+		self.PrivateIP = "10.0.0.1"
+		view.Commit(self)
+
+		dbc := view.InsertContainer()
+		dbc.StitchID = "a"
+		dbc.DockerID = "docker-a"
+		dbc.Status = "running"
+		dbc.Created = created
+		view.Commit(dbc)
+
+		// Containers that haven't booted yet aren't reported.
+		dbc = view.InsertContainer()
+		dbc.StitchID = "b"
+		view.Commit(dbc)
+		return nil
+	})
+
+	// Workers publish the status of their containers.
+	assert.NoError(t, runStatusOnce(conn, store))
+	str, err := store.Get(statusPath + "/10.0.0.1")
+	assert.NoError(t, err)
+	var published map[string]containerStatus
+	assert.NoError(t, json.Unmarshal([]byte(str), &published))
+	assert.Equal(t, map[string]containerStatus{
+		"a": {DockerID: "docker-a", Status: "running", Created: created}},
+		published)
+
+	assert.NoError(t, store.Set(statusPath+"/10.0.0.3", "bad json", 0))
+
+	// The leader records the status reported by each container's worker.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self, _ := view.MinionSelf()
+		self.Role = db.Master
+		view.Commit(self)
+
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+
+		for _, dbc := range view.SelectFromContainer(nil) {
+			dbc.DockerID = ""
+			dbc.Status = ""
+			dbc.Created = time.Time{}
+			dbc.Minion = "10.0.0.1"
+			if dbc.StitchID == "b" {
+				dbc.Minion = "10.0.0.2"
+				dbc.Status = "stale"
+			}
+			view.Commit(dbc)
+		}
+		return nil
+	})
+
+	assert.NoError(t, runStatusOnce(conn, store))
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		switch dbc.StitchID {
+		case "a":
+			assert.Equal(t, "docker-a", dbc.DockerID)
+			assert.Equal(t, "running", dbc.Status)
+			assert.Equal(t, created, dbc.Created)
+		case "b":
+			assert.Empty(t, dbc.DockerID)
+			assert.Empty(t, dbc.Status)
+			assert.True(t, dbc.Created.IsZero())
+		}
+	}
+}
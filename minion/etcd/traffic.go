@@ -0,0 +1,170 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	trafficPath = "/traffic"
+
+	// Workers that stop reporting their traffic are forgotten after trafficTTL
+	// seconds.
+	trafficTTL = 30
+)
+
+// A trafficReport is what each worker publishes: the traffic of each connection to
+// its containers, and the traffic of each of its containers' ports.
+type trafficReport struct {
+	Connections []db.Traffic
+	Ports       []db.PortTraffic
+}
+
+func runTraffic(conn db.Conn, store Store) {
+	for range conn.TriggerTick(trafficTTL/2, db.EtcdTable).C {
+		if err := runTrafficOnce(conn, store); err != nil {
+			log.WithError(err).Warn("Failed to sync traffic with Etcd.")
+		}
+	}
+}
+
+// runTrafficOnce publishes the traffic counted by workers, and totals it on the
+// leader.
+func runTrafficOnce(conn db.Conn, store Store) error {
+	defer syncDuration.ObserveSince(time.Now(), "traffic")
+
+	self, err := conn.MinionSelf()
+	if err != nil {
+		return nil
+	}
+
+	if self.Role == db.Worker && self.PrivateIP != "" {
+		traffic := db.TrafficSlice(conn.SelectFromTraffic(nil))
+		sort.Sort(traffic)
+		ports := db.PortTrafficSlice(conn.SelectFromPortTraffic(nil))
+		sort.Sort(ports)
+		js, err := jsonMarshal(trafficReport{traffic, ports})
+		if err != nil {
+			return err
+		}
+
+		key := path.Join(trafficPath, self.PrivateIP)
+		if err := store.Set(key, string(js), trafficTTL*time.Second); err != nil {
+			return fmt.Errorf("etcd write error: %s", err)
+		}
+		return nil
+	}
+
+	if !conn.EtcdLeader() {
+		return nil
+	}
+
+	tree, err := store.GetTree(trafficPath)
+	if err != nil {
+		return fmt.Errorf("etcd read error: %s", err)
+	}
+
+	totals := map[db.Traffic]db.Traffic{}
+	var ports []db.PortTraffic
+	for _, worker := range tree.Children {
+		var report trafficReport
+		if err := json.Unmarshal([]byte(worker.Value), &report); err != nil {
+			log.WithField("json", worker.Value).Warning(
+				"Failed to parse Traffic.")
+			continue
+		}
+
+		for _, t := range report.Connections {
+			key := trafficKey(t)
+			total, ok := totals[key]
+			if !ok {
+				total = key
+			}
+			total.Packets += t.Packets
+			total.Bytes += t.Bytes
+			totals[key] = total
+		}
+		ports = append(ports, report.Ports...)
+	}
+
+	var traffic []db.Traffic
+	for _, t := range totals {
+		traffic = append(traffic, t)
+	}
+
+	conn.Txn(db.TrafficTable,
+		db.PortTrafficTable).Run(func(view db.Database) error {
+		joinTraffic(view, traffic)
+		joinPortTraffic(view, ports)
+		return nil
+	})
+	return nil
+}
+
+// trafficKey identifies the connection whose traffic `t` counts.
+func trafficKey(t db.Traffic) db.Traffic {
+	return db.Traffic{From: t.From, To: t.To, MinPort: t.MinPort,
+		MaxPort: t.MaxPort}
+}
+
+func joinTraffic(view db.Database, traffic []db.Traffic) {
+	key := func(iface interface{}) interface{} {
+		return trafficKey(iface.(db.Traffic))
+	}
+
+	pairs, dbTraffic, newTraffic := join.HashJoin(
+		db.TrafficSlice(view.SelectFromTraffic(nil)), db.TrafficSlice(traffic),
+		key, key)
+
+	for _, t := range dbTraffic {
+		view.Remove(t.(db.Traffic))
+	}
+
+	for _, t := range newTraffic {
+		pairs = append(pairs, join.Pair{L: view.InsertTraffic(), R: t})
+	}
+
+	for _, pair := range pairs {
+		dbt := pair.L.(db.Traffic)
+		t := pair.R.(db.Traffic)
+		t.ID = dbt.ID
+		if dbt != t {
+			view.Commit(t)
+		}
+	}
+}
+
+func joinPortTraffic(view db.Database, ports []db.PortTraffic) {
+	key := func(iface interface{}) interface{} {
+		return iface.(db.PortTraffic).StitchID
+	}
+
+	pairs, dbPorts, newPorts := join.HashJoin(
+		db.PortTrafficSlice(view.SelectFromPortTraffic(nil)),
+		db.PortTrafficSlice(ports), key, key)
+
+	for _, p := range dbPorts {
+		view.Remove(p.(db.PortTraffic))
+	}
+
+	for _, p := range newPorts {
+		pairs = append(pairs, join.Pair{L: view.InsertPortTraffic(), R: p})
+	}
+
+	for _, pair := range pairs {
+		dbp := pair.L.(db.PortTraffic)
+		p := pair.R.(db.PortTraffic)
+		p.ID = dbp.ID
+		if dbp != p {
+			view.Commit(p)
+		}
+	}
+}
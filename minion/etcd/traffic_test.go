@@ -0,0 +1,105 @@
+package etcd
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTrafficOnce(t *testing.T) {
+	store := newTestMock()
+	conn := db.New()
+
+	// Without a self minion there's nothing to do.
+	assert.NoError(t, runTrafficOnce(conn, store))
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.Role = db.Worker
+		self.PrivateIP = "10.0.0.1"
+		view.Commit(self)
+
+		tr := view.InsertTraffic()
+		tr.From = "a"
+		tr.To = "b"
+		tr.MinPort = 80
+		tr.MaxPort = 80
+		tr.Packets = 2
+		tr.Bytes = 200
+		view.Commit(tr)
+
+		port := view.InsertPortTraffic()
+		port.StitchID = "1"
+		port.Minion = "10.0.0.1"
+		port.RxBytes = 10
+		view.Commit(port)
+		return nil
+	})
+
+	// Workers publish their counters.
+	assert.NoError(t, runTrafficOnce(conn, store))
+	str, err := store.Get(trafficPath + "/10.0.0.1")
+	assert.NoError(t, err)
+	var published trafficReport
+	assert.NoError(t, json.Unmarshal([]byte(str), &published))
+	assert.Equal(t, trafficReport{
+		Connections: []db.Traffic{{From: "a", To: "b", MinPort: 80,
+			MaxPort: 80, Packets: 2, Bytes: 200}},
+		Ports: []db.PortTraffic{{StitchID: "1", Minion: "10.0.0.1",
+			RxBytes: 10}},
+	}, published)
+
+	err = store.Set(trafficPath+"/10.0.0.2", `{"Connections":[`+
+		`{"From":"a","To":"b","MinPort":80,"MaxPort":80,`+
+		`"Packets":1,"Bytes":100},`+
+		`{"From":"a","To":"b","MinPort":90,"MaxPort":90,`+
+		`"Packets":4,"Bytes":400},`+
+		`{"From":"b","To":"c","Packets":3,"Bytes":300}],`+
+		`"Ports":[{"StitchID":"2","Minion":"10.0.0.2","TxBytes":20}]}`, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Set(trafficPath+"/10.0.0.3", "bad json", 0))
+
+	// The leader totals the counters of every worker.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self, _ := view.MinionSelf()
+		self.Role = db.Master
+		view.Commit(self)
+
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+
+	assert.NoError(t, runTrafficOnce(conn, store))
+	traffic := db.TrafficSlice(conn.SelectFromTraffic(nil))
+	for i := range traffic {
+		traffic[i].ID = 0
+	}
+	assert.Len(t, traffic, 3)
+	assert.Contains(t, traffic, db.Traffic{From: "a", To: "b",
+		MinPort: 80, MaxPort: 80, Packets: 3, Bytes: 300})
+	assert.Contains(t, traffic, db.Traffic{From: "a", To: "b",
+		MinPort: 90, MaxPort: 90, Packets: 4, Bytes: 400})
+	assert.Contains(t, traffic, db.Traffic{From: "b", To: "c",
+		Packets: 3, Bytes: 300})
+
+	ports := db.PortTrafficSlice(conn.SelectFromPortTraffic(nil))
+	for i := range ports {
+		ports[i].ID = 0
+	}
+	sort.Sort(ports)
+	assert.Equal(t, db.PortTrafficSlice{
+		{StitchID: "1", Minion: "10.0.0.1", RxBytes: 10},
+		{StitchID: "2", Minion: "10.0.0.2", TxBytes: 20},
+	}, ports)
+
+	// Idempotent once converged.
+	assert.NoError(t, runTrafficOnce(conn, store))
+	assert.Len(t, conn.SelectFromTraffic(nil), 3)
+	assert.Len(t, conn.SelectFromPortTraffic(nil), 2)
+}
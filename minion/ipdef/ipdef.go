@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"net"
 	"syscall"
+
+	"github.com/quilt/quilt/util"
 )
 
+// DefaultSubnet is the container subnet used by deployments that don't specify one.
+const DefaultSubnet = "10.0.0.0/8"
+
 var (
 	// QuiltSubnet is the subnet under which quilt containers are given IP addresses.
 	QuiltSubnet = net.IPNet{
@@ -19,6 +24,14 @@ var (
 	// GatewayMac is the Mac address of the default gateway.
 	GatewayMac = IPToMac(GatewayIP)
 
+	// QuiltSubnet6 is the subnet under which quilt containers are given IPv6
+	// addresses, or nil if IPv6 is disabled.
+	QuiltSubnet6 *net.IPNet
+
+	// GatewayIP6 is the IPv6 address of the border router in the logical network,
+	// or nil if IPv6 is disabled.
+	GatewayIP6 net.IP
+
 	// QuiltBridge is the Open vSwitch bridge controlled by the Quilt minion.
 	QuiltBridge = "quilt-int"
 
@@ -26,6 +39,48 @@ var (
 	OvnBridge = "br-int"
 )
 
+// SetSubnets configures the container subnets.  `subnet` must be an IPv4 CIDR, and
+// defaults to DefaultSubnet if empty.  `subnet6` must be an IPv6 CIDR, or empty to
+// disable IPv6.  The gateway takes the first address of each subnet.
+//
+// The subnets are read without synchronization, so SetSubnets must be called before
+// any of the minion's modules start.
+func SetSubnets(subnet, subnet6 string) error {
+	if subnet == "" {
+		subnet = DefaultSubnet
+	}
+
+	ipNet, err := util.ParseSubnet(subnet, false)
+	if err != nil {
+		return err
+	}
+
+	var ipNet6 *net.IPNet
+	if subnet6 != "" {
+		if ipNet6, err = util.ParseSubnet(subnet6, true); err != nil {
+			return err
+		}
+	}
+
+	QuiltSubnet = *ipNet
+	GatewayIP = firstIP(*ipNet)
+	GatewayMac = IPToMac(GatewayIP)
+
+	QuiltSubnet6 = ipNet6
+	GatewayIP6 = nil
+	if ipNet6 != nil {
+		GatewayIP6 = firstIP(*ipNet6)
+	}
+	return nil
+}
+
+func firstIP(subnet net.IPNet) net.IP {
+	ip := make(net.IP, len(subnet.IP))
+	copy(ip, subnet.IP)
+	ip[len(ip)-1]++
+	return ip
+}
+
 // IPStrToMac converts the given IP address string into a MAC address.
 func IPStrToMac(ipStr string) string {
 	parsedIP := net.ParseIP(ipStr)
@@ -35,10 +90,21 @@ func IPStrToMac(ipStr string) string {
 	return IPToMac(parsedIP)
 }
 
-// IPToMac converts the given IP address into a MAC address.
+// IPToMac converts the given IP address into a MAC address.  IPv6 addresses are
+// converted based on their last 32 bits, which is where their container addresses are
+// allocated from, and use a different prefix so they can't collide with IPv4 MACs.
+// Dual stack containers use the MAC derived from their IPv4 address.
 func IPToMac(ip net.IP) string {
-	ip = ip.To4()
-	return fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", ip[0], ip[1], ip[2], ip[3])
+	prefix := "02:00"
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if len(ip) == net.IPv6len {
+		prefix = "02:01"
+		ip = ip[12:]
+	} else {
+		return ""
+	}
+	return fmt.Sprintf("%s:%02x:%02x:%02x:%02x", prefix, ip[0], ip[1], ip[2], ip[3])
 }
 
 // Allow mocking out for unit tests.
@@ -27,3 +27,34 @@ func TestIFName(t *testing.T) {
 	assert.Equal(t, IFName("1"), "1")
 	assert.Equal(t, IFName(""), "")
 }
+
+func TestToMac6(t *testing.T) {
+	assert.Equal(t, "02:01:0a:00:00:02", IPStrToMac("fd00::a00:2"))
+	assert.Equal(t, "", IPStrToMac("bad"))
+	assert.Equal(t, "", IPToMac(net.IP{1, 2}))
+}
+
+func TestSetSubnets(t *testing.T) {
+	defer SetSubnets("", "")
+
+	assert.NoError(t, SetSubnets("", ""))
+	assert.Equal(t, "10.0.0.0/8", QuiltSubnet.String())
+	assert.Equal(t, "10.0.0.1", GatewayIP.String())
+	assert.Equal(t, "02:00:0a:00:00:01", GatewayMac)
+	assert.Nil(t, QuiltSubnet6)
+	assert.Nil(t, GatewayIP6)
+
+	assert.NoError(t, SetSubnets("172.16.0.0/12", "fd00:1::/64"))
+	assert.Equal(t, "172.16.0.0/12", QuiltSubnet.String())
+	assert.Equal(t, "172.16.0.1", GatewayIP.String())
+	assert.Equal(t, "02:00:ac:10:00:01", GatewayMac)
+	assert.Equal(t, "fd00:1::/64", QuiltSubnet6.String())
+	assert.Equal(t, "fd00:1::1", GatewayIP6.String())
+
+	assert.EqualError(t, SetSubnets("bad", ""), "invalid subnet: bad")
+	assert.EqualError(t, SetSubnets("", "10.0.0.0/8"),
+		"not an IPv6 subnet: 10.0.0.0/8")
+
+	// A failed call leaves the subnets untouched.
+	assert.Equal(t, "172.16.0.0/12", QuiltSubnet.String())
+}
@@ -0,0 +1,66 @@
+package ipdef
+
+import "sync/atomic"
+
+// TunnelProtocol is the tunneling protocol used between machines.  "stt" and
+// "geneve" are supported.
+const TunnelProtocol = "stt"
+
+// DefaultMTU is the MTU of the container network when the MTU of the public
+// interface can't be detected.  It leaves room for the overhead of any tunneling
+// protocol on a standard 1500 byte network, and is reduced by espOverhead when the
+// tunnels are encrypted.
+const DefaultMTU = 1400
+
+// The bytes each tunneling protocol adds to the packets it encapsulates.
+var tunnelOverhead = map[string]int{
+	// Outer IPv4 (20), STT's TCP-like (20) and STT (18) headers, and the inner
+	// Ethernet header (14).
+	"stt": 72,
+
+	// Outer IPv4 (20), UDP (8), Geneve (8) and OVN's Geneve option (8) headers,
+	// and the inner Ethernet header (14).
+	"geneve": 58,
+}
+
+// The most bytes IPsec adds to tunneled packets when the overlay is encrypted with
+// ESP in transport mode using rfc4106(gcm(aes)): the ESP header (8), the IV (8), up
+// to 3 bytes of padding followed by the pad length and next header (5), and the
+// 16 byte ICV.
+const espOverhead = 37
+
+// The smallest MTU that IPv6 allows.  Networks that can't fit it once tunneled
+// fall back to the DefaultMTU, and rely on fragmentation.
+const minMTU = 1280
+
+// The MTU of the container network.  It changes as the overlay is encrypted and
+// decrypted while the minion's modules read it, so it's accessed atomically.
+var mtu int32 = DefaultMTU
+
+// MTU returns the MTU of the container network, which OVS and the containers'
+// interfaces are configured with.
+func MTU() int {
+	return int(atomic.LoadInt32(&mtu))
+}
+
+// SetMTU sets the MTU of the container network.
+func SetMTU(newMTU int) {
+	atomic.StoreInt32(&mtu, int32(newMTU))
+}
+
+// TunnelMTU returns the MTU of the container network when the packets are tunneled
+// with `protocol` over an interface whose MTU is `publicMTU`, and encrypted with
+// IPsec if `encrypted` is set.
+func TunnelMTU(publicMTU int, protocol string, encrypted bool) int {
+	overhead, ok := tunnelOverhead[protocol]
+	defaultMTU := DefaultMTU
+	if encrypted {
+		overhead += espOverhead
+		defaultMTU -= espOverhead
+	}
+
+	if !ok || publicMTU-overhead < minMTU {
+		return defaultMTU
+	}
+	return publicMTU - overhead
+}
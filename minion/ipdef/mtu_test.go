@@ -0,0 +1,33 @@
+package ipdef
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMTU(t *testing.T) {
+	defer SetMTU(MTU())
+
+	SetMTU(1337)
+	assert.Equal(t, 1337, MTU())
+}
+
+func TestTunnelMTU(t *testing.T) {
+	assert.Equal(t, 1428, TunnelMTU(1500, "stt", false))
+	assert.Equal(t, 1442, TunnelMTU(1500, "geneve", false))
+	assert.Equal(t, 1402, TunnelMTU(1460, "geneve", false))
+	assert.Equal(t, 8929, TunnelMTU(9001, "stt", false))
+
+	// Encryption leaves room for the ESP headers and trailer.
+	assert.Equal(t, 1391, TunnelMTU(1500, "stt", true))
+	assert.Equal(t, 1405, TunnelMTU(1500, "geneve", true))
+	assert.Equal(t, 8892, TunnelMTU(9001, "stt", true))
+
+	// Unknown protocols and tiny MTUs fall back to the default.
+	assert.Equal(t, DefaultMTU, TunnelMTU(1500, "vxlan", false))
+	assert.Equal(t, DefaultMTU, TunnelMTU(1300, "stt", false))
+	assert.Equal(t, DefaultMTU, TunnelMTU(0, "stt", false))
+	assert.Equal(t, DefaultMTU-espOverhead, TunnelMTU(1350, "stt", true))
+	assert.Equal(t, DefaultMTU-espOverhead, TunnelMTU(0, "stt", true))
+}
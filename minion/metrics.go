@@ -0,0 +1,42 @@
+package minion
+
+import (
+	"github.com/quilt/quilt/db"
+)
+
+// containerStatuses reports the number of containers on this minion in each status,
+// such as "running" or "exited".  Containers that haven't been started yet are
+// "pending", and the leader also reports the containers it couldn't place as
+// "unscheduled".
+func containerStatuses(conn db.Conn) func(set func(float64, ...string)) {
+	return func(set func(float64, ...string)) {
+		counts := map[string]int{}
+		conn.Txn(db.ContainerTable, db.EtcdTable,
+			db.MinionTable).Run(func(view db.Database) error {
+
+			self, err := view.MinionSelf()
+			if err != nil {
+				return err
+			}
+
+			leader := view.EtcdLeader()
+			for _, dbc := range view.SelectFromContainer(nil) {
+				switch {
+				case dbc.Minion == "" && leader:
+					counts["unscheduled"]++
+				case dbc.Minion != "" && dbc.Minion == self.PrivateIP:
+					status := dbc.Status
+					if status == "" {
+						status = "pending"
+					}
+					counts[status]++
+				}
+			}
+			return nil
+		})
+
+		for status, n := range counts {
+			set(float64(n), status)
+		}
+	}
+}
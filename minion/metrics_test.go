@@ -0,0 +1,59 @@
+package minion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/db"
+)
+
+func TestContainerStatuses(t *testing.T) {
+	t.Parallel()
+
+	statuses := func(conn db.Conn) map[string]float64 {
+		result := map[string]float64{}
+		containerStatuses(conn)(func(v float64, labels ...string) {
+			result[labels[0]] = v
+		})
+		return result
+	}
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range []db.Container{
+			{Minion: "1.2.3.4", Status: "running"},
+			{Minion: "1.2.3.4", Status: "running"},
+			{Minion: "1.2.3.4", Status: "exited"},
+			{Minion: "1.2.3.4"},
+			{Minion: "1.2.3.5", Status: "running"},
+			{},
+		} {
+			dbc.ID = view.InsertContainer().ID
+			view.Commit(dbc)
+		}
+		return nil
+	})
+
+	// Without a minion row, there's nothing to report.
+	assert.Empty(t, statuses(conn))
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.PrivateIP = "1.2.3.4"
+		view.Commit(self)
+		return nil
+	})
+	assert.Equal(t, map[string]float64{"running": 2, "exited": 1, "pending": 1},
+		statuses(conn))
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		etcd := view.InsertEtcd()
+		etcd.Leader = true
+		view.Commit(etcd)
+		return nil
+	})
+	assert.Equal(t, map[string]float64{"running": 2, "exited": 1, "pending": 1,
+		"unscheduled": 1}, statuses(conn))
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
 	"github.com/quilt/quilt/stitch"
 
@@ -56,6 +57,16 @@ func syncAddressSets(ovsdbClient ovsdb.Client, labels []db.Label) {
 				Addresses: unique(append(l.ContainerIPs, l.IP)),
 			},
 		)
+
+		if len(l.ContainerIPv6s) > 0 {
+			expAddressSets = append(expAddressSets,
+				ovsdb.AddressSet{
+					Name: addressSetName6(l.Label),
+					Addresses: unique(
+						append(l.ContainerIPv6s, l.IPv6)),
+				},
+			)
+		}
 	}
 	ovsdbKey := func(intf interface{}) interface{} {
 		addrSet := intf.(ovsdb.AddressSet)
@@ -172,11 +183,20 @@ func portConstraint(minPort, maxPort int, direction string) string {
 }
 
 func from(label string) string {
-	return fmt.Sprintf("ip4.src == $%s", addressSetName(label))
+	return addressMatch(label, "src")
 }
 
 func to(label string) string {
-	return fmt.Sprintf("ip4.dst == $%s", addressSetName(label))
+	return addressMatch(label, "dst")
+}
+
+func addressMatch(label, direction string) string {
+	match := fmt.Sprintf("ip4.%s == $%s", direction, addressSetName(label))
+	if ipdef.QuiltSubnet6 == nil {
+		return match
+	}
+	return or(match,
+		fmt.Sprintf("ip6.%s == $%s", direction, addressSetName6(label)))
 }
 
 func or(predicates ...string) string {
@@ -199,6 +219,13 @@ func addressSetName(label string) string {
 	return label
 }
 
+// addressSetName6 converts `label` to the name of its IPv6 address set.  The names
+// returned by addressSetName are never mixed case, so the "Ip6_" prefix guarantees the
+// two can't conflict.
+func addressSetName6(label string) string {
+	return "Ip6_" + addressSetName(label)
+}
+
 // ovsdbACLSlice is a wrapper around []ovsdb.ACL to allow us to perform a join
 type ovsdbACLSlice []ovsdb.ACL
 
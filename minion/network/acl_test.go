@@ -5,9 +5,11 @@ import (
 	"testing"
 
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
 	"github.com/quilt/quilt/minion/ovsdb/mocks"
 	"github.com/quilt/quilt/stitch"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -43,6 +45,24 @@ func TestSyncAddressSets(t *testing.T) {
 	client.AssertCalled(t, "CreateAddressSet", mock.Anything, mock.Anything)
 }
 
+func TestSyncAddressSets6(t *testing.T) {
+	t.Parallel()
+	client := new(mocks.Client)
+
+	labels := []db.Label{{
+		Label:          "a",
+		IP:             "1.2.3.4",
+		ContainerIPs:   []string{"1.2.3.4"},
+		IPv6:           "fd00::1",
+		ContainerIPv6s: []string{"fd00::1"},
+	}}
+	client.On("ListAddressSets").Return(nil, nil)
+	client.On("CreateAddressSet", "a", []string{"1.2.3.4"}).Return(nil).Once()
+	client.On("CreateAddressSet", "Ip6_a", []string{"fd00::1"}).Return(nil).Once()
+	syncAddressSets(client, labels)
+	client.AssertExpectations(t)
+}
+
 func TestSyncACLs(t *testing.T) {
 	t.Parallel()
 	client := new(mocks.Client)
@@ -78,3 +98,18 @@ func TestSyncACLs(t *testing.T) {
 	client.AssertCalled(t, "CreateACL", mock.Anything, mock.Anything, mock.Anything,
 		mock.Anything, mock.Anything)
 }
+
+func TestMatchString6(t *testing.T) {
+	conn := db.Connection{From: "a", To: "b-b", MinPort: 80, MaxPort: 80}
+	assert.Equal(t, "(((ip4.src == $a && ip4.dst == $B_B) && "+
+		"(icmp || 80 <= udp.dst <= 80 || 80 <= tcp.dst <= 80)) || "+
+		"((ip4.src == $B_B && ip4.dst == $a) && "+
+		"(icmp || 80 <= udp.src <= 80 || 80 <= tcp.src <= 80)))",
+		matchString(conn))
+
+	assert.NoError(t, ipdef.SetSubnets("", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	assert.Equal(t, "(ip4.src == $a || ip6.src == $Ip6_a)", from("a"))
+	assert.Equal(t, "(ip4.dst == $B_B || ip6.dst == $Ip6_B_B)", to("b-b"))
+}
@@ -3,29 +3,55 @@ package network
 import (
 	"fmt"
 	"net"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/stitch"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/miekg/dns"
 )
 
-const dnsTTL = 60 // Seconds
+// The TTL of local records grows with the time since they last changed.  While labels
+// are churning clients re-resolve quickly, and once things settle down they're
+// allowed to cache for longer.
+const (
+	minDNSTTL = 5  // Seconds
+	maxDNSTTL = 60 // Seconds
+)
 
 type dnsTable struct {
 	server dns.Server
 
 	recordLock sync.Mutex
-	records    map[string]net.IP
+	records    dnsRecords
+	forwarders []string
+	lastChange time.Time
+}
+
+// dnsRecords are the records the Quilt DNS server is authoritative for.
+type dnsRecords struct {
+	a    map[string]net.IP
+	aaaa map[string]net.IP
+	srv  map[string][]srvRecord
+	ptr  map[string][]string
+}
+
+// A srvRecord advertises a port on which a replica accepts connections.
+type srvRecord struct {
+	target string
+	port   uint16
 }
 
 var table *dnsTable
 
 func runDNS(conn db.Conn) {
-	for range conn.Trigger(db.LabelTable, db.MinionTable).C {
+	for range conn.Trigger(db.LabelTable, db.ConnectionTable, db.MinionTable).C {
 		runDNSOnce(conn)
 	}
 }
@@ -54,18 +80,31 @@ func runDNSOnce(conn db.Conn) {
 		return
 	}
 
-	table = updateTable(table, conn.SelectFromLabel(nil))
+	var forwarders []string
+	if spec, err := stitch.FromJSON(self.Spec); err == nil {
+		forwarders = spec.DNSForwarders
+	}
+
+	table = updateTable(table, conn.SelectFromLabel(nil),
+		conn.SelectFromConnection(nil), forwarders)
 }
 
-func updateTable(table *dnsTable, labels []db.Label) *dnsTable {
-	records := labelsToDNS(labels)
+func updateTable(table *dnsTable, labels []db.Label, connections []db.Connection,
+	forwarders []string) *dnsTable {
+
+	records := makeRecords(labels, connections)
+	forwarders = forwarderAddrs(forwarders)
 	if table != nil {
 		table.recordLock.Lock()
-		table.records = records
+		if !reflect.DeepEqual(table.records, records) {
+			table.records = records
+			table.lastChange = now()
+		}
+		table.forwarders = forwarders
 		table.recordLock.Unlock()
 		return table
 	}
-	table = makeTable(records)
+	table = makeTable(records, forwarders)
 
 	// There could be multiple messages depending on how listenAndServe is
 	// implemented.  We don't want anyone to block, so we make a bit of a buffer.
@@ -104,36 +143,109 @@ func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 	q := req.Question[0]
-	if q.Qclass != dns.ClassINET || q.Qtype != dns.TypeA {
+	if q.Qclass != dns.ClassINET {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 
-	ips := table.lookupA(q.Name)
-	if len(ips) == 0 {
+	if !isLocalName(q.Name) {
+		return table.genExternalResponse(req)
+	}
+
+	table.recordLock.Lock()
+	defer table.recordLock.Unlock()
+
+	ttl := table.ttl()
+	var answer, extra []dns.RR
+	switch q.Qtype {
+	case dns.TypeA:
+		if ip := table.records.a[q.Name]; ip != nil {
+			answer = append(answer, aRecord(q.Name, ip, ttl))
+		}
+	case dns.TypeAAAA:
+		if ip := table.records.aaaa[q.Name]; ip != nil {
+			answer = append(answer, aaaaRecord(q.Name, ip, ttl))
+		}
+	case dns.TypeSRV:
+		for _, srv := range table.records.srv[q.Name] {
+			answer = append(answer, &dns.SRV{
+				Hdr:      rrHeader(q.Name, dns.TypeSRV, ttl),
+				Priority: 0,
+				Weight:   1,
+				Port:     srv.port,
+				Target:   srv.target,
+			})
+
+			if ip := table.records.a[srv.target]; ip != nil {
+				extra = append(extra, aRecord(srv.target, ip, ttl))
+			}
+			if ip := table.records.aaaa[srv.target]; ip != nil {
+				extra = append(extra, aaaaRecord(srv.target, ip, ttl))
+			}
+		}
+	case dns.TypePTR:
+		for _, name := range table.records.ptr[q.Name] {
+			answer = append(answer, &dns.PTR{
+				Hdr: rrHeader(q.Name, dns.TypePTR, ttl),
+				Ptr: name,
+			})
+		}
+	default:
+		return resp.SetRcode(req, dns.RcodeNotImplemented)
+	}
+
+	if len(answer) == 0 {
 		// Even though the client asked for a hostname within `.q` that we know
 		// nothing about, it's possible we'll learn about it in the future.  For
 		// now, we'll just not respond, the client will time out, and try again
 		// later.  Hopefully by then we have a response for them -- or if not,
 		// eventually they'll give up.
-		//
-		// XXX: The above logic is correct for things in the .q domain name, but
-		// we're also doing the same thing for failures to resolve external
-		// hosts.  This isn't entirely correct, it would be much better to return
-		// whatever upstream gave us in case of a failure.
+		return nil
+	}
+
+	resp.SetReply(req)
+	resp.Authoritative = true
+	resp.Answer = answer
+	resp.Extra = extra
+	return resp
+}
+
+// genExternalResponse resolves names outside of Quilt.  If the deployment configured
+// DNS forwarders, the request is relayed to them verbatim, and whatever they respond
+// is passed back to the client.  Otherwise we fall back to resolving A records with
+// the minion's own resolver.
+func (table *dnsTable) genExternalResponse(req *dns.Msg) *dns.Msg {
+	table.recordLock.Lock()
+	forwarders := table.forwarders
+	table.recordLock.Unlock()
+
+	resp := &dns.Msg{}
+	if len(forwarders) > 0 {
+		for _, server := range forwarders {
+			fwdResp, err := exchange(req, server)
+			if err == nil {
+				return fwdResp
+			}
+			log.WithError(err).WithField("server", server).Debug(
+				"Failed to forward DNS request")
+		}
+		return resp.SetRcode(req, dns.RcodeServerFailure)
+	}
+
+	q := req.Question[0]
+	if q.Qtype != dns.TypeA {
+		return resp.SetRcode(req, dns.RcodeNotImplemented)
+	}
+
+	ips := table.lookupA(q.Name)
+	if len(ips) == 0 {
+		// XXX: Without forwarders we only learn whether the lookup failed, not
+		// why, so there's no meaningful error to hand back to the client.
 		return nil
 	}
 
 	resp.SetReply(req)
 	for _, ip := range ips {
-		resp.Answer = append(resp.Answer, &dns.A{
-			Hdr: dns.RR_Header{
-				Name:   q.Name,
-				Rrtype: dns.TypeA,
-				Class:  dns.ClassINET,
-				Ttl:    dnsTTL,
-			},
-			A: ip,
-		})
+		resp.Answer = append(resp.Answer, aRecord(q.Name, ip, maxDNSTTL))
 	}
 	return resp
 }
@@ -141,7 +253,7 @@ func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
 func (table *dnsTable) lookupA(name string) []net.IP {
 	if strings.HasSuffix(name, ".q.") {
 		table.recordLock.Lock()
-		ip := table.records[name]
+		ip := table.records.a[name]
 		table.recordLock.Unlock()
 		if ip == nil {
 			return nil
@@ -164,9 +276,77 @@ func (table *dnsTable) lookupA(name string) []net.IP {
 	return ips
 }
 
-func makeTable(records map[string]net.IP) *dnsTable {
+// ttl returns the TTL of local records.  The caller must hold `recordLock`.
+func (table *dnsTable) ttl() uint32 {
+	age := int(now().Sub(table.lastChange) / time.Second)
+	switch {
+	case age < minDNSTTL:
+		return minDNSTTL
+	case age > maxDNSTTL:
+		return maxDNSTTL
+	default:
+		return uint32(age)
+	}
+}
+
+// isLocalName returns true if the Quilt DNS server is authoritative for `name`.  That
+// is, names within `.q`, and the reverse lookup zones of the Quilt subnets.
+func isLocalName(name string) bool {
+	if strings.HasSuffix(name, ".q.") {
+		return true
+	}
+
+	ip := ptrToIP(name)
+	if ip == nil {
+		return false
+	}
+
+	return ipdef.QuiltSubnet.Contains(ip) ||
+		(ipdef.QuiltSubnet6 != nil && ipdef.QuiltSubnet6.Contains(ip))
+}
+
+// ptrToIP converts a name in the `in-addr.arpa.` or `ip6.arpa.` domains into the IP
+// address it represents, or returns nil if `name` isn't such a name.
+func ptrToIP(name string) net.IP {
+	var labels []string
+	var sep string
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa."):
+		labels = strings.Split(strings.TrimSuffix(name, ".in-addr.arpa."), ".")
+		if len(labels) != net.IPv4len {
+			return nil
+		}
+		sep = "."
+	case strings.HasSuffix(name, ".ip6.arpa."):
+		// Each label is a nibble of the address.
+		labels = strings.Split(strings.TrimSuffix(name, ".ip6.arpa."), ".")
+		if len(labels) != 2*net.IPv6len {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+
+	if sep == "" {
+		// Regroup the nibbles into the colon separated hextets ParseIP expects.
+		var hextets []string
+		for i := 0; i < len(labels); i += 4 {
+			hextets = append(hextets, strings.Join(labels[i:i+4], ""))
+		}
+		labels, sep = hextets, ":"
+	}
+	return net.ParseIP(strings.Join(labels, sep))
+}
+
+func makeTable(records dnsRecords, forwarders []string) *dnsTable {
 	tbl := &dnsTable{
-		records: records,
+		records:    records,
+		forwarders: forwarders,
+		lastChange: now(),
 		server: dns.Server{
 			Addr: fmt.Sprintf("%s:53", ipdef.GatewayIP),
 			Net:  "udp",
@@ -176,24 +356,143 @@ func makeTable(records map[string]net.IP) *dnsTable {
 	return tbl
 }
 
-func labelsToDNS(labels []db.Label) map[string]net.IP {
-	records := map[string]net.IP{}
+// Port ranges with more ports than this aren't advertised with SRV records, as
+// they would bloat the responses without being any more useful to clients.
+const maxSRVRangePorts = 16
+
+// Connections allow both protocols, so SRV records are published for each.
+var srvProtocols = []string{"tcp", "udp"}
+
+// makeRecords generates the DNS records for `labels`.  Each label gets an A record for
+// its load balanced IP, and one per replica that matches the names generated by
+// `Service.children()` in Stitch.  Dual stack labels get AAAA records for the same
+// names.  Each replica also gets a PTR record, and an SRV record under
+// `_<port>._<protocol>.<label>.q` for every port other labels may connect to it on.
+// Port ranges wider than `maxSRVRangePorts` are skipped.
+func makeRecords(labels []db.Label, connections []db.Connection) dnsRecords {
+	ports := map[string][]uint16{}
+	for _, conn := range connections {
+		if conn.MaxPort-conn.MinPort >= maxSRVRangePorts {
+			continue
+		}
+
+		for p := conn.MinPort; p <= conn.MaxPort; p++ {
+			port := uint16(p)
+			if !containsPort(ports[conn.To], port) {
+				ports[conn.To] = append(ports[conn.To], port)
+			}
+		}
+	}
+
+	records := dnsRecords{
+		a:    map[string]net.IP{},
+		aaaa: map[string]net.IP{},
+		srv:  map[string][]srvRecord{},
+		ptr:  map[string][]string{},
+	}
 	for _, label := range labels {
+		labelName := label.Label + ".q."
 		if ip := net.ParseIP(label.IP); ip != nil {
-			records[label.Label+".q."] = ip
+			records.a[labelName] = ip
+		}
+		if ip := net.ParseIP(label.IPv6); ip != nil {
+			records.aaaa[labelName] = ip
 		}
 
 		for i, ipStr := range label.ContainerIPs {
-			if ip := net.ParseIP(ipStr); ip != nil {
-				records[fmt.Sprintf("%d.%s.q.", i+1, label.Label)] = ip
+			ip := net.ParseIP(ipStr)
+			if ip == nil {
+				continue
+			}
+
+			name := fmt.Sprintf("%d.%s", i+1, labelName)
+			records.a[name] = ip
+			records.addPTR(ipStr, name)
+
+			if i < len(label.ContainerIPv6s) {
+				ip6Str := label.ContainerIPv6s[i]
+				if ip6 := net.ParseIP(ip6Str); ip6 != nil {
+					records.aaaa[name] = ip6
+					records.addPTR(ip6Str, name)
+				}
+			}
+
+			for _, port := range ports[label.Label] {
+				for _, proto := range srvProtocols {
+					srv := fmt.Sprintf("_%d._%s.%s", port, proto,
+						labelName)
+					records.srv[srv] = append(records.srv[srv],
+						srvRecord{target: name, port: port})
+				}
 			}
 		}
 	}
+
+	// Labels come out of the database in a random order, so sort the names
+	// to avoid spurious changes.
+	for _, names := range records.ptr {
+		sort.Strings(names)
+	}
 	return records
 }
 
+func (records dnsRecords) addPTR(ip, name string) {
+	if arpa, err := dns.ReverseAddr(ip); err == nil {
+		records.ptr[arpa] = append(records.ptr[arpa], name)
+	}
+}
+
+func containsPort(ports []uint16, port uint16) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarderAddrs converts the forwarders listed in the spec into addresses suitable
+// for dialing, defaulting to port 53.
+func forwarderAddrs(forwarders []string) []string {
+	var addrs []string
+	for _, fwd := range forwarders {
+		if _, _, err := net.SplitHostPort(fwd); err == nil {
+			addrs = append(addrs, fwd)
+		} else if net.ParseIP(fwd) != nil {
+			addrs = append(addrs, net.JoinHostPort(fwd, "53"))
+		} else {
+			log.WithField("forwarder", fwd).Warn("Invalid DNS forwarder")
+		}
+	}
+	return addrs
+}
+
+func rrHeader(name string, rrtype uint16, ttl uint32) dns.RR_Header {
+	return dns.RR_Header{
+		Name:   name,
+		Rrtype: rrtype,
+		Class:  dns.ClassINET,
+		Ttl:    ttl,
+	}
+}
+
+func aRecord(name string, ip net.IP, ttl uint32) dns.RR {
+	return &dns.A{Hdr: rrHeader(name, dns.TypeA, ttl), A: ip}
+}
+
+func aaaaRecord(name string, ip net.IP, ttl uint32) dns.RR {
+	return &dns.AAAA{Hdr: rrHeader(name, dns.TypeAAAA, ttl), AAAA: ip}
+}
+
 var listenAndServe = func(table *dnsTable) error {
 	return table.server.ListenAndServe()
 }
 
+var exchange = func(req *dns.Msg, server string) (*dns.Msg, error) {
+	client := dns.Client{Net: "udp"}
+	resp, _, err := client.Exchange(req, server)
+	return resp, err
+}
+
 var lookupHost = net.LookupHost
+var now = time.Now
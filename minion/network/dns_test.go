@@ -3,40 +3,66 @@ package network
 import (
 	"net"
 	"testing"
+	"time"
 
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestUpdateTable(t *testing.T) {
-	t.Parallel()
-
 	listenAndServe = func(table *dnsTable) error { return assert.AnError }
-	assert.Nil(t, updateTable(nil, nil))
+	assert.Nil(t, updateTable(nil, nil, nil, nil))
 
 	listenAndServe = func(table *dnsTable) error {
 		table.server.NotifyStartedFunc()
 		return nil
 	}
 
-	table := updateTable(nil, []db.Label{{Label: "foo", IP: "1.2.3.4"}})
+	start := time.Unix(0, 0)
+	now = func() time.Time { return start }
+
+	table := updateTable(nil, []db.Label{{Label: "foo", IP: "1.2.3.4"}}, nil,
+		[]string{"8.8.8.8"})
 	assert.NotNil(t, table)
-	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(1, 2, 3, 4)}, table.records)
+	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(1, 2, 3, 4)},
+		table.records.a)
+	assert.Equal(t, []string{"8.8.8.8:53"}, table.forwarders)
+	assert.Equal(t, start, table.lastChange)
+
+	now = func() time.Time { return start.Add(time.Minute) }
+	newTable := updateTable(table, []db.Label{{Label: "foo", IP: "1.2.3.4"}}, nil,
+		nil)
+	assert.True(t, table == newTable) // Pointer Equality.
+	assert.Equal(t, start, newTable.lastChange)
+	assert.Empty(t, newTable.forwarders)
 
-	newTable := updateTable(table, []db.Label{{Label: "foo", IP: "5.6.7.8"}})
+	newTable = updateTable(table, []db.Label{{Label: "foo", IP: "5.6.7.8"}}, nil,
+		nil)
 	assert.NotNil(t, newTable)
 	assert.True(t, table == newTable) // Pointer Equality.
 	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(5, 6, 7, 8)},
-		newTable.records)
+		newTable.records.a)
+	assert.Equal(t, start.Add(time.Minute), newTable.lastChange)
 }
 
 func TestGenResponse(t *testing.T) {
-	t.Parallel()
-
-	table := makeTable(map[string]net.IP{
-		"a.q.": net.IPv4(1, 2, 3, 4),
-	})
+	now = time.Now
+	table := makeTable(makeRecords([]db.Label{{
+		Label:        "a",
+		IP:           "10.0.0.2",
+		ContainerIPs: []string{"10.0.0.2"},
+	}, {
+		Label:          "c",
+		IP:             "10.0.0.3",
+		ContainerIPs:   []string{"10.0.0.3"},
+		IPv6:           "fd00::3",
+		ContainerIPv6s: []string{"fd00::3"},
+	}}, []db.Connection{
+		{From: "b", To: "a", MinPort: 80, MaxPort: 80},
+		{From: "b", To: "c", MinPort: 80, MaxPort: 80},
+	}), nil)
 
 	req := &dns.Msg{}
 	req.SetQuestion("foo.", dns.TypeAAAA)
@@ -49,34 +75,112 @@ func TestGenResponse(t *testing.T) {
 	assert.Equal(t, req.Id, resp.Id)
 	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
 
+	req.SetQuestion("a.q.", dns.TypeMX)
+	resp = table.genResponse(req)
+	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
+
 	req.SetQuestion("bad.q.", dns.TypeA)
 	resp = table.genResponse(req)
 	assert.Nil(t, resp)
 
+	aRR := &dns.A{Hdr: rrHeader("a.q.", dns.TypeA, minDNSTTL),
+		A: net.IPv4(10, 0, 0, 2)}
 	req.SetQuestion("a.q.", dns.TypeA)
 	resp = table.genResponse(req)
 	exp := *req
 	exp.Response = true
+	exp.Authoritative = true
 	exp.Rcode = dns.RcodeSuccess
-	exp.Answer = []dns.RR{&dns.A{
-		Hdr: dns.RR_Header{
-			Name:   "a.q.",
-			Rrtype: dns.TypeA,
-			Class:  dns.ClassINET,
-			Ttl:    dnsTTL,
-		},
-		A: net.IPv4(1, 2, 3, 4),
-	}}
+	exp.Answer = []dns.RR{aRR}
 	assert.Equal(t, &exp, resp)
 
+	replicaRR := &dns.A{Hdr: rrHeader("1.a.q.", dns.TypeA, minDNSTTL),
+		A: net.IPv4(10, 0, 0, 2)}
+	req.SetQuestion("a.q.", dns.TypeSRV)
+	assert.Nil(t, table.genResponse(req))
+
+	req.SetQuestion("_80._tcp.a.q.", dns.TypeSRV)
+	resp = table.genResponse(req)
+	assert.Equal(t, []dns.RR{&dns.SRV{
+		Hdr:    rrHeader("_80._tcp.a.q.", dns.TypeSRV, minDNSTTL),
+		Weight: 1,
+		Port:   80,
+		Target: "1.a.q.",
+	}}, resp.Answer)
+	assert.Equal(t, []dns.RR{replicaRR}, resp.Extra)
+
+	req.SetQuestion("2.0.0.10.in-addr.arpa.", dns.TypePTR)
+	resp = table.genResponse(req)
+	assert.Equal(t, []dns.RR{&dns.PTR{
+		Hdr: rrHeader("2.0.0.10.in-addr.arpa.", dns.TypePTR, minDNSTTL),
+		Ptr: "1.a.q.",
+	}}, resp.Answer)
+
+	req.SetQuestion("4.0.0.10.in-addr.arpa.", dns.TypePTR)
+	assert.Nil(t, table.genResponse(req))
+
+	req.SetQuestion("a.q.", dns.TypeAAAA)
+	assert.Nil(t, table.genResponse(req))
+
+	req.SetQuestion("c.q.", dns.TypeAAAA)
+	resp = table.genResponse(req)
+	assert.Equal(t, []dns.RR{&dns.AAAA{
+		Hdr:  rrHeader("c.q.", dns.TypeAAAA, minDNSTTL),
+		AAAA: net.ParseIP("fd00::3"),
+	}}, resp.Answer)
+
+	req.SetQuestion("_80._udp.c.q.", dns.TypeSRV)
+	resp = table.genResponse(req)
+	assert.Equal(t, []dns.RR{
+		aRecord("1.c.q.", net.IPv4(10, 0, 0, 3), minDNSTTL),
+		aaaaRecord("1.c.q.", net.ParseIP("fd00::3"), minDNSTTL),
+	}, resp.Extra)
 }
 
-func TestLookupA(t *testing.T) {
-	t.Parallel()
+func TestGenExternalResponse(t *testing.T) {
+	table := makeTable(dnsRecords{}, nil)
+
+	req := &dns.Msg{}
+	req.SetQuestion("quilt.io.", dns.TypeA)
+
+	lookupHost = func(string) ([]string, error) { return nil, assert.AnError }
+	assert.Nil(t, table.genResponse(req))
+
+	lookupHost = func(string) ([]string, error) { return []string{"1.2.3.4"}, nil }
+	resp := table.genResponse(req)
+	assert.Equal(t, []dns.RR{&dns.A{Hdr: rrHeader("quilt.io.", dns.TypeA, maxDNSTTL),
+		A: net.IPv4(1, 2, 3, 4)}}, resp.Answer)
+
+	// PTR records outside of the Quilt subnet aren't ours to answer.
+	req.SetQuestion("4.3.2.1.in-addr.arpa.", dns.TypePTR)
+	resp = table.genResponse(req)
+	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
 
-	table := makeTable(map[string]net.IP{
+	var servers []string
+	exchange = func(req *dns.Msg, server string) (*dns.Msg, error) {
+		servers = append(servers, server)
+		return nil, assert.AnError
+	}
+	table.forwarders = []string{"8.8.8.8:53", "8.8.4.4:53"}
+	resp = table.genResponse(req)
+	assert.Equal(t, dns.RcodeServerFailure, resp.Rcode)
+	assert.Equal(t, []string{"8.8.8.8:53", "8.8.4.4:53"}, servers)
+
+	servers = nil
+	upstream := &dns.Msg{}
+	upstream.SetReply(req)
+	exchange = func(req *dns.Msg, server string) (*dns.Msg, error) {
+		servers = append(servers, server)
+		return upstream, nil
+	}
+	assert.True(t, upstream == table.genResponse(req))
+	assert.Equal(t, []string{"8.8.8.8:53"}, servers)
+}
+
+func TestLookupA(t *testing.T) {
+	table := makeTable(dnsRecords{a: map[string]net.IP{
 		"a.q.": net.IPv4(1, 2, 3, 4),
-	})
+	}}, nil)
 
 	assert.Empty(t, table.lookupA("bad.q."))
 	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("a.q."))
@@ -99,20 +203,35 @@ func TestLookupA(t *testing.T) {
 		table.lookupA("quilt.io."))
 }
 
+func TestTTL(t *testing.T) {
+	start := time.Unix(0, 0)
+	table := &dnsTable{lastChange: start}
+
+	now = func() time.Time { return start }
+	assert.Equal(t, uint32(minDNSTTL), table.ttl())
+
+	now = func() time.Time { return start.Add(30 * time.Second) }
+	assert.Equal(t, uint32(30), table.ttl())
+
+	now = func() time.Time { return start.Add(time.Hour) }
+	assert.Equal(t, uint32(maxDNSTTL), table.ttl())
+}
+
 func TestMakeTable(t *testing.T) {
 	t.Parallel()
 
-	records := map[string]net.IP{"a": net.IPv4(1, 2, 3, 4)}
-	tbl := makeTable(records)
+	records := dnsRecords{a: map[string]net.IP{"a": net.IPv4(1, 2, 3, 4)}}
+	tbl := makeTable(records, []string{"8.8.8.8:53"})
 	assert.Equal(t, tbl.records, records)
+	assert.Equal(t, tbl.forwarders, []string{"8.8.8.8:53"})
 	assert.Equal(t, tbl.server.Addr, "10.0.0.1:53")
 	assert.Equal(t, tbl.server.Net, "udp")
 }
 
-func TestLabelsToDNS(t *testing.T) {
+func TestMakeRecords(t *testing.T) {
 	t.Parallel()
 
-	res := labelsToDNS([]db.Label{{
+	res := makeRecords([]db.Label{{
 		Label: "l1",
 	}, {
 		Label:        "l2",
@@ -125,12 +244,97 @@ func TestLabelsToDNS(t *testing.T) {
 		Label:        "l4",
 		IP:           "5.6.7.8",
 		ContainerIPs: []string{"1.1.1.1", "2.2.2.2"},
-	}})
-	exp := map[string]net.IP{
+	}, {
+		Label:        "l5",
+		IP:           "2.2.2.2",
+		ContainerIPs: []string{"2.2.2.2"},
+	}, {
+		Label:          "l6",
+		IP:             "3.3.3.3",
+		ContainerIPs:   []string{"3.3.3.3"},
+		IPv6:           "fd00::3",
+		ContainerIPv6s: []string{"fd00::3"},
+	}}, []db.Connection{
+		{From: "l1", To: "l4", MinPort: 80, MaxPort: 80},
+		{From: "l3", To: "l4", MinPort: 80, MaxPort: 80},
+		{From: "public", To: "l4", MinPort: 443, MaxPort: 443},
+		{From: "l1", To: "l5", MinPort: 1000, MaxPort: 2000},
+		{From: "l1", To: "l6", MinPort: 8000, MaxPort: 8001},
+	})
+
+	assert.Equal(t, map[string]net.IP{
 		"l3.q.":   net.IPv4(1, 2, 3, 4),
 		"l4.q.":   net.IPv4(5, 6, 7, 8),
 		"1.l4.q.": net.IPv4(1, 1, 1, 1),
 		"2.l4.q.": net.IPv4(2, 2, 2, 2),
+		"l5.q.":   net.IPv4(2, 2, 2, 2),
+		"1.l5.q.": net.IPv4(2, 2, 2, 2),
+		"l6.q.":   net.IPv4(3, 3, 3, 3),
+		"1.l6.q.": net.IPv4(3, 3, 3, 3),
+	}, res.a)
+
+	assert.Equal(t, map[string]net.IP{
+		"l6.q.":   net.ParseIP("fd00::3"),
+		"1.l6.q.": net.ParseIP("fd00::3"),
+	}, res.aaaa)
+
+	ptr6 := "3.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa."
+	assert.Equal(t, map[string][]string{
+		"1.1.1.1.in-addr.arpa.": {"1.l4.q."},
+		"2.2.2.2.in-addr.arpa.": {"1.l5.q.", "2.l4.q."},
+		"3.3.3.3.in-addr.arpa.": {"1.l6.q."},
+		ptr6:                    {"1.l6.q."},
+	}, res.ptr)
+
+	l4Port80 := []srvRecord{
+		{target: "1.l4.q.", port: 80},
+		{target: "2.l4.q.", port: 80},
 	}
-	assert.Equal(t, exp, res)
+	l4Port443 := []srvRecord{
+		{target: "1.l4.q.", port: 443},
+		{target: "2.l4.q.", port: 443},
+	}
+	assert.Equal(t, map[string][]srvRecord{
+		"_80._tcp.l4.q.":   l4Port80,
+		"_80._udp.l4.q.":   l4Port80,
+		"_443._tcp.l4.q.":  l4Port443,
+		"_443._udp.l4.q.":  l4Port443,
+		"_8000._tcp.l6.q.": {{target: "1.l6.q.", port: 8000}},
+		"_8000._udp.l6.q.": {{target: "1.l6.q.", port: 8000}},
+		"_8001._tcp.l6.q.": {{target: "1.l6.q.", port: 8001}},
+		"_8001._udp.l6.q.": {{target: "1.l6.q.", port: 8001}},
+	}, res.srv)
+}
+
+func TestPtrToIP(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, net.IPv4(10, 1, 2, 3), ptrToIP("3.2.1.10.in-addr.arpa."))
+	assert.Nil(t, ptrToIP("2.1.10.in-addr.arpa."))
+	assert.Nil(t, ptrToIP("a.q."))
+
+	ptr6 := "3.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa."
+	assert.Equal(t, net.ParseIP("fd00::3"), ptrToIP(ptr6))
+	assert.Nil(t, ptrToIP("3.0.d.f.ip6.arpa."))
+
+	assert.True(t, isLocalName("foo.q."))
+	assert.True(t, isLocalName("3.2.1.10.in-addr.arpa."))
+	assert.False(t, isLocalName("3.2.1.11.in-addr.arpa."))
+	assert.False(t, isLocalName("quilt.io."))
+}
+
+func TestIsLocalName6(t *testing.T) {
+	ptr6 := "3.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa."
+	assert.False(t, isLocalName(ptr6))
+
+	assert.NoError(t, ipdef.SetSubnets("", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+	assert.True(t, isLocalName(ptr6))
+}
+
+func TestForwarderAddrs(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"8.8.8.8:53", "1.2.3.4:5353"},
+		forwarderAddrs([]string{"8.8.8.8", "bad", "1.2.3.4:5353"}))
 }
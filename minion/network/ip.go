@@ -10,6 +10,7 @@ import (
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
 	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/stitch"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -20,7 +21,7 @@ func runUpdateIPs(conn db.Conn) {
 			continue
 		}
 
-		txn := conn.Txn(db.ContainerTable, db.LabelTable)
+		txn := conn.Txn(db.ContainerTable, db.LabelTable, db.MinionTable)
 		err := txn.Run(func(view db.Database) error {
 			err := allocateContainerIPs(view)
 			if err == nil {
@@ -45,22 +46,43 @@ func allocateContainerIPs(view db.Database) error {
 		ipdef.QuiltSubnet.IP.String(): {},
 	}
 
+	ip6Set := map[string]struct{}{}
+	if ipdef.QuiltSubnet6 != nil {
+		ip6Set[ipdef.GatewayIP6.String()] = struct{}{}
+		ip6Set[ipdef.QuiltSubnet6.IP.String()] = struct{}{}
+	}
+
 	var unassigned []db.Container
 	for _, dbc := range dbcs {
 		if dbc.IP != "" {
 			ipSet[dbc.IP] = struct{}{}
-		} else {
+		}
+		if dbc.IPv6 != "" {
+			ip6Set[dbc.IPv6] = struct{}{}
+		}
+
+		if dbc.IP == "" || (ipdef.QuiltSubnet6 != nil && dbc.IPv6 == "") {
 			unassigned = append(unassigned, dbc)
 		}
 	}
 
 	for _, dbc := range unassigned {
-		ip, err := allocateIP(ipSet, ipdef.QuiltSubnet)
-		if err != nil {
-			return err
+		if dbc.IP == "" {
+			ip, err := allocateIP(ipSet, ipdef.QuiltSubnet)
+			if err != nil {
+				return err
+			}
+			dbc.IP = ip
+		}
+
+		if ipdef.QuiltSubnet6 != nil && dbc.IPv6 == "" {
+			ip, err := allocateIP(ip6Set, *ipdef.QuiltSubnet6)
+			if err != nil {
+				return err
+			}
+			dbc.IPv6 = ip
 		}
 
-		dbc.IP = ip
 		view.Commit(dbc)
 	}
 
@@ -72,14 +94,37 @@ func updateLabelIPs(view db.Database) error {
 		return dbc.IP != ""
 	})
 
-	// XXX:  We sort the containers by StitchID to guarantee that the sub-label
-	// ordering is consistent between function calls.  This is pretty darn fragile.
+	// Containers the spec doesn't know about are ordered by StitchID to guarantee
+	// that the sub-label ordering is consistent between function calls.
 	sort.Sort(db.ContainerSlice(dbcs))
 
-	containerIPs := map[string][]string{}
+	idMap := map[string]db.Container{}
+	labelDBCs := map[string][]db.Container{}
 	for _, dbc := range dbcs {
+		idMap[dbc.StitchID] = dbc
 		for _, l := range dbc.Labels {
-			containerIPs[l] = append(containerIPs[l], dbc.IP)
+			labelDBCs[l] = append(labelDBCs[l], dbc)
+		}
+	}
+
+	// The replica hostnames (`1.label.q`, `2.label.q`, ...) are derived from the
+	// order of `ContainerIPs`, so it must match the order in which the spec lists
+	// each label's containers.  That's what `Service.children()` promises.
+	specIDs := specLabelIDs(view)
+	orderedDBCs := map[string][]db.Container{}
+	for l, ldbcs := range labelDBCs {
+		ordered := map[string]struct{}{}
+		for _, id := range specIDs[l] {
+			if dbc, ok := idMap[id]; ok {
+				orderedDBCs[l] = append(orderedDBCs[l], dbc)
+				ordered[id] = struct{}{}
+			}
+		}
+
+		for _, dbc := range ldbcs {
+			if _, ok := ordered[dbc.StitchID]; !ok {
+				orderedDBCs[l] = append(orderedDBCs[l], dbc)
+			}
 		}
 	}
 
@@ -88,7 +133,7 @@ func updateLabelIPs(view db.Database) error {
 	}
 
 	labelKeySlice := join.StringSlice{}
-	for l := range containerIPs {
+	for l := range orderedDBCs {
 		labelKeySlice = append(labelKeySlice, l)
 	}
 
@@ -106,7 +151,15 @@ func updateLabelIPs(view db.Database) error {
 	for _, pair := range pairs {
 		dbl := pair.L.(db.Label)
 		dbl.Label = pair.R.(string)
-		dbl.ContainerIPs = containerIPs[dbl.Label]
+
+		dbl.ContainerIPs = nil
+		dbl.ContainerIPv6s = nil
+		for _, dbc := range orderedDBCs[dbl.Label] {
+			dbl.ContainerIPs = append(dbl.ContainerIPs, dbc.IP)
+			if ipdef.QuiltSubnet6 != nil {
+				dbl.ContainerIPv6s = append(dbl.ContainerIPv6s, dbc.IPv6)
+			}
+		}
 
 		// XXX: In effect, we're implementing a dumb load balancer where all
 		// traffic goes to the first container.  Something more sophisticated is
@@ -115,23 +168,57 @@ func updateLabelIPs(view db.Database) error {
 		if len(dbl.ContainerIPs) > 0 {
 			dbl.IP = dbl.ContainerIPs[0]
 		}
+
+		dbl.IPv6 = ""
+		if len(dbl.ContainerIPv6s) > 0 {
+			dbl.IPv6 = dbl.ContainerIPv6s[0]
+		}
 		view.Commit(dbl)
 	}
 
 	return nil
 }
 
+// specLabelIDs maps each label in the minion's spec to the StitchIDs of its
+// containers, in the order the spec lists them.
+func specLabelIDs(view db.Database) map[string][]string {
+	self, err := view.MinionSelf()
+	if err != nil {
+		return nil
+	}
+
+	spec, err := stitch.FromJSON(self.Spec)
+	if err != nil {
+		return nil
+	}
+
+	ids := map[string][]string{}
+	for _, label := range spec.Labels {
+		ids[label.Name] = label.IDs
+	}
+	return ids
+}
+
+// allocateIP picks a random unused address within `subnet`.  Addresses are only
+// allocated from the last 32 bits of the subnet, which for IPv6 subnets of a /96 or
+// larger leaves the rest of the host bits zero.
 func allocateIP(ipSet map[string]struct{}, subnet net.IPNet) (string, error) {
-	prefix := binary.BigEndian.Uint32(subnet.IP.To4())
-	mask := binary.BigEndian.Uint32(subnet.Mask)
+	base := subnet.IP.To4()
+	if base == nil || len(subnet.Mask) == net.IPv6len {
+		base = subnet.IP.To16()
+	}
+
+	prefix := binary.BigEndian.Uint32(base[len(base)-4:])
+	mask := binary.BigEndian.Uint32(subnet.Mask[len(subnet.Mask)-4:])
 
 	randStart := rand32() & ^mask
-	for offset := uint32(0); offset <= ^mask; offset++ {
+	for offset := uint64(0); offset <= uint64(^mask); offset++ {
 
-		randIP32 := ((randStart + offset) & ^mask) | (prefix & mask)
+		randIP32 := ((randStart + uint32(offset)) & ^mask) | (prefix & mask)
 
-		randIP := net.IP(make([]byte, 4))
-		binary.BigEndian.PutUint32(randIP, randIP32)
+		randIP := net.IP(make([]byte, len(base)))
+		copy(randIP, base)
+		binary.BigEndian.PutUint32(randIP[len(randIP)-4:], randIP32)
 		randIPStr := randIP.String()
 
 		if _, ok := ipSet[randIPStr]; !ok {
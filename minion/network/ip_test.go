@@ -2,6 +2,7 @@ package network
 
 import (
 	"fmt"
+	"math/rand"
 	"net"
 	"sort"
 	"testing"
@@ -43,6 +44,39 @@ func TestAllocateContainerIPs(t *testing.T) {
 	assert.True(t, ipdef.QuiltSubnet.Contains(net.ParseIP(dbc.IP)))
 }
 
+func TestAllocateContainerIPv6s(t *testing.T) {
+	require.NoError(t, ipdef.SetSubnets("", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertContainer()
+		dbc.IP = "10.0.0.2"
+		dbc.IPv6 = "fd00::2"
+		dbc.StitchID = "1"
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.IP = "10.0.0.3"
+		dbc.StitchID = "2"
+		view.Commit(dbc)
+
+		assert.NoError(t, allocateContainerIPs(view))
+		return nil
+	})
+
+	dbcs := conn.SelectFromContainer(nil)
+	assert.Len(t, dbcs, 2)
+
+	sort.Sort(db.ContainerSlice(dbcs))
+	assert.Equal(t, "10.0.0.2", dbcs[0].IP)
+	assert.Equal(t, "fd00::2", dbcs[0].IPv6)
+
+	assert.Equal(t, "10.0.0.3", dbcs[1].IP)
+	assert.NotEqual(t, "fd00::2", dbcs[1].IPv6)
+	assert.True(t, ipdef.QuiltSubnet6.Contains(net.ParseIP(dbcs[1].IPv6)))
+}
+
 func TestUpdateLabelIPs(t *testing.T) {
 	conn := db.New()
 
@@ -91,6 +125,61 @@ func TestUpdateLabelIPs(t *testing.T) {
 	}, labels)
 }
 
+func TestUpdateLabelIPsSpecOrder(t *testing.T) {
+	conn := db.New()
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.Spec = `{"Labels": [{"Name": "red", "IDs": ["3", "1"]}]}`
+		view.Commit(self)
+
+		for _, id := range []string{"1", "2", "3"} {
+			dbc := view.InsertContainer()
+			dbc.Labels = []string{"red"}
+			dbc.StitchID = id
+			dbc.IP = fmt.Sprintf("%s.%s.%s.%s", id, id, id, id)
+			view.Commit(dbc)
+		}
+
+		assert.NoError(t, updateLabelIPs(view))
+		return nil
+	})
+
+	// Containers are ordered as in the spec, followed by containers the spec
+	// doesn't know about.
+	labels := conn.SelectFromLabel(nil)
+	assert.Len(t, labels, 1)
+	assert.Equal(t, "3.3.3.3", labels[0].IP)
+	assert.Equal(t, []string{"3.3.3.3", "1.1.1.1", "2.2.2.2"},
+		labels[0].ContainerIPs)
+}
+
+func TestUpdateLabelIPv6s(t *testing.T) {
+	require.NoError(t, ipdef.SetSubnets("", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, id := range []string{"1", "2"} {
+			dbc := view.InsertContainer()
+			dbc.Labels = []string{"red"}
+			dbc.StitchID = id
+			dbc.IP = fmt.Sprintf("10.0.0.%s", id)
+			dbc.IPv6 = fmt.Sprintf("fd00::%s", id)
+			view.Commit(dbc)
+		}
+
+		assert.NoError(t, updateLabelIPs(view))
+		return nil
+	})
+
+	labels := conn.SelectFromLabel(nil)
+	assert.Len(t, labels, 1)
+	assert.Equal(t, "fd00::1", labels[0].IPv6)
+	assert.Equal(t, []string{"fd00::1", "fd00::2"}, labels[0].ContainerIPv6s)
+}
+
 func TestAllocate(t *testing.T) {
 	subnet := net.IPNet{
 		IP:   net.IPv4(0xab, 0xcd, 0xe0, 0x00),
@@ -122,3 +211,24 @@ func TestAllocate(t *testing.T) {
 		t.Errorf("Too few conflicts: %d", len(conflicts))
 	}
 }
+
+func TestAllocateIPv6(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("fd00:1:2::/112")
+	ipSet := map[string]struct{}{}
+
+	for i := 0; i < 1000; i++ {
+		ip, err := allocateIP(ipSet, *subnet)
+		require.NoError(t, err)
+		require.True(t, subnet.Contains(net.ParseIP(ip)),
+			fmt.Sprintf("\"%s\" is not in %s", ip, subnet))
+	}
+	assert.Len(t, ipSet, 1000)
+
+	_, subnet, _ = net.ParseCIDR("fd00::/64")
+	rand32 = func() uint32 { return 0xdeadbeef }
+	defer func() { rand32 = rand.Uint32 }()
+
+	ip, err := allocateIP(map[string]struct{}{}, *subnet)
+	assert.NoError(t, err)
+	assert.Equal(t, "fd00::dead:beef", ip)
+}
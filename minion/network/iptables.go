@@ -0,0 +1,148 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// iptablesBackend manages the NAT rules with iptables.  It's responsible for every
+// rule in the nat table, so rules it doesn't recognize are deleted.
+type iptablesBackend struct{}
+
+func (iptablesBackend) rules() ([]natRule, error) {
+	stdout, _, err := shVerbose("iptables -t nat -S")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP tables: %s", err)
+	}
+
+	var rules []natRule
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Chain policies aren't rules, and can't be deleted.
+		if line == "" || strings.HasPrefix(line, "-P ") {
+			continue
+		}
+		rules = append(rules, parseIPTablesRule(line))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner error while getting IP tables: %s", err)
+	}
+	return rules, nil
+}
+
+func (iptablesBackend) add(rule natRule) error {
+	cmd := fmt.Sprintf("iptables -t nat %s", iptablesArgs(rule))
+	if _, _, err := shVerbose("%s", cmd); err != nil {
+		return fmt.Errorf("failed to add NAT rule %s: %s", cmd, err)
+	}
+	return nil
+}
+
+func (iptablesBackend) delete(rule natRule) error {
+	// The handle of an iptables rule is the command that created it.
+	fields := strings.Fields(rule.handle)
+	if len(fields) < 2 {
+		return fmt.Errorf("malformed iptables rule: %s", rule.handle)
+	}
+
+	switch fields[0] {
+	case "-A":
+		fields[0] = "-D"
+	case "-N":
+		// Delete new chains.
+		fields = []string{"-X", fields[1]}
+	default:
+		return fmt.Errorf("unknown iptables command: %s", rule.handle)
+	}
+
+	cmd := "iptables -t nat " + strings.Join(fields, " ")
+	if _, _, err := shVerbose("%s", cmd); err != nil {
+		return fmt.Errorf("failed to delete NAT rule %s: %s", cmd, err)
+	}
+	return nil
+}
+
+// iptablesArgs returns the arguments to iptables that append `rule`, in the form
+// `iptables -S` prints them.
+func iptablesArgs(rule natRule) string {
+	switch rule.action {
+	case masquerade:
+		return fmt.Sprintf("-A %s -s %s -o %s -j MASQUERADE",
+			rule.chain, rule.srcNet, rule.iface)
+	case dnat:
+		return fmt.Sprintf("-A %[1]s -i %[2]s -p %[3]s -m %[3]s --dport %[4]d "+
+			"-j DNAT --to-destination %[5]s:%[4]d", rule.chain, rule.iface,
+			rule.protocol, rule.port, rule.destIP)
+	default:
+		return rule.handle
+	}
+}
+
+// parseIPTablesRule converts a rule as printed by `iptables -S` into a natRule.
+// Rules that Quilt wouldn't have installed are left unrecognized.
+func parseIPTablesRule(line string) natRule {
+	fields := strings.Fields(line)
+	unknown := natRule{handle: line}
+	if len(fields) < 2 {
+		return unknown
+	}
+	unknown.chain = fields[1]
+
+	if fields[0] != "-A" || len(fields)%2 != 0 {
+		return unknown
+	}
+
+	opts := map[string]string{}
+	for i := 2; i < len(fields); i += 2 {
+		if _, ok := opts[fields[i]]; ok {
+			return unknown
+		}
+		opts[fields[i]] = fields[i+1]
+	}
+
+	rule := natRule{chain: fields[1], handle: line}
+	switch opts["-j"] {
+	case masquerade:
+		rule.action = masquerade
+		rule.srcNet = opts["-s"]
+		rule.iface = opts["-o"]
+		delete(opts, "-s")
+		delete(opts, "-o")
+	case dnat:
+		rule.action = dnat
+		rule.iface = opts["-i"]
+		rule.protocol = opts["-p"]
+
+		port, err := strconv.Atoi(opts["--dport"])
+		if err != nil || opts["-m"] != rule.protocol {
+			return unknown
+		}
+		rule.port = port
+
+		dest := strings.Split(opts["--to-destination"], ":")
+		if len(dest) != 2 || dest[1] != opts["--dport"] {
+			return unknown
+		}
+		rule.destIP = dest[0]
+
+		for _, opt := range []string{"-i", "-p", "-m", "--dport",
+			"--to-destination"} {
+			delete(opts, opt)
+		}
+	default:
+		return unknown
+	}
+	delete(opts, "-j")
+
+	// Any other options would change the meaning of the rule.
+	if len(opts) != 0 {
+		return unknown
+	}
+	return rule
+}
@@ -0,0 +1,98 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIPTablesRule(t *testing.T) {
+	check := func(line string, exp natRule) {
+		exp.handle = line
+		assert.Equal(t, exp, parseIPTablesRule(line))
+	}
+
+	check("-A POSTROUTING -s 10.0.0.0/8 -o eth0 -j MASQUERADE",
+		natRule{chain: postRouting, action: masquerade, iface: "eth0",
+			srcNet: "10.0.0.0/8"})
+	check("-A PREROUTING -i eth0 -p tcp -m tcp --dport 80 -j DNAT "+
+		"--to-destination 10.31.0.23:80",
+		natRule{chain: preRouting, action: dnat, iface: "eth0",
+			protocol: "tcp", port: 80, destIP: "10.31.0.23"})
+
+	// Rules Quilt doesn't install.
+	check("-N DOCKER", natRule{chain: "DOCKER"})
+	check("-A POSTROUTING -s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE",
+		natRule{chain: postRouting})
+	check("-A POSTROUTING -s 10.0.0.0/8 -d 10.0.0.0/8 -o eth0 -j MASQUERADE",
+		natRule{chain: postRouting})
+	check("-A PREROUTING -i eth0 -p tcp -m tcp --dport 80 -j DNAT "+
+		"--to-destination 10.31.0.23:8080", natRule{chain: preRouting})
+	check("-A PREROUTING -i eth0 -p tcp --dport 80 -j DNAT "+
+		"--to-destination 10.31.0.23:80", natRule{chain: preRouting})
+	check("-A DOCKER -i docker0 -j RETURN", natRule{chain: "DOCKER"})
+	check("-X", natRule{})
+}
+
+func TestIPTablesArgs(t *testing.T) {
+	for _, line := range []string{
+		"-A POSTROUTING -s 10.0.0.0/8 -o eth0 -j MASQUERADE",
+		"-A PREROUTING -i eth0 -p udp -m udp --dport 53 -j DNAT " +
+			"--to-destination 10.0.0.2:53",
+		"-N DOCKER",
+	} {
+		rule := parseIPTablesRule(line)
+		assert.Equal(t, line, iptablesArgs(rule))
+	}
+}
+
+func TestIPTablesBackend(t *testing.T) {
+	oldShVerbose := shVerbose
+	defer func() { shVerbose = oldShVerbose }()
+
+	var cmds []string
+	var listErr error
+	shVerbose = func(format string, args ...interface{}) (
+		stdout, stderr []byte, err error) {
+		cmd := fmt.Sprintf(format, args...)
+		if cmd == "iptables -t nat -S" {
+			return []byte(rules()), nil, listErr
+		}
+
+		cmds = append(cmds, cmd)
+		return nil, nil, nil
+	}
+
+	backend := iptablesBackend{}
+	actual, err := backend.rules()
+	assert.NoError(t, err)
+	assert.Equal(t, []natRule{
+		{chain: "DOCKER", handle: "-N DOCKER"},
+		{chain: postRouting, handle: "-A POSTROUTING " +
+			"-s 11.0.0.0/8,10.0.0.0/8 -o eth0 -j MASQUERADE",
+			action: masquerade, iface: "eth0", srcNet: "11.0.0.0/8,10.0.0.0/8"},
+		{chain: postRouting, handle: "-A POSTROUTING " +
+			"-s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE"},
+	}, actual)
+
+	for _, rule := range actual {
+		assert.NoError(t, backend.delete(rule))
+	}
+	assert.NoError(t, backend.add(natRule{chain: preRouting, action: dnat,
+		iface: "eth0", protocol: "tcp", port: 80, destIP: "10.0.0.2"}))
+	assert.Equal(t, []string{
+		"iptables -t nat -X DOCKER",
+		"iptables -t nat -D POSTROUTING -s 11.0.0.0/8,10.0.0.0/8 -o eth0 " +
+			"-j MASQUERADE",
+		"iptables -t nat -D POSTROUTING -s 10.0.3.0/24 ! -d 10.0.3.0/24 " +
+			"-j MASQUERADE",
+		"iptables -t nat -A PREROUTING -i eth0 -p tcp -m tcp --dport 80 " +
+			"-j DNAT --to-destination 10.0.0.2:80",
+	}, cmds)
+
+	listErr = errors.New("err")
+	_, err = backend.rules()
+	assert.Error(t, err)
+}
@@ -1,32 +1,75 @@
 package network
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"os/exec"
-	"regexp"
 	"strings"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/stitch"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/vishvananda/netlink"
 )
 
-// This represents a rule in the iptables
-type ipRule struct {
-	cmd   string
-	chain string
-	opts  string // Must be sorted - see makeIPRule
+// The chains of the NAT rules.
+const (
+	preRouting  = "PREROUTING"
+	postRouting = "POSTROUTING"
+)
+
+// The actions of the NAT rules.
+const (
+	masquerade = "MASQUERADE"
+	dnat       = "DNAT"
+)
+
+// A natRule is a NAT rule in a form independent of the firewall it's installed in.
+// Quilt installs two kinds of rules: masquerade rules for traffic leaving the
+// container subnet through `iface`, and DNAT rules that forward `port` on `iface` to
+// the same port of `destIP`.
+type natRule struct {
+	chain  string
+	action string // Empty if the rule isn't one Quilt installs.
+
+	iface    string
+	srcNet   string // Masquerade only.
+	protocol string // DNAT only.
+	port     int    // DNAT only.
+	destIP   string // DNAT only.
+
+	// handle identifies an installed rule to the backend that listed it.
+	handle string
+}
+
+type natRuleSlice []natRule
+
+// A natBackend installs NAT rules in the kernel.
+type natBackend interface {
+	// rules lists the installed NAT rules, including those Quilt doesn't
+	// recognize.
+	rules() ([]natRule, error)
+
+	// add installs `rule`.
+	add(rule natRule) error
+
+	// delete removes `rule`, which must have been listed by rules().
+	delete(rule natRule) error
 }
 
-type ipRuleSlice []ipRule
+var newNATBackend = func(name string) natBackend {
+	if name == stitch.NFTablesBackend {
+		return nftablesBackend{}
+	}
+	return iptablesBackend{}
+}
 
 func runNat(conn db.Conn) {
+	var backendName string
 	tables := []db.TableType{db.ContainerTable, db.ConnectionTable, db.MinionTable}
 	for range conn.TriggerTick(30, tables...).C {
 		minion, err := conn.MinionSelf()
@@ -34,79 +77,86 @@ func runNat(conn db.Conn) {
 			continue
 		}
 
+		// Without a valid spec, the previous backend is kept.
+		name := backendName
+		if spec, err := stitch.FromJSON(minion.Spec); err == nil {
+			name = spec.FirewallBackend
+		}
+		if name == "" {
+			name = stitch.IPTablesBackend
+		}
+
+		// Rules installed by the previous backend are removed when the
+		// deployment switches to another.
+		if backendName != "" && backendName != name {
+			syncNAT(newNATBackend(backendName), nil)
+		}
+		backendName = name
+
 		containers := conn.SelectFromContainer(func(c db.Container) bool {
 			return c.IP != ""
 		})
-		updateNAT(containers, conn.SelectFromConnection(nil))
+		updateNAT(newNATBackend(backendName), containers,
+			conn.SelectFromConnection(nil))
 	}
 }
 
-func updateNAT(containers []db.Container, connections []db.Connection) {
+func updateNAT(backend natBackend, containers []db.Container,
+	connections []db.Connection) {
+
 	publicInterface, err := getPublicInterface()
 	if err != nil {
 		log.WithError(err).Error("Failed to get public interface")
 		return
 	}
 
-	targetRules := generateTargetNatRules(publicInterface, containers, connections)
-	currRules, err := generateCurrentNatRules()
+	syncNAT(backend, generateTargetNatRules(publicInterface, containers,
+		connections))
+}
+
+// syncNAT makes the rules installed by `backend` match `targetRules`.
+func syncNAT(backend natBackend, targetRules natRuleSlice) {
+	currRules, err := backend.rules()
 	if err != nil {
 		log.WithError(err).Error("failed to get NAT rules")
 		return
 	}
 
-	_, rulesToDel, rulesToAdd := join.HashJoin(currRules, targetRules, nil, nil)
+	// Installed rules are compared to the target rules by their contents, except
+	// for unrecognized rules, which are all distinct so that they're all removed.
+	key := func(val interface{}) interface{} {
+		rule := val.(natRule)
+		if rule.action != "" {
+			rule.handle = ""
+		}
+		return rule
+	}
+	_, rulesToDel, rulesToAdd := join.HashJoin(natRuleSlice(currRules),
+		targetRules, key, key)
 
 	for _, rule := range rulesToDel {
-		if err := deleteNatRule(rule.(ipRule)); err != nil {
-			log.WithError(err).Error("failed to delete ip rule")
+		if err := backend.delete(rule.(natRule)); err != nil {
+			log.WithError(err).Error("failed to delete NAT rule")
 			continue
 		}
 	}
 
 	for _, rule := range rulesToAdd {
-		if err := addNatRule(rule.(ipRule)); err != nil {
-			log.WithError(err).Error("failed to add ip rule")
+		if err := backend.add(rule.(natRule)); err != nil {
+			log.WithError(err).Error("failed to add NAT rule")
 			continue
 		}
 	}
 }
 
-func generateCurrentNatRules() (ipRuleSlice, error) {
-	stdout, _, err := shVerbose("iptables -t nat -S")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get IP tables: %s", err)
-	}
-
-	scanner := bufio.NewScanner(bytes.NewReader(stdout))
-	var rules ipRuleSlice
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		rule, err := makeIPRule(line)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get current IP rules: %s", err)
-		}
-		rules = append(rules, rule)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanner error while getting IP tables: %s", err)
-	}
-	return rules, nil
-}
-
 func generateTargetNatRules(publicInterface string, containers []db.Container,
-	connections []db.Connection) ipRuleSlice {
-	strRules := []string{
-		"-P PREROUTING ACCEPT",
-		"-P INPUT ACCEPT",
-		"-P OUTPUT ACCEPT",
-		"-P POSTROUTING ACCEPT",
-		fmt.Sprintf("-A POSTROUTING -s 10.0.0.0/8 -o %s -j MASQUERADE",
-			publicInterface),
-	}
+	connections []db.Connection) natRuleSlice {
+	rules := natRuleSlice{{
+		chain:  postRouting,
+		action: masquerade,
+		iface:  publicInterface,
+		srcNet: ipdef.QuiltSubnet.String(),
+	}}
 
 	protocols := []string{"tcp", "udp"}
 	// Map each container IP to all ports on which it can receive packets
@@ -139,23 +189,17 @@ func generateTargetNatRules(publicInterface string, containers []db.Container,
 	for ip, ports := range portsFromWeb {
 		for port := range ports {
 			for _, protocol := range protocols {
-				strRules = append(strRules, fmt.Sprintf(
-					"-A PREROUTING -i %[1]s "+
-						"-p %[2]s -m %[2]s --dport %[3]d -j "+
-						"DNAT --to-destination %[4]s:%[3]d",
-					publicInterface, protocol, port, ip))
+				rules = append(rules, natRule{
+					chain:    preRouting,
+					action:   dnat,
+					iface:    publicInterface,
+					protocol: protocol,
+					port:     port,
+					destIP:   ip,
+				})
 			}
 		}
 	}
-
-	var rules ipRuleSlice
-	for _, r := range strRules {
-		rule, err := makeIPRule(r)
-		if err != nil {
-			panic("malformed target NAT rule")
-		}
-		rules = append(rules, rule)
-	}
 	return rules
 }
 
@@ -180,70 +224,28 @@ var shVerbose = func(format string, args ...interface{}) (
 	return outBuf.Bytes(), errBuf.Bytes(), nil
 }
 
-// makeIPRule takes an ip rule as formatted in the output of `iptables -S`,
-// and returns the corresponding ipRule. The output options will be in the same
-// order as output by `iptables -S`.
-func makeIPRule(inputRule string) (ipRule, error) {
-	cmdRE := regexp.MustCompile("(-[A-Z]+)\\s+([A-Z]+)")
-	cmdMatch := cmdRE.FindSubmatch([]byte(inputRule))
-	if len(cmdMatch) < 3 {
-		return ipRule{}, fmt.Errorf("missing iptables command")
-	}
-
-	var opts string
-	optsRE := regexp.MustCompile("-(?:[A-Z]+\\s+)+[A-Z]+\\s+(.*)")
-	optsMatch := optsRE.FindSubmatch([]byte(inputRule))
-
-	if len(optsMatch) > 2 {
-		return ipRule{}, fmt.Errorf("malformed iptables options")
-	}
-
-	if len(optsMatch) == 2 {
-		opts = strings.TrimSpace(string(optsMatch[1]))
-	}
-
-	rule := ipRule{
-		cmd:   strings.TrimSpace(string(cmdMatch[1])),
-		chain: strings.TrimSpace(string(cmdMatch[2])),
-		opts:  opts,
-	}
-	return rule, nil
-}
-
-func deleteNatRule(rule ipRule) error {
-	var command string
-	args := fmt.Sprintf("%s %s", rule.chain, rule.opts)
-	if rule.cmd == "-A" {
-		command = fmt.Sprintf("iptables -t nat -D %s", args)
-	} else if rule.cmd == "-N" {
-		// Delete new chains.
-		command = fmt.Sprintf("iptables -t nat -X %s", rule.chain)
-	}
-
-	stdout, _, err := shVerbose(command)
+// getPublicInterface gets the interface with the default route.
+func getPublicInterface() (string, error) {
+	link, err := getPublicLink()
 	if err != nil {
-		return fmt.Errorf("failed to delete NAT rule %s: %s", command,
-			string(stdout))
+		return "", err
 	}
-	return nil
+	return link.Attrs().Name, nil
 }
 
-func addNatRule(rule ipRule) error {
-	args := fmt.Sprintf("%s %s", rule.chain, rule.opts)
-	cmd := fmt.Sprintf("iptables -t nat -A %s", args)
-	_, _, err := shVerbose(cmd)
-
+// PublicMTU returns the MTU of the interface with the default route.
+func PublicMTU() (int, error) {
+	link, err := getPublicLink()
 	if err != nil {
-		return fmt.Errorf("failed to add NAT rule %s: %s", cmd, err)
+		return 0, err
 	}
-	return nil
+	return link.Attrs().MTU, nil
 }
 
-// getPublicInterface gets the interface with the default route.
-func getPublicInterface() (string, error) {
+func getPublicLink() (netlink.Link, error) {
 	routes, err := routeList(nil, 0)
 	if err != nil {
-		return "", fmt.Errorf("route list: %s", err)
+		return nil, fmt.Errorf("route list: %s", err)
 	}
 
 	var defaultRoute *netlink.Route
@@ -255,23 +257,22 @@ func getPublicInterface() (string, error) {
 	}
 
 	if defaultRoute == nil {
-		return "", errors.New("missing default route")
+		return nil, errors.New("missing default route")
 	}
 
 	link, err := linkByIndex(defaultRoute.LinkIndex)
 	if err != nil {
-		return "", fmt.Errorf("default route missing interface: %s", err)
+		return nil, fmt.Errorf("default route missing interface: %s", err)
 	}
-
-	return link.Attrs().Name, err
+	return link, nil
 }
 
-func (iprs ipRuleSlice) Get(ii int) interface{} {
-	return iprs[ii]
+func (rules natRuleSlice) Get(ii int) interface{} {
+	return rules[ii]
 }
 
-func (iprs ipRuleSlice) Len() int {
-	return len(iprs)
+func (rules natRuleSlice) Len() int {
+	return len(rules)
 }
 
 var routeList = netlink.RouteList
@@ -1,113 +1,97 @@
 package network
 
 import (
-	"reflect"
+	"errors"
+	"fmt"
+	"net"
 	"testing"
 
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/stitch"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
 )
 
-func TestMakeIPRule(t *testing.T) {
-	inp := "-A INPUT -p tcp -i eth0 -m multiport --dports 465,110,995 -j ACCEPT"
-	rule, _ := makeIPRule(inp)
-	expCmd := "-A"
-	expChain := "INPUT"
-	expOpts := "-p tcp -i eth0 -m multiport --dports 465,110,995 -j ACCEPT"
-
-	if rule.cmd != expCmd {
-		t.Errorf("Bad ipRule command.\nExpected:\n%s\n\nGot:\n%s\n",
-			expCmd, rule.cmd)
-	}
-
-	if rule.chain != expChain {
-		t.Errorf("Bad ipRule chain.\nExpected:\n%s\n\nGot:\n%s\n",
-			expChain, rule.chain)
-	}
-
-	if rule.opts != expOpts {
-		t.Errorf("Bad ipRule options.\nExpected:\n%s\n\nGot:\n%s\n",
-			expOpts, rule.opts)
-	}
-
-	inp = "-A POSTROUTING -s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE"
-	rule, _ = makeIPRule(inp)
-	expCmd = "-A"
-	expChain = "POSTROUTING"
-	expOpts = "-s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE"
+type fakeNATBackend struct {
+	installed map[string]natRule
+	next      int
+}
 
-	if rule.cmd != expCmd {
-		t.Errorf("Bad ipRule command.\nExpected:\n%s\n\nGot:\n%s\n",
-			expCmd, rule.cmd)
+func (f *fakeNATBackend) rules() ([]natRule, error) {
+	var rules []natRule
+	for _, rule := range f.installed {
+		rules = append(rules, rule)
 	}
+	return rules, nil
+}
 
-	if rule.chain != expChain {
-		t.Errorf("Bad ipRule chain.\nExpected:\n%s\n\nGot:\n%s\n",
-			expChain, rule.chain)
-	}
+func (f *fakeNATBackend) add(rule natRule) error {
+	f.next++
+	rule.handle = fmt.Sprintf("%d", f.next)
+	f.installed[rule.handle] = rule
+	return nil
+}
 
-	if rule.opts != expOpts {
-		t.Errorf("Bad ipRule options.\nExpected:\n%s\n\nGot:\n%s\n",
-			expOpts, rule.opts)
-	}
+func (f *fakeNATBackend) delete(rule natRule) error {
+	delete(f.installed, rule.handle)
+	return nil
+}
 
-	inp = "-A PREROUTING -i eth0 -p tcp --dport 80 -j DNAT " +
-		"--to-destination 10.31.0.23:80"
-	rule, _ = makeIPRule(inp)
-	expCmd = "-A"
-	expChain = "PREROUTING"
-	expOpts = "-i eth0 -p tcp --dport 80 -j DNAT --to-destination 10.31.0.23:80"
+func TestSyncNAT(t *testing.T) {
+	masq := natRule{chain: postRouting, action: masquerade, iface: "eth0",
+		srcNet: "10.0.0.0/8"}
+	web := natRule{chain: preRouting, action: dnat, iface: "eth0",
+		protocol: "tcp", port: 80, destIP: "10.0.0.2"}
+
+	backend := &fakeNATBackend{installed: map[string]natRule{
+		"a": {chain: "DOCKER", handle: "a"},
+		"b": {chain: postRouting, handle: "b"},
+		"c": {chain: preRouting, action: dnat, iface: "eth0", protocol: "tcp",
+			port: 22, destIP: "10.0.0.3", handle: "c"},
+	}}
+	installedMasq := masq
+	installedMasq.handle = "d"
+	backend.installed["d"] = installedMasq
+
+	syncNAT(backend, natRuleSlice{masq, web})
+
+	rules, _ := backend.rules()
+	assert.Len(t, rules, 2)
+
+	// The existing masquerade rule is kept, and unrecognized rules are removed.
+	assert.Equal(t, "d", backend.installed["d"].handle)
+	web.handle = "1"
+	assert.Equal(t, web, backend.installed["1"])
+
+	syncNAT(backend, nil)
+	assert.Empty(t, backend.installed)
+}
 
-	if rule.cmd != expCmd {
-		t.Errorf("Bad ipRule command.\nExpected:\n%s\n\nGot:\n%s\n",
-			expCmd, rule.cmd)
+func TestGenerateTargetNatRules(t *testing.T) {
+	containers := []db.Container{
+		{IP: "10.0.0.2", Labels: []string{"web"}},
+		{IP: "10.0.0.3", Labels: []string{"db"}},
 	}
-
-	if rule.chain != expChain {
-		t.Errorf("Bad ipRule chain.\nExpected:\n%s\n\nGot:\n%s\n",
-			expChain, rule.chain)
+	connections := []db.Connection{
+		{From: "public", To: "web", MinPort: 80, MaxPort: 80},
+		{From: "web", To: "db", MinPort: 5432, MaxPort: 5432},
 	}
 
-	if rule.opts != expOpts {
-		t.Errorf("Bad ipRule options.\nExpected:\n%s\n\nGot:\n%s\n",
-			expOpts, rule.opts)
-	}
+	rules := generateTargetNatRules("eth0", containers, connections)
+	assert.Len(t, rules, 3)
+	assert.Contains(t, rules, natRule{chain: postRouting, action: masquerade,
+		iface: "eth0", srcNet: ipdef.QuiltSubnet.String()})
+	assert.Contains(t, rules, natRule{chain: preRouting, action: dnat,
+		iface: "eth0", protocol: "tcp", port: 80, destIP: "10.0.0.2"})
+	assert.Contains(t, rules, natRule{chain: preRouting, action: dnat,
+		iface: "eth0", protocol: "udp", port: 80, destIP: "10.0.0.2"})
 }
 
-func TestGenerateCurrentNatRules(t *testing.T) {
-	oldShVerbose := shVerbose
-	defer func() { shVerbose = oldShVerbose }()
-	shVerbose = func(format string, args ...interface{}) (
-		stdout, stderr []byte, err error) {
-		return []byte(rules()), nil, nil
-	}
-
-	actual, _ := generateCurrentNatRules()
-	exp := ipRuleSlice{
-		{
-			cmd:   "-P",
-			chain: "POSTROUTING",
-			opts:  "ACCEPT",
-		},
-		{
-			cmd:   "-N",
-			chain: "DOCKER",
-		},
-		{
-			cmd:   "-A",
-			chain: "POSTROUTING",
-			opts:  "-s 11.0.0.0/8,10.0.0.0/8 -o eth0 -j MASQUERADE",
-		},
-		{
-			cmd:   "-A",
-			chain: "POSTROUTING",
-			opts:  "-s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE",
-		},
-	}
-
-	if !(reflect.DeepEqual(actual, exp)) {
-		t.Errorf("Generated wrong routes.\nExpected:\n%+v\n\nGot:\n%+v\n",
-			exp, actual)
-	}
+func TestNewNATBackend(t *testing.T) {
+	assert.Equal(t, iptablesBackend{}, newNATBackend(""))
+	assert.Equal(t, iptablesBackend{}, newNATBackend(stitch.IPTablesBackend))
+	assert.Equal(t, nftablesBackend{}, newNATBackend(stitch.NFTablesBackend))
 }
 
 func defaultLabelsConnections() (map[string]db.Label, map[string][]string) {
@@ -158,3 +142,36 @@ func rules() string {
 -A POSTROUTING -s 11.0.0.0/8,10.0.0.0/8 -o eth0 -j MASQUERADE
 -A POSTROUTING -s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE`
 }
+
+func TestPublicMTU(t *testing.T) {
+	routeList = func(netlink.Link, int) ([]netlink.Route, error) {
+		return nil, errors.New("err")
+	}
+	_, err := PublicMTU()
+	assert.EqualError(t, err, "route list: err")
+
+	_, dst, _ := net.ParseCIDR("10.0.0.0/8")
+	routeList = func(netlink.Link, int) ([]netlink.Route, error) {
+		return []netlink.Route{{Dst: dst, LinkIndex: 1}}, nil
+	}
+	_, err = PublicMTU()
+	assert.EqualError(t, err, "missing default route")
+
+	routeList = func(netlink.Link, int) ([]netlink.Route, error) {
+		return []netlink.Route{{Dst: dst, LinkIndex: 1}, {LinkIndex: 2}}, nil
+	}
+	linkByIndex = func(index int) (netlink.Link, error) {
+		if index != 2 {
+			return nil, errors.New("no such link")
+		}
+		return &netlink.Device{LinkAttrs: netlink.LinkAttrs{
+			Name: "eth0", MTU: 9001}}, nil
+	}
+	mtu, err := PublicMTU()
+	assert.NoError(t, err)
+	assert.Equal(t, 9001, mtu)
+
+	iface, err := getPublicInterface()
+	assert.NoError(t, err)
+	assert.Equal(t, "eth0", iface)
+}
@@ -6,8 +6,12 @@
 package network
 
 import (
+	"reflect"
+	"time"
+
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/metrics"
 	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
 
@@ -17,11 +21,16 @@ import (
 const labelMac = "0a:00:00:00:00:00"
 const lSwitch = "quilt"
 
+var ovnDuration = metrics.NewHistogram("quilt_ovn_reconcile_duration_seconds",
+	"How long it takes the leader to reconcile the OVN logical network with the "+
+		"containers and labels.", metrics.DefBuckets)
+
 // Run blocks implementing the network services.
 func Run(conn db.Conn) {
 	go runNat(conn)
 	go runDNS(conn)
 	go runUpdateIPs(conn)
+	go runTraffic(conn)
 
 	for range conn.TriggerTick(30, db.MinionTable, db.ContainerTable,
 		db.ConnectionTable, db.LabelTable, db.EtcdTable).C {
@@ -60,6 +69,7 @@ func runMaster(conn db.Conn) {
 	if !init {
 		return
 	}
+	defer ovnDuration.ObserveSince(time.Now())
 
 	ovsdbClient, err := ovsdb.Open()
 	if err != nil {
@@ -82,13 +92,32 @@ func runMaster(conn db.Conn) {
 		return val.(ovsdb.LPort).Name
 	}
 
-	_, ovsps, dbcs := join.HashJoin(ovsdb.LPortSlice(lports),
+	pairs, ovsps, dbcs := join.HashJoin(ovsdb.LPortSlice(lports),
 		db.ContainerSlice(containers), portKey, dbcKey)
 
+	// Ports created before the cluster was dual stack are missing their IPv6
+	// addresses, which are added once the containers are assigned them.
+	for _, pair := range pairs {
+		lport := pair.L.(ovsdb.LPort)
+		dbc := pair.R.(db.Container)
+		mac, ips := ipdef.IPStrToMac(dbc.IP), lportIPs(dbc)
+		if reflect.DeepEqual(lport.Addresses, ovsdb.LPortAddresses(mac, ips)) {
+			continue
+		}
+
+		err := ovsdbClient.SetLogicalPortAddresses(lport, mac, ips)
+		if err != nil {
+			log.WithError(err).Warnf("Failed to update logical port: %s",
+				dbc.IP)
+		} else {
+			log.Infof("Updated logical port: %s", dbc.IP)
+		}
+	}
+
 	for _, dbcIface := range dbcs {
 		dbc := dbcIface.(db.Container)
 		err := ovsdbClient.CreateLogicalPort(lSwitch, dbc.IP,
-			ipdef.IPStrToMac(dbc.IP), dbc.IP)
+			ipdef.IPStrToMac(dbc.IP), lportIPs(dbc))
 		if err != nil {
 			log.WithError(err).Warnf("Failed to create logical port: %s",
 				dbc.IP)
@@ -110,6 +139,15 @@ func runMaster(conn db.Conn) {
 	updateACLs(ovsdbClient, connections, labels)
 }
 
+// lportIPs returns the addresses of the logical port of `dbc`.
+func lportIPs(dbc db.Container) []string {
+	ips := []string{dbc.IP}
+	if dbc.IPv6 != "" {
+		ips = append(ips, dbc.IPv6)
+	}
+	return ips
+}
+
 func checkSupervisorInit(view db.Database) bool {
 	self, err := view.MinionSelf()
 	return err == nil && self.SupervisorInit
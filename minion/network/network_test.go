@@ -7,6 +7,7 @@ import (
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/ovsdb"
 	"github.com/quilt/quilt/minion/ovsdb/mocks"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -28,7 +29,9 @@ func TestRunMaster(t *testing.T) {
 	conn := db.New()
 
 	// Supervisor isn't initialized, nothing should happen.
+	reconciles := ovnDuration.Count()
 	runMaster(conn)
+	assert.Equal(t, reconciles, ovnDuration.Count())
 
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		etcd := view.InsertEtcd()
@@ -46,6 +49,7 @@ func TestRunMaster(t *testing.T) {
 
 		c := view.InsertContainer()
 		c.IP = "1.2.3.4"
+		c.IPv6 = "fd00::1"
 		view.Commit(c)
 		return nil
 	})
@@ -55,6 +59,7 @@ func TestRunMaster(t *testing.T) {
 	client := new(mocks.Client)
 	ovsdb.Open = func() (ovsdb.Client, error) { return nil, anErr }
 	runMaster(conn)
+	assert.Equal(t, reconciles+1, ovnDuration.Count())
 
 	ovsdb.Open = func() (ovsdb.Client, error) {
 		return client, nil
@@ -74,7 +79,7 @@ func TestRunMaster(t *testing.T) {
 	client.On("DeleteLogicalPort", lSwitch, ovsdb.LPort{
 		Name: "1.2.3.5", Addresses: nil}).Return(anErr).Once()
 	client.On("CreateLogicalPort", lSwitch, "1.2.3.4",
-		"02:00:01:02:03:04", "1.2.3.4").Return(anErr).Once()
+		"02:00:01:02:03:04", []string{"1.2.3.4", "fd00::1"}).Return(anErr).Once()
 	runMaster(conn)
 	client.AssertCalled(t, "Disconnect")
 	client.AssertCalled(t, "ListLogicalPorts")
@@ -87,7 +92,7 @@ func TestRunMaster(t *testing.T) {
 	client.On("DeleteLogicalPort", lSwitch, ovsdb.LPort{
 		Name: "1.2.3.5", Addresses: []string(nil)}).Return(nil)
 	client.On("CreateLogicalPort", lSwitch, "1.2.3.4",
-		"02:00:01:02:03:04", "1.2.3.4").Return(nil).Once()
+		"02:00:01:02:03:04", []string{"1.2.3.4", "fd00::1"}).Return(nil).Once()
 	runMaster(conn)
 	client.AssertCalled(t, "Disconnect")
 	client.AssertCalled(t, "ListLogicalPorts")
@@ -95,4 +100,29 @@ func TestRunMaster(t *testing.T) {
 	client.AssertCalled(t, "DeleteLogicalPort", mock.Anything, mock.Anything)
 	client.AssertCalled(t, "CreateLogicalPort", mock.Anything, mock.Anything,
 		mock.Anything, mock.Anything)
+
+	// A port created before IPv6 was enabled is given its IPv6 address.
+	client = new(mocks.Client)
+	client.On("CreateLogicalSwitch", lSwitch).Return(nil)
+	client.On("Disconnect").Return(nil)
+	client.On("ListAddressSets").Return(nil, anErr)
+	client.On("ListACLs").Return(nil, anErr)
+
+	ipv4Only := ovsdb.LPort{Name: "1.2.3.4",
+		Addresses: []string{"02:00:01:02:03:04 1.2.3.4"}}
+	client.On("ListLogicalPorts").Return([]ovsdb.LPort{ipv4Only}, nil).Once()
+	client.On("SetLogicalPortAddresses", ipv4Only, "02:00:01:02:03:04",
+		[]string{"1.2.3.4", "fd00::1"}).Return(nil).Once()
+	runMaster(conn)
+	client.AssertNumberOfCalls(t, "SetLogicalPortAddresses", 1)
+	client.AssertNotCalled(t, "CreateLogicalPort", mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything)
+	client.AssertNotCalled(t, "DeleteLogicalPort", mock.Anything, mock.Anything)
+
+	// Up to date ports are left alone.
+	dualStack := ovsdb.LPort{Name: "1.2.3.4",
+		Addresses: []string{"02:00:01:02:03:04 1.2.3.4 fd00::1"}}
+	client.On("ListLogicalPorts").Return([]ovsdb.LPort{dualStack}, nil).Once()
+	runMaster(conn)
+	client.AssertNumberOfCalls(t, "SetLogicalPortAddresses", 1)
 }
@@ -0,0 +1,754 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+)
+
+// The nftables table that holds Quilt's NAT rules.  Unlike iptables, nftables
+// allows any number of tables to hook into NAT, so rules outside of it are left
+// alone.
+const (
+	nftFamily = syscall.AF_INET // NFPROTO_IPV4, the "ip" family.
+	nftTable  = "quilt"
+)
+
+// The nftables chains of the NAT rules, their hooks, and their priorities (the
+// standard dstnat and srcnat priorities).
+var nftChains = map[string]struct {
+	name string
+	hook uint32
+	prio int32
+}{
+	preRouting:  {"prerouting", nfInetPreRouting, -100},
+	postRouting: {"postrouting", nfInetPostRouting, 100},
+}
+
+var nftProtocols = map[string]byte{
+	"tcp": syscall.IPPROTO_TCP,
+	"udp": syscall.IPPROTO_UDP,
+}
+
+// Constants from linux/netfilter/nfnetlink.h and linux/netfilter/nf_tables.h.
+const (
+	nfnlSubsysNFTables = 10
+	nfnlMsgBatchBegin  = syscall.NLMSG_MIN_TYPE
+	nfnlMsgBatchEnd    = syscall.NLMSG_MIN_TYPE + 1
+
+	nftMsgNewTable = 0
+	nftMsgNewChain = 3
+	nftMsgNewRule  = 6
+	nftMsgGetRule  = 7
+	nftMsgDelRule  = 8
+
+	nftaTableName = 1
+
+	nftaChainTable  = 1
+	nftaChainName   = 3
+	nftaChainHook   = 4
+	nftaChainPolicy = 5
+	nftaChainType   = 7
+
+	nftaHookHooknum  = 1
+	nftaHookPriority = 2
+
+	nftaRuleTable       = 1
+	nftaRuleChain       = 2
+	nftaRuleHandle      = 3
+	nftaRuleExpressions = 4
+
+	nftaListElem  = 1
+	nftaExprName  = 1
+	nftaExprData  = 2
+	nftaDataValue = 1
+
+	nftaMetaDreg = 1
+	nftaMetaKey  = 2
+
+	nftaCmpSreg = 1
+	nftaCmpOp   = 2
+	nftaCmpData = 3
+
+	nftaPayloadDreg   = 1
+	nftaPayloadBase   = 2
+	nftaPayloadOffset = 3
+	nftaPayloadLen    = 4
+
+	nftaBitwiseSreg = 1
+	nftaBitwiseDreg = 2
+	nftaBitwiseLen  = 3
+	nftaBitwiseMask = 4
+	nftaBitwiseXor  = 5
+
+	nftaImmediateDreg = 1
+	nftaImmediateData = 2
+
+	nftaNatType        = 1
+	nftaNatFamily      = 2
+	nftaNatRegAddrMin  = 3
+	nftaNatRegProtoMin = 5
+
+	nftReg1 = 1
+	nftReg2 = 2
+
+	nftMetaIIFName = 6
+	nftMetaOIFName = 7
+	nftMetaL4Proto = 16
+
+	nftCmpEq = 0
+
+	nftPayloadNetworkHeader   = 1
+	nftPayloadTransportHeader = 2
+
+	nftNATDNAT = 1
+
+	nfInetPreRouting  = 0
+	nfInetPostRouting = 4
+	nfAccept          = 1
+
+	nlaFNetByteorder = 0x4000
+	nlaTypeMask      = ^uint16(syscall.NLA_F_NESTED | nlaFNetByteorder)
+
+	ifNameSize = 16
+)
+
+// nftablesBackend manages the NAT rules with nftables, by speaking to the kernel
+// over netlink.
+type nftablesBackend struct{}
+
+func (nftablesBackend) rules() ([]natRule, error) {
+	if err := nftSetup(); err != nil {
+		return nil, err
+	}
+
+	msgs, err := nftDump(nftRequest(nftMsgGetRule, 0,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(nftTable))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nftables: %s", err)
+	}
+
+	var rules []natRule
+	for _, msg := range msgs {
+		rule, err := parseNFTRuleMsg(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse nftables rule: %s", err)
+		}
+
+		if rule.table == nftTable {
+			rules = append(rules, parseNFTRule(rule))
+		}
+	}
+	return rules, nil
+}
+
+func (nftablesBackend) add(rule natRule) error {
+	chain, ok := nftChains[rule.chain]
+	if !ok {
+		return fmt.Errorf("unknown chain: %s", rule.chain)
+	}
+
+	exprs, err := nftExprs(rule)
+	if err != nil {
+		return err
+	}
+
+	var exprAttrs []*nl.RtAttr
+	for _, expr := range exprs {
+		exprAttrs = append(exprAttrs, expr.attr())
+	}
+
+	err = nftBatch([]*nl.NetlinkRequest{nftRequest(nftMsgNewRule,
+		syscall.NLM_F_CREATE|syscall.NLM_F_APPEND,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(nftTable)),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain.name)),
+		nftNested(nftaRuleExpressions, exprAttrs...))})
+	if err != nil {
+		return fmt.Errorf("failed to add nftables rule: %s", err)
+	}
+	return nil
+}
+
+func (nftablesBackend) delete(rule natRule) error {
+	chain, ok := nftChains[rule.chain]
+	if !ok {
+		return fmt.Errorf("unknown chain: %s", rule.chain)
+	}
+
+	handle, err := strconv.ParseUint(rule.handle, 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad nftables rule handle: %s", rule.handle)
+	}
+
+	handleBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(handleBytes, handle)
+	err = nftBatch([]*nl.NetlinkRequest{nftRequest(nftMsgDelRule, 0,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(nftTable)),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain.name)),
+		nl.NewRtAttr(nftaRuleHandle, handleBytes))})
+	if err != nil {
+		return fmt.Errorf("failed to delete nftables rule: %s", err)
+	}
+	return nil
+}
+
+// nftSetup creates Quilt's NAT table and chains if they don't already exist.
+func nftSetup() error {
+	reqs := []*nl.NetlinkRequest{nftRequest(nftMsgNewTable, syscall.NLM_F_CREATE,
+		nl.NewRtAttr(nftaTableName, nl.ZeroTerminated(nftTable)))}
+
+	for _, chain := range []string{preRouting, postRouting} {
+		c := nftChains[chain]
+		reqs = append(reqs, nftRequest(nftMsgNewChain, syscall.NLM_F_CREATE,
+			nl.NewRtAttr(nftaChainTable, nl.ZeroTerminated(nftTable)),
+			nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(c.name)),
+			nftNested(nftaChainHook,
+				nl.NewRtAttr(nftaHookHooknum, be32(c.hook)),
+				nl.NewRtAttr(nftaHookPriority, be32(uint32(c.prio)))),
+			nl.NewRtAttr(nftaChainPolicy, be32(nfAccept)),
+			nl.NewRtAttr(nftaChainType, nl.ZeroTerminated("nat"))))
+	}
+
+	if err := nftBatch(reqs); err != nil {
+		return fmt.Errorf("failed to create nftables chains: %s", err)
+	}
+	return nil
+}
+
+// An nftExpr is an nftables expression, such as loading a field of the packet into
+// a register, or comparing a register to a value.
+type nftExpr struct {
+	name  string
+	attrs []*nl.RtAttr
+}
+
+func (expr nftExpr) attr() *nl.RtAttr {
+	attrs := []*nl.RtAttr{nl.NewRtAttr(nftaExprName, nl.ZeroTerminated(expr.name))}
+	if len(expr.attrs) > 0 {
+		attrs = append(attrs, nftNested(nftaExprData, expr.attrs...))
+	}
+	return nftNested(nftaListElem, attrs...)
+}
+
+// nftExprs returns the nftables expressions that implement `rule`.  They're the
+// expressions nft generates for the equivalent rules:
+//
+//	ip saddr <srcNet> oifname <iface> masquerade
+//	iifname <iface> <protocol> dport <port> dnat to <destIP>:<port>
+func nftExprs(rule natRule) ([]nftExpr, error) {
+	switch rule.action {
+	case masquerade:
+		_, srcNet, err := net.ParseCIDR(rule.srcNet)
+		if err != nil || srcNet.IP.To4() == nil {
+			return nil, fmt.Errorf("bad subnet: %s", rule.srcNet)
+		}
+
+		return []nftExpr{
+			nftPayload(nftPayloadNetworkHeader, 12, 4),
+			nftBitwise(srcNet.Mask),
+			nftCmp(srcNet.IP.To4()),
+			nftMeta(nftMetaOIFName),
+			nftCmp(nftIFName(rule.iface)),
+			{name: "masq"},
+		}, nil
+	case dnat:
+		protocol, ok := nftProtocols[rule.protocol]
+		if !ok {
+			return nil, fmt.Errorf("bad protocol: %s", rule.protocol)
+		}
+
+		destIP := net.ParseIP(rule.destIP).To4()
+		if destIP == nil {
+			return nil, fmt.Errorf("bad destination: %s", rule.destIP)
+		}
+
+		port := be16(uint16(rule.port))
+		return []nftExpr{
+			nftMeta(nftMetaIIFName),
+			nftCmp(nftIFName(rule.iface)),
+			nftMeta(nftMetaL4Proto),
+			nftCmp([]byte{protocol}),
+			nftPayload(nftPayloadTransportHeader, 2, 2),
+			nftCmp(port),
+			nftImmediate(nftReg1, destIP),
+			nftImmediate(nftReg2, port),
+			{name: "nat", attrs: []*nl.RtAttr{
+				nl.NewRtAttr(nftaNatType, be32(nftNATDNAT)),
+				nl.NewRtAttr(nftaNatFamily, be32(nftFamily)),
+				nl.NewRtAttr(nftaNatRegAddrMin, be32(nftReg1)),
+				nl.NewRtAttr(nftaNatRegProtoMin, be32(nftReg2)),
+			}},
+		}, nil
+	default:
+		return nil, errors.New("only Quilt's NAT rules may be added")
+	}
+}
+
+// nftMeta loads the meta data `key`, such as the input interface, into register 1.
+func nftMeta(key uint32) nftExpr {
+	return nftExpr{name: "meta", attrs: []*nl.RtAttr{
+		nl.NewRtAttr(nftaMetaDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaMetaKey, be32(key)),
+	}}
+}
+
+// nftPayload loads `length` bytes at `offset` of the header `base` into register 1.
+func nftPayload(base, offset, length uint32) nftExpr {
+	return nftExpr{name: "payload", attrs: []*nl.RtAttr{
+		nl.NewRtAttr(nftaPayloadDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaPayloadBase, be32(base)),
+		nl.NewRtAttr(nftaPayloadOffset, be32(offset)),
+		nl.NewRtAttr(nftaPayloadLen, be32(length)),
+	}}
+}
+
+// nftBitwise masks register 1 with `mask`.
+func nftBitwise(mask []byte) nftExpr {
+	return nftExpr{name: "bitwise", attrs: []*nl.RtAttr{
+		nl.NewRtAttr(nftaBitwiseSreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaBitwiseDreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaBitwiseLen, be32(uint32(len(mask)))),
+		nftData(nftaBitwiseMask, mask),
+		nftData(nftaBitwiseXor, make([]byte, len(mask))),
+	}}
+}
+
+// nftCmp matches packets whose register 1 equals `value`.
+func nftCmp(value []byte) nftExpr {
+	return nftExpr{name: "cmp", attrs: []*nl.RtAttr{
+		nl.NewRtAttr(nftaCmpSreg, be32(nftReg1)),
+		nl.NewRtAttr(nftaCmpOp, be32(nftCmpEq)),
+		nftData(nftaCmpData, value),
+	}}
+}
+
+// nftImmediate sets register `reg` to `value`.
+func nftImmediate(reg uint32, value []byte) nftExpr {
+	return nftExpr{name: "immediate", attrs: []*nl.RtAttr{
+		nl.NewRtAttr(nftaImmediateDreg, be32(reg)),
+		nftData(nftaImmediateData, value),
+	}}
+}
+
+func nftData(attrType int, value []byte) *nl.RtAttr {
+	return nftNested(attrType, nl.NewRtAttr(nftaDataValue, value))
+}
+
+func nftNested(attrType int, children ...*nl.RtAttr) *nl.RtAttr {
+	var data []byte
+	for _, child := range children {
+		data = append(data, child.Serialize()...)
+	}
+	return nl.NewRtAttr(attrType|syscall.NLA_F_NESTED, data)
+}
+
+// nftIFName returns the value of an interface name as the meta expression loads it.
+func nftIFName(name string) []byte {
+	value := make([]byte, ifNameSize)
+	copy(value, name)
+	return value
+}
+
+// An nftRule is a rule as dumped by the kernel.
+type nftRule struct {
+	table, chain string
+	handle       uint64
+	exprs        []nftParsedExpr
+}
+
+type nftParsedExpr struct {
+	name  string
+	attrs map[uint16][]byte
+}
+
+// u32 returns the value of the 32 bit attribute `attrType`.
+func (expr nftParsedExpr) u32(attrType uint16) (uint32, bool) {
+	value, ok := expr.attrs[attrType]
+	if !ok || len(value) != 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(value), true
+}
+
+// data returns the value of the nested data attribute `attrType`.
+func (expr nftParsedExpr) data(attrType uint16) ([]byte, bool) {
+	attrs, err := parseNLAttrs(expr.attrs[attrType])
+	if err != nil || len(attrs) != 1 || attrs[0].attrType != nftaDataValue {
+		return nil, false
+	}
+	return attrs[0].value, true
+}
+
+// has returns true if every attribute in `want` has the given 32 bit value.
+func (expr nftParsedExpr) has(want map[uint16]uint32) bool {
+	for attrType, v := range want {
+		if value, ok := expr.u32(attrType); !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+type nlAttr struct {
+	attrType uint16
+	value    []byte
+}
+
+func parseNLAttrs(b []byte) ([]nlAttr, error) {
+	var attrs []nlAttr
+	for len(b) > 0 {
+		if len(b) < syscall.SizeofRtAttr {
+			return nil, errors.New("truncated netlink attribute")
+		}
+
+		length := int(nl.NativeEndian().Uint16(b[0:2]))
+		if length < syscall.SizeofRtAttr || length > len(b) {
+			return nil, errors.New("bad netlink attribute length")
+		}
+
+		attrType := nl.NativeEndian().Uint16(b[2:4]) & nlaTypeMask
+		attrs = append(attrs, nlAttr{attrType, b[syscall.SizeofRtAttr:length]})
+
+		align := syscall.RTA_ALIGNTO - 1
+		aligned := (length + align) &^ align
+		if aligned > len(b) {
+			aligned = len(b)
+		}
+		b = b[aligned:]
+	}
+	return attrs, nil
+}
+
+// parseNFTRuleMsg parses a rule message dumped by the kernel.
+func parseNFTRuleMsg(msg []byte) (nftRule, error) {
+	var rule nftRule
+	if len(msg) < nfgenmsgLen {
+		return rule, errors.New("truncated message")
+	}
+
+	attrs, err := parseNLAttrs(msg[nfgenmsgLen:])
+	if err != nil {
+		return rule, err
+	}
+
+	for _, attr := range attrs {
+		switch attr.attrType {
+		case nftaRuleTable:
+			rule.table = cString(attr.value)
+		case nftaRuleChain:
+			rule.chain = cString(attr.value)
+		case nftaRuleHandle:
+			if len(attr.value) != 8 {
+				return rule, errors.New("bad rule handle")
+			}
+			rule.handle = binary.BigEndian.Uint64(attr.value)
+		case nftaRuleExpressions:
+			rule.exprs, err = parseNFTExprs(attr.value)
+			if err != nil {
+				return rule, err
+			}
+		}
+	}
+	return rule, nil
+}
+
+func parseNFTExprs(b []byte) ([]nftParsedExpr, error) {
+	elems, err := parseNLAttrs(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var exprs []nftParsedExpr
+	for _, elem := range elems {
+		attrs, err := parseNLAttrs(elem.value)
+		if err != nil {
+			return nil, err
+		}
+
+		expr := nftParsedExpr{attrs: map[uint16][]byte{}}
+		for _, attr := range attrs {
+			switch attr.attrType {
+			case nftaExprName:
+				expr.name = cString(attr.value)
+			case nftaExprData:
+				data, err := parseNLAttrs(attr.value)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, d := range data {
+					expr.attrs[d.attrType] = d.value
+				}
+			}
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+// parseNFTRule converts a rule dumped by the kernel into a natRule.  Rules that
+// Quilt wouldn't have installed are left unrecognized.
+func parseNFTRule(nftr nftRule) natRule {
+	unknown := natRule{handle: strconv.FormatUint(nftr.handle, 10)}
+	for chain, c := range nftChains {
+		if c.name == nftr.chain {
+			unknown.chain = chain
+		}
+	}
+	if unknown.chain == "" {
+		return unknown
+	}
+
+	rule := unknown
+	var dport, natPort int
+	exprs := nftr.exprs
+	for i := 0; i < len(exprs); i++ {
+		expr := exprs[i]
+		var ok bool
+		switch expr.name {
+		case "meta", "payload":
+			// A load is followed by an optional mask, and the comparison
+			// that makes it a match.
+			var mask []byte
+			if i+1 < len(exprs) && exprs[i+1].name == "bitwise" {
+				i++
+				if mask, ok = parseNFTMask(exprs[i]); !ok {
+					return unknown
+				}
+			}
+
+			if i+1 >= len(exprs) || exprs[i+1].name != "cmp" ||
+				!exprs[i+1].has(map[uint16]uint32{
+					nftaCmpSreg: nftReg1, nftaCmpOp: nftCmpEq}) {
+				return unknown
+			}
+			i++
+
+			value, valueOK := exprs[i].data(nftaCmpData)
+			ok = valueOK && parseNFTMatch(expr, mask, value, &rule, &dport)
+		case "immediate":
+			value, valueOK := expr.data(nftaImmediateData)
+			switch reg, _ := expr.u32(nftaImmediateDreg); {
+			case !valueOK:
+			case reg == nftReg1 && len(value) == net.IPv4len &&
+				rule.destIP == "":
+				rule.destIP = net.IP(value).String()
+				ok = true
+			case reg == nftReg2 && len(value) == 2 && natPort == 0:
+				natPort = int(binary.BigEndian.Uint16(value))
+				ok = true
+			}
+		case "nat":
+			ok = rule.action == "" && expr.has(map[uint16]uint32{
+				nftaNatType:        nftNATDNAT,
+				nftaNatFamily:      nftFamily,
+				nftaNatRegAddrMin:  nftReg1,
+				nftaNatRegProtoMin: nftReg2,
+			})
+			rule.action = dnat
+		case "masq":
+			ok = rule.action == "" && len(expr.attrs) == 0
+			rule.action = masquerade
+		}
+
+		if !ok {
+			return unknown
+		}
+	}
+
+	switch rule.action {
+	case masquerade:
+		if rule.chain != postRouting || rule.srcNet == "" || rule.iface == "" ||
+			rule.protocol != "" || rule.destIP != "" || natPort != 0 {
+			return unknown
+		}
+	case dnat:
+		if rule.chain != preRouting || rule.srcNet != "" || rule.iface == "" ||
+			rule.protocol == "" || rule.destIP == "" || dport == 0 ||
+			dport != natPort {
+			return unknown
+		}
+		rule.port = dport
+	default:
+		return unknown
+	}
+	return rule
+}
+
+// parseNFTMask returns the mask of a bitwise expression, if it's one that masks
+// register 1 in place.
+func parseNFTMask(expr nftParsedExpr) ([]byte, bool) {
+	mask, maskOK := expr.data(nftaBitwiseMask)
+	xor, xorOK := expr.data(nftaBitwiseXor)
+	ok := maskOK && xorOK && bytes.Equal(xor, make([]byte, len(mask))) &&
+		expr.has(map[uint16]uint32{
+			nftaBitwiseSreg: nftReg1,
+			nftaBitwiseDreg: nftReg1,
+			nftaBitwiseLen:  uint32(len(mask)),
+		})
+	return mask, ok
+}
+
+// parseNFTMatch fills in `rule` with the condition that the value loaded by `load`,
+// masked by `mask`, equals `value`.  It returns false if Quilt wouldn't have used
+// the condition.
+func parseNFTMatch(load nftParsedExpr, mask, value []byte, rule *natRule,
+	dport *int) bool {
+
+	if load.name == "meta" {
+		if !load.has(map[uint16]uint32{nftaMetaDreg: nftReg1}) {
+			return false
+		}
+
+		key, _ := load.u32(nftaMetaKey)
+		switch {
+		case mask != nil:
+			return false
+		case key == nftMetaL4Proto && len(value) == 1 && rule.protocol == "":
+			for name, protocol := range nftProtocols {
+				if value[0] == protocol {
+					rule.protocol = name
+					return true
+				}
+			}
+		case (key == nftMetaIIFName && rule.chain == preRouting ||
+			key == nftMetaOIFName && rule.chain == postRouting) &&
+			len(value) == ifNameSize && rule.iface == "":
+			rule.iface = cString(value)
+			return rule.iface != ""
+		}
+		return false
+	}
+
+	switch {
+	case !load.has(map[uint16]uint32{nftaPayloadDreg: nftReg1}):
+		return false
+	case load.has(map[uint16]uint32{nftaPayloadBase: nftPayloadNetworkHeader,
+		nftaPayloadOffset: 12, nftaPayloadLen: 4}):
+		ones, bits := net.IPMask(mask).Size()
+		if len(value) != 4 || bits != 32 || rule.srcNet != "" {
+			return false
+		}
+		rule.srcNet = fmt.Sprintf("%s/%d", net.IP(value), ones)
+		return true
+	case load.has(map[uint16]uint32{nftaPayloadBase: nftPayloadTransportHeader,
+		nftaPayloadOffset: 2, nftaPayloadLen: 2}):
+		// The protocol must be matched before its header is read.
+		if mask != nil || len(value) != 2 || rule.protocol == "" || *dport != 0 {
+			return false
+		}
+		*dport = int(binary.BigEndian.Uint16(value))
+		return true
+	}
+	return false
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+const nfgenmsgLen = 4
+
+// nfgenmsg is the header of nfnetlink messages.
+type nfgenmsg struct {
+	family uint8
+	resID  uint16
+}
+
+func (msg nfgenmsg) Len() int {
+	return nfgenmsgLen
+}
+
+func (msg nfgenmsg) Serialize() []byte {
+	b := []byte{msg.family, 0, 0, 0} // Version NFNETLINK_V0.
+	binary.BigEndian.PutUint16(b[2:], msg.resID)
+	return b
+}
+
+func nftRequest(msgType, flags int, attrs ...*nl.RtAttr) *nl.NetlinkRequest {
+	req := nl.NewNetlinkRequest(nfnlSubsysNFTables<<8|msgType, flags)
+	req.AddData(nfgenmsg{family: nftFamily})
+	for _, attr := range attrs {
+		req.AddData(attr)
+	}
+	return req
+}
+
+// nftBatch applies `reqs` to nftables as a single transaction.
+var nftBatch = func(reqs []*nl.NetlinkRequest) error {
+	s, err := nl.Subscribe(syscall.NETLINK_NETFILTER)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	batch := func(msgType int) []byte {
+		req := nl.NewNetlinkRequest(msgType, 0)
+		req.AddData(nfgenmsg{family: syscall.AF_UNSPEC,
+			resID: nfnlSubsysNFTables})
+		return req.Serialize()
+	}
+
+	// Every request is acknowledged, so that we know when the kernel is done.
+	pending := map[uint32]struct{}{}
+	buf := batch(nfnlMsgBatchBegin)
+	for _, req := range reqs {
+		req.Flags |= syscall.NLM_F_ACK
+		buf = append(buf, req.Serialize()...)
+		pending[req.Seq] = struct{}{}
+	}
+	buf = append(buf, batch(nfnlMsgBatchEnd)...)
+
+	lsa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err := syscall.Sendto(s.GetFd(), buf, 0, lsa); err != nil {
+		return err
+	}
+
+	for len(pending) > 0 {
+		msgs, err := s.Receive()
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			if msg.Header.Type != syscall.NLMSG_ERROR || len(msg.Data) < 4 {
+				continue
+			}
+
+			errno := int32(nl.NativeEndian().Uint32(msg.Data))
+			if errno != 0 {
+				return syscall.Errno(-errno)
+			}
+			delete(pending, msg.Header.Seq)
+		}
+	}
+	return nil
+}
+
+// nftDump sends the get request `req`, and returns the messages the kernel dumps in
+// response.
+var nftDump = func(req *nl.NetlinkRequest) ([][]byte, error) {
+	req.Flags |= syscall.NLM_F_DUMP
+	return req.Execute(syscall.NETLINK_NETFILTER, 0)
+}
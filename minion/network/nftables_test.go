@@ -0,0 +1,274 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// nftDumped returns the message the kernel would dump for a rule with `exprs`.
+func nftDumped(table, chain string, handle uint64, exprs []nftExpr) []byte {
+	var exprAttrs []*nl.RtAttr
+	for _, expr := range exprs {
+		exprAttrs = append(exprAttrs, expr.attr())
+	}
+
+	handleBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(handleBytes, handle)
+	req := nftRequest(nftMsgNewRule, 0,
+		nl.NewRtAttr(nftaRuleTable, nl.ZeroTerminated(table)),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain)),
+		nl.NewRtAttr(nftaRuleHandle, handleBytes),
+		nftNested(nftaRuleExpressions, exprAttrs...))
+	return req.Serialize()[syscall.SizeofNlMsghdr:]
+}
+
+// nftDecoded returns the message type and top level attributes of `req`.
+func nftDecoded(t *testing.T, req *nl.NetlinkRequest) (int, map[uint16][]byte) {
+	msg := req.Serialize()
+	attrs, err := parseNLAttrs(msg[syscall.SizeofNlMsghdr+nfgenmsgLen:])
+	assert.NoError(t, err)
+
+	attrMap := map[uint16][]byte{}
+	for _, attr := range attrs {
+		attrMap[attr.attrType] = attr.value
+	}
+	return int(req.Type) &^ (nfnlSubsysNFTables << 8), attrMap
+}
+
+func TestNFTablesRules(t *testing.T) {
+	oldBatch, oldDump := nftBatch, nftDump
+	defer func() { nftBatch, nftDump = oldBatch, oldDump }()
+
+	dnatExprs, err := nftExprs(natRule{chain: preRouting, action: dnat,
+		iface: "eth0", protocol: "tcp", port: 80, destIP: "10.0.0.2"})
+	assert.NoError(t, err)
+
+	masqExprs, err := nftExprs(natRule{chain: postRouting, action: masquerade,
+		iface: "eth0", srcNet: "10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	var batches [][]*nl.NetlinkRequest
+	nftBatch = func(reqs []*nl.NetlinkRequest) error {
+		batches = append(batches, reqs)
+		return nil
+	}
+
+	nftDump = func(req *nl.NetlinkRequest) ([][]byte, error) {
+		msgType, attrs := nftDecoded(t, req)
+		assert.Equal(t, nftMsgGetRule, msgType)
+		assert.Equal(t, "quilt", cString(attrs[nftaRuleTable]))
+
+		return [][]byte{
+			nftDumped("quilt", "prerouting", 4, dnatExprs),
+
+			// Without the interface match, the rule isn't Quilt's.
+			nftDumped("quilt", "prerouting", 5, dnatExprs[2:]),
+			nftDumped("quilt", "postrouting", 6, masqExprs),
+			nftDumped("quilt", "postrouting", 8, []nftExpr{
+				{name: "counter"}, {name: "masq"}}),
+			nftDumped("other", "postrouting", 9, masqExprs),
+		}, nil
+	}
+
+	rules, err := nftablesBackend{}.rules()
+	assert.NoError(t, err)
+	assert.Equal(t, []natRule{
+		{chain: preRouting, action: dnat, iface: "eth0", protocol: "tcp",
+			port: 80, destIP: "10.0.0.2", handle: "4"},
+		{chain: preRouting, handle: "5"},
+		{chain: postRouting, action: masquerade, iface: "eth0",
+			srcNet: "10.0.0.0/8", handle: "6"},
+		{chain: postRouting, handle: "8"},
+	}, rules)
+
+	// The table and chains are created in one transaction before they're listed.
+	assert.Len(t, batches, 1)
+	var created []string
+	for _, req := range batches[0] {
+		msgType, attrs := nftDecoded(t, req)
+		switch msgType {
+		case nftMsgNewTable:
+			created = append(created, "table "+cString(attrs[nftaTableName]))
+		case nftMsgNewChain:
+			assert.Equal(t, "quilt", cString(attrs[nftaChainTable]))
+			assert.Equal(t, "nat", cString(attrs[nftaChainType]))
+			created = append(created, "chain "+cString(attrs[nftaChainName]))
+		}
+	}
+	assert.Equal(t, []string{"table quilt", "chain prerouting", "chain postrouting"},
+		created)
+
+	nftDump = func(req *nl.NetlinkRequest) ([][]byte, error) {
+		return [][]byte{{0, 0, 0, 0, 1}}, nil
+	}
+	_, err = nftablesBackend{}.rules()
+	assert.EqualError(t, err,
+		"failed to parse nftables rule: truncated netlink attribute")
+
+	nftDump = func(req *nl.NetlinkRequest) ([][]byte, error) {
+		return nil, errors.New("err")
+	}
+	_, err = nftablesBackend{}.rules()
+	assert.EqualError(t, err, "failed to list nftables: err")
+
+	nftBatch = func(reqs []*nl.NetlinkRequest) error {
+		return errors.New("err")
+	}
+	_, err = nftablesBackend{}.rules()
+	assert.EqualError(t, err, "failed to create nftables chains: err")
+}
+
+func TestNFTablesAddDelete(t *testing.T) {
+	oldBatch := nftBatch
+	defer func() { nftBatch = oldBatch }()
+
+	var reqs []*nl.NetlinkRequest
+	var batchErr error
+	nftBatch = func(batch []*nl.NetlinkRequest) error {
+		reqs = append(reqs, batch...)
+		return batchErr
+	}
+
+	backend := nftablesBackend{}
+	rule := natRule{chain: preRouting, action: dnat, iface: "eth0",
+		protocol: "udp", port: 53, destIP: "10.0.0.2"}
+	assert.NoError(t, backend.add(rule))
+	assert.NoError(t, backend.delete(natRule{chain: postRouting, handle: "6"}))
+	assert.Len(t, reqs, 2)
+
+	msgType, attrs := nftDecoded(t, reqs[0])
+	assert.Equal(t, nftMsgNewRule, msgType)
+	assert.Equal(t, uint16(syscall.NLM_F_CREATE|syscall.NLM_F_APPEND),
+		reqs[0].Flags&(syscall.NLM_F_CREATE|syscall.NLM_F_APPEND))
+	assert.Equal(t, "quilt", cString(attrs[nftaRuleTable]))
+	assert.Equal(t, "prerouting", cString(attrs[nftaRuleChain]))
+
+	exprs, err := parseNFTExprs(attrs[nftaRuleExpressions])
+	assert.NoError(t, err)
+	rule.handle = "0"
+	assert.Equal(t, rule, parseNFTRule(nftRule{chain: "prerouting", exprs: exprs}))
+
+	msgType, attrs = nftDecoded(t, reqs[1])
+	assert.Equal(t, nftMsgDelRule, msgType)
+	assert.Equal(t, "postrouting", cString(attrs[nftaRuleChain]))
+	assert.Equal(t, uint64(6), binary.BigEndian.Uint64(attrs[nftaRuleHandle]))
+
+	assert.EqualError(t, backend.add(natRule{chain: postRouting}),
+		"only Quilt's NAT rules may be added")
+	assert.EqualError(t, backend.add(natRule{chain: "DOCKER"}),
+		"unknown chain: DOCKER")
+	assert.EqualError(t, backend.delete(natRule{chain: preRouting}),
+		"bad nftables rule handle: ")
+
+	batchErr = errors.New("err")
+	assert.EqualError(t, backend.add(rule), "failed to add nftables rule: err")
+	assert.EqualError(t, backend.delete(natRule{chain: postRouting, handle: "6"}),
+		"failed to delete nftables rule: err")
+}
+
+func TestNFTExprsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []natRule{
+		{chain: postRouting, action: masquerade, iface: "eth0",
+			srcNet: "172.16.0.0/12"},
+		{chain: preRouting, action: dnat, iface: "ens3", protocol: "tcp",
+			port: 443, destIP: "10.1.2.3"},
+	} {
+		exprs, err := nftExprs(rule)
+		assert.NoError(t, err)
+
+		listed, err := parseNFTRuleMsg(nftDumped("quilt",
+			nftChains[rule.chain].name, 3, exprs))
+		assert.NoError(t, err)
+
+		rule.handle = "3"
+		assert.Equal(t, rule, parseNFTRule(listed))
+
+		// Rules that do more than Quilt's aren't recognized.
+		listed.exprs = append(listed.exprs, nftParsedExpr{name: "counter"})
+		assert.Equal(t, natRule{chain: rule.chain, handle: "3"},
+			parseNFTRule(listed))
+	}
+
+	_, err := nftExprs(natRule{chain: postRouting, action: masquerade,
+		srcNet: "bad"})
+	assert.EqualError(t, err, "bad subnet: bad")
+
+	_, err = nftExprs(natRule{chain: preRouting, action: dnat, protocol: "icmp"})
+	assert.EqualError(t, err, "bad protocol: icmp")
+
+	_, err = nftExprs(natRule{chain: preRouting, action: dnat, protocol: "tcp",
+		destIP: "fd00::1"})
+	assert.EqualError(t, err, "bad destination: fd00::1")
+}
+
+func TestParseNFTRule(t *testing.T) {
+	t.Parallel()
+
+	dnatExprs, err := nftExprs(natRule{chain: preRouting, action: dnat,
+		iface: "eth0", protocol: "tcp", port: 80, destIP: "10.0.0.2"})
+	assert.NoError(t, err)
+
+	parse := func(chain string, exprs []nftExpr) natRule {
+		listed, err := parseNFTRuleMsg(nftDumped("quilt", chain, 1, exprs))
+		assert.NoError(t, err)
+		return parseNFTRule(listed)
+	}
+
+	// The port the traffic is forwarded to must be the one it's sent to.
+	exprs := append([]nftExpr{}, dnatExprs...)
+	exprs[7] = nftImmediate(nftReg2, be16(8080))
+	assert.Equal(t, natRule{chain: preRouting, handle: "1"},
+		parse("prerouting", exprs))
+
+	// DNAT rules belong in the prerouting chain.
+	assert.Equal(t, natRule{chain: postRouting, handle: "1"},
+		parse("postrouting", dnatExprs))
+
+	// Only rules in Quilt's chains are recognized.
+	assert.Equal(t, natRule{handle: "1"}, parse("input", dnatExprs))
+
+	// The destination port can't be matched before the protocol.
+	exprs = append([]nftExpr{}, dnatExprs[:2]...)
+	exprs = append(exprs, dnatExprs[4:6]...)
+	exprs = append(exprs, dnatExprs[2:4]...)
+	exprs = append(exprs, dnatExprs[6:]...)
+	assert.Equal(t, natRule{chain: preRouting, handle: "1"},
+		parse("prerouting", exprs))
+
+	// A load without a comparison isn't a match.
+	exprs = append([]nftExpr{}, dnatExprs[:1]...)
+	exprs = append(exprs, dnatExprs[2:]...)
+	assert.Equal(t, natRule{chain: preRouting, handle: "1"},
+		parse("prerouting", exprs))
+}
+
+func TestParseNLAttrs(t *testing.T) {
+	t.Parallel()
+
+	b := append(nl.NewRtAttr(1, []byte("a")).Serialize(),
+		nftNested(2, nl.NewRtAttr(3, []byte{4, 5})).Serialize()...)
+	attrs, err := parseNLAttrs(b)
+	assert.NoError(t, err)
+	assert.Len(t, attrs, 2)
+	assert.Equal(t, nlAttr{1, []byte("a")}, attrs[0])
+	assert.Equal(t, uint16(2), attrs[1].attrType)
+
+	nested, err := parseNLAttrs(attrs[1].value)
+	assert.NoError(t, err)
+	assert.Equal(t, []nlAttr{{3, []byte{4, 5}}}, nested)
+
+	_, err = parseNLAttrs([]byte{1, 2})
+	assert.EqualError(t, err, "truncated netlink attribute")
+
+	long := make([]byte, 4)
+	nl.NativeEndian().PutUint16(long, 64)
+	_, err = parseNLAttrs(long)
+	assert.EqualError(t, err, "bad netlink attribute length")
+}
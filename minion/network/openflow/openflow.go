@@ -2,8 +2,13 @@ package openflow
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
@@ -31,6 +36,9 @@ the packet is forwarded to the specified table and the process begins again.
 Finally, note that some tables have loops which should be interpreted as duplicating the
 inner if statements per loop element.
 
+The Simulator traces packets through the flows that actually get installed, and the tests
+in simulator_test.go check each of the cases below.  Update them together.
+
 Registers
 ---------
 
@@ -72,7 +80,9 @@ Table_0 { // Initial Table
 }
 
 // Table_1 handles special cases for broadcast packets and the default gateway.  If no
-special cases apply, it outputs the packet.
+special cases apply, it outputs the packet.  In dual stack deployments, IPv6 multicast
+packets (dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00) are treated like broadcasts so
+that neighbor discovery works.
 Table_1 {
 	// If the veth sends a broadcast, send it to the gateway and the patch port.
 	if reg0=1 && dl_dst=ff:ff:ff:ff:ff:ff {
@@ -110,6 +120,29 @@ Table_1 {
 		output:reg2
 	}
 
+	// Traffic from containers connected to the veth gets its own flows, so that
+	// their counters measure the traffic between them.  TCP and UDP traffic to
+	// the ports of each connection is counted separately, by matching blocks of
+	// destination ports that are each within or outside of every connection's
+	// port range.  Traffic from rate limited connections is also placed in the
+	// veth's queue for its source.
+	for each db.Container {
+		for each source {
+			for each port block, for tcp and udp {
+				if in_port=dbc.PatchPort && ip_src=source.IP &&
+				    tp_dst=block {
+					set_queue:source.Queue  // Only if rate limited.
+					output:dbc.VethPort
+				}
+			}
+
+			if in_port=dbc.PatchPort && ip_src=source.IP {
+				set_queue:source.Queue  // Only if rate limited.
+				output:dbc.VethPort
+			}
+		}
+	}
+
 	// Send packets from the patch port to the veth.
 	if reg0=2 {
 		output:reg1
@@ -132,45 +165,182 @@ type Container struct {
 	Veth  string
 	Patch string
 	Mac   string
+
+	// Sources maps the IPs of the containers that may connect to this one, to the
+	// veth queue their traffic is placed in, or 0 if it isn't rate limited.
+	Sources map[string]int
+
+	// Ports maps the IPs of the sources to the destination port ranges of their
+	// connections, whose traffic is counted separately.
+	Ports map[string][]PortRange
+}
+
+// A PortRange is the range of destination ports of a connection, inclusive.
+type PortRange struct {
+	Min, Max int
 }
 
 type container struct {
-	veth  int
-	patch int
-	mac   string
+	veth    int
+	patch   int
+	mac     string
+	sources map[string]int
+	ports   map[string][]PortRange
+}
+
+// A Counter is the traffic a container has received from a source IP.  Counters of
+// TCP and UDP traffic are for a block of destination ports, MinPort through MaxPort,
+// which is either within or outside of each of the source's port ranges.  The
+// counters of the other traffic have no ports.
+type Counter struct {
+	Patch   string // The container's patch port.
+	SrcIP   string
+	MinPort int
+	MaxPort int
+	Packets uint64
+	Bytes   uint64
 }
 
-var staticFlows = []string{
-	// Table 0
-	"table=0,priority=1000,in_port=LOCAL,actions=resubmit(,1)",
+// The priorities of the flows for traffic from a container's sources.  The flows
+// that count traffic to specific ports take precedence over the others.
+const (
+	sourcePriority = 450
+	portPriority   = 451
+)
+
+// IPv6 neighbor discovery relies on multicast where IPv4 ARP uses broadcast, so dual
+// stack deployments treat the two alike.
+const (
+	broadcastMac = "ff:ff:ff:ff:ff:ff"
+	multicastMac = "33:33:00:00:00:00/ff:ff:00:00:00:00"
+)
+
+// The static flows depend on the gateway, which isn't known until the minion has
+// been configured, so they can't be computed at initialization.
+func staticFlows() []string {
+	flows := []string{
+		// Table 0
+		"table=0,priority=1000,in_port=LOCAL,actions=resubmit(,1)",
+	}
 
 	// Table 1
-	"table=1,priority=1000,reg0=0x1,dl_dst=ff:ff:ff:ff:ff:ff," +
-		"actions=output:LOCAL,output:NXM_NX_REG2[]",
-	"table=1,priority=900,reg0=0x2,dl_dst=ff:ff:ff:ff:ff:ff," +
-		"actions=output:NXM_NX_REG1[]",
-	fmt.Sprintf("table=1,priority=800,reg0=1,dl_dst=%s,actions=LOCAL",
-		ipdef.GatewayMac),
-	fmt.Sprintf("table=1,priority=700,dl_dst=%s,actions=drop", ipdef.GatewayMac),
-	"table=1,priority=600,in_port=LOCAL,actions=resubmit(,2)",
-	"table=1,priority=500,reg0=1,actions=output:NXM_NX_REG2[]",
-	"table=1,priority=400,reg0=2,actions=output:NXM_NX_REG1[]",
+	for _, dst := range broadcastMacs() {
+		flows = append(flows,
+			fmt.Sprintf("table=1,priority=1000,reg0=0x1,dl_dst=%s,"+
+				"actions=output:LOCAL,output:NXM_NX_REG2[]", dst),
+			fmt.Sprintf("table=1,priority=900,reg0=0x2,dl_dst=%s,"+
+				"actions=output:NXM_NX_REG1[]", dst))
+	}
+
+	return append(flows,
+		fmt.Sprintf("table=1,priority=800,reg0=1,dl_dst=%s,actions=LOCAL",
+			ipdef.GatewayMac),
+		fmt.Sprintf("table=1,priority=700,dl_dst=%s,actions=drop",
+			ipdef.GatewayMac),
+		"table=1,priority=600,in_port=LOCAL,actions=resubmit(,2)",
+		"table=1,priority=500,reg0=1,actions=output:NXM_NX_REG2[]",
+		"table=1,priority=400,reg0=2,actions=output:NXM_NX_REG1[]")
 }
 
-// ReplaceFlows adds flows associated with the provided containers, and removes all
-// other flows.
-func ReplaceFlows(containers []Container) error {
+func broadcastMacs() []string {
+	if ipdef.QuiltSubnet6 == nil {
+		return []string{broadcastMac}
+	}
+	return []string{broadcastMac, multicastMac}
+}
+
+// FlowChanges counts the flows changed by UpdateFlows.
+type FlowChanges struct {
+	Added, Modified, Deleted int
+
+	// Total is the number of flows installed after the update.
+	Total int
+}
+
+// UpdateFlows makes the installed flows match those required by the provided
+// containers.  Only the flows that differ are touched, so traffic handled by the
+// other flows is never interrupted.
+func UpdateFlows(containers []Container) (FlowChanges, error) {
 	ofports, err := openflowPorts()
 	if err != nil {
-		return err
+		return FlowChanges{}, err
 	}
 
 	flows := allFlows(resolveContainers(ofports, containers))
-	if err := ofctl("replace-flows", flows); err != nil {
-		return fmt.Errorf("ovs-ofctl: %s", err)
+	diff, err := diffFlows(flows)
+	if err != nil {
+		return FlowChanges{}, fmt.Errorf("ovs-ofctl: %s", err)
 	}
 
-	return nil
+	toAdd, toDel, changes := planFlowChanges(diff)
+	changes.Total = len(flows)
+
+	// Modified flows are replaced by adding them, so deleting first never
+	// interrupts traffic handled by a flow that's still desired.
+	if len(toDel) > 0 {
+		if err := ofctl("--strict del-flows", toDel); err != nil {
+			return FlowChanges{}, fmt.Errorf("ovs-ofctl: %s", err)
+		}
+	}
+
+	if len(toAdd) > 0 {
+		if err := ofctl("add-flows", toAdd); err != nil {
+			return FlowChanges{}, fmt.Errorf("ovs-ofctl: %s", err)
+		}
+	}
+
+	return changes, nil
+}
+
+// planFlowChanges converts the output of `ovs-ofctl diff-flows <bridge> <desired>`
+// into the flows that must be added and deleted.  The diff prefixes installed
+// flows that aren't desired with "-", and desired flows that aren't installed with
+// "+".  A flow whose actions changed appears as both.
+func planFlowChanges(diff string) (toAdd, toDel []string, changes FlowChanges) {
+	added := map[string]string{}
+	deleted := map[string]string{}
+	var addOrder, delOrder []string
+	for _, line := range strings.Split(diff, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < 2 {
+			continue
+		}
+
+		flow := line[1:]
+		match := strings.SplitN(flow, " actions=", 2)[0]
+		switch line[0] {
+		case '+':
+			added[match] = flow
+			addOrder = append(addOrder, match)
+		case '-':
+			deleted[match] = flow
+			delOrder = append(delOrder, match)
+		}
+	}
+
+	for _, match := range addOrder {
+		toAdd = append(toAdd, added[match])
+		if _, ok := deleted[match]; ok {
+			changes.Modified++
+		} else {
+			changes.Added++
+		}
+	}
+
+	for _, match := range delOrder {
+		if _, ok := added[match]; ok {
+			continue
+		}
+
+		// Strict deletes of flows in table 0 must say so, as they would
+		// otherwise apply to every table.
+		if !strings.HasPrefix(match, "table=") {
+			match = "table=0 " + match
+		}
+		toDel = append(toDel, match)
+		changes.Deleted++
+	}
+	return toAdd, toDel, changes
 }
 
 // AddFlows adds flows associated with the provided containers without touching flows
@@ -201,19 +371,202 @@ func containerFlows(containers []container) []string {
 			fmt.Sprintf(template, c.patch, "", 2),
 			fmt.Sprintf("table=2,priority=1000,dl_dst=%s,actions=output:%d",
 				c.mac, c.veth))
+
+		var srcs []string
+		for ip := range c.sources {
+			srcs = append(srcs, ip)
+		}
+		sort.Strings(srcs)
+
+		for _, ip := range srcs {
+			ipType, srcMatch := "ip", "nw_src="+ip
+			protocols := []string{"tcp", "udp"}
+			if strings.Contains(ip, ":") {
+				ipType, srcMatch = "ipv6", "ipv6_src="+ip
+				protocols = []string{"tcp6", "udp6"}
+			}
+
+			actions := fmt.Sprintf("output:%d", c.veth)
+			if queue := c.sources[ip]; queue != 0 {
+				actions = fmt.Sprintf("set_queue:%d,%s", queue, actions)
+			}
+
+			for _, block := range portBlocks(c.ports[ip]) {
+				for _, proto := range protocols {
+					flows = append(flows, fmt.Sprintf(
+						"table=1,priority=%d,in_port=%d,%s,%s,"+
+							"tp_dst=%s,actions=%s",
+						portPriority, c.patch, proto, srcMatch,
+						block, actions))
+				}
+			}
+
+			flows = append(flows, fmt.Sprintf("table=1,priority=%d,"+
+				"in_port=%d,%s,%s,actions=%s", sourcePriority, c.patch,
+				ipType, srcMatch, actions))
+		}
 	}
 	return flows
 }
 
+// A portBlock is a block of destination ports that OpenFlow can match, i.e. the
+// ports whose bits under `mask` equal `value`.
+type portBlock struct {
+	value, mask int
+}
+
+func (block portBlock) String() string {
+	if block.mask == 0xffff {
+		return strconv.Itoa(block.value)
+	}
+	return fmt.Sprintf("0x%x/0x%x", block.value, block.mask)
+}
+
+// portBlocks covers the ports in `ranges` with blocks that are each entirely within
+// or outside of every range, so that the traffic counted by the flow of each block
+// can be attributed to the ranges that contain it.
+func portBlocks(ranges []PortRange) []portBlock {
+	boundSet := map[int]struct{}{}
+	for _, r := range ranges {
+		boundSet[r.Min] = struct{}{}
+		boundSet[r.Max+1] = struct{}{}
+	}
+
+	var bounds []int
+	for bound := range boundSet {
+		bounds = append(bounds, bound)
+	}
+	sort.Ints(bounds)
+
+	var blocks []portBlock
+	for i := 0; i+1 < len(bounds); i++ {
+		min, max := bounds[i], bounds[i+1]-1
+		for _, r := range ranges {
+			if r.Min <= min && max <= r.Max {
+				blocks = append(blocks, maskPorts(min, max)...)
+				break
+			}
+		}
+	}
+	return blocks
+}
+
+// maskPorts splits the ports `min` through `max` into the fewest blocks.
+func maskPorts(min, max int) []portBlock {
+	var blocks []portBlock
+	for min <= max {
+		size := 1
+		for size < 0x10000 && min%(size*2) == 0 && min+size*2-1 <= max {
+			size *= 2
+		}
+		blocks = append(blocks, portBlock{min, 0xffff &^ (size - 1)})
+		min += size
+	}
+	return blocks
+}
+
 func allFlows(containers []container) []string {
 	var gatewayBroadcastActions []string
 	for _, c := range containers {
 		gatewayBroadcastActions = append(gatewayBroadcastActions,
 			fmt.Sprintf("output:%d", c.veth))
 	}
-	flows := append(staticFlows, containerFlows(containers)...)
-	return append(flows, "table=1,priority=850,dl_dst=ff:ff:ff:ff:ff:ff,actions="+
-		strings.Join(gatewayBroadcastActions, ","))
+	flows := append(staticFlows(), containerFlows(containers)...)
+	for _, dst := range broadcastMacs() {
+		flows = append(flows, fmt.Sprintf("table=1,priority=850,dl_dst=%s,"+
+			"actions=%s", dst, strings.Join(gatewayBroadcastActions, ",")))
+	}
+	return flows
+}
+
+// ReadCounters returns the traffic each container has received from each of its
+// sources since their flows were installed.
+func ReadCounters() ([]Counter, error) {
+	ofports, err := openflowPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	flows, err := dumpFlows()
+	if err != nil {
+		return nil, fmt.Errorf("ovs-ofctl: %s", err)
+	}
+
+	portNames := map[string]string{}
+	for name, ofport := range ofports {
+		portNames[strconv.Itoa(ofport)] = name
+	}
+
+	return parseCounters(flows, portNames), nil
+}
+
+// parseCounters extracts the counters of the source flows in the output of
+// `ovs-ofctl dump-flows`.
+func parseCounters(flows string, portNames map[string]string) []Counter {
+	var counters []Counter
+	for _, line := range strings.Split(flows, "\n") {
+		fields := map[string]string{}
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' '
+		}) {
+			if kv := strings.SplitN(field, "=", 2); len(kv) == 2 {
+				fields[kv[0]] = kv[1]
+			}
+		}
+
+		var minPort, maxPort int
+		switch fields["priority"] {
+		case strconv.Itoa(sourcePriority):
+		case strconv.Itoa(portPriority):
+			block, err := parsePortBlock(fields["tp_dst"])
+			if err != nil {
+				continue
+			}
+			minPort = block.value
+			maxPort = block.value | (0xffff &^ block.mask)
+		default:
+			continue
+		}
+
+		src := fields["nw_src"]
+		if src == "" {
+			src = fields["ipv6_src"]
+		}
+
+		// Newer versions of ovs-ofctl may print port names instead of numbers.
+		patch := fields["in_port"]
+		if name, ok := portNames[patch]; ok {
+			patch = name
+		}
+
+		packets, errPackets := strconv.ParseUint(fields["n_packets"], 10, 64)
+		bytes, errBytes := strconv.ParseUint(fields["n_bytes"], 10, 64)
+		if src == "" || patch == "" || errPackets != nil || errBytes != nil {
+			continue
+		}
+
+		counters = append(counters, Counter{Patch: patch, SrcIP: src,
+			MinPort: minPort, MaxPort: maxPort, Packets: packets,
+			Bytes: bytes})
+	}
+	return counters
+}
+
+// parsePortBlock parses a tp_dst match, which may be masked.
+func parsePortBlock(match string) (portBlock, error) {
+	parts := strings.SplitN(match, "/", 2)
+	value, err := strconv.ParseUint(parts[0], 0, 16)
+	if err != nil {
+		return portBlock{}, err
+	}
+
+	mask := uint64(0xffff)
+	if len(parts) == 2 {
+		if mask, err = strconv.ParseUint(parts[1], 0, 16); err != nil {
+			return portBlock{}, err
+		}
+	}
+	return portBlock{int(value & mask), int(mask)}, nil
 }
 
 func resolveContainers(portMap map[string]int, containers []Container) []container {
@@ -225,7 +578,9 @@ func resolveContainers(portMap map[string]int, containers []Container) []contain
 			continue
 		}
 
-		ofcs = append(ofcs, container{patch: patch, veth: veth, mac: c.Mac})
+		ofcs = append(ofcs, container{patch: patch, veth: veth, mac: c.Mac,
+			sources: c.Sources,
+			ports:   c.Ports})
 	}
 	return ofcs
 }
@@ -241,8 +596,8 @@ func openflowPorts() (map[string]int, error) {
 }
 
 var ofctl = func(action string, flows []string) error {
-	cmd := exec.Command("ovs-ofctl", "-O", "OpenFlow13", action,
-		ipdef.QuiltBridge, "-")
+	args := append([]string{"-O", "OpenFlow13"}, strings.Fields(action)...)
+	cmd := exec.Command("ovs-ofctl", append(args, ipdef.QuiltBridge, "-")...)
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -264,3 +619,38 @@ var ofctl = func(action string, flows []string) error {
 
 	return nil
 }
+
+// diffFlows compares the installed flows with `flows`.
+var diffFlows = func(flows []string) (string, error) {
+	f, err := ioutil.TempFile("", "quilt-flows")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(strings.Join(flows, "\n") + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	out, err := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "diff-flows",
+		ipdef.QuiltBridge, f.Name()).Output()
+
+	// diff-flows exits with status 2 if the flows differ.
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok &&
+			status.ExitStatus() == 2 {
+			err = nil
+		}
+	}
+	return string(out), err
+}
+
+var dumpFlows = func() (string, error) {
+	out, err := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "dump-flows",
+		ipdef.QuiltBridge, "table=1").Output()
+	return string(out), err
+}
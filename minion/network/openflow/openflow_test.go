@@ -4,15 +4,15 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/ovsdb"
 	"github.com/quilt/quilt/minion/ovsdb/mocks"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestAddReplaceFlows(t *testing.T) {
+func TestAddFlows(t *testing.T) {
 	anErr := errors.New("err")
 	ovsdb.Open = func() (ovsdb.Client, error) { return nil, anErr }
-	assert.EqualError(t, ReplaceFlows(nil), "ovsdb-server connection: err")
 	assert.EqualError(t, AddFlows(nil), "ovsdb-server connection: err")
 
 	client := new(mocks.Client)
@@ -30,12 +30,6 @@ func TestAddReplaceFlows(t *testing.T) {
 
 	client.On("Disconnect").Return(nil)
 	client.On("OpenFlowPorts").Return(map[string]int{}, nil)
-	assert.NoError(t, ReplaceFlows(nil))
-	client.AssertCalled(t, "Disconnect")
-	client.AssertCalled(t, "OpenFlowPorts")
-	assert.Equal(t, "replace-flows", action)
-	assert.Equal(t, allFlows(nil), flows)
-
 	assert.NoError(t, AddFlows(nil))
 	client.AssertCalled(t, "Disconnect")
 	client.AssertCalled(t, "OpenFlowPorts")
@@ -44,13 +38,96 @@ func TestAddReplaceFlows(t *testing.T) {
 	assert.Equal(t, containerFlows(nil), flows)
 
 	ofctl = func(a string, f []string) error { return anErr }
-	assert.EqualError(t, ReplaceFlows(nil), "ovs-ofctl: err")
+	assert.EqualError(t, AddFlows(nil), "ovs-ofctl: err")
 	client.AssertCalled(t, "Disconnect")
 	client.AssertCalled(t, "OpenFlowPorts")
+}
 
-	assert.EqualError(t, AddFlows(nil), "ovs-ofctl: err")
+func TestUpdateFlows(t *testing.T) {
+	anErr := errors.New("err")
+	ovsdb.Open = func() (ovsdb.Client, error) { return nil, anErr }
+	_, err := UpdateFlows(nil)
+	assert.EqualError(t, err, "ovsdb-server connection: err")
+
+	client := new(mocks.Client)
+	ovsdb.Open = func() (ovsdb.Client, error) {
+		return client, nil
+	}
+	client.On("Disconnect").Return(nil)
+	client.On("OpenFlowPorts").Return(map[string]int{}, nil)
+
+	var desired []string
+	diff := ""
+	diffFlows = func(flows []string) (string, error) {
+		desired = flows
+		return diff, nil
+	}
+
+	actions := map[string][]string{}
+	ofctl = func(a string, f []string) error {
+		actions[a] = f
+		return nil
+	}
+
+	changes, err := UpdateFlows(nil)
+	assert.NoError(t, err)
 	client.AssertCalled(t, "Disconnect")
 	client.AssertCalled(t, "OpenFlowPorts")
+	assert.Equal(t, allFlows(nil), desired)
+	assert.Equal(t, FlowChanges{Total: len(desired)}, changes)
+	assert.Empty(t, actions)
+
+	diff = "-table=2 priority=1000,reg0=0x1 actions=drop\n" +
+		"+table=2 priority=1000,reg0=0x1 actions=LOCAL\n" +
+		"+priority=1000,in_port=3 actions=output:4\n" +
+		"-priority=1000,in_port=5 actions=output:6\n"
+	changes, err = UpdateFlows(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, FlowChanges{Added: 1, Modified: 1, Deleted: 1,
+		Total: len(desired)}, changes)
+	assert.Equal(t, map[string][]string{
+		"--strict del-flows": {"table=0 priority=1000,in_port=5"},
+		"add-flows": {
+			"table=2 priority=1000,reg0=0x1 actions=LOCAL",
+			"priority=1000,in_port=3 actions=output:4",
+		},
+	}, actions)
+
+	ofctl = func(a string, f []string) error { return anErr }
+	_, err = UpdateFlows(nil)
+	assert.EqualError(t, err, "ovs-ofctl: err")
+
+	// Adding fails even when there's nothing to delete.
+	diff = "+priority=1000,in_port=3 actions=output:4\n"
+	ofctl = func(a string, f []string) error {
+		if a == "add-flows" {
+			return anErr
+		}
+		return nil
+	}
+	_, err = UpdateFlows(nil)
+	assert.EqualError(t, err, "ovs-ofctl: err")
+
+	diffFlows = func(flows []string) (string, error) { return "", anErr }
+	_, err = UpdateFlows(nil)
+	assert.EqualError(t, err, "ovs-ofctl: err")
+}
+
+func TestPlanFlowChanges(t *testing.T) {
+	toAdd, toDel, changes := planFlowChanges("")
+	assert.Empty(t, toAdd)
+	assert.Empty(t, toDel)
+	assert.Equal(t, FlowChanges{}, changes)
+
+	toAdd, toDel, changes = planFlowChanges(
+		"-table=1 priority=5000,in_port=1 actions=drop\n" +
+			"-table=1 priority=5000,in_port=2 actions=drop\n" +
+			"+table=1 priority=5000,in_port=2 actions=output:3\n" +
+			"\n")
+	assert.Equal(t, []string{"table=1 priority=5000,in_port=2 actions=output:3"},
+		toAdd)
+	assert.Equal(t, []string{"table=1 priority=5000,in_port=1"}, toDel)
+	assert.Equal(t, FlowChanges{Modified: 1, Deleted: 1}, changes)
 }
 
 func TestAllFlows(t *testing.T) {
@@ -58,7 +135,7 @@ func TestAllFlows(t *testing.T) {
 	flows := allFlows([]container{
 		{patch: 4, veth: 5, mac: "66:66:66:66:66:66"},
 		{patch: 9, veth: 8, mac: "99:99:99:99:99:99"}})
-	exp := append(staticFlows,
+	exp := append(staticFlows(),
 		"table=0,priority=1000,in_port=5,dl_src=66:66:66:66:66:66,"+
 			"actions=load:0x1->NXM_NX_REG0[],load:0x5->NXM_NX_REG1[],"+
 			"load:0x4->NXM_NX_REG2[],resubmit(,1)",
@@ -77,11 +154,173 @@ func TestAllFlows(t *testing.T) {
 	assert.Equal(t, exp, flows)
 }
 
+func TestAllFlows6(t *testing.T) {
+	assert.NoError(t, ipdef.SetSubnets("172.16.0.0/12", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	flows := allFlows([]container{{patch: 4, veth: 5, mac: "66:66:66:66:66:66"}})
+	assert.Contains(t, flows, "table=1,priority=1000,reg0=0x1,"+
+		"dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00,"+
+		"actions=output:LOCAL,output:NXM_NX_REG2[]")
+	assert.Contains(t, flows, "table=1,priority=900,reg0=0x2,"+
+		"dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00,"+
+		"actions=output:NXM_NX_REG1[]")
+	assert.Contains(t, flows, "table=1,priority=850,"+
+		"dl_dst=33:33:00:00:00:00/ff:ff:00:00:00:00,actions=output:5")
+
+	// The gateway flows follow the configured subnet.
+	assert.Contains(t, flows, "table=1,priority=800,reg0=1,"+
+		"dl_dst=02:00:ac:10:00:01,actions=LOCAL")
+}
+
+func TestContainerFlowsSources(t *testing.T) {
+	t.Parallel()
+
+	flows := containerFlows([]container{{patch: 4, veth: 5, mac: "mac",
+		sources: map[string]int{"10.0.0.3": 2, "10.0.0.2": 0, "fd00::2": 1}}})
+	assert.Equal(t, []string{
+		"table=1,priority=450,in_port=4,ip,nw_src=10.0.0.2," +
+			"actions=output:5",
+		"table=1,priority=450,in_port=4,ip,nw_src=10.0.0.3," +
+			"actions=set_queue:2,output:5",
+		"table=1,priority=450,in_port=4,ipv6,ipv6_src=fd00::2," +
+			"actions=set_queue:1,output:5",
+	}, flows[3:])
+}
+
+func TestContainerFlowsPorts(t *testing.T) {
+	t.Parallel()
+
+	flows := containerFlows([]container{{patch: 4, veth: 5, mac: "mac",
+		sources: map[string]int{"10.0.0.2": 1, "fd00::2": 0},
+		ports: map[string][]PortRange{
+			"10.0.0.2": {{80, 80}},
+			"fd00::2":  {{8, 9}},
+		}}})
+	assert.Equal(t, []string{
+		"table=1,priority=451,in_port=4,tcp,nw_src=10.0.0.2,tp_dst=80," +
+			"actions=set_queue:1,output:5",
+		"table=1,priority=451,in_port=4,udp,nw_src=10.0.0.2,tp_dst=80," +
+			"actions=set_queue:1,output:5",
+		"table=1,priority=450,in_port=4,ip,nw_src=10.0.0.2," +
+			"actions=set_queue:1,output:5",
+		"table=1,priority=451,in_port=4,tcp6,ipv6_src=fd00::2," +
+			"tp_dst=0x8/0xfffe,actions=output:5",
+		"table=1,priority=451,in_port=4,udp6,ipv6_src=fd00::2," +
+			"tp_dst=0x8/0xfffe,actions=output:5",
+		"table=1,priority=450,in_port=4,ipv6,ipv6_src=fd00::2," +
+			"actions=output:5",
+	}, flows[3:])
+}
+
+func TestPortBlocks(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, portBlocks(nil))
+	assert.Equal(t, []portBlock{{80, 0xffff}}, portBlocks([]PortRange{{80, 80}}))
+	assert.Equal(t, []portBlock{{0, 0}}, portBlocks([]PortRange{{0, 65535}}))
+	assert.Equal(t, []portBlock{
+		{1, 0xffff}, {2, 0xfffe}, {4, 0xfffc}, {8, 0xfff8}, {16, 0xfff0},
+		{32, 0xffe0}, {64, 0xffc0}, {128, 0xff80}, {256, 0xff00},
+		{512, 0xfe00}, {1024, 0xfc00}, {2048, 0xf800}, {4096, 0xf000},
+		{8192, 0xe000}, {16384, 0xc000}, {32768, 0x8000},
+	}, portBlocks([]PortRange{{1, 65535}}))
+
+	// Overlapping ranges are split so that each block is within or outside of
+	// each range.
+	assert.Equal(t, []portBlock{{8, 0xfffc}, {12, 0xfffe}, {14, 0xffff},
+		{15, 0xffff}}, portBlocks([]PortRange{{8, 15}, {14, 15}, {8, 14}}))
+
+	// Ports between the ranges aren't counted.
+	assert.Equal(t, []portBlock{{80, 0xffff}, {443, 0xffff}},
+		portBlocks([]PortRange{{443, 443}, {80, 80}}))
+}
+
+func TestParsePortBlock(t *testing.T) {
+	t.Parallel()
+
+	block, err := parsePortBlock("80")
+	assert.NoError(t, err)
+	assert.Equal(t, portBlock{80, 0xffff}, block)
+	assert.Equal(t, "80", block.String())
+
+	block, err = parsePortBlock("0x400/0xfc00")
+	assert.NoError(t, err)
+	assert.Equal(t, portBlock{1024, 0xfc00}, block)
+	assert.Equal(t, "0x400/0xfc00", block.String())
+
+	_, err = parsePortBlock("junk")
+	assert.Error(t, err)
+	_, err = parsePortBlock("0x400/junk")
+	assert.Error(t, err)
+}
+
 func TestResolveContainers(t *testing.T) {
 	t.Parallel()
 
+	sources := map[string]int{"10.0.0.2": 1}
+	ports := map[string][]PortRange{"10.0.0.2": {{80, 80}}}
 	res := resolveContainers(map[string]int{"a": 3, "b": 4}, []Container{
-		{Veth: "a", Patch: "b", Mac: "mac"},
+		{Veth: "a", Patch: "b", Mac: "mac", Sources: sources, Ports: ports},
 		{Veth: "c", Patch: "d", Mac: "mac2"}})
-	assert.Equal(t, []container{{veth: 3, patch: 4, mac: "mac"}}, res)
+	assert.Equal(t, []container{{veth: 3, patch: 4, mac: "mac",
+		sources: sources, ports: ports}}, res)
+}
+
+func TestReadCounters(t *testing.T) {
+	anErr := errors.New("err")
+	ovsdb.Open = func() (ovsdb.Client, error) { return nil, anErr }
+	_, err := ReadCounters()
+	assert.EqualError(t, err, "ovsdb-server connection: err")
+
+	client := new(mocks.Client)
+	ovsdb.Open = func() (ovsdb.Client, error) {
+		return client, nil
+	}
+	client.On("Disconnect").Return(nil)
+	client.On("OpenFlowPorts").Return(map[string]int{"q_a": 4, "a": 5}, nil)
+
+	dumpFlows = func() (string, error) { return "", anErr }
+	_, err = ReadCounters()
+	assert.EqualError(t, err, "ovs-ofctl: err")
+
+	dumpFlows = func() (string, error) {
+		return "OFPST_FLOW reply (OF1.3) (xid=0x2):\n" +
+			" cookie=0x0, duration=5.1s, table=1, n_packets=3, " +
+			"n_bytes=300, priority=450,ip,in_port=4,nw_src=10.0.0.2 " +
+			"actions=output:5\n" +
+			" cookie=0x0, duration=5.1s, table=1, n_packets=1, " +
+			"n_bytes=80, priority=400,reg0=0x2 actions=output:NXM_NX_REG1[]\n", nil
+	}
+	counters, err := ReadCounters()
+	assert.NoError(t, err)
+	assert.Equal(t, []Counter{{Patch: "q_a", SrcIP: "10.0.0.2", Packets: 3,
+		Bytes: 300}}, counters)
+}
+
+func TestParseCounters(t *testing.T) {
+	t.Parallel()
+
+	flows := " cookie=0x0, duration=1s, table=1, n_packets=7, n_bytes=700, " +
+		"priority=450,ipv6,in_port=q_b,ipv6_src=fd00::2 " +
+		"actions=set_queue:1,output:6\n" +
+		" cookie=0x0, duration=1s, table=1, n_packets=bad, n_bytes=700, " +
+		"priority=450,ip,in_port=4,nw_src=10.0.0.2 actions=output:5\n" +
+		" cookie=0x0, duration=1s, table=1, n_packets=1, n_bytes=70, " +
+		"priority=450,ip,in_port=4 actions=output:5\n" +
+		" cookie=0x0, duration=1s, table=1, n_packets=2, n_bytes=200, " +
+		"priority=451,tcp,in_port=4,nw_src=10.0.0.2,tp_dst=80 " +
+		"actions=output:5\n" +
+		" cookie=0x0, duration=1s, table=1, n_packets=3, n_bytes=300, " +
+		"priority=451,udp6,in_port=4,ipv6_src=fd00::2,tp_dst=0x400/0xfc00 " +
+		"actions=output:5\n" +
+		" cookie=0x0, duration=1s, table=1, n_packets=3, n_bytes=300, " +
+		"priority=451,udp,in_port=4,nw_src=10.0.0.2 actions=output:5\n"
+	assert.Equal(t, []Counter{
+		{Patch: "q_b", SrcIP: "fd00::2", Packets: 7, Bytes: 700},
+		{Patch: "q_a", SrcIP: "10.0.0.2", MinPort: 80, MaxPort: 80,
+			Packets: 2, Bytes: 200},
+		{Patch: "q_a", SrcIP: "fd00::2", MinPort: 1024, MaxPort: 2047,
+			Packets: 3, Bytes: 300},
+	}, parseCounters(flows, map[string]string{"4": "q_a"}))
 }
@@ -0,0 +1,352 @@
+package openflow
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// LocalPort is the OpenFlow port number of the bridge's LOCAL port, i.e. the
+// gateway.
+const LocalPort = 0xfffe
+
+// A Packet is traced through the flows by a Simulator.
+type Packet struct {
+	InPort int
+	DlSrc  string
+	DlDst  string
+	IPSrc  string // Empty for non-IP packets, such as ARP.
+
+	Protocol string // "tcp", "udp", or empty for other packets.
+	TpDst    int    // The destination port of TCP and UDP packets.
+}
+
+// An Output is a port a traced packet was sent out of, and the queue it was
+// placed in.
+type Output struct {
+	Port  int
+	Queue int
+}
+
+// A Simulator traces packets through OpenFlow flows in-process, so that tests can
+// check what the flows do rather than how they're written.  It understands the
+// subset of `ovs-ofctl` flow syntax that this package generates.
+type Simulator struct {
+	tables map[int][]simFlow
+}
+
+type simFlow struct {
+	flow     string
+	priority int
+	match    []simMatch
+	actions  []string
+}
+
+type simMatch struct {
+	field, value string
+}
+
+type simState struct {
+	packet  Packet
+	regs    [3]uint64
+	queue   int
+	outputs []Output
+}
+
+// The limit on nested resubmits, which OVS also enforces to prevent loops.
+const maxResubmits = 64
+
+// NewSimulator parses `flows`, in the format accepted by `ovs-ofctl add-flows`.
+func NewSimulator(flows []string) (*Simulator, error) {
+	sim := &Simulator{tables: map[int][]simFlow{}}
+	for _, flow := range flows {
+		parts := strings.SplitN(flow, ",actions=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("flow without actions: %s", flow)
+		}
+
+		f := simFlow{flow: flow, actions: splitActions(parts[1])}
+		var table int
+		for _, field := range strings.Split(parts[0], ",") {
+			kv := strings.SplitN(field, "=", 2)
+			var err error
+			switch kv[0] {
+			case "table":
+				table, err = strconv.Atoi(kv[1])
+			case "priority":
+				f.priority, err = strconv.Atoi(kv[1])
+			case "ip", "ipv6", "tcp", "udp", "tcp6", "udp6":
+				f.match = append(f.match, simMatch{field: kv[0]})
+			case "in_port", "dl_src", "dl_dst", "reg0", "reg1", "reg2",
+				"nw_src", "ipv6_src", "tp_dst":
+				if len(kv) != 2 {
+					err = fmt.Errorf("missing value")
+				} else if kv[0] == "tp_dst" {
+					_, err = parsePortBlock(kv[1])
+				}
+				f.match = append(f.match, simMatch{kv[0], kv[len(kv)-1]})
+			default:
+				err = fmt.Errorf("unsupported match")
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("bad match %q in flow %s: %s",
+					field, flow, err)
+			}
+		}
+		sim.tables[table] = append(sim.tables[table], f)
+	}
+	return sim, nil
+}
+
+// SimulateContainers returns a Simulator for the flows UpdateFlows installs for
+// `containers`.  `ofports` maps the names of the containers' interfaces to their
+// OpenFlow port numbers.
+func SimulateContainers(ofports map[string]int, containers []Container) (
+	*Simulator, error) {
+	return NewSimulator(allFlows(resolveContainers(ofports, containers)))
+}
+
+// Trace returns where the flows send `packet`, in order.  Dropped packets have no
+// outputs.
+func (sim *Simulator) Trace(packet Packet) ([]Output, error) {
+	state := &simState{packet: packet}
+	if err := sim.runTable(state, 0, 0); err != nil {
+		return nil, err
+	}
+	return state.outputs, nil
+}
+
+func (sim *Simulator) runTable(state *simState, table, depth int) error {
+	if depth > maxResubmits {
+		return fmt.Errorf("too many resubmits")
+	}
+
+	var match *simFlow
+	for i, flow := range sim.tables[table] {
+		ok, err := flow.matches(state)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !ok:
+		case match == nil || flow.priority > match.priority:
+			match = &sim.tables[table][i]
+		case flow.priority == match.priority:
+			return fmt.Errorf("ambiguous flows in table %d: %s and %s",
+				table, match.flow, flow.flow)
+		}
+	}
+
+	// Packets that don't match any flow are dropped.
+	if match == nil {
+		return nil
+	}
+
+	for _, action := range match.actions {
+		drop, err := sim.runAction(state, action, depth)
+		if err != nil {
+			return fmt.Errorf("flow %s: %s", match.flow, err)
+		}
+
+		if drop {
+			break
+		}
+	}
+	return nil
+}
+
+func (sim *Simulator) runAction(state *simState, action string, depth int) (
+	drop bool, err error) {
+
+	switch {
+	case action == "drop":
+		return true, nil
+	case action == "LOCAL":
+		state.output(LocalPort)
+	case strings.HasPrefix(action, "output:"):
+		port, err := state.port(strings.TrimPrefix(action, "output:"))
+		if err != nil {
+			return false, err
+		}
+		state.output(port)
+	case strings.HasPrefix(action, "set_queue:"):
+		queue, err := strconv.Atoi(strings.TrimPrefix(action, "set_queue:"))
+		if err != nil {
+			return false, err
+		}
+		state.queue = queue
+	case strings.HasPrefix(action, "load:"):
+		parts := strings.SplitN(strings.TrimPrefix(action, "load:"), "->", 2)
+		if len(parts) != 2 {
+			return false, fmt.Errorf("bad load: %s", action)
+		}
+
+		reg, err := regIndex(parts[1])
+		if err != nil {
+			return false, err
+		}
+
+		value, err := strconv.ParseUint(parts[0], 0, 32)
+		if err != nil {
+			return false, err
+		}
+		state.regs[reg] = value
+	case strings.HasPrefix(action, "resubmit(,") && strings.HasSuffix(action, ")"):
+		table, err := strconv.Atoi(action[len("resubmit(,") : len(action)-1])
+		if err != nil {
+			return false, err
+		}
+		return false, sim.runTable(state, table, depth+1)
+	default:
+		return false, fmt.Errorf("unsupported action: %s", action)
+	}
+	return false, nil
+}
+
+func (flow simFlow) matches(state *simState) (bool, error) {
+	pkt := state.packet
+	isIPv6 := strings.Contains(pkt.IPSrc, ":")
+	for _, m := range flow.match {
+		var ok bool
+		switch m.field {
+		case "ip":
+			ok = pkt.IPSrc != "" && !isIPv6
+		case "ipv6":
+			ok = isIPv6
+		case "tcp", "udp":
+			ok = pkt.IPSrc != "" && !isIPv6 && pkt.Protocol == m.field
+		case "tcp6", "udp6":
+			ok = isIPv6 && pkt.Protocol == m.field[:3]
+		case "tp_dst":
+			block, err := parsePortBlock(m.value)
+			if err != nil {
+				return false, err
+			}
+			ok = pkt.Protocol != "" && pkt.TpDst&block.mask == block.value
+		case "nw_src", "ipv6_src":
+			ok = pkt.IPSrc == m.value
+		case "in_port":
+			port, err := state.port(m.value)
+			if err != nil {
+				return false, err
+			}
+			ok = pkt.InPort == port
+		case "reg0", "reg1", "reg2":
+			value, err := strconv.ParseUint(m.value, 0, 32)
+			if err != nil {
+				return false, err
+			}
+			ok = state.regs[m.field[3]-'0'] == value
+		case "dl_src":
+			var err error
+			if ok, err = macMatches(pkt.DlSrc, m.value); err != nil {
+				return false, err
+			}
+		case "dl_dst":
+			var err error
+			if ok, err = macMatches(pkt.DlDst, m.value); err != nil {
+				return false, err
+			}
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// port resolves a port in a match or output action, which may be read from a
+// register.
+func (state *simState) port(port string) (int, error) {
+	if port == "LOCAL" {
+		return LocalPort, nil
+	}
+
+	if strings.HasPrefix(port, "NXM_NX_REG") {
+		reg, err := regIndex(port)
+		if err != nil {
+			return 0, err
+		}
+		return int(state.regs[reg]), nil
+	}
+
+	return strconv.Atoi(port)
+}
+
+// output sends the packet out of `port`.  As in OVS, packets are never output to
+// the port they arrived on.
+func (state *simState) output(port int) {
+	if port == state.packet.InPort {
+		return
+	}
+	state.outputs = append(state.outputs, Output{Port: port, Queue: state.queue})
+}
+
+func regIndex(field string) (int, error) {
+	const prefix = "NXM_NX_REG"
+	if len(field) != len(prefix)+3 || !strings.HasPrefix(field, prefix) ||
+		!strings.HasSuffix(field, "[]") {
+		return 0, fmt.Errorf("unsupported field: %s", field)
+	}
+
+	reg := int(field[len(prefix)] - '0')
+	if reg < 0 || reg > 2 {
+		return 0, fmt.Errorf("unsupported register: %s", field)
+	}
+	return reg, nil
+}
+
+// macMatches reports whether `mac` matches `pattern`, which may be masked.
+func macMatches(mac, pattern string) (bool, error) {
+	parts := strings.SplitN(pattern, "/", 2)
+	value, err := net.ParseMAC(parts[0])
+	if err != nil {
+		return false, err
+	}
+
+	mask := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if len(parts) == 2 {
+		if mask, err = net.ParseMAC(parts[1]); err != nil {
+			return false, err
+		}
+	}
+
+	addr, err := net.ParseMAC(mac)
+	if err != nil || len(addr) != len(value) || len(mask) != len(value) {
+		return false, nil
+	}
+
+	for i := range value {
+		if addr[i]&mask[i] != value[i]&mask[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// splitActions splits a list of actions on the commas that aren't within
+// parentheses.
+func splitActions(actions string) []string {
+	var result []string
+	var depth int
+	var current bytes.Buffer
+	for _, r := range actions {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			result = append(result, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	return append(result, current.String())
+}
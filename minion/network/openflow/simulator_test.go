@@ -0,0 +1,155 @@
+package openflow
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	macA = "66:66:66:66:66:66"
+	macB = "99:99:99:99:99:99"
+)
+
+// Container A has veth 5 and patch port 4, container B has veth 8 and patch port 9.
+func testSimulator(t *testing.T) *Simulator {
+	sim, err := SimulateContainers(
+		map[string]int{"vethA": 5, "patchA": 4, "vethB": 8, "patchB": 9},
+		[]Container{
+			{Veth: "vethA", Patch: "patchA", Mac: macA},
+			{Veth: "vethB", Patch: "patchB", Mac: macB,
+				Sources: map[string]int{"10.0.0.2": 2, "10.0.0.3": 0},
+				Ports: map[string][]PortRange{
+					"10.0.0.2": {{80, 80}, {1000, 1999}}}},
+			{Veth: "missing", Patch: "missing", Mac: "00:00:00:00:00:01"},
+		})
+	assert.NoError(t, err)
+	return sim
+}
+
+func checkTrace(t *testing.T, sim *Simulator, pkt Packet, exp ...Output) {
+	outputs, err := sim.Trace(pkt)
+	assert.NoError(t, err)
+	assert.Equal(t, exp, outputs, "trace of %+v", pkt)
+}
+
+func TestSimulateVeth(t *testing.T) {
+	t.Parallel()
+	sim := testSimulator(t)
+
+	// Unicast from a veth goes to its patch port, for OVN to route.
+	checkTrace(t, sim, Packet{InPort: 5, DlSrc: macA, DlDst: macB,
+		IPSrc: "10.0.0.2"}, Output{Port: 4})
+
+	// ARP requests and other broadcasts go to the gateway and the patch port.
+	checkTrace(t, sim, Packet{InPort: 5, DlSrc: macA, DlDst: broadcastMac},
+		Output{Port: LocalPort}, Output{Port: 4})
+
+	// Traffic to the gateway, such as ARP replies, only goes to the gateway.
+	checkTrace(t, sim, Packet{InPort: 5, DlSrc: macA, DlDst: ipdef.GatewayMac},
+		Output{Port: LocalPort})
+
+	// Containers can't spoof their MAC.
+	checkTrace(t, sim, Packet{InPort: 5, DlSrc: macB, DlDst: macA})
+}
+
+func TestSimulatePatch(t *testing.T) {
+	t.Parallel()
+	sim := testSimulator(t)
+
+	// Traffic from the patch port goes to the veth, and is placed in the queue
+	// of its source if there is one.
+	checkTrace(t, sim, Packet{InPort: 9, DlDst: macB, IPSrc: "10.0.0.2"},
+		Output{Port: 8, Queue: 2})
+	checkTrace(t, sim, Packet{InPort: 9, DlDst: macB, IPSrc: "10.0.0.3"},
+		Output{Port: 8})
+	checkTrace(t, sim, Packet{InPort: 9, DlDst: macB, IPSrc: "10.0.0.4"},
+		Output{Port: 8})
+	checkTrace(t, sim, Packet{InPort: 9, DlDst: macB}, Output{Port: 8})
+
+	// Traffic to the ports of a connection is counted by its own flows, which
+	// treat it like the source's other traffic.
+	for _, port := range []int{80, 81, 1500} {
+		for _, proto := range []string{"tcp", "udp"} {
+			pkt := Packet{InPort: 9, DlDst: macB, IPSrc: "10.0.0.2",
+				Protocol: proto, TpDst: port}
+			checkTrace(t, sim, pkt, Output{Port: 8, Queue: 2})
+		}
+	}
+
+	// Broadcasts from the patch port only go to the veth.
+	checkTrace(t, sim, Packet{InPort: 4, DlDst: broadcastMac}, Output{Port: 5})
+
+	// Only veths may send to the gateway.
+	checkTrace(t, sim, Packet{InPort: 4, DlDst: ipdef.GatewayMac})
+}
+
+func TestSimulateGateway(t *testing.T) {
+	t.Parallel()
+	sim := testSimulator(t)
+
+	// Gateway broadcasts go to every veth.
+	checkTrace(t, sim, Packet{InPort: LocalPort, DlDst: broadcastMac},
+		Output{Port: 5}, Output{Port: 8})
+
+	// Gateway unicast is forwarded by destination MAC.
+	checkTrace(t, sim, Packet{InPort: LocalPort, DlDst: macB}, Output{Port: 8})
+	checkTrace(t, sim, Packet{InPort: LocalPort, DlDst: "00:00:00:00:00:01"})
+
+	// Packets from unknown ports are dropped.
+	checkTrace(t, sim, Packet{InPort: 7, DlDst: macB})
+}
+
+func TestSimulateIPv6(t *testing.T) {
+	assert.NoError(t, ipdef.SetSubnets("", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	sim := testSimulator(t)
+
+	// Neighbor discovery multicasts are treated like broadcasts.
+	checkTrace(t, sim, Packet{InPort: 5, DlSrc: macA, DlDst: "33:33:ff:00:00:02"},
+		Output{Port: LocalPort}, Output{Port: 4})
+	checkTrace(t, sim, Packet{InPort: LocalPort, DlDst: "33:33:00:00:00:01"},
+		Output{Port: 5}, Output{Port: 8})
+	checkTrace(t, sim, Packet{InPort: 9, DlDst: macB, IPSrc: "fd00::2"},
+		Output{Port: 8})
+}
+
+func TestSimulatorErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, flows := range [][]string{
+		{"table=0,priority=1"},
+		{"table=x,actions=drop"},
+		{"table=0,sctp,actions=drop"},
+		{"table=0,tcp,tp_dst=x,actions=drop"},
+		{"table=0,in_port,actions=drop"},
+	} {
+		_, err := NewSimulator(flows)
+		assert.Error(t, err, "%v", flows)
+	}
+
+	for _, flows := range [][]string{
+		{"table=0,priority=1,actions=drop", "table=0,priority=1,actions=LOCAL"},
+		{"table=0,priority=1,actions=mod_vlan_vid:3"},
+		{"table=0,priority=1,actions=output:NXM_NX_REG9[]"},
+		{"table=0,priority=1,actions=load:0x1->NXM_NX_REG0"},
+		{"table=0,priority=1,actions=resubmit(,0)"},
+		{"table=0,priority=1,dl_src=bad,actions=drop"},
+	} {
+		sim, err := NewSimulator(flows)
+		assert.NoError(t, err)
+
+		_, err = sim.Trace(Packet{InPort: 1, DlSrc: macA})
+		assert.Error(t, err, "%v", flows)
+	}
+}
+
+func TestSplitActions(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"load:0x1->NXM_NX_REG0[]", "resubmit(,1)"},
+		splitActions("load:0x1->NXM_NX_REG0[],resubmit(,1)"))
+	assert.Equal(t, []string{"drop"}, splitActions("drop"))
+}
@@ -31,8 +31,6 @@ var (
 
 type driver struct{}
 
-const mtu int = 1400
-
 // Run runs the network driver and starts the server to listen for requests. It will
 // block until the server socket has been created.
 func Run() {
@@ -80,7 +78,7 @@ func (d driver) CreateEndpoint(req *dnet.CreateEndpointRequest) (
 	outer := ipdef.IFName(req.EndpointID)
 	inner := ipdef.IFName("tmp_" + req.EndpointID)
 	err = linkAdd(&netlink.Veth{
-		LinkAttrs: netlink.LinkAttrs{Name: outer, MTU: mtu},
+		LinkAttrs: netlink.LinkAttrs{Name: outer, MTU: ipdef.MTU()},
 		PeerName:  inner})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create veth: %s", err)
@@ -160,6 +158,9 @@ func (d driver) Join(req *dnet.JoinRequest) (*dnet.JoinResponse, error) {
 	inner := ipdef.IFName("tmp_" + req.EndpointID)
 	resp := &dnet.JoinResponse{}
 	resp.Gateway = ipdef.GatewayIP.String()
+	if ipdef.GatewayIP6 != nil {
+		resp.GatewayIPv6 = ipdef.GatewayIP6.String()
+	}
 	resp.InterfaceName = dnet.InterfaceName{SrcName: inner, DstPrefix: ifacePrefix}
 	return resp, nil
 }
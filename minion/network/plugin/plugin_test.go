@@ -209,6 +209,17 @@ func TestJoin(t *testing.T) {
 		Gateway: "10.0.0.1"}, resp)
 }
 
+func TestJoin6(t *testing.T) {
+	assert.NoError(t, ipdef.SetSubnets("", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	d := driver{}
+	resp, err := d.Join(&dnet.JoinRequest{EndpointID: zero})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", resp.Gateway)
+	assert.Equal(t, "fd00::1", resp.GatewayIPv6)
+}
+
 func TestLeave(t *testing.T) {
 	setup()
 
@@ -0,0 +1,201 @@
+package network
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/quilt/quilt/minion/ovsdb"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var readCounters = openflow.ReadCounters
+
+var readInterfaceStats = func() (map[string]ovsdb.InterfaceStats, error) {
+	odb, err := ovsdb.Open()
+	if err != nil {
+		return nil, fmt.Errorf("ovsdb-server connection: %s", err)
+	}
+	defer odb.Disconnect()
+
+	return odb.InterfaceStats()
+}
+
+// runTraffic periodically totals the OpenFlow counters of the local containers into
+// the Traffic table, and their interface statistics into the PortTraffic table, so
+// that etcd can report them to the leader.
+func runTraffic(conn db.Conn) {
+	for range time.Tick(10 * time.Second) {
+		minion, err := conn.MinionSelf()
+		if err != nil || !minion.SupervisorInit || minion.Role != db.Worker {
+			continue
+		}
+
+		counters, err := readCounters()
+		if err != nil {
+			log.WithError(err).Warning("Failed to read OpenFlow counters.")
+			continue
+		}
+
+		stats, err := readInterfaceStats()
+		if err != nil {
+			log.WithError(err).Warning(
+				"Failed to read interface statistics.")
+			continue
+		}
+
+		conn.Txn(db.ContainerTable, db.ConnectionTable, db.TrafficTable,
+			db.PortTrafficTable).Run(func(view db.Database) error {
+			updateTraffic(view, minion.PrivateIP, counters)
+			updatePortTraffic(view, minion.PrivateIP, stats)
+			return nil
+		})
+	}
+}
+
+func updateTraffic(view db.Database, myIP string, counters []openflow.Counter) {
+	patchLabels := map[string][]string{}
+	ipLabels := map[string][]string{}
+	for _, dbc := range view.SelectFromContainer(nil) {
+		if dbc.IP == "" {
+			continue
+		}
+		ipLabels[dbc.IP] = dbc.Labels
+
+		if dbc.Minion == myIP && dbc.EndpointID != "" {
+			_, patch := ipdef.PatchPorts(dbc.EndpointID)
+			patchLabels[patch] = dbc.Labels
+		}
+	}
+
+	labelConns := map[[2]string][]db.Connection{}
+	for _, conn := range view.SelectFromConnection(nil) {
+		pair := [2]string{conn.From, conn.To}
+		labelConns[pair] = append(labelConns[pair], conn)
+	}
+
+	totals := map[db.Traffic]db.Traffic{}
+	for _, counter := range counters {
+		// Only TCP and UDP traffic to the ports of a connection is counted.
+		// The counters' port blocks never straddle the bounds of a
+		// connection's range, so each is either within it or outside of it.
+		if counter.MaxPort == 0 {
+			continue
+		}
+
+		for _, to := range patchLabels[counter.Patch] {
+			for _, from := range ipLabels[counter.SrcIP] {
+				for _, conn := range labelConns[[2]string{from, to}] {
+					if counter.MinPort < conn.MinPort ||
+						counter.MaxPort > conn.MaxPort {
+						continue
+					}
+
+					key := db.Traffic{From: from, To: to,
+						MinPort: conn.MinPort,
+						MaxPort: conn.MaxPort}
+					total, ok := totals[key]
+					if !ok {
+						total = key
+					}
+					total.Packets += counter.Packets
+					total.Bytes += counter.Bytes
+					totals[key] = total
+				}
+			}
+		}
+	}
+
+	var traffic []db.Traffic
+	for _, t := range totals {
+		traffic = append(traffic, t)
+	}
+
+	key := func(iface interface{}) interface{} {
+		return trafficKey(iface.(db.Traffic))
+	}
+
+	pairs, dbTraffic, newTraffic := join.HashJoin(
+		db.TrafficSlice(view.SelectFromTraffic(nil)), db.TrafficSlice(traffic),
+		key, key)
+
+	for _, t := range dbTraffic {
+		view.Remove(t.(db.Traffic))
+	}
+
+	for _, t := range newTraffic {
+		pairs = append(pairs, join.Pair{L: view.InsertTraffic(), R: t})
+	}
+
+	for _, pair := range pairs {
+		dbt := pair.L.(db.Traffic)
+		t := pair.R.(db.Traffic)
+		t.ID = dbt.ID
+		if dbt != t {
+			view.Commit(t)
+		}
+	}
+}
+
+// trafficKey identifies the connection whose traffic `t` counts.
+func trafficKey(t db.Traffic) db.Traffic {
+	return db.Traffic{From: t.From, To: t.To, MinPort: t.MinPort,
+		MaxPort: t.MaxPort}
+}
+
+func updatePortTraffic(view db.Database, myIP string,
+	stats map[string]ovsdb.InterfaceStats) {
+
+	var ports []db.PortTraffic
+	for _, dbc := range view.SelectFromContainer(nil) {
+		if dbc.Minion != myIP || dbc.EndpointID == "" {
+			continue
+		}
+
+		veth := ipdef.IFName(dbc.EndpointID)
+		s, ok := stats[veth]
+		if !ok {
+			continue
+		}
+
+		// OVS receives what the container transmits, and vice versa.
+		ports = append(ports, db.PortTraffic{
+			StitchID:  dbc.StitchID,
+			Minion:    myIP,
+			Port:      veth,
+			RxPackets: s.TxPackets,
+			RxBytes:   s.TxBytes,
+			TxPackets: s.RxPackets,
+			TxBytes:   s.RxBytes,
+		})
+	}
+
+	key := func(iface interface{}) interface{} {
+		return iface.(db.PortTraffic).StitchID
+	}
+
+	pairs, dbPorts, newPorts := join.HashJoin(
+		db.PortTrafficSlice(view.SelectFromPortTraffic(nil)),
+		db.PortTrafficSlice(ports), key, key)
+
+	for _, p := range dbPorts {
+		view.Remove(p.(db.PortTraffic))
+	}
+
+	for _, p := range newPorts {
+		pairs = append(pairs, join.Pair{L: view.InsertPortTraffic(), R: p})
+	}
+
+	for _, pair := range pairs {
+		dbp := pair.L.(db.PortTraffic)
+		p := pair.R.(db.PortTraffic)
+		p.ID = dbp.ID
+		if dbp != p {
+			view.Commit(p)
+		}
+	}
+}
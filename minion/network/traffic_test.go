@@ -0,0 +1,152 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/quilt/quilt/minion/ovsdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateTraffic(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	_, patchA := ipdef.PatchPorts("a")
+	_, patchB := ipdef.PatchPorts("b")
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range []db.Container{
+			{IP: "10.0.0.1", Minion: "1.1.1.1", EndpointID: "a",
+				Labels: []string{"web"}},
+			{IP: "10.0.0.2", Minion: "1.1.1.1", EndpointID: "b",
+				Labels: []string{"db", "cache"}},
+			{IP: "10.0.0.3", Minion: "2.2.2.2", EndpointID: "c",
+				Labels: []string{"web"}},
+		} {
+			c := view.InsertContainer()
+			dbc.ID = c.ID
+			view.Commit(dbc)
+		}
+
+		for _, c := range []db.Connection{
+			{From: "web", To: "db", MinPort: 5432, MaxPort: 5432},
+			{From: "web", To: "db", MinPort: 8000, MaxPort: 8999},
+			{From: "web", To: "cache", MinPort: 6379, MaxPort: 6379},
+			{From: "db", To: "web", MinPort: 80, MaxPort: 80},
+		} {
+			dbConn := view.InsertConnection()
+			c.ID = dbConn.ID
+			view.Commit(c)
+		}
+
+		stale := view.InsertTraffic()
+		stale.From = "old"
+		stale.To = "gone"
+		view.Commit(stale)
+		return nil
+	})
+
+	counters := []openflow.Counter{
+		{Patch: patchB, SrcIP: "10.0.0.1", MinPort: 5432, MaxPort: 5432,
+			Packets: 1, Bytes: 10},
+		{Patch: patchB, SrcIP: "10.0.0.3", MinPort: 5432, MaxPort: 5432,
+			Packets: 2, Bytes: 20},
+		{Patch: patchB, SrcIP: "10.0.0.1", MinPort: 8192, MaxPort: 8703,
+			Packets: 3, Bytes: 30},
+		{Patch: patchB, SrcIP: "10.0.0.1", MinPort: 6379, MaxPort: 6379,
+			Packets: 5, Bytes: 50},
+		{Patch: patchA, SrcIP: "10.0.0.2", MinPort: 80, MaxPort: 80,
+			Packets: 4, Bytes: 40},
+
+		// Traffic to no connection's ports isn't counted.
+		{Patch: patchA, SrcIP: "10.0.0.2", Packets: 6, Bytes: 60},
+		{Patch: patchA, SrcIP: "10.0.0.3", MinPort: 80, MaxPort: 80,
+			Packets: 8, Bytes: 80},
+		{Patch: "unknown", SrcIP: "10.0.0.1", MinPort: 5432, MaxPort: 5432,
+			Packets: 16, Bytes: 160},
+	}
+
+	update := func() []db.Traffic {
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			updateTraffic(view, "1.1.1.1", counters)
+			return nil
+		})
+
+		traffic := conn.SelectFromTraffic(nil)
+		for i := range traffic {
+			traffic[i].ID = 0
+		}
+		return traffic
+	}
+
+	traffic := update()
+	assert.Len(t, traffic, 4)
+	assert.Contains(t, traffic, db.Traffic{From: "web", To: "db",
+		MinPort: 5432, MaxPort: 5432, Packets: 3, Bytes: 30})
+	assert.Contains(t, traffic, db.Traffic{From: "web", To: "db",
+		MinPort: 8000, MaxPort: 8999, Packets: 3, Bytes: 30})
+	assert.Contains(t, traffic, db.Traffic{From: "web", To: "cache",
+		MinPort: 6379, MaxPort: 6379, Packets: 5, Bytes: 50})
+	assert.Contains(t, traffic, db.Traffic{From: "db", To: "web",
+		MinPort: 80, MaxPort: 80, Packets: 4, Bytes: 40})
+
+	counters[0].Packets = 5
+	traffic = update()
+	assert.Len(t, traffic, 4)
+	assert.Contains(t, traffic, db.Traffic{From: "web", To: "db",
+		MinPort: 5432, MaxPort: 5432, Packets: 7, Bytes: 30})
+}
+
+func TestUpdatePortTraffic(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range []db.Container{
+			{StitchID: "1", Minion: "1.1.1.1", EndpointID: "a"},
+			{StitchID: "2", Minion: "1.1.1.1", EndpointID: "b"},
+			{StitchID: "3", Minion: "1.1.1.1"},
+			{StitchID: "4", Minion: "2.2.2.2", EndpointID: "d"},
+		} {
+			c := view.InsertContainer()
+			dbc.ID = c.ID
+			view.Commit(dbc)
+		}
+
+		stale := view.InsertPortTraffic()
+		stale.StitchID = "5"
+		view.Commit(stale)
+		return nil
+	})
+
+	stats := map[string]ovsdb.InterfaceStats{
+		ipdef.IFName("a"): {RxPackets: 1, RxBytes: 10,
+			TxPackets: 2, TxBytes: 20},
+		ipdef.IFName("d"): {RxPackets: 3, RxBytes: 30},
+	}
+
+	update := func() []db.PortTraffic {
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			updatePortTraffic(view, "1.1.1.1", stats)
+			return nil
+		})
+
+		ports := conn.SelectFromPortTraffic(nil)
+		for i := range ports {
+			ports[i].ID = 0
+		}
+		return ports
+	}
+
+	exp := db.PortTraffic{StitchID: "1", Minion: "1.1.1.1",
+		Port: ipdef.IFName("a"), RxPackets: 2, RxBytes: 20, TxPackets: 1,
+		TxBytes: 10}
+	assert.Equal(t, []db.PortTraffic{exp}, update())
+
+	stats[ipdef.IFName("a")] = ovsdb.InterfaceStats{RxPackets: 5}
+	exp.RxPackets, exp.RxBytes, exp.TxPackets, exp.TxBytes = 0, 0, 5, 0
+	assert.Equal(t, []db.PortTraffic{exp}, update())
+}
@@ -36,13 +36,13 @@ func (_m *Client) CreateAddressSet(name string, addresses []string) error {
 	return r0
 }
 
-// CreateLogicalPort provides a mock function with given fields: lswitch, name, mac, ip
-func (_m *Client) CreateLogicalPort(lswitch string, name string, mac string, ip string) error {
-	ret := _m.Called(lswitch, name, mac, ip)
+// CreateLogicalPort provides a mock function with given fields: lswitch, name, mac, ips
+func (_m *Client) CreateLogicalPort(lswitch string, name string, mac string, ips []string) error {
+	ret := _m.Called(lswitch, name, mac, ips)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, string, string) error); ok {
-		r0 = rf(lswitch, name, mac, ip)
+	if rf, ok := ret.Get(0).(func(string, string, string, []string) error); ok {
+		r0 = rf(lswitch, name, mac, ips)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -180,6 +180,29 @@ func (_m *Client) ListLogicalPorts() ([]ovsdb.LPort, error) {
 	return r0, r1
 }
 
+// ListQoS provides a mock function with given fields:
+func (_m *Client) ListQoS() ([]ovsdb.QoS, error) {
+	ret := _m.Called()
+
+	var r0 []ovsdb.QoS
+	if rf, ok := ret.Get(0).(func() []ovsdb.QoS); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ovsdb.QoS)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // OpenFlowPorts provides a mock function with given fields:
 func (_m *Client) OpenFlowPorts() (map[string]int, error) {
 	ret := _m.Called()
@@ -203,4 +226,55 @@ func (_m *Client) OpenFlowPorts() (map[string]int, error) {
 	return r0, r1
 }
 
+// InterfaceStats provides a mock function with given fields:
+func (_m *Client) InterfaceStats() (map[string]ovsdb.InterfaceStats, error) {
+	ret := _m.Called()
+
+	var r0 map[string]ovsdb.InterfaceStats
+	if rf, ok := ret.Get(0).(func() map[string]ovsdb.InterfaceStats); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]ovsdb.InterfaceStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetLogicalPortAddresses provides a mock function with given fields: lport, mac, ips
+func (_m *Client) SetLogicalPortAddresses(lport ovsdb.LPort, mac string, ips []string) error {
+	ret := _m.Called(lport, mac, ips)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(ovsdb.LPort, string, []string) error); ok {
+		r0 = rf(lport, mac, ips)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetQoS provides a mock function with given fields: qos
+func (_m *Client) SetQoS(qos ovsdb.QoS) error {
+	ret := _m.Called(qos)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(ovsdb.QoS) error); ok {
+		r0 = rf(qos)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 var _ ovsdb.Client = (*Client)(nil)
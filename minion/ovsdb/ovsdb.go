@@ -7,6 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quilt/quilt/minion/ipdef"
 
 	ovs "github.com/socketplane/libovsdb"
 )
@@ -20,7 +25,8 @@ type transact interface {
 type Client interface {
 	CreateLogicalSwitch(lswitch string) error
 	ListLogicalPorts() ([]LPort, error)
-	CreateLogicalPort(lswitch, name, mac, ip string) error
+	CreateLogicalPort(lswitch, name, mac string, ips []string) error
+	SetLogicalPortAddresses(lport LPort, mac string, ips []string) error
 	DeleteLogicalPort(lswitch string, lport LPort) error
 	ListACLs() ([]ACL, error)
 	CreateACL(lswitch, direction string, priority int, match, action string) error
@@ -29,6 +35,9 @@ type Client interface {
 	CreateAddressSet(name string, addresses []string) error
 	DeleteAddressSet(name string) error
 	OpenFlowPorts() (map[string]int, error)
+	InterfaceStats() (map[string]InterfaceStats, error)
+	ListQoS() ([]QoS, error)
+	SetQoS(qos QoS) error
 	Disconnect()
 }
 
@@ -141,9 +150,10 @@ func (ovsdb client) ListLogicalPorts() ([]LPort, error) {
 	return result, nil
 }
 
-// CreateLogicalPort creates a new logical port in OVN.
-func (ovsdb client) CreateLogicalPort(lswitch, name, mac, ip string) error {
-	addrs := newOvsSet([]string{fmt.Sprintf("%s %s", mac, ip)})
+// CreateLogicalPort creates a new logical port in OVN.  Dual stack ports have both an
+// IPv4 and IPv6 address in `ips`.
+func (ovsdb client) CreateLogicalPort(lswitch, name, mac string, ips []string) error {
+	addrs := newOvsSet(LPortAddresses(mac, ips))
 
 	port := map[string]interface{}{"name": name, "addresses": addrs}
 
@@ -172,6 +182,34 @@ func (ovsdb client) CreateLogicalPort(lswitch, name, mac, ip string) error {
 	return errorCheck(results, 2)
 }
 
+// SetLogicalPortAddresses replaces the addresses of `lport`, such as when a port
+// created before the cluster was dual stack is assigned an IPv6 address.
+func (ovsdb client) SetLogicalPortAddresses(lport LPort, mac string,
+	ips []string) error {
+
+	updateOp := ovs.Operation{
+		Op:    "update",
+		Table: "Logical_Switch_Port",
+		Row: map[string]interface{}{
+			"addresses": newOvsSet(LPortAddresses(mac, ips)),
+		},
+		Where: newCondition("_uuid", "==", lport.uuid),
+	}
+
+	results, err := ovsdb.Transact("OVN_Northbound", updateOp)
+	if err != nil {
+		return fmt.Errorf("transaction error: updating lport %s: %s",
+			lport.Name, err)
+	}
+	return errorCheck(results, 1)
+}
+
+// LPortAddresses returns the addresses of a logical port with `mac` and `ips`, as
+// they're listed by ListLogicalPorts.
+func LPortAddresses(mac string, ips []string) []string {
+	return []string{strings.Join(append([]string{mac}, ips...), " ")}
+}
+
 // DeleteLogicalPort removes a logical port from OVN.
 func (ovsdb client) DeleteLogicalPort(lswitch string, lport LPort) error {
 	deleteOp := ovs.Operation{
@@ -392,6 +430,223 @@ func (ovsdb client) OpenFlowPorts() (map[string]int, error) {
 	return ifaceMap, nil
 }
 
+// InterfaceStats are the counters OVS keeps for an interface.  They're from OVS's
+// point of view, so the packets OVS receives from a container's veth are those the
+// container sent.
+type InterfaceStats struct {
+	RxPackets uint64
+	RxBytes   uint64
+	TxPackets uint64
+	TxBytes   uint64
+}
+
+// InterfaceStats returns the statistics of each interface attached to OVS, by name.
+func (ovsdb client) InterfaceStats() (map[string]InterfaceStats, error) {
+	reply, err := ovsdb.Transact("Open_vSwitch", ovs.Operation{
+		Op:      "select",
+		Table:   "Interface",
+		Where:   noCondition,
+		Columns: []string{"name", "statistics"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("select interface error: %s", err)
+	}
+
+	result := map[string]InterfaceStats{}
+	for _, iface := range reply[0].Rows {
+		name, ok := iface["name"].(string)
+		if !ok {
+			continue
+		}
+
+		counter := func(stats map[interface{}]interface{}, key string) uint64 {
+			value, _ := stats[key].(float64)
+			return uint64(value)
+		}
+
+		stats := ovsMap(iface["statistics"])
+		result[name] = InterfaceStats{
+			RxPackets: counter(stats, "rx_packets"),
+			RxBytes:   counter(stats, "rx_bytes"),
+			TxPackets: counter(stats, "tx_packets"),
+			TxBytes:   counter(stats, "tx_bytes"),
+		}
+	}
+	return result, nil
+}
+
+// QoS is the bandwidth configuration of a port attached to OVS.  Rates are in kbit/s.
+type QoS struct {
+	Port string
+
+	// IngressRate polices the traffic OVS receives from the port, or is 0 if the
+	// port is unlimited.
+	IngressRate int
+
+	// QueueRates limits the queues of traffic OVS sends to the port.  Queue i+1 is
+	// limited to QueueRates[i], while queue 0 is always unlimited.
+	QueueRates []int
+}
+
+// The external ID marking the QoS and Queue rows created by SetQoS.
+const qosPortKey = "quilt-port"
+
+// ListQoS returns the configuration of every port that is either policed, or has
+// queues created by SetQoS.
+func (ovsdb client) ListQoS() ([]QoS, error) {
+	selectOp := func(table string) ovs.Operation {
+		return ovs.Operation{Op: "select", Table: table, Where: noCondition}
+	}
+
+	reply, err := ovsdb.Transact("Open_vSwitch", selectOp("Interface"),
+		selectOp("QoS"), selectOp("Queue"))
+	if err != nil {
+		return nil, fmt.Errorf("transaction error: listing qos: %s", err)
+	}
+
+	if err := errorCheck(reply, 3); err != nil {
+		return nil, err
+	}
+
+	qosMap := map[string]*QoS{}
+	getQoS := func(port string) *QoS {
+		if _, ok := qosMap[port]; !ok {
+			qosMap[port] = &QoS{Port: port}
+		}
+		return qosMap[port]
+	}
+
+	for _, iface := range reply[0].Rows {
+		name, _ := iface["name"].(string)
+		rate, _ := iface["ingress_policing_rate"].(float64)
+		if name != "" && rate > 0 {
+			getQoS(name).IngressRate = int(rate)
+		}
+	}
+
+	queueRates := map[string]int{}
+	for _, queue := range reply[2].Rows {
+		config := ovsStringMap(queue["other_config"])
+		rate, _ := strconv.Atoi(config["max-rate"])
+		queueRates[ovsUUIDFromRow(queue).GoUUID] = rate / 1000
+	}
+
+	for _, qos := range reply[1].Rows {
+		port := ovsStringMap(qos["external_ids"])[qosPortKey]
+		if port == "" {
+			continue
+		}
+
+		var ids []int
+		uuids := map[int]string{}
+		for key, val := range ovsMap(qos["queues"]) {
+			id, _ := key.(float64)
+			uuid, _ := val.([]interface{})
+			if len(uuid) == 2 {
+				ids = append(ids, int(id))
+				uuids[int(id)], _ = uuid[1].(string)
+			}
+		}
+		sort.Ints(ids)
+
+		q := getQoS(port)
+		for _, id := range ids {
+			q.QueueRates = append(q.QueueRates, queueRates[uuids[id]])
+		}
+	}
+
+	var result []QoS
+	for _, q := range qosMap {
+		result = append(result, *q)
+	}
+	return result, nil
+}
+
+// The Ethernet header, which the policer counts as part of each packet.
+const ethHeaderLen = 14
+
+// policingBurst returns how many kilobits traffic policed at `rate` kbps may burst
+// by: a tenth of a second's worth, but at least one packet of the container
+// network's MTU, as the policer drops every packet larger than its burst.
+func policingBurst(rate int) int {
+	if rate == 0 {
+		return 0
+	}
+
+	burst := rate / 10
+	if min := ((ipdef.MTU()+ethHeaderLen)*8 + 999) / 1000; burst < min {
+		burst = min
+	}
+	return burst
+}
+
+// SetQoS replaces the bandwidth configuration of `qos.Port`.
+func (ovsdb client) SetQoS(qos QoS) error {
+	externalIDs := newOvsMap(map[string]string{qosPortKey: qos.Port})
+	ops := []ovs.Operation{{
+		Op:    "update",
+		Table: "Interface",
+		Row: map[string]interface{}{
+			"ingress_policing_rate":  qos.IngressRate,
+			"ingress_policing_burst": policingBurst(qos.IngressRate),
+		},
+		Where: newCondition("name", "==", qos.Port),
+	}, {
+		Op:    "delete",
+		Table: "QoS",
+		Where: newCondition("external_ids", "includes", externalIDs),
+	}, {
+		Op:    "delete",
+		Table: "Queue",
+		Where: newCondition("external_ids", "includes", externalIDs),
+	}}
+
+	portQoS := interface{}(newOvsSet([]ovs.UUID{}))
+	if len(qos.QueueRates) > 0 {
+		queues := map[int]ovs.UUID{}
+		for i, rate := range qos.QueueRates {
+			uuid := ovs.UUID{GoUUID: fmt.Sprintf("qqueue%d", i+1)}
+			queues[i+1] = uuid
+			ops = append(ops, ovs.Operation{
+				Op:    "insert",
+				Table: "Queue",
+				Row: map[string]interface{}{
+					"other_config": newOvsMap(map[string]string{
+						"max-rate": strconv.Itoa(rate * 1000)}),
+					"external_ids": externalIDs,
+				},
+				UUIDName: uuid.GoUUID,
+			})
+		}
+
+		ops = append(ops, ovs.Operation{
+			Op:    "insert",
+			Table: "QoS",
+			Row: map[string]interface{}{
+				"type":         "linux-htb",
+				"queues":       newOvsMap(queues),
+				"external_ids": externalIDs,
+			},
+			UUIDName: "qqos",
+		})
+		portQoS = ovs.UUID{GoUUID: "qqos"}
+	}
+
+	ops = append(ops, ovs.Operation{
+		Op:    "update",
+		Table: "Port",
+		Row:   map[string]interface{}{"qos": portQoS},
+		Where: newCondition("name", "==", qos.Port),
+	})
+
+	results, err := ovsdb.Transact("Open_vSwitch", ops...)
+	if err != nil {
+		return fmt.Errorf("transaction error: setting qos on %s: %s",
+			qos.Port, err)
+	}
+	return errorCheck(results, len(ops))
+}
+
 // This does not cover all cases, they should just be added as needed
 func newMutation(column, mutator string, value interface{}) mutation {
 	switch typedValue := value.(type) {
@@ -417,6 +672,34 @@ func ovsStringSetToSlice(oSet interface{}) []string {
 	return ret
 }
 
+func ovsMap(oMap interface{}) map[interface{}]interface{} {
+	ret := map[interface{}]interface{}{}
+	t, ok := oMap.([]interface{})
+	if !ok || len(t) != 2 || t[0] != "map" {
+		return ret
+	}
+
+	pairs, _ := t[1].([]interface{})
+	for _, pair := range pairs {
+		if kv, ok := pair.([]interface{}); ok && len(kv) == 2 {
+			ret[kv[0]] = kv[1]
+		}
+	}
+	return ret
+}
+
+func ovsStringMap(oMap interface{}) map[string]string {
+	ret := map[string]string{}
+	for key, val := range ovsMap(oMap) {
+		k, okKey := key.(string)
+		v, okVal := val.(string)
+		if okKey && okVal {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
 func ovsUUIDFromRow(row row) ovs.UUID {
 	uuid := ovs.UUID{}
 	block, ok := row["_uuid"].([]interface{})
@@ -458,3 +741,11 @@ func newOvsSet(slice interface{}) *ovs.OvsSet {
 	}
 	return result
 }
+
+func newOvsMap(goMap interface{}) *ovs.OvsMap {
+	result, err := ovs.NewOvsMap(goMap)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/quilt/quilt/minion/ipdef"
+
 	ovs "github.com/socketplane/libovsdb"
 
 	"github.com/stretchr/testify/assert"
@@ -89,7 +91,7 @@ func TestCreateLogicalPort(t *testing.T) {
 	api := new(mockTransact)
 	odb := Client(client{api})
 
-	addrs := newOvsSet([]string{"mac ip"})
+	addrs := newOvsSet([]string{"mac ip ip6"})
 	ops := []ovs.Operation{{
 		Op:       "insert",
 		Table:    "Logical_Switch_Port",
@@ -103,13 +105,36 @@ func TestCreateLogicalPort(t *testing.T) {
 		},
 		Where: newCondition("name", "==", "lswitch")}}
 	api.On("Transact", "OVN_Northbound", ops).Return(nil, errors.New("err")).Once()
-	err := odb.CreateLogicalPort("lswitch", "name", "mac", "ip")
+	err := odb.CreateLogicalPort("lswitch", "name", "mac", []string{"ip", "ip6"})
 	assert.EqualError(t, err,
 		"transaction error: creating lport name on lswitch: err")
 
 	api.On("Transact", "OVN_Northbound", ops).Return(
 		[]ovs.OperationResult{{}, {}}, nil)
-	err = odb.CreateLogicalPort("lswitch", "name", "mac", "ip")
+	err = odb.CreateLogicalPort("lswitch", "name", "mac", []string{"ip", "ip6"})
+	assert.NoError(t, err)
+}
+
+func TestSetLogicalPortAddresses(t *testing.T) {
+	t.Parallel()
+
+	api := new(mockTransact)
+	odb := Client(client{api})
+
+	lport := LPort{Name: "name", uuid: ovs.UUID{GoUUID: "uuid"}}
+	ops := []ovs.Operation{{
+		Op:    "update",
+		Table: "Logical_Switch_Port",
+		Row: map[string]interface{}{
+			"addresses": newOvsSet([]string{"mac ip ip6"})},
+		Where: newCondition("_uuid", "==", ovs.UUID{GoUUID: "uuid"})}}
+	api.On("Transact", "OVN_Northbound", ops).Return(nil, errors.New("err")).Once()
+	err := odb.SetLogicalPortAddresses(lport, "mac", []string{"ip", "ip6"})
+	assert.EqualError(t, err, "transaction error: updating lport name: err")
+
+	api.On("Transact", "OVN_Northbound", ops).Return(
+		[]ovs.OperationResult{{}}, nil)
+	err = odb.SetLogicalPortAddresses(lport, "mac", []string{"ip", "ip6"})
 	assert.NoError(t, err)
 }
 
@@ -330,6 +355,41 @@ func TestOpenFlowPorts(t *testing.T) {
 	assert.Equal(t, map[string]int{"name": 12}, mp)
 }
 
+func TestInterfaceStats(t *testing.T) {
+	t.Parallel()
+
+	api := new(mockTransact)
+	odb := Client(client{api})
+
+	ops := []ovs.Operation{{
+		Op:      "select",
+		Table:   "Interface",
+		Where:   noCondition,
+		Columns: []string{"name", "statistics"}}}
+	api.On("Transact", "Open_vSwitch", ops).Return(nil, errors.New("err")).Once()
+	_, err := odb.InterfaceStats()
+	assert.EqualError(t, err, "select interface error: err")
+
+	stats := []interface{}{"map", []interface{}{
+		[]interface{}{"rx_packets", float64(1)},
+		[]interface{}{"rx_bytes", float64(2)},
+		[]interface{}{"tx_packets", float64(3)},
+		[]interface{}{"tx_bytes", float64(4)},
+		[]interface{}{"collisions", float64(0)},
+	}}
+	res := []ovs.OperationResult{{Rows: []map[string]interface{}{
+		{},
+		{"name": "empty"},
+		{"name": "veth", "statistics": stats}}}}
+	api.On("Transact", "Open_vSwitch", ops).Return(res, nil).Once()
+	result, err := odb.InterfaceStats()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]InterfaceStats{
+		"empty": {},
+		"veth":  {RxPackets: 1, RxBytes: 2, TxPackets: 3, TxBytes: 4},
+	}, result)
+}
+
 func TestOvsStringSetToSlice(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, []string{"b"}, ovsStringSetToSlice("b"))
@@ -351,3 +411,157 @@ func TestLPortSlice(t *testing.T) {
 	assert.Equal(t, slice[0], slice.Get(0))
 	assert.Equal(t, 1, slice.Len())
 }
+
+func TestListQoS(t *testing.T) {
+	t.Parallel()
+
+	api := new(mockTransact)
+	odb := Client(client{api})
+
+	ops := []ovs.Operation{
+		{Op: "select", Table: "Interface", Where: noCondition},
+		{Op: "select", Table: "QoS", Where: noCondition},
+		{Op: "select", Table: "Queue", Where: noCondition},
+	}
+	api.On("Transact", "Open_vSwitch", ops).Return(nil, errors.New("err")).Once()
+	_, err := odb.ListQoS()
+	assert.EqualError(t, err, "transaction error: listing qos: err")
+
+	uuid := func(id string) []interface{} {
+		return []interface{}{"uuid", id}
+	}
+	ovsMap := func(pairs ...[]interface{}) []interface{} {
+		var ifaces []interface{}
+		for _, pair := range pairs {
+			ifaces = append(ifaces, pair)
+		}
+		return []interface{}{"map", ifaces}
+	}
+
+	res := []ovs.OperationResult{{Rows: []map[string]interface{}{
+		{"name": "eth0", "ingress_policing_rate": float64(0)},
+		{"name": "veth", "ingress_policing_rate": float64(100)},
+	}}, {Rows: []map[string]interface{}{
+		{"external_ids": ovsMap()},
+		{"external_ids": ovsMap([]interface{}{qosPortKey, "veth"}),
+			"queues": ovsMap([]interface{}{float64(2), uuid("b")},
+				[]interface{}{float64(1), uuid("a")})},
+		{"external_ids": ovsMap([]interface{}{qosPortKey, "gone"}),
+			"queues": ovsMap([]interface{}{float64(1), uuid("c")})},
+	}}, {Rows: []map[string]interface{}{
+		{"_uuid": uuid("a"), "other_config": ovsMap(
+			[]interface{}{"max-rate", "1000000"})},
+		{"_uuid": uuid("b"), "other_config": ovsMap(
+			[]interface{}{"max-rate", "2000"})},
+		{"_uuid": uuid("c"), "other_config": ovsMap()},
+	}}}
+	api.On("Transact", "Open_vSwitch", ops).Return(res, nil).Once()
+
+	qos, err := odb.ListQoS()
+	assert.NoError(t, err)
+
+	qosMap := map[string]QoS{}
+	for _, q := range qos {
+		qosMap[q.Port] = q
+	}
+	assert.Equal(t, map[string]QoS{
+		"veth": {Port: "veth", IngressRate: 100, QueueRates: []int{1000, 2}},
+		"gone": {Port: "gone", QueueRates: []int{0}},
+	}, qosMap)
+}
+
+func TestSetQoS(t *testing.T) {
+	t.Parallel()
+
+	api := new(mockTransact)
+	odb := Client(client{api})
+
+	externalIDs := newOvsMap(map[string]string{qosPortKey: "veth"})
+	clearOps := []ovs.Operation{{
+		Op:    "update",
+		Table: "Interface",
+		Row: map[string]interface{}{
+			"ingress_policing_rate":  0,
+			"ingress_policing_burst": 0,
+		},
+		Where: newCondition("name", "==", "veth"),
+	}, {
+		Op:    "delete",
+		Table: "QoS",
+		Where: newCondition("external_ids", "includes", externalIDs),
+	}, {
+		Op:    "delete",
+		Table: "Queue",
+		Where: newCondition("external_ids", "includes", externalIDs),
+	}, {
+		Op:    "update",
+		Table: "Port",
+		Row:   map[string]interface{}{"qos": newOvsSet([]ovs.UUID{})},
+		Where: newCondition("name", "==", "veth"),
+	}}
+	api.On("Transact", "Open_vSwitch", clearOps).Return(nil,
+		errors.New("err")).Once()
+	assert.EqualError(t, odb.SetQoS(QoS{Port: "veth"}),
+		"transaction error: setting qos on veth: err")
+
+	api.On("Transact", "Open_vSwitch", clearOps).Return(
+		[]ovs.OperationResult{{}, {}, {}, {}}, nil).Once()
+	assert.NoError(t, odb.SetQoS(QoS{Port: "veth"}))
+
+	setOps := []ovs.Operation{{
+		Op:    "update",
+		Table: "Interface",
+		Row: map[string]interface{}{
+			"ingress_policing_rate":  1000,
+			"ingress_policing_burst": 100,
+		},
+		Where: newCondition("name", "==", "veth"),
+	}, clearOps[1], clearOps[2], {
+		Op:    "insert",
+		Table: "Queue",
+		Row: map[string]interface{}{
+			"other_config": newOvsMap(map[string]string{
+				"max-rate": "500000"}),
+			"external_ids": externalIDs,
+		},
+		UUIDName: "qqueue1",
+	}, {
+		Op:    "insert",
+		Table: "QoS",
+		Row: map[string]interface{}{
+			"type": "linux-htb",
+			"queues": newOvsMap(map[int]ovs.UUID{
+				1: {GoUUID: "qqueue1"}}),
+			"external_ids": externalIDs,
+		},
+		UUIDName: "qqos",
+	}, {
+		Op:    "update",
+		Table: "Port",
+		Row:   map[string]interface{}{"qos": ovs.UUID{GoUUID: "qqos"}},
+		Where: newCondition("name", "==", "veth"),
+	}}
+	api.On("Transact", "Open_vSwitch", setOps).Return(
+		[]ovs.OperationResult{{}, {}, {}, {}, {}, {Error: "bad"}}, nil).Once()
+	assert.EqualError(t, odb.SetQoS(QoS{Port: "veth", IngressRate: 1000,
+		QueueRates: []int{500}}), "operation 5 failed due to error: bad: ")
+}
+
+func TestPolicingBurst(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, policingBurst(0))
+	assert.Equal(t, 100, policingBurst(1000))
+
+	// Low rates still allow a full-size packet through.
+	assert.Equal(t, 12, policingBurst(100))
+	assert.True(t, policingBurst(1)*1000 >= ipdef.MTU()*8)
+}
+
+func TestOvsStringMap(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]string{}, ovsStringMap("bad"))
+	assert.Equal(t, map[string]string{"a": "b"}, ovsStringMap([]interface{}{"map",
+		[]interface{}{[]interface{}{"a", "b"}, []interface{}{"c", 1.0}}}))
+}
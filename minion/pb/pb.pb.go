@@ -12,6 +12,8 @@ It has these top-level messages:
 	MinionConfig
 	Reply
 	Request
+	MinionEventsRequest
+	MinionEventsReply
 */
 package pb
 
@@ -69,6 +71,10 @@ type MinionConfig struct {
 	Region         string            `protobuf:"bytes,7,opt,name=Region,json=region" json:"Region,omitempty"`
 	EtcdMembers    []string          `protobuf:"bytes,8,rep,name=EtcdMembers,json=etcdMembers" json:"EtcdMembers,omitempty"`
 	AuthorizedKeys []string          `protobuf:"bytes,9,rep,name=AuthorizedKeys,json=authorizedKeys" json:"AuthorizedKeys,omitempty"`
+	Subnet         string            `protobuf:"bytes,10,opt,name=Subnet,json=subnet" json:"Subnet,omitempty"`
+	IPv6Subnet     string            `protobuf:"bytes,11,opt,name=IPv6Subnet,json=iPv6Subnet" json:"IPv6Subnet,omitempty"`
+	Secrets        map[string]string `protobuf:"bytes,12,rep,name=Secrets,json=secrets" json:"Secrets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SecretsDigest  string            `protobuf:"bytes,13,opt,name=SecretsDigest,json=secretsDigest" json:"SecretsDigest,omitempty"`
 }
 
 func (m *MinionConfig) Reset()                    { *m = MinionConfig{} }
@@ -139,6 +145,34 @@ func (m *MinionConfig) GetAuthorizedKeys() []string {
 	return nil
 }
 
+func (m *MinionConfig) GetSubnet() string {
+	if m != nil {
+		return m.Subnet
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetIPv6Subnet() string {
+	if m != nil {
+		return m.IPv6Subnet
+	}
+	return ""
+}
+
+func (m *MinionConfig) GetSecrets() map[string]string {
+	if m != nil {
+		return m.Secrets
+	}
+	return nil
+}
+
+func (m *MinionConfig) GetSecretsDigest() string {
+	if m != nil {
+		return m.SecretsDigest
+	}
+	return ""
+}
+
 type Reply struct {
 }
 
@@ -155,10 +189,44 @@ func (m *Request) String() string            { return proto.CompactTextString(m)
 func (*Request) ProtoMessage()               {}
 func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
+type MinionEventsRequest struct {
+	Since int64 `protobuf:"varint,1,opt,name=Since,json=since" json:"Since,omitempty"`
+}
+
+func (m *MinionEventsRequest) Reset()                    { *m = MinionEventsRequest{} }
+func (m *MinionEventsRequest) String() string            { return proto.CompactTextString(m) }
+func (*MinionEventsRequest) ProtoMessage()               {}
+func (*MinionEventsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *MinionEventsRequest) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+type MinionEventsReply struct {
+	Events string `protobuf:"bytes,1,opt,name=Events,json=events" json:"Events,omitempty"`
+}
+
+func (m *MinionEventsReply) Reset()                    { *m = MinionEventsReply{} }
+func (m *MinionEventsReply) String() string            { return proto.CompactTextString(m) }
+func (*MinionEventsReply) ProtoMessage()               {}
+func (*MinionEventsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *MinionEventsReply) GetEvents() string {
+	if m != nil {
+		return m.Events
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*MinionConfig)(nil), "MinionConfig")
 	proto.RegisterType((*Reply)(nil), "Reply")
 	proto.RegisterType((*Request)(nil), "Request")
+	proto.RegisterType((*MinionEventsRequest)(nil), "MinionEventsRequest")
+	proto.RegisterType((*MinionEventsReply)(nil), "MinionEventsReply")
 	proto.RegisterEnum("MinionConfig_Role", MinionConfig_Role_name, MinionConfig_Role_value)
 }
 
@@ -175,6 +243,7 @@ const _ = grpc.SupportPackageIsVersion4
 type MinionClient interface {
 	SetMinionConfig(ctx context.Context, in *MinionConfig, opts ...grpc.CallOption) (*Reply, error)
 	GetMinionConfig(ctx context.Context, in *Request, opts ...grpc.CallOption) (*MinionConfig, error)
+	GetEvents(ctx context.Context, in *MinionEventsRequest, opts ...grpc.CallOption) (*MinionEventsReply, error)
 }
 
 type minionClient struct {
@@ -203,11 +272,21 @@ func (c *minionClient) GetMinionConfig(ctx context.Context, in *Request, opts ..
 	return out, nil
 }
 
+func (c *minionClient) GetEvents(ctx context.Context, in *MinionEventsRequest, opts ...grpc.CallOption) (*MinionEventsReply, error) {
+	out := new(MinionEventsReply)
+	err := grpc.Invoke(ctx, "/Minion/GetEvents", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Minion service
 
 type MinionServer interface {
 	SetMinionConfig(context.Context, *MinionConfig) (*Reply, error)
 	GetMinionConfig(context.Context, *Request) (*MinionConfig, error)
+	GetEvents(context.Context, *MinionEventsRequest) (*MinionEventsReply, error)
 }
 
 func RegisterMinionServer(s *grpc.Server, srv MinionServer) {
@@ -250,6 +329,24 @@ func _Minion_GetMinionConfig_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Minion_GetEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinionEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MinionServer).GetEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Minion/GetEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MinionServer).GetEvents(ctx, req.(*MinionEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Minion_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Minion",
 	HandlerType: (*MinionServer)(nil),
@@ -262,6 +359,10 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetMinionConfig",
 			Handler:    _Minion_GetMinionConfig_Handler,
 		},
+		{
+			MethodName: "GetEvents",
+			Handler:    _Minion_GetEvents_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "minion/pb/pb.proto",
@@ -270,26 +371,36 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("minion/pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 321 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x5c, 0x91, 0xcf, 0x6a, 0xea, 0x40,
-	0x14, 0xc6, 0x4d, 0x8c, 0x31, 0x39, 0xde, 0x1b, 0xe5, 0x2c, 0x2e, 0x83, 0xdc, 0x45, 0xc8, 0x42,
-	0x42, 0x29, 0x11, 0xec, 0x13, 0x48, 0x0d, 0x45, 0xc4, 0x3f, 0x8c, 0x85, 0xae, 0x8d, 0x9e, 0xda,
-	0x01, 0xcd, 0x4c, 0x27, 0x51, 0xd0, 0xe7, 0xea, 0x03, 0x16, 0x47, 0x4b, 0x9b, 0xee, 0xe6, 0xfc,
-	0xce, 0xef, 0x1b, 0x98, 0x6f, 0x00, 0xf7, 0x22, 0x17, 0x32, 0xef, 0xab, 0xac, 0xaf, 0xb2, 0x44,
-	0x69, 0x59, 0xca, 0xe8, 0xc3, 0x86, 0x3f, 0x53, 0x83, 0x1f, 0x65, 0xfe, 0x2a, 0xb6, 0x18, 0x80,
-	0x3d, 0x1e, 0x31, 0x2b, 0xb4, 0x62, 0x9f, 0xdb, 0x62, 0x84, 0x3d, 0x70, 0xb4, 0xdc, 0x11, 0xb3,
-	0x43, 0x2b, 0x0e, 0x06, 0x98, 0xfc, 0x94, 0x13, 0x2e, 0x77, 0xc4, 0xcd, 0x1e, 0xff, 0x83, 0xbf,
-	0xd0, 0xe2, 0xb8, 0x2a, 0x69, 0xbc, 0x60, 0x75, 0x13, 0xf7, 0xd5, 0x17, 0x40, 0x04, 0x67, 0xa9,
-	0x68, 0xcd, 0x1c, 0xb3, 0x70, 0x0a, 0x45, 0x6b, 0xec, 0x82, 0xb7, 0xd0, 0xf2, 0x28, 0x36, 0xa4,
-	0x59, 0xc3, 0x70, 0x4f, 0xdd, 0x66, 0xe3, 0x8b, 0x33, 0x31, 0xf7, 0xe6, 0x8b, 0x33, 0xe1, 0x3f,
-	0x70, 0x39, 0x6d, 0x85, 0xcc, 0x59, 0xd3, 0x50, 0x57, 0x9b, 0x09, 0x43, 0x68, 0xa5, 0xe5, 0x7a,
-	0x33, 0xa5, 0x7d, 0x46, 0xba, 0x60, 0x5e, 0x58, 0x8f, 0x7d, 0xde, 0xa2, 0x6f, 0x84, 0x3d, 0x08,
-	0x86, 0x87, 0xf2, 0x4d, 0x6a, 0x71, 0xa6, 0xcd, 0x84, 0x4e, 0x05, 0xf3, 0x8d, 0x14, 0xac, 0x2a,
-	0x34, 0x8a, 0xc1, 0xb9, 0xbc, 0x08, 0x3d, 0x70, 0x66, 0xf3, 0x59, 0xda, 0xa9, 0x21, 0x80, 0xfb,
-	0x32, 0xe7, 0x93, 0x94, 0x77, 0xac, 0xcb, 0x79, 0x3a, 0x5c, 0x3e, 0xa7, 0xbc, 0x63, 0x47, 0x4d,
-	0x68, 0x70, 0x52, 0xbb, 0x53, 0xe4, 0x43, 0x93, 0xd3, 0xfb, 0x81, 0x8a, 0x72, 0x90, 0x81, 0x7b,
-	0x2d, 0x07, 0xef, 0xa0, 0xbd, 0xa4, 0xb2, 0x52, 0xeb, 0xdf, 0x4a, 0x71, 0x5d, 0x37, 0xb9, 0xc6,
-	0x6b, 0x78, 0x0f, 0xed, 0xa7, 0x5f, 0xae, 0x97, 0xdc, 0xae, 0xec, 0x56, 0x53, 0x51, 0x2d, 0x73,
-	0xcd, 0xaf, 0x3d, 0x7c, 0x06, 0x00, 0x00, 0xff, 0xff, 0xe1, 0x0f, 0x6b, 0x6a, 0xcb, 0x01, 0x00,
+	// 481 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0x5d, 0x8b, 0xd3, 0x4c,
+	0x14, 0x6e, 0xda, 0x34, 0x6d, 0x4e, 0x3f, 0xb6, 0xef, 0x79, 0x17, 0x19, 0x82, 0x48, 0x09, 0xb2,
+	0x14, 0x57, 0xb2, 0x50, 0x45, 0x65, 0xef, 0x16, 0x1b, 0x96, 0xb2, 0x74, 0xb7, 0x4c, 0x04, 0xaf,
+	0xfb, 0x71, 0xac, 0x83, 0xdd, 0x24, 0xce, 0x4c, 0x03, 0xe9, 0xb5, 0xff, 0xc2, 0x3f, 0x2b, 0x99,
+	0x44, 0x6d, 0xd4, 0xbb, 0x79, 0x3e, 0x4e, 0xce, 0x33, 0xe7, 0x64, 0x00, 0x1f, 0x45, 0x2c, 0x92,
+	0xf8, 0x2a, 0x5d, 0x5f, 0xa5, 0xeb, 0x20, 0x95, 0x89, 0x4e, 0xfc, 0x6f, 0x36, 0xf4, 0x17, 0x86,
+	0x7e, 0x9f, 0xc4, 0x9f, 0xc4, 0x0e, 0x87, 0xd0, 0x9c, 0xcf, 0x98, 0x35, 0xb6, 0x26, 0x2e, 0x6f,
+	0x8a, 0x19, 0x5e, 0x80, 0x2d, 0x93, 0x3d, 0xb1, 0xe6, 0xd8, 0x9a, 0x0c, 0xa7, 0x18, 0x9c, 0x9a,
+	0x03, 0x9e, 0xec, 0x89, 0x1b, 0x1d, 0x9f, 0x82, 0xbb, 0x94, 0x22, 0x5b, 0x69, 0x9a, 0x2f, 0x59,
+	0xcb, 0x94, 0xbb, 0xe9, 0x4f, 0x02, 0x11, 0xec, 0x28, 0xa5, 0x0d, 0xb3, 0x8d, 0x60, 0xab, 0x94,
+	0x36, 0xe8, 0x41, 0x77, 0x29, 0x93, 0x4c, 0x6c, 0x49, 0xb2, 0xb6, 0xe1, 0xbb, 0x69, 0x85, 0x8d,
+	0x5f, 0x1c, 0x89, 0x39, 0x95, 0x5f, 0x1c, 0x09, 0x9f, 0x80, 0xc3, 0x69, 0x27, 0x92, 0x98, 0x75,
+	0x0c, 0xeb, 0x48, 0x83, 0x70, 0x0c, 0xbd, 0x50, 0x6f, 0xb6, 0x0b, 0x7a, 0x5c, 0x93, 0x54, 0xac,
+	0x3b, 0x6e, 0x4d, 0x5c, 0xde, 0xa3, 0xdf, 0x14, 0x5e, 0xc0, 0xf0, 0xe6, 0xa0, 0x3f, 0x27, 0x52,
+	0x1c, 0x69, 0x7b, 0x47, 0xb9, 0x62, 0xae, 0x31, 0x0d, 0x57, 0x35, 0xb6, 0xe8, 0x10, 0x1d, 0xd6,
+	0x31, 0x69, 0x06, 0x65, 0x07, 0x65, 0x10, 0x3e, 0x03, 0x98, 0x2f, 0xb3, 0x37, 0x95, 0xd6, 0x33,
+	0x1a, 0x88, 0x5f, 0x0c, 0xbe, 0x86, 0x4e, 0x44, 0x1b, 0x49, 0x5a, 0xb1, 0xfe, 0xb8, 0x35, 0xe9,
+	0x4d, 0xbd, 0xfa, 0x98, 0x2a, 0x31, 0x8c, 0xb5, 0xcc, 0x79, 0x47, 0x95, 0x08, 0x9f, 0xc3, 0xa0,
+	0x12, 0x66, 0x62, 0x47, 0x4a, 0xb3, 0x81, 0xf9, 0xf0, 0x40, 0x9d, 0x92, 0xde, 0x35, 0xf4, 0x4f,
+	0xcb, 0x71, 0x04, 0xad, 0x2f, 0x94, 0x57, 0x0b, 0x2a, 0x8e, 0x78, 0x0e, 0xed, 0x6c, 0xb5, 0x3f,
+	0x94, 0x2b, 0x72, 0x79, 0x09, 0xae, 0x9b, 0xef, 0x2c, 0x7f, 0x02, 0x76, 0xb1, 0x21, 0xec, 0x82,
+	0x7d, 0xff, 0x70, 0x1f, 0x8e, 0x1a, 0x08, 0xe0, 0x7c, 0x7c, 0xe0, 0x77, 0x21, 0x1f, 0x59, 0xc5,
+	0x79, 0x71, 0x13, 0x7d, 0x08, 0xf9, 0xa8, 0xe9, 0x77, 0xa0, 0xcd, 0x29, 0xdd, 0xe7, 0xbe, 0x0b,
+	0x1d, 0x4e, 0x5f, 0x0f, 0xa4, 0xb4, 0x7f, 0x09, 0xff, 0x97, 0xb7, 0x08, 0x33, 0x8a, 0xb5, 0xaa,
+	0xe8, 0xa2, 0x5d, 0x24, 0xe2, 0x0d, 0x99, 0x08, 0x2d, 0xde, 0x56, 0x05, 0xf0, 0x2f, 0xe1, 0xbf,
+	0xba, 0x39, 0xdd, 0xe7, 0xc5, 0x3c, 0x4b, 0x58, 0xc5, 0x75, 0xc8, 0xa0, 0xe9, 0x77, 0x0b, 0x9c,
+	0xd2, 0x8d, 0x2f, 0xe0, 0x2c, 0x22, 0x5d, 0xfb, 0x03, 0x07, 0xb5, 0xe1, 0x79, 0x4e, 0x50, 0x26,
+	0x6b, 0xe0, 0x4b, 0x38, 0xbb, 0xfd, 0xc3, 0xdb, 0x0d, 0xaa, 0x58, 0x5e, 0xbd, 0xca, 0x6f, 0xe0,
+	0x5b, 0x70, 0x6f, 0x49, 0x97, 0xfd, 0xf1, 0x3c, 0xf8, 0xc7, 0x55, 0x3c, 0x0c, 0xfe, 0xca, 0xec,
+	0x37, 0xd6, 0x8e, 0x79, 0x19, 0xaf, 0x7e, 0x0c, 0x00, 0x60, 0x53, 0x5f, 0x17, 0x2f, 0x03, 0x00,
 	0x00,
 }
@@ -0,0 +1,155 @@
+package minion
+
+import (
+	"sort"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/stitch"
+)
+
+// now is mocked out by the unit tests.
+var now = time.Now
+
+// A rollout tracks how far the update of a label may proceed this round.
+type rollout struct {
+	strategy stitch.UpdateStrategy
+
+	surge       int // The number of containers that may still be booted.
+	unavailable int // The number of available containers that may still be stopped.
+}
+
+// planRollout decides which of the containers `news` that were added to the
+// deployment may be booted, and which of the containers `olds` that were removed
+// from it may be stopped, without exceeding the update strategies of their labels.
+// `current` holds the containers that are in both the deployment and the database.
+// Containers whose labels have no update strategy are booted and stopped
+// immediately.
+//
+// It's called each time the containers change, so an update proceeds as the new
+// containers become available.
+func planRollout(labels []stitch.Label, current, news, olds []db.Container) (
+	boot, stop []db.Container) {
+
+	var all []db.Container
+	all = append(all, current...)
+	all = append(all, olds...)
+
+	rollouts := map[string]*rollout{}
+	for _, label := range labels {
+		if label.UpdateStrategy == nil {
+			continue
+		}
+
+		r := &rollout{strategy: *label.UpdateStrategy}
+		var total, available int
+		for _, dbc := range all {
+			if hasLabel(dbc, label.Name) {
+				total++
+				if r.available(dbc) {
+					available++
+				}
+			}
+		}
+
+		// The same container may appear in a label more than once.
+		size := len(uniqueStrings(label.IDs))
+		r.surge = size + r.strategy.MaxSurge - total
+		r.unavailable = available - (size - r.strategy.MaxUnavailable)
+		rollouts[label.Name] = r
+	}
+
+	news = append([]db.Container{}, news...)
+	sort.Sort(db.ContainerSlice(news))
+	for _, dbc := range news {
+		if !allowed(rollouts, dbc, func(r *rollout) bool { return r.surge > 0 }) {
+			continue
+		}
+
+		boot = append(boot, dbc)
+		for _, label := range dbc.Labels {
+			if r := rollouts[label]; r != nil {
+				r.surge--
+			}
+		}
+	}
+
+	olds = append([]db.Container{}, olds...)
+	sort.Sort(stopOrder(olds))
+	for _, dbc := range olds {
+		canStop := func(r *rollout) bool {
+			return !r.available(dbc) || r.unavailable > 0
+		}
+		if !allowed(rollouts, dbc, canStop) {
+			continue
+		}
+
+		stop = append(stop, dbc)
+		for _, label := range dbc.Labels {
+			if r := rollouts[label]; r != nil && r.available(dbc) {
+				r.unavailable--
+			}
+		}
+	}
+
+	return boot, stop
+}
+
+// available returns whether `dbc` counts towards the available containers of the
+// label being rolled out.  Containers aren't health checked, so one that's been
+// running for MinReadySeconds is assumed to be ready.
+func (r rollout) available(dbc db.Container) bool {
+	minReady := time.Duration(r.strategy.MinReadySeconds) * time.Second
+	return dbc.Status == "running" && now().Sub(dbc.Created) >= minReady
+}
+
+// allowed returns whether `ok` holds for the rollouts of all of the labels of
+// `dbc`.
+func allowed(rollouts map[string]*rollout, dbc db.Container,
+	ok func(*rollout) bool) bool {
+
+	for _, label := range dbc.Labels {
+		if r := rollouts[label]; r != nil && !ok(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(dbc db.Container, label string) bool {
+	for _, l := range dbc.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(strs []string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, str := range strs {
+		set[str] = struct{}{}
+	}
+	return set
+}
+
+// stopOrder sorts containers so that those that aren't running are stopped first,
+// as stopping them doesn't make their labels any less available.
+type stopOrder []db.Container
+
+func (so stopOrder) Len() int {
+	return len(so)
+}
+
+func (so stopOrder) Less(i, j int) bool {
+	iRunning := so[i].Status == "running"
+	jRunning := so[j].Status == "running"
+	if iRunning != jRunning {
+		return jRunning
+	}
+	return so[i].StitchID < so[j].StitchID
+}
+
+func (so stopOrder) Swap(i, j int) {
+	so[i], so[j] = so[j], so[i]
+}
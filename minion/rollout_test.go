@@ -0,0 +1,111 @@
+package minion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/stitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanRolloutNoStrategy(t *testing.T) {
+	labels := []stitch.Label{{Name: "web", IDs: []string{"new1", "new2"}}}
+	news := []db.Container{
+		{StitchID: "new2", Labels: []string{"web"}},
+		{StitchID: "new1", Labels: []string{"web"}},
+	}
+	olds := []db.Container{
+		{ID: 1, StitchID: "old1", Labels: []string{"web"}, Status: "running"},
+		{ID: 2, StitchID: "old2", Labels: []string{"web"}, Status: "running"},
+	}
+
+	boot, stop := planRollout(labels, nil, news, olds)
+	assert.Equal(t, []db.Container{news[1], news[0]}, boot)
+	assert.Equal(t, olds, stop)
+}
+
+func TestPlanRollout(t *testing.T) {
+	strategy := &stitch.UpdateStrategy{MaxUnavailable: 1, MaxSurge: 1}
+	labels := []stitch.Label{{
+		Name:           "web",
+		IDs:            []string{"new1", "new2", "new3"},
+		UpdateStrategy: strategy,
+	}}
+
+	newc := func(id string) db.Container {
+		return db.Container{StitchID: id, Labels: []string{"web"}}
+	}
+	oldc := func(id string, status string) db.Container {
+		return db.Container{StitchID: id, Labels: []string{"web"}, Status: status}
+	}
+
+	news := []db.Container{newc("new1"), newc("new2"), newc("new3")}
+	olds := []db.Container{oldc("old1", "running"), oldc("old2", "running"),
+		oldc("old3", "running")}
+
+	// One container may be booted beyond the label's size, and one of the
+	// available containers may be stopped.
+	boot, stop := planRollout(labels, nil, news, olds)
+	assert.Equal(t, []db.Container{newc("new1")}, boot)
+	assert.Equal(t, []db.Container{oldc("old1", "running")}, stop)
+
+	// Once the new container is running, the update continues.
+	current := []db.Container{oldc("new1", "running")}
+	boot, stop = planRollout(labels, current, news[1:], olds[1:])
+	assert.Equal(t, []db.Container{newc("new2")}, boot)
+	assert.Equal(t, []db.Container{oldc("old2", "running")}, stop)
+
+	// But not while it's still starting.
+	current = []db.Container{oldc("new1", "")}
+	boot, stop = planRollout(labels, current, news[1:], olds[1:])
+	assert.Equal(t, []db.Container{newc("new2")}, boot)
+	assert.Empty(t, stop)
+
+	// Containers that aren't running may always be stopped, as they're already
+	// unavailable.
+	boot, stop = planRollout(labels, nil, news,
+		[]db.Container{oldc("old1", "exited"), oldc("old2", "running"),
+			oldc("old3", "exited"), oldc("old4", "running")})
+	assert.Empty(t, boot)
+	assert.Equal(t, []db.Container{oldc("old1", "exited"),
+		oldc("old3", "exited")}, stop)
+
+	// Without surge, the old containers must be stopped first.
+	strategy.MaxSurge = 0
+	boot, stop = planRollout(labels, nil, news, olds)
+	assert.Empty(t, boot)
+	assert.Equal(t, []db.Container{oldc("old1", "running")}, stop)
+
+	boot, stop = planRollout(labels, nil, news, olds[1:])
+	assert.Equal(t, []db.Container{newc("new1")}, boot)
+	assert.Empty(t, stop)
+}
+
+func TestPlanRolloutMinReady(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	labels := []stitch.Label{{
+		Name: "web",
+		IDs:  []string{"new1"},
+		UpdateStrategy: &stitch.UpdateStrategy{
+			MaxUnavailable:  0,
+			MaxSurge:        1,
+			MinReadySeconds: 10,
+		},
+	}}
+
+	current := []db.Container{{StitchID: "new1", Labels: []string{"web"},
+		Status: "running", Created: start.Add(-5 * time.Second)}}
+	olds := []db.Container{{StitchID: "old1", Labels: []string{"web"},
+		Status: "running", Created: start.Add(-time.Hour)}}
+
+	_, stop := planRollout(labels, current, nil, olds)
+	assert.Empty(t, stop)
+
+	current[0].Created = start.Add(-10 * time.Second)
+	_, stop = planRollout(labels, current, nil, olds)
+	assert.Equal(t, olds, stop)
+}
@@ -6,14 +6,18 @@ import (
 
 	"github.com/quilt/quilt/api"
 	apiServer "github.com/quilt/quilt/api/server"
+	"github.com/quilt/quilt/auth"
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/metrics"
 	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/minion/etcd"
+	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/network"
 	"github.com/quilt/quilt/minion/network/plugin"
 	"github.com/quilt/quilt/minion/pprofile"
 	"github.com/quilt/quilt/minion/scheduler"
 	"github.com/quilt/quilt/minion/supervisor"
+	"github.com/quilt/quilt/stitch"
 	"github.com/quilt/quilt/util"
 
 	log "github.com/Sirupsen/logrus"
@@ -29,21 +33,39 @@ func Run() {
 	conn := db.New()
 	dk := docker.New("unix:///var/run/docker.sock")
 
+	creds := loadCredentials()
+	go minionServerRun(conn, creds)
+
+	// Much of the minion depends on the container subnets, so wait for the foreman
+	// to tell us what they are before starting anything else.
+	configureSubnets(conn)
+	configureMTU(conn)
+	go runMTU(conn)
+
 	// Not in a goroutine, want the plugin to start before the scheduler
 	plugin.Run()
 
-	go minionServerRun(conn)
 	go supervisor.Run(conn, dk)
 	go scheduler.Run(conn, dk)
 	go network.Run(conn)
 	go etcd.Run(conn)
 	go syncAuthorizedKeys(conn)
+	go runStats(conn, dk)
+
+	go apiServer.RunMinion(conn,
+		fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort), dk, creds)
 
-	go apiServer.Run(conn, fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort))
+	metrics.NewGaugeFunc("quilt_containers",
+		"The number of containers in each status.", []string{"status"},
+		containerStatuses(conn))
+	go metrics.Run(fmt.Sprintf(":%d", metrics.DefaultPort))
 
 	loopLog := util.NewEventTimer("Minion-Update")
 
-	for range conn.Trigger(db.MinionTable, db.EtcdTable).C {
+	// The policy is also updated as the containers change, and periodically, so that
+	// rolling updates proceed as new containers become available.
+	trigg := conn.TriggerTick(10, db.MinionTable, db.EtcdTable, db.ContainerTable)
+	for range trigg.C {
 		loopLog.LogStart()
 		txn := conn.Txn(db.ConnectionTable, db.ContainerTable, db.MinionTable,
 			db.EtcdTable, db.PlacementTable)
@@ -58,6 +80,95 @@ func Run() {
 	}
 }
 
+// loadCredentials blocks until the TLS credentials installed when the machine booted
+// are loaded.  Both the foreman and quiltctl must authenticate with them.
+func loadCredentials() auth.Credentials {
+	for {
+		creds, err := auth.Load(auth.MinionDir)
+		if err == nil {
+			if _, err = creds.TLSConfig(); err == nil {
+				return creds
+			}
+		}
+		log.WithError(err).Error("Failed to load TLS credentials.")
+
+		time.Sleep(30 * time.Second)
+	}
+}
+
+// configureSubnets blocks until the minion has received its configuration, and then
+// sets up the container subnets accordingly.  Changing the subnets of a running minion
+// isn't supported.
+func configureSubnets(conn db.Conn) {
+	trig := conn.TriggerTick(30, db.MinionTable)
+	defer trig.Stop()
+
+	for range trig.C {
+		self, err := conn.MinionSelf()
+		if err != nil {
+			continue
+		}
+
+		if err := ipdef.SetSubnets(self.Subnet, self.IPv6Subnet); err != nil {
+			log.WithError(err).Error("Invalid container subnet, using the default")
+		}
+		return
+	}
+}
+
+// runMTU keeps the MTU of the container network up to date as the overlay is
+// encrypted and decrypted.
+func runMTU(conn db.Conn) {
+	for range conn.Trigger(db.MinionTable, db.EtcdTable).C {
+		configureMTU(conn)
+	}
+}
+
+var publicMTU = network.PublicMTU
+
+// configureMTU sets the MTU of the container network to fit in the public interface
+// once tunneled, and encrypted if the spec asks for it or the leader has published
+// an overlay key, and reports it in the Minion table.  Like the subnets, it must be
+// configured before the modules that use it start.
+func configureMTU(conn db.Conn) {
+	var encrypted bool
+	var reported int
+	conn.Txn(db.MinionTable, db.EtcdTable).Run(func(view db.Database) error {
+		if self, err := view.MinionSelf(); err == nil {
+			reported = self.MTU
+			spec, err := stitch.FromJSON(self.Spec)
+			encrypted = err == nil && spec.EncryptOverlay
+		}
+
+		if etcdRows := view.SelectFromEtcd(nil); len(etcdRows) == 1 {
+			encrypted = encrypted || etcdRows[0].OverlayKey != ""
+		}
+		return nil
+	})
+
+	// Without the public MTU, TunnelMTU falls back to the default.
+	public, err := publicMTU()
+	if err != nil {
+		log.WithError(err).Warn("Failed to detect the public MTU, using the default")
+	}
+
+	mtu := ipdef.TunnelMTU(public, ipdef.TunnelProtocol, encrypted)
+	if mtu == ipdef.MTU() && mtu == reported {
+		return
+	}
+
+	log.WithField("mtu", mtu).Info("Configured container network MTU")
+	ipdef.SetMTU(mtu)
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self, err := view.MinionSelf()
+		if err == nil {
+			self.MTU = mtu
+			view.Commit(self)
+		}
+		return err
+	})
+}
+
 func runProfiler(duration time.Duration) {
 	go func() {
 		p := pprofile.New("minion")
@@ -0,0 +1,71 @@
+package minion
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/ipdef"
+)
+
+func TestConfigureMTU(t *testing.T) {
+	oldPublicMTU := publicMTU
+	defer func() { publicMTU = oldPublicMTU }()
+	defer ipdef.SetMTU(ipdef.MTU())
+
+	publicMTU = func() (int, error) { return 1500, nil }
+
+	conn := db.New()
+	conn.Txn(db.MinionTable, db.EtcdTable).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		view.Commit(self)
+		view.Commit(view.InsertEtcd())
+		return nil
+	})
+
+	checkMTU := func(exp int) {
+		assert.Equal(t, exp, ipdef.MTU())
+		self, err := conn.MinionSelf()
+		assert.NoError(t, err)
+		assert.Equal(t, exp, self.MTU)
+	}
+
+	configureMTU(conn)
+	checkMTU(1428)
+
+	// Publishing an overlay key leaves room for ESP.
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.OverlayKey = "key"
+		view.Commit(etcd)
+		return nil
+	})
+	configureMTU(conn)
+	checkMTU(1391)
+
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.OverlayKey = ""
+		view.Commit(etcd)
+		return nil
+	})
+	configureMTU(conn)
+	checkMTU(1428)
+
+	// So does a spec that asks for encryption, before the key is published.
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		self, _ := view.MinionSelf()
+		self.Spec = `{"EncryptOverlay": true}`
+		view.Commit(self)
+		return nil
+	})
+	configureMTU(conn)
+	checkMTU(1391)
+
+	publicMTU = func() (int, error) { return 0, errors.New("no route") }
+	configureMTU(conn)
+	checkMTU(1363)
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/metrics"
 	"github.com/quilt/quilt/util"
 	log "github.com/Sirupsen/logrus"
 )
@@ -15,15 +16,20 @@ type minion struct {
 	containers []*db.Container
 }
 
+var placementFailures = metrics.NewCounter(
+	"quilt_scheduler_placement_failures_total",
+	"The number of times a container couldn't be placed on any minion.")
+
 type context struct {
 	minions     []*minion
 	constraints []db.Placement
 	unassigned  []*db.Container
 	changed     []*db.Container
+	events      []db.Event
 }
 
 func runMaster(conn db.Conn) {
-	conn.Txn(db.ContainerTable, db.EtcdTable, db.MinionTable,
+	conn.Txn(db.ContainerTable, db.EtcdTable, db.EventTable, db.MinionTable,
 		db.PlacementTable).Run(func(view db.Database) error {
 
 		if view.EtcdLeader() {
@@ -45,6 +51,10 @@ func placeContainers(view db.Database) {
 	for _, change := range ctx.changed {
 		view.Commit(*change)
 	}
+
+	for _, e := range ctx.events {
+		view.RecordEvent(e)
+	}
 }
 
 // Unassign all containers that are placed incorrectly.
@@ -77,11 +87,27 @@ Outer:
 				m.containers = append(m.containers, dbc)
 				heap.Fix(&minions, i)
 				log.WithField("container", dbc).Info("Placed container.")
+				ctx.events = append(ctx.events, db.Event{
+					Type:    db.EventNormal,
+					Reason:  "Scheduled",
+					Object:  "container/" + dbc.StitchID,
+					Message: "placed on " + m.PrivateIP,
+					Source:  "scheduler",
+				})
 				continue Outer
 			}
 		}
 
 		log.WithField("container", dbc).Warning("Failed to place container.")
+		placementFailures.Inc()
+		ctx.events = append(ctx.events, db.Event{
+			Type:   db.EventWarning,
+			Reason: "FailedScheduling",
+			Object: "container/" + dbc.StitchID,
+			Message: "no minion satisfies the container's placement " +
+				"constraints",
+			Source: "scheduler",
+		})
 	}
 }
 
@@ -37,6 +37,10 @@ func TestPlaceContainers(t *testing.T) {
 		dbcs := view.SelectFromContainer(nil)
 		assert.Len(t, dbcs, 1)
 		assert.Equal(t, "1", dbcs[0].Minion)
+
+		events := view.SelectFromEvent(nil)
+		assert.Len(t, events, 1)
+		assert.Equal(t, "Scheduled", events[0].Reason)
 		return nil
 	})
 }
@@ -260,16 +264,35 @@ func TestPlaceUnassigned(t *testing.T) {
 
 	assert.Equal(t, exp, ctx.changed)
 
+	assert.Len(t, ctx.events, 3)
+	assert.Equal(t, db.Event{
+		Type:    db.EventNormal,
+		Reason:  "Scheduled",
+		Object:  "container/",
+		Message: "placed on 2",
+		Source:  "scheduler",
+	}, ctx.events[0])
+
 	ctx = makeContext(minions, placements, containers)
 	placeUnassigned(ctx)
 	assert.Nil(t, ctx.changed)
+	assert.Nil(t, ctx.events)
 
 	placements[0].Exclusive = false
 	placements[0].Region = "Nowhere"
 	containers[0].Minion = ""
+	failures := placementFailures.Get()
 	ctx = makeContext(minions, placements, containers)
 	placeUnassigned(ctx)
 	assert.Nil(t, ctx.changed)
+	assert.True(t, placementFailures.Get() > failures)
+	assert.Equal(t, []db.Event{{
+		Type:    db.EventWarning,
+		Reason:  "FailedScheduling",
+		Object:  "container/",
+		Message: "no minion satisfies the container's placement constraints",
+		Source:  "scheduler",
+	}}, ctx.events)
 }
 
 func TestMakeContext(t *testing.T) {
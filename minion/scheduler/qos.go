@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/quilt/quilt/minion/ovsdb"
+	"github.com/quilt/quilt/stitch"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// updateQoS configures OVS to enforce the bandwidth limits of the containers running
+// on this worker.  A container's own limit is enforced by policing the traffic OVS
+// receives from its veth, while connection limits are enforced by queues on the veth
+// that the OpenFlow rules place traffic in according to its source.
+func updateQoS(conn db.Conn, myIP string) {
+	var dbcs []db.Container
+	var conns []db.Connection
+	var labels []db.Label
+	conn.Txn(db.ContainerTable, db.ConnectionTable,
+		db.LabelTable).Run(func(view db.Database) error {
+		dbcs = localContainers(view, myIP)
+		conns = view.SelectFromConnection(nil)
+		labels = view.SelectFromLabel(nil)
+		return nil
+	})
+
+	odb, err := ovsdb.Open()
+	if err != nil {
+		log.WithError(err).Warning("Failed to connect to OVSDB")
+		return
+	}
+	defer odb.Disconnect()
+
+	if err := syncQoS(odb, dbcs, desiredQoS(dbcs, conns, labels)); err != nil {
+		log.WithError(err).Warning("Failed to update QoS")
+	}
+}
+
+func syncQoS(odb ovsdb.Client, dbcs []db.Container, desired []ovsdb.QoS) error {
+	qos, err := odb.ListQoS()
+	if err != nil {
+		return err
+	}
+
+	veths := map[string]struct{}{}
+	for _, dbc := range dbcs {
+		veths[ipdef.IFName(dbc.EndpointID)] = struct{}{}
+	}
+
+	// Only consider ports we're responsible for: the veths of our containers, and
+	// the queues we created for veths that no longer exist.
+	var current []ovsdb.QoS
+	for _, q := range qos {
+		if _, ok := veths[q.Port]; ok || len(q.QueueRates) > 0 {
+			current = append(current, q)
+		}
+	}
+
+	key := func(val interface{}) interface{} {
+		return val.(ovsdb.QoS).Port
+	}
+	pairs, toClear, toSet := join.HashJoin(qosSlice(current), qosSlice(desired),
+		key, key)
+
+	for _, pair := range pairs {
+		if !reflect.DeepEqual(pair.L, pair.R) {
+			toSet = append(toSet, pair.R)
+		}
+	}
+
+	for _, iface := range toClear {
+		toSet = append(toSet, ovsdb.QoS{Port: iface.(ovsdb.QoS).Port})
+	}
+
+	for _, iface := range toSet {
+		q := iface.(ovsdb.QoS)
+		if err := odb.SetQoS(q); err != nil {
+			return fmt.Errorf("failed to set QoS of %s: %s", q.Port, err)
+		}
+	}
+	return nil
+}
+
+func desiredQoS(dbcs []db.Container, conns []db.Connection,
+	labels []db.Label) []ovsdb.QoS {
+
+	var qos []ovsdb.QoS
+	for _, dbc := range dbcs {
+		rates, _ := containerQueues(dbc, conns, labels)
+		if dbc.Bandwidth == 0 && len(rates) == 0 {
+			continue
+		}
+
+		qos = append(qos, ovsdb.QoS{
+			Port:        ipdef.IFName(dbc.EndpointID),
+			IngressRate: dbc.Bandwidth,
+			QueueRates:  rates,
+		})
+	}
+	return qos
+}
+
+// containerQueues computes the queues on `dbc`'s veth required to enforce the
+// bandwidth limits of connections to it.  Each label with a limited connection to
+// `dbc` gets its own queue, limited to the strictest of its connections.  Queue i+1 is
+// limited to rates[i], and `sources` maps the IP address of every container that may
+// connect to `dbc` to its queue, or 0 if it isn't limited.
+func containerQueues(dbc db.Container, conns []db.Connection, labels []db.Label) (
+	rates []int, sources map[string]int) {
+
+	dbcLabels := map[string]struct{}{}
+	for _, label := range dbc.Labels {
+		dbcLabels[label] = struct{}{}
+	}
+
+	limits := map[string]int{}
+	for _, conn := range conns {
+		if _, ok := dbcLabels[conn.To]; !ok ||
+			conn.From == stitch.PublicInternetLabel {
+			continue
+		}
+
+		// Zero stands for unlimited here, so any limit is stricter.
+		limit, ok := limits[conn.From]
+		if !ok || (conn.Bandwidth > 0 && (limit == 0 || conn.Bandwidth < limit)) {
+			limits[conn.From] = conn.Bandwidth
+		}
+	}
+
+	if len(limits) == 0 {
+		return nil, nil
+	}
+
+	var froms []string
+	for from := range limits {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	queues := map[string]int{}
+	for _, from := range froms {
+		if limits[from] > 0 {
+			rates = append(rates, limits[from])
+			queues[from] = len(rates)
+		}
+	}
+
+	labelIPs := labelIPs(labels)
+	sources = map[string]int{}
+	for _, from := range froms {
+		for _, ip := range labelIPs[from] {
+			// A container in several labels is limited by the first that
+			// has a limit.
+			if queue, ok := sources[ip]; !ok || queue == 0 {
+				sources[ip] = queues[from]
+			}
+		}
+	}
+	return rates, sources
+}
+
+// containerPorts maps the IPs of the containers allowed to connect to `dbc` to the
+// port ranges of their connections, so that the traffic of each connection can be
+// counted.
+func containerPorts(dbc db.Container, conns []db.Connection, labels []db.Label) (
+	ports map[string][]openflow.PortRange) {
+
+	dbcLabels := map[string]struct{}{}
+	for _, label := range dbc.Labels {
+		dbcLabels[label] = struct{}{}
+	}
+
+	labelIPs := labelIPs(labels)
+	for _, conn := range conns {
+		if _, ok := dbcLabels[conn.To]; !ok ||
+			conn.From == stitch.PublicInternetLabel {
+			continue
+		}
+
+		for _, ip := range labelIPs[conn.From] {
+			if ports == nil {
+				ports = map[string][]openflow.PortRange{}
+			}
+			ports[ip] = append(ports[ip],
+				openflow.PortRange{Min: conn.MinPort, Max: conn.MaxPort})
+		}
+	}
+	return ports
+}
+
+// labelIPs maps each label to the IPv4 and IPv6 addresses of its containers.
+func labelIPs(labels []db.Label) map[string][]string {
+	labelIPs := map[string][]string{}
+	for _, label := range labels {
+		// The label's slices are shared with the database, so they're
+		// copied rather than appended to.
+		ips := append([]string{}, label.ContainerIPs...)
+		labelIPs[label.Label] = append(ips, label.ContainerIPv6s...)
+	}
+	return labelIPs
+}
+
+type qosSlice []ovsdb.QoS
+
+func (qs qosSlice) Get(i int) interface{} {
+	return qs[i]
+}
+
+func (qs qosSlice) Len() int {
+	return len(qs)
+}
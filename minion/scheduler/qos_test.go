@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/quilt/quilt/minion/ovsdb"
+	"github.com/quilt/quilt/minion/ovsdb/mocks"
+	"github.com/quilt/quilt/stitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerQueues(t *testing.T) {
+	t.Parallel()
+
+	labels := []db.Label{
+		{Label: "a", ContainerIPs: []string{"10.0.0.2", "10.0.0.3"}},
+		{Label: "b", ContainerIPs: []string{"10.0.0.3", "10.0.0.4"},
+			ContainerIPv6s: []string{"fd00::3", "fd00::4"}},
+	}
+	conns := []db.Connection{
+		{From: "b", To: "c", Bandwidth: 100},
+		{From: "a", To: "c", Bandwidth: 30},
+		{From: "a", To: "c", MinPort: 80, MaxPort: 80, Bandwidth: 20},
+		{From: "a", To: "d", Bandwidth: 10},
+		{From: "b", To: "e"},
+		{From: "public", To: "c", Bandwidth: 10},
+	}
+
+	rates, sources := containerQueues(db.Container{Labels: []string{"c"}}, conns,
+		labels)
+	assert.Equal(t, []int{20, 100}, rates)
+	assert.Equal(t, map[string]int{
+		"10.0.0.2": 1,
+		"10.0.0.3": 1,
+		"10.0.0.4": 2,
+		"fd00::3":  2,
+		"fd00::4":  2,
+	}, sources)
+
+	// Unlimited connections have sources, but no queues.
+	rates, sources = containerQueues(db.Container{Labels: []string{"e"}}, conns,
+		labels)
+	assert.Nil(t, rates)
+	assert.Equal(t, map[string]int{
+		"10.0.0.3": 0,
+		"10.0.0.4": 0,
+		"fd00::3":  0,
+		"fd00::4":  0,
+	}, sources)
+
+	rates, sources = containerQueues(db.Container{Labels: []string{"f"}}, conns,
+		labels)
+	assert.Nil(t, rates)
+	assert.Nil(t, sources)
+
+	// The labels' slices must not be written to, even if they have room to grow.
+	ips := make([]string, 1, 2)
+	ips[0] = "10.0.0.2"
+	labels = []db.Label{{Label: "a", ContainerIPs: ips,
+		ContainerIPv6s: []string{"fd00::2"}}}
+	containerQueues(db.Container{Labels: []string{"c"}}, conns, labels)
+	assert.Equal(t, "", ips[:2][1])
+}
+
+func TestDesiredQoS(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{
+		{EndpointID: "a", Bandwidth: 1000},
+		{EndpointID: "b", Labels: []string{"b"}},
+		{EndpointID: "c"},
+	}
+	conns := []db.Connection{{From: "c", To: "b", Bandwidth: 10}}
+	labels := []db.Label{{Label: "c", ContainerIPs: []string{"10.0.0.4"}}}
+
+	assert.Equal(t, []ovsdb.QoS{
+		{Port: "a", IngressRate: 1000},
+		{Port: "b", QueueRates: []int{10}},
+	}, desiredQoS(dbcs, conns, labels))
+}
+
+func TestSyncQoS(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{{EndpointID: "a"}, {EndpointID: "b"},
+		{EndpointID: "c"}}
+
+	client := new(mocks.Client)
+	client.On("ListQoS").Return(nil, errors.New("err")).Once()
+	assert.EqualError(t, syncQoS(client, dbcs, nil), "err")
+
+	client.On("ListQoS").Return([]ovsdb.QoS{
+		{Port: "a", IngressRate: 10},
+		{Port: "b", IngressRate: 10},
+		{Port: "gone", QueueRates: []int{10}},
+		{Port: "eth0", IngressRate: 10},
+	}, nil)
+	client.On("SetQoS", ovsdb.QoS{Port: "b", IngressRate: 20}).Return(nil)
+	client.On("SetQoS", ovsdb.QoS{Port: "c", QueueRates: []int{5}}).Return(nil)
+	client.On("SetQoS", ovsdb.QoS{Port: "gone"}).Return(nil)
+
+	assert.NoError(t, syncQoS(client, dbcs, []ovsdb.QoS{
+		{Port: "a", IngressRate: 10},
+		{Port: "b", IngressRate: 20},
+		{Port: "c", QueueRates: []int{5}},
+	}))
+	client.AssertNumberOfCalls(t, "SetQoS", 3)
+
+	client = new(mocks.Client)
+	client.On("ListQoS").Return(nil, nil)
+	client.On("SetQoS", ovsdb.QoS{Port: "a", IngressRate: 10}).Return(
+		errors.New("err"))
+	assert.EqualError(t, syncQoS(client, dbcs, []ovsdb.QoS{
+		{Port: "a", IngressRate: 10}}), "failed to set QoS of a: err")
+}
+
+func TestUpdateQoS(t *testing.T) {
+	client := new(mocks.Client)
+	open := ovsdb.Open
+	ovsdb.Open = func() (ovsdb.Client, error) { return client, nil }
+	defer func() { ovsdb.Open = open }()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertContainer()
+		dbc.EndpointID = "a"
+		dbc.IP = "10.0.0.2"
+		dbc.Minion = "1.2.3.4"
+		dbc.Bandwidth = 100
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.EndpointID = "b"
+		dbc.IP = "10.0.0.3"
+		dbc.Minion = "1.2.3.5"
+		dbc.Bandwidth = 100
+		view.Commit(dbc)
+		return nil
+	})
+
+	client.On("ListQoS").Return(nil, nil)
+	client.On("SetQoS", ovsdb.QoS{Port: "a", IngressRate: 100}).Return(nil)
+	client.On("Disconnect").Return()
+
+	updateQoS(conn, "1.2.3.4")
+	client.AssertNumberOfCalls(t, "SetQoS", 1)
+	client.AssertCalled(t, "Disconnect")
+}
+
+func TestContainerPorts(t *testing.T) {
+	t.Parallel()
+
+	dbc := db.Container{Labels: []string{"a", "b"}}
+	labels := []db.Label{
+		{Label: "c", ContainerIPs: []string{"1.2.3.4"},
+			ContainerIPv6s: []string{"fd00::4"}},
+		{Label: "d", ContainerIPs: []string{"1.2.3.5"}},
+	}
+	assert.Nil(t, containerPorts(dbc, nil, labels))
+
+	conns := []db.Connection{
+		{From: "c", To: "a", MinPort: 80, MaxPort: 80},
+		{From: "c", To: "b", MinPort: 1000, MaxPort: 2000},
+		{From: "d", To: "a", MinPort: 22, MaxPort: 22},
+		{From: "d", To: "e", MinPort: 443, MaxPort: 443},
+		{From: stitch.PublicInternetLabel, To: "a", MinPort: 8, MaxPort: 8},
+	}
+	assert.Equal(t, map[string][]openflow.PortRange{
+		"1.2.3.4": {{Min: 80, Max: 80}, {Min: 1000, Max: 2000}},
+		"fd00::4": {{Min: 80, Max: 80}, {Min: 1000, Max: 2000}},
+		"1.2.3.5": {{Min: 22, Max: 22}},
+	}, containerPorts(dbc, conns, labels))
+}
@@ -1,11 +1,16 @@
 package scheduler
 
 import (
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/metrics"
 	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/minion/ipdef"
 	"github.com/quilt/quilt/minion/network/openflow"
@@ -17,10 +22,41 @@ import (
 const labelKey = "quilt"
 const labelValue = "scheduler"
 const labelPair = labelKey + "=" + labelValue
+
+// filesLabel holds a hash of the files written into a container, so that it can
+// be restarted when they change.
+const filesLabel = "quilt.files"
+
+// secretFilesLabel is like filesLabel, but for the files holding secrets.
+const secretFilesLabel = "quilt.secretFiles"
+
+// stopTimeoutLabel holds the seconds a container is given to exit after SIGTERM,
+// as its database row may be gone by the time it's stopped.
+const stopTimeoutLabel = "quilt.stopTimeout"
+
+// defaultStopTimeout is the time containers are given to exit after SIGTERM when
+// their stop timeout isn't set.
+const defaultStopTimeout = 10 * time.Second
 const concurrencyLimit = 32
 
+var openflowDuration = metrics.NewHistogram(
+	"quilt_openflow_reconcile_duration_seconds",
+	"How long it takes to reconcile the OpenFlow rules with the local containers.",
+	metrics.DefBuckets)
+
+var flowChanges = metrics.NewCounter("quilt_openflow_flow_changes_total",
+	"The number of OpenFlow flows changed while reconciling, by kind of change.",
+	"change")
+
 var once sync.Once
 
+// The Docker IDs of the containers being stopped in the background, so that they
+// aren't stopped again while they exit.
+var stopping = struct {
+	sync.Mutex
+	ids map[string]struct{}
+}{ids: map[string]struct{}{}}
+
 func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 	if myIP == "" {
 		return
@@ -42,14 +78,18 @@ func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 			log.WithError(err).Warning("Failed to list docker containers.")
 			return
 		}
+		dkcs = withoutStopping(dkcs)
 
-		conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+		var secrets map[string]string
+		conn.Txn(db.ContainerTable,
+			db.SecretTable).Run(func(view db.Database) error {
 			dbcs := view.SelectFromContainer(func(dbc db.Container) bool {
 				return dbc.IP != "" && dbc.Minion == myIP
 			})
+			secrets = secretValues(view.SelectFromSecret(nil))
 
 			var changed []db.Container
-			changed, toBoot, toKill = syncWorker(dbcs, dkcs)
+			changed, toBoot, toKill = syncWorker(dbcs, dkcs, secrets)
 			for _, dbc := range changed {
 				view.Commit(dbc)
 			}
@@ -62,19 +102,24 @@ func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 		}
 
 		start := time.Now()
-		doContainers(dk, toBoot, dockerRun)
-		doContainers(dk, toKill, dockerKill)
-		log.Infof("Scheduler spent %v starting/stopping containers",
-			time.Since(start))
+		doContainers(dk, toBoot, func(dk docker.Client, iface interface{}) {
+			dockerRun(conn, dk, iface, secrets)
+		})
+		stopContainers(dk, toKill)
+		log.Infof("Scheduler spent %v starting containers", time.Since(start))
 	}
 
+	updateQoS(conn, myIP)
 	updateOpenflow(conn, myIP)
 }
 
-func syncWorker(dbcs []db.Container, dkcs []docker.Container) (
-	changed []db.Container, toBoot, toKill []interface{}) {
+func syncWorker(dbcs []db.Container, dkcs []docker.Container,
+	secrets map[string]string) (changed []db.Container, toBoot, toKill []interface{}) {
 
-	pairs, dbci, dkci := join.Join(dbcs, dkcs, syncJoinScore)
+	score := func(left, right interface{}) int {
+		return syncJoinScore(left, right, secrets)
+	}
+	pairs, dbci, dkci := join.Join(dbcs, dkcs, score)
 
 	for _, i := range dkci {
 		toKill = append(toKill, i.(docker.Container))
@@ -93,6 +138,15 @@ func syncWorker(dbcs []db.Container, dkcs []docker.Container) (
 
 	for _, i := range dbci {
 		dbc := i.(db.Container)
+		_, err := containerEnv(dbc, secrets)
+		if err == nil {
+			_, err = containerFiles(dbc, secrets)
+		}
+		if err != nil {
+			log.WithError(err).WithField("container", dbc.StitchID).Warning(
+				"Can't start container until its secrets are set")
+			continue
+		}
 		toBoot = append(toBoot, dbc)
 	}
 
@@ -117,15 +171,48 @@ func doContainers(dk docker.Client, ifaces []interface{},
 	}
 }
 
-func dockerRun(dk docker.Client, iface interface{}) {
+// dockerRun starts the container `iface` with its secrets injected into its
+// environment and files.  The secrets are never logged, as only their names appear in
+// the container row.  Failures to start the container are recorded as events.
+func dockerRun(conn db.Conn, dk docker.Client, iface interface{},
+	secrets map[string]string) {
+
 	dbc := iface.(db.Container)
+	env, err := containerEnv(dbc, secrets)
+	if err != nil {
+		log.WithError(err).WithField("container", dbc).Warning(
+			"Failed to run container")
+		return
+	}
+
+	files, err := containerFiles(dbc, secrets)
+	if err != nil {
+		log.WithError(err).WithField("container", dbc).Warning(
+			"Failed to run container")
+		return
+	}
+
+	labels := map[string]string{labelKey: labelValue}
+	if len(dbc.Files) > 0 {
+		labels[filesLabel] = filesHash(dbc.Files)
+	}
+	if len(dbc.SecretFiles) > 0 {
+		secretFiles, _ := secretFileContents(dbc, secrets)
+		labels[secretFilesLabel] = filesHash(secretFiles)
+	}
+	if dbc.StopTimeout != 0 {
+		labels[stopTimeoutLabel] = strconv.Itoa(dbc.StopTimeout)
+	}
+
 	log.WithField("container", dbc).Info("Start container")
-	_, err := dk.Run(docker.RunOptions{
+	_, err = dk.Run(docker.RunOptions{
 		Image:       dbc.Image,
 		Args:        dbc.Command,
-		Env:         dbc.Env,
-		Labels:      map[string]string{labelKey: labelValue},
+		Env:         env,
+		Files:       files,
+		Labels:      labels,
 		IP:          dbc.IP,
+		IPv6:        dbc.IPv6,
 		NetworkMode: plugin.NetworkName,
 		DNS:         []string{ipdef.GatewayIP.String()},
 		DNSSearch:   []string{"q"},
@@ -135,12 +222,63 @@ func dockerRun(dk docker.Client, iface interface{}) {
 			"error":     err,
 			"container": dbc,
 		}).WithError(err).Warning("Failed to run container", dbc)
+
+		reason := "Failed"
+		if _, ok := err.(docker.PullError); ok {
+			reason = "FailedPull"
+		}
+		conn.RecordEvent(db.Event{
+			Type:    db.EventWarning,
+			Reason:  reason,
+			Object:  "container/" + dbc.StitchID,
+			Message: err.Error(),
+			Source:  "scheduler",
+		})
+	}
+}
+
+// stopContainers stops the containers `toKill` in the background, as each may take
+// its stop timeout to exit, and the network shouldn't wait on them to be updated.
+func stopContainers(dk docker.Client, toKill []interface{}) {
+	stopping.Lock()
+	for _, iface := range toKill {
+		stopping.ids[iface.(docker.Container).ID] = struct{}{}
+	}
+	stopping.Unlock()
+
+	go doContainers(dk, toKill, func(dk docker.Client, iface interface{}) {
+		dockerKill(dk, iface)
+
+		stopping.Lock()
+		delete(stopping.ids, iface.(docker.Container).ID)
+		stopping.Unlock()
+	})
+}
+
+// withoutStopping returns the containers in `dkcs` that aren't being stopped.
+func withoutStopping(dkcs []docker.Container) []docker.Container {
+	stopping.Lock()
+	defer stopping.Unlock()
+
+	var result []docker.Container
+	for _, dkc := range dkcs {
+		if _, ok := stopping.ids[dkc.ID]; !ok {
+			result = append(result, dkc)
+		}
 	}
+	return result
 }
 
 func dockerKill(dk docker.Client, iface interface{}) {
 	dkc := iface.(docker.Container)
-	log.WithField("container", dkc.ID).Info("Remove container")
+	log.WithField("container", dkc.ID).Info("Stop container")
+
+	// If the container doesn't exit in time, it's killed when it's removed.
+	if err := dk.Stop(dkc.ID, stopTimeout(dkc)); err != nil {
+		log.WithError(err).WithField("id", dkc.ID).Warning(
+			"Failed to stop container.")
+	}
+
 	if err := dk.RemoveID(dkc.ID); err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
@@ -149,11 +287,115 @@ func dockerKill(dk docker.Client, iface interface{}) {
 	}
 }
 
-func syncJoinScore(left, right interface{}) int {
+// stopTimeout returns the time `dkc` is given to exit after SIGTERM.
+func stopTimeout(dkc docker.Container) time.Duration {
+	seconds, err := strconv.Atoi(dkc.Labels[stopTimeoutLabel])
+	if err != nil || seconds <= 0 {
+		return defaultStopTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// containerEnv returns the environment of `dbc`, including its secrets.
+func containerEnv(dbc db.Container, secrets map[string]string) (
+	map[string]string, error) {
+
+	if len(dbc.Secrets) == 0 {
+		return dbc.Env, nil
+	}
+
+	env := map[string]string{}
+	for key, value := range dbc.Env {
+		env[key] = value
+	}
+
+	for key, name := range dbc.Secrets {
+		value, ok := secrets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown secret: %s", name)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// containerFiles returns the files written into `dbc`, including its secrets.
+func containerFiles(dbc db.Container, secrets map[string]string) (
+	map[string]string, error) {
+
+	if len(dbc.SecretFiles) == 0 {
+		return dbc.Files, nil
+	}
+
+	secretFiles, err := secretFileContents(dbc, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]string{}
+	for path, content := range dbc.Files {
+		files[path] = content
+	}
+	for path, content := range secretFiles {
+		files[path] = content
+	}
+	return files, nil
+}
+
+// secretFileContents maps the paths of the secret files of `dbc` to their contents.
+func secretFileContents(dbc db.Container, secrets map[string]string) (
+	map[string]string, error) {
+
+	files := map[string]string{}
+	for path, name := range dbc.SecretFiles {
+		value, ok := secrets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown secret: %s", name)
+		}
+		files[path] = value
+	}
+	return files, nil
+}
+
+// secretValues maps the names of `dbSecrets` to their values.
+func secretValues(dbSecrets []db.Secret) map[string]string {
+	secrets := map[string]string{}
+	for _, secret := range dbSecrets {
+		secrets[secret.Name] = secret.Value
+	}
+	return secrets
+}
+
+// filesHash returns a digest of the paths and contents of `files`, or the empty
+// string if there are none.
+func filesHash(files map[string]string) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha1.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%d:%s%d:%s", len(path), path,
+			len(files[path]), files[path])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func syncJoinScore(left, right interface{}, secrets map[string]string) int {
 	dbc := left.(db.Container)
 	dkc := right.(docker.Container)
 
-	if dbc.Image != dkc.Image || dbc.IP != dkc.IP {
+	if dbc.Image != dkc.Image || dbc.IP != dkc.IP || dbc.IPv6 != dkc.IPv6 {
+		return -1
+	}
+
+	if filesHash(dbc.Files) != dkc.Labels[filesLabel] {
 		return -1
 	}
 
@@ -163,6 +405,19 @@ func syncJoinScore(left, right interface{}) int {
 		}
 	}
 
+	// Containers keep running if their secrets are removed, but are restarted if
+	// their secrets change.
+	for key, name := range dbc.Secrets {
+		if value, ok := secrets[name]; ok && dkc.Env[key] != value {
+			return -1
+		}
+	}
+
+	secretFiles, err := secretFileContents(dbc, secrets)
+	if err == nil && filesHash(secretFiles) != dkc.Labels[secretFilesLabel] {
+		return -1
+	}
+
 	// Depending on the container, the command in the database could be
 	// either the command plus it's arguments, or just it's arguments.  To
 	// handle that case, we check both.
@@ -178,26 +433,63 @@ func syncJoinScore(left, right interface{}) int {
 }
 
 func updateOpenflow(conn db.Conn, myIP string) {
-	dbcs := conn.SelectFromContainer(func(dbc db.Container) bool {
-		return dbc.EndpointID != "" && dbc.IP != "" && dbc.Minion == myIP
+	var dbcs []db.Container
+	var conns []db.Connection
+	var labels []db.Label
+	conn.Txn(db.ContainerTable, db.ConnectionTable,
+		db.LabelTable).Run(func(view db.Database) error {
+		dbcs = localContainers(view, myIP)
+		conns = view.SelectFromConnection(nil)
+		labels = view.SelectFromLabel(nil)
+		return nil
 	})
 
-	ofcs := openflowContainers(dbcs)
-	if err := replaceFlows(ofcs); err != nil {
+	start := time.Now()
+	ofcs := openflowContainers(dbcs, conns, labels)
+	changes, err := updateFlows(ofcs)
+	openflowDuration.ObserveSince(start)
+	if err != nil {
 		log.WithError(err).Warning("Failed to update OpenFlow")
+		return
+	}
+
+	flowChanges.Add(float64(changes.Added), "added")
+	flowChanges.Add(float64(changes.Modified), "modified")
+	flowChanges.Add(float64(changes.Deleted), "deleted")
+
+	if changes.Added+changes.Modified+changes.Deleted > 0 {
+		log.WithFields(log.Fields{
+			"added":    changes.Added,
+			"modified": changes.Modified,
+			"deleted":  changes.Deleted,
+			"total":    changes.Total,
+		}).Info("Updated OpenFlow")
 	}
 }
 
-func openflowContainers(dbcs []db.Container) []openflow.Container {
+// localContainers returns the containers running on this worker that have been
+// attached to the network.
+func localContainers(view db.Database, myIP string) []db.Container {
+	return view.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.EndpointID != "" && dbc.IP != "" && dbc.Minion == myIP
+	})
+}
+
+func openflowContainers(dbcs []db.Container, conns []db.Connection,
+	labels []db.Label) []openflow.Container {
+
 	var ofcs []openflow.Container
 	for _, dbc := range dbcs {
 		_, peerQuilt := ipdef.PatchPorts(dbc.EndpointID)
+		_, sources := containerQueues(dbc, conns, labels)
 		ofcs = append(ofcs, openflow.Container{
-			Veth:  ipdef.IFName(dbc.EndpointID),
-			Patch: peerQuilt,
-			Mac:   ipdef.IPStrToMac(dbc.IP)})
+			Veth:    ipdef.IFName(dbc.EndpointID),
+			Patch:   peerQuilt,
+			Mac:     ipdef.IPStrToMac(dbc.IP),
+			Sources: sources,
+			Ports:   containerPorts(dbc, conns, labels)})
 	}
 	return ofcs
 }
 
-var replaceFlows = openflow.ReplaceFlows
+var updateFlows = openflow.UpdateFlows
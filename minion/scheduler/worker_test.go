@@ -2,19 +2,50 @@ package scheduler
 
 import (
 	"errors"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/docker"
 	"github.com/quilt/quilt/minion/network/openflow"
+	"github.com/quilt/quilt/util"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestUpdateOpenflowMetrics(t *testing.T) {
+	oldUpdateFlows := updateFlows
+	defer func() { updateFlows = oldUpdateFlows }()
+
+	updateFlows = func(ofcs []openflow.Container) (openflow.FlowChanges, error) {
+		return openflow.FlowChanges{Added: 3, Modified: 2, Deleted: 1,
+			Total: 10}, nil
+	}
+
+	added := flowChanges.Get("added")
+	modified := flowChanges.Get("modified")
+	deleted := flowChanges.Get("deleted")
+
+	updateOpenflow(db.New(), "1.2.3.4")
+	assert.Equal(t, added+3, flowChanges.Get("added"))
+	assert.Equal(t, modified+2, flowChanges.Get("modified"))
+	assert.Equal(t, deleted+1, flowChanges.Get("deleted"))
+
+	// Failed updates aren't counted.
+	updateFlows = func(ofcs []openflow.Container) (openflow.FlowChanges, error) {
+		return openflow.FlowChanges{Added: 3}, errors.New("err")
+	}
+	updateOpenflow(db.New(), "1.2.3.4")
+	assert.Equal(t, added+3, flowChanges.Get("added"))
+}
+
 func TestRunWorker(t *testing.T) {
 	t.Parallel()
 
-	replaceFlows = func(ofcs []openflow.Container) error { return errors.New("err") }
+	updateFlows = func(ofcs []openflow.Container) (openflow.FlowChanges, error) {
+		return openflow.FlowChanges{}, errors.New("err")
+	}
 
 	md, dk := docker.NewMock()
 	conn := db.New()
@@ -33,10 +64,12 @@ func TestRunWorker(t *testing.T) {
 	})
 
 	// Wrong Minion IP, should do nothing.
+	reconciles := openflowDuration.Count()
 	runWorker(conn, dk, "1.2.3.5")
 	dkcs, err := dk.List(nil)
 	assert.NoError(t, err)
 	assert.Len(t, dkcs, 0)
+	assert.True(t, openflowDuration.Count() > reconciles)
 
 	// Run with a list error, should do nothing.
 	md.ListError = true
@@ -46,6 +79,20 @@ func TestRunWorker(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, dkcs, 0)
 
+	// Run with a pull error, which should be recorded as an event.
+	md.PullError = true
+	runWorker(conn, dk, "1.2.3.4")
+	md.PullError = false
+	dkcs, err = dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 0)
+
+	events := conn.SelectFromEvent(nil)
+	assert.Len(t, events, 1)
+	assert.Equal(t, db.EventWarning, events[0].Type)
+	assert.Equal(t, "FailedPull", events[0].Reason)
+	assert.Equal(t, "failed to pull Image: pull error", events[0].Message)
+
 	runWorker(conn, dk, "1.2.3.4")
 	dkcs, err = dk.List(nil)
 	assert.NoError(t, err)
@@ -55,10 +102,18 @@ func TestRunWorker(t *testing.T) {
 
 func runSync(dk docker.Client, dbcs []db.Container,
 	dkcs []docker.Container) []db.Container {
+	return runSyncSecrets(dk, dbcs, dkcs, nil)
+}
+
+func runSyncSecrets(dk docker.Client, dbcs []db.Container,
+	dkcs []docker.Container, secrets map[string]string) []db.Container {
 
-	changes, tdbcs, tdkcs := syncWorker(dbcs, dkcs)
+	conn := db.New()
+	changes, tdbcs, tdkcs := syncWorker(dbcs, dkcs, secrets)
 	doContainers(dk, tdkcs, dockerKill)
-	doContainers(dk, tdbcs, dockerRun)
+	doContainers(dk, tdbcs, func(dk docker.Client, iface interface{}) {
+		dockerRun(conn, dk, iface, secrets)
+	})
 	return changes
 }
 
@@ -82,7 +137,7 @@ func TestSyncWorker(t *testing.T) {
 
 	runSync(dk, dbcs, nil)
 	dkcs, err := dk.List(nil)
-	changed, _, _ = syncWorker(dbcs, dkcs)
+	changed, _, _ = syncWorker(dbcs, dkcs, nil)
 	assert.NoError(t, err)
 
 	if changed[0].DockerID != dkcs[0].ID {
@@ -119,9 +174,9 @@ func TestSyncWorker(t *testing.T) {
 	md.RemoveError = false
 	assert.Len(t, changed, 0)
 
-	newDkcs, err = dk.List(nil)
-	assert.NoError(t, err)
-	assert.Equal(t, dkcs, newDkcs)
+	// The container is stopped, but not removed.
+	assert.Contains(t, md.Containers, dkcs[0].ID)
+	assert.False(t, md.Containers[dkcs[0].ID].Running)
 
 	changed = runSync(dk, nil, dkcs)
 	assert.Len(t, changed, 0)
@@ -131,6 +186,179 @@ func TestSyncWorker(t *testing.T) {
 	assert.Len(t, dkcs, 0)
 }
 
+func TestSyncWorkerSecrets(t *testing.T) {
+	t.Parallel()
+
+	_, dk := docker.NewMock()
+	dbcs := []db.Container{{
+		ID:      1,
+		Image:   "Image",
+		Env:     map[string]string{"USER": "admin"},
+		Secrets: map[string]string{"PASS": "db-pass"},
+	}}
+
+	// Containers aren't started until their secrets are known.
+	runSyncSecrets(dk, dbcs, nil, map[string]string{"other": "value"})
+	dkcs, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 0)
+
+	secrets := map[string]string{"db-pass": "hunter2"}
+	runSyncSecrets(dk, dbcs, nil, secrets)
+	dkcs, err = dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+	assert.Equal(t, map[string]string{"USER": "admin", "PASS": "hunter2"},
+		dkcs[0].Env)
+	assert.Equal(t, map[string]string{"USER": "admin"}, dbcs[0].Env)
+
+	assert.Zero(t, syncJoinScore(dbcs[0], dkcs[0], secrets))
+
+	// Removing a secret doesn't stop the containers using it.
+	assert.Zero(t, syncJoinScore(dbcs[0], dkcs[0], nil))
+
+	// But changing it restarts them.
+	assert.Equal(t, -1, syncJoinScore(dbcs[0], dkcs[0],
+		map[string]string{"db-pass": "changed"}))
+}
+
+func TestSyncWorkerFiles(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	dbcs := []db.Container{{
+		ID:    1,
+		Image: "Image",
+		Files: map[string]string{"/etc/a.cfg": "a"},
+	}}
+
+	runSync(dk, dbcs, nil)
+	dkcs, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+	assert.Equal(t, dbcs[0].Files, md.Containers[dkcs[0].ID].Files)
+	assert.Zero(t, syncJoinScore(dbcs[0], dkcs[0], nil))
+
+	// Changing the contents of a file restarts the container.
+	changed := dbcs[0]
+	changed.Files = map[string]string{"/etc/a.cfg": "b"}
+	assert.Equal(t, -1, syncJoinScore(changed, dkcs[0], nil))
+
+	changed.Files = nil
+	assert.Equal(t, -1, syncJoinScore(changed, dkcs[0], nil))
+}
+
+func TestSyncWorkerSecretFiles(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	dbcs := []db.Container{{
+		ID:          1,
+		Image:       "Image",
+		Files:       map[string]string{"/etc/a.cfg": "a"},
+		SecretFiles: map[string]string{"/etc/key.pem": "key"},
+	}}
+
+	// Containers aren't started until the secrets in their files are known.
+	runSyncSecrets(dk, dbcs, nil, nil)
+	dkcs, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 0)
+
+	secrets := map[string]string{"key": "private"}
+	runSyncSecrets(dk, dbcs, nil, secrets)
+	dkcs, err = dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+	assert.Equal(t, map[string]string{"/etc/a.cfg": "a", "/etc/key.pem": "private"},
+		md.Containers[dkcs[0].ID].Files)
+	assert.Equal(t, map[string]string{"/etc/a.cfg": "a"}, dbcs[0].Files)
+	assert.Zero(t, syncJoinScore(dbcs[0], dkcs[0], secrets))
+
+	// Removing a secret doesn't stop the containers using it, but changing it
+	// restarts them.
+	assert.Zero(t, syncJoinScore(dbcs[0], dkcs[0], nil))
+	assert.Equal(t, -1, syncJoinScore(dbcs[0], dkcs[0],
+		map[string]string{"key": "changed"}))
+}
+
+func TestStopContainers(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	runSync(dk, []db.Container{{ID: 1, Image: "Image", StopTimeout: 30}}, nil)
+	dkcs, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+
+	// The worker doesn't wait for containers to stop, and doesn't stop them again
+	// in the meantime.
+	md.Lock()
+	stopContainers(dk, []interface{}{dkcs[0]})
+	assert.Empty(t, withoutStopping(dkcs))
+	md.Unlock()
+
+	err = util.WaitFor(func() bool {
+		return len(withoutStopping(dkcs)) == 1
+	}, 10*time.Millisecond, time.Second)
+	assert.NoError(t, err)
+
+	remaining, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	md.Lock()
+	assert.Equal(t, uint(30), md.Stopped[dkcs[0].ID])
+	md.Unlock()
+}
+
+func TestSyncWorkerStop(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	dbcs := []db.Container{
+		{ID: 1, Image: "Image1", StopTimeout: 30},
+		{ID: 2, Image: "Image2"},
+	}
+
+	runSync(dk, dbcs, nil)
+	dkcs, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 2)
+
+	runSync(dk, nil, dkcs)
+	dkcs, err = dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 0)
+
+	var timeouts []int
+	for _, timeout := range md.Stopped {
+		timeouts = append(timeouts, int(timeout))
+	}
+	sort.Ints(timeouts)
+	assert.Equal(t, []int{10, 30}, timeouts)
+}
+
+func TestStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, defaultStopTimeout, stopTimeout(docker.Container{}))
+	assert.Equal(t, defaultStopTimeout, stopTimeout(docker.Container{
+		Labels: map[string]string{stopTimeoutLabel: "bad"}}))
+	assert.Equal(t, 30*time.Second, stopTimeout(docker.Container{
+		Labels: map[string]string{stopTimeoutLabel: "30"}}))
+}
+
+func TestFilesHash(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, filesHash(nil))
+	assert.Equal(t, filesHash(map[string]string{"/a": "b", "/c": "d"}),
+		filesHash(map[string]string{"/c": "d", "/a": "b"}))
+	assert.NotEqual(t, filesHash(map[string]string{"/a": "b/c"}),
+		filesHash(map[string]string{"/a": "b", "/c": ""}))
+}
+
 func TestSyncJoinScore(t *testing.T) {
 	t.Parallel()
 
@@ -149,42 +377,53 @@ func TestSyncJoinScore(t *testing.T) {
 		ID:    dbc.DockerID,
 	}
 
-	score := syncJoinScore(dbc, dkc)
+	score := syncJoinScore(dbc, dkc, nil)
 	assert.Zero(t, score)
 
 	dbc.Image = "Image1"
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Equal(t, -1, score)
 
 	dbc.Image = dkc.Image
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Zero(t, score)
 
 	dbc.Command = []string{"wrong"}
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Equal(t, -1, score)
 
 	dbc.Command = dkc.Args
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Zero(t, score)
 
 	dbc.IP = "wrong"
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Equal(t, -1, score)
 
 	dbc.IP = dkc.IP
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Zero(t, score)
 
 	dbc.Command = dkc.Args
 	dbc.Env = map[string]string{"a": "wrong"}
-	score = syncJoinScore(dbc, dkc)
+	score = syncJoinScore(dbc, dkc, nil)
 	assert.Equal(t, -1, score)
 	dbc.Env = dkc.Env
 }
 
 func TestOpenFlowContainers(t *testing.T) {
-	res := openflowContainers([]db.Container{{EndpointID: "f", IP: "1.2.3.4"}})
+	res := openflowContainers([]db.Container{{EndpointID: "f", IP: "1.2.3.4"}},
+		nil, nil)
 	exp := []openflow.Container{{Veth: "f", Patch: "q_f", Mac: "02:00:01:02:03:04"}}
 	assert.Equal(t, exp, res)
+
+	res = openflowContainers([]db.Container{{EndpointID: "f", IP: "1.2.3.4",
+		Labels: []string{"to"}}},
+		[]db.Connection{{From: "from", To: "to", Bandwidth: 10}},
+		[]db.Label{{Label: "from", ContainerIPs: []string{"1.2.3.5"}}})
+	exp = []openflow.Container{{Veth: "f", Patch: "q_f", Mac: "02:00:01:02:03:04",
+		Sources: map[string]int{"1.2.3.5": 1},
+		Ports: map[string][]openflow.PortRange{
+			"1.2.3.5": {{Min: 0, Max: 0}}}}}
+	assert.Equal(t, exp, res)
 }
@@ -1,11 +1,13 @@
 package minion
 
 import (
+	"encoding/json"
 	"net"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/quilt/quilt/auth"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/minion/pb"
 
@@ -19,22 +21,27 @@ type server struct {
 	db.Conn
 }
 
-func minionServerRun(conn db.Conn) {
+// minionServerRun serves the foreman, which must authenticate with `creds`.
+func minionServerRun(conn db.Conn, creds auth.Credentials) {
+	credsOpt, err := creds.ServerOption()
+	if err != nil {
+		log.WithError(err).Error("Failed to load TLS credentials.")
+		return
+	}
+
 	var sock net.Listener
 	server := server{conn}
 	for {
-		var err error
 		sock, err = net.Listen("tcp", ":9999")
-		if err != nil {
-			log.WithError(err).Error("Failed to open socket.")
-		} else {
+		if err == nil {
 			break
 		}
+		log.WithError(err).Error("Failed to open socket.")
 
 		time.Sleep(30 * time.Second)
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(credsOpt)
 	pb.RegisterMinionServer(s, server)
 	s.Serve(sock)
 }
@@ -52,6 +59,8 @@ func (s server) GetMinionConfig(cts context.Context,
 		cfg.Size = m.Size
 		cfg.Region = m.Region
 		cfg.AuthorizedKeys = strings.Split(m.AuthorizedKeys, "\n")
+		cfg.Subnet = m.Subnet
+		cfg.IPv6Subnet = m.IPv6Subnet
 	} else {
 		cfg.Role = db.RoleToPB(db.None)
 	}
@@ -63,13 +72,21 @@ func (s server) GetMinionConfig(cts context.Context,
 		return nil
 	})
 
+	// The secrets themselves are never sent back, only enough for the foreman to
+	// tell whether they're up to date.
+	secrets := map[string]string{}
+	for _, secret := range s.SelectFromSecret(nil) {
+		secrets[secret.Name] = secret.Value
+	}
+	cfg.SecretsDigest = db.SecretsDigest(secrets)
+
 	return &cfg, nil
 }
 
 func (s server) SetMinionConfig(ctx context.Context,
 	msg *pb.MinionConfig) (*pb.Reply, error) {
-	go s.Txn(db.EtcdTable,
-		db.MinionTable).Run(func(view db.Database) error {
+	go s.Txn(db.EtcdTable, db.MinionTable,
+		db.SecretTable).Run(func(view db.Database) error {
 
 		minion, err := view.MinionSelf()
 		if err != nil {
@@ -84,6 +101,8 @@ func (s server) SetMinionConfig(ctx context.Context,
 		minion.Size = msg.Size
 		minion.Region = msg.Region
 		minion.AuthorizedKeys = strings.Join(msg.AuthorizedKeys, "\n")
+		minion.Subnet = msg.Subnet
+		minion.IPv6Subnet = msg.IPv6Subnet
 		minion.Self = true
 		view.Commit(minion)
 
@@ -97,8 +116,55 @@ func (s server) SetMinionConfig(ctx context.Context,
 		sort.Strings(etcdRow.EtcdIPs)
 		view.Commit(etcdRow)
 
+		updateSecrets(view, msg.Secrets)
 		return nil
 	})
 
 	return &pb.Reply{}, nil
 }
+
+// GetEvents returns the events last seen after the requested time, so that the
+// daemon can replicate them.
+func (s server) GetEvents(cts context.Context, req *pb.MinionEventsRequest) (
+	*pb.MinionEventsReply, error) {
+
+	since := time.Unix(0, req.Since)
+	events := s.SelectFromEvent(func(e db.Event) bool {
+		return e.LastSeen.After(since)
+	})
+
+	js, err := json.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.MinionEventsReply{Events: string(js)}, nil
+}
+
+// updateSecrets makes the Secret table match the secrets sent by the daemon.
+func updateSecrets(view db.Database, secrets map[string]string) {
+	toAdd := map[string]string{}
+	for name, value := range secrets {
+		toAdd[name] = value
+	}
+
+	for _, dbSecret := range view.SelectFromSecret(nil) {
+		value, ok := toAdd[dbSecret.Name]
+		if !ok {
+			view.Remove(dbSecret)
+			continue
+		}
+		delete(toAdd, dbSecret.Name)
+
+		if dbSecret.Value != value {
+			dbSecret.Value = value
+			view.Commit(dbSecret)
+		}
+	}
+
+	for name, value := range toAdd {
+		dbSecret := view.InsertSecret()
+		dbSecret.Name = name
+		dbSecret.Value = value
+		view.Commit(dbSecret)
+	}
+}
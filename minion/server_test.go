@@ -1,7 +1,9 @@
 package minion
 
 import (
+	"encoding/json"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -24,6 +26,8 @@ func TestSetMinionConfig(t *testing.T) {
 		Region:         "region",
 		EtcdMembers:    []string{"etcd1", "etcd2"},
 		AuthorizedKeys: []string{"key1", "key2"},
+		Subnet:         "172.16.0.0/12",
+		IPv6Subnet:     "fd00::/64",
 	}
 	expMinion := db.Minion{
 		Self:           true,
@@ -34,6 +38,8 @@ func TestSetMinionConfig(t *testing.T) {
 		Size:           "size",
 		Region:         "region",
 		AuthorizedKeys: "key1\nkey2",
+		Subnet:         "172.16.0.0/12",
+		IPv6Subnet:     "fd00::/64",
 	}
 	_, err := s.SetMinionConfig(nil, &cfg)
 	assert.NoError(t, err)
@@ -54,6 +60,61 @@ func TestSetMinionConfig(t *testing.T) {
 	})
 }
 
+func TestSetMinionConfigSecrets(t *testing.T) {
+	t.Parallel()
+	s := server{db.New()}
+
+	cfg := pb.MinionConfig{
+		Role:    pb.MinionConfig_WORKER,
+		Secrets: map[string]string{"a": "1", "b": "2"},
+	}
+	_, err := s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkSecretsEqual(t, s.Conn, map[string]string{"a": "1", "b": "2"})
+
+	cfg.Secrets = map[string]string{"b": "3", "c": "4"}
+	_, err = s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkSecretsEqual(t, s.Conn, map[string]string{"b": "3", "c": "4"})
+	assert.Equal(t, map[string]string{"b": "3", "c": "4"}, cfg.Secrets)
+
+	// Only a digest of the secrets is reported.
+	got, err := s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.Nil(t, got.Secrets)
+	assert.Equal(t, db.SecretsDigest(cfg.Secrets), got.SecretsDigest)
+
+	cfg.Secrets = nil
+	_, err = s.SetMinionConfig(nil, &cfg)
+	assert.NoError(t, err)
+	checkSecretsEqual(t, s.Conn, map[string]string{})
+
+	got, err = s.GetMinionConfig(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.Empty(t, got.SecretsDigest)
+}
+
+func checkSecretsEqual(t *testing.T, conn db.Conn, exp map[string]string) {
+	timeout := time.After(1 * time.Second)
+	for {
+		actual := map[string]string{}
+		for _, secret := range conn.SelectFromSecret(nil) {
+			actual[secret.Name] = secret.Value
+		}
+
+		if reflect.DeepEqual(exp, actual) {
+			return
+		}
+		select {
+		case <-timeout:
+			t.Errorf("Expected secrets to be %v, but got %v\n", exp, actual)
+			return
+		default:
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
 func checkMinionEquals(t *testing.T, conn db.Conn, exp db.Minion) {
 	timeout := time.After(1 * time.Second)
 	var actual db.Minion
@@ -115,6 +176,7 @@ func TestGetMinionConfig(t *testing.T) {
 		m.Size = "size"
 		m.Region = "region"
 		m.AuthorizedKeys = "key1\nkey2"
+		m.Subnet = "172.16.0.0/12"
 		view.Commit(m)
 		return nil
 	})
@@ -144,5 +206,44 @@ func TestGetMinionConfig(t *testing.T) {
 		Region:         "region",
 		EtcdMembers:    []string{"etcd1", "etcd2"},
 		AuthorizedKeys: []string{"key1", "key2"},
+		Subnet:         "172.16.0.0/12",
 	}, *cfg)
 }
+
+func TestGetEvents(t *testing.T) {
+	t.Parallel()
+	s := server{db.New()}
+
+	start := time.Now()
+	s.Conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for i, object := range []string{"container/a", "container/b"} {
+			e := view.InsertEvent()
+			e.Object = object
+			e.Count = 1
+			e.LastSeen = start.Add(time.Duration(i) * time.Second)
+			view.Commit(e)
+		}
+		return nil
+	})
+
+	getEvents := func(since time.Time) []string {
+		req := &pb.MinionEventsRequest{Since: since.UnixNano()}
+		reply, err := s.GetEvents(nil, req)
+		assert.NoError(t, err)
+
+		var events []db.Event
+		assert.NoError(t, json.Unmarshal([]byte(reply.Events), &events))
+
+		var objects []string
+		for _, e := range events {
+			objects = append(objects, e.Object)
+		}
+		sort.Strings(objects)
+		return objects
+	}
+
+	assert.Equal(t, []string{"container/a", "container/b"},
+		getEvents(start.Add(-time.Second)))
+	assert.Equal(t, []string{"container/b"}, getEvents(start))
+	assert.Empty(t, getEvents(start.Add(time.Second)))
+}
@@ -0,0 +1,210 @@
+package minion
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/util"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const statsInterval = 10 * time.Second
+
+// cpuTimes are the total and idle jiffies spent by all CPUs, as read from /proc/stat.
+type cpuTimes struct {
+	total, idle uint64
+	cores       int
+}
+
+// runStats periodically samples the resource usage of the local containers, and of
+// the machine itself, into the Stats table, where `quilt top` reads it.
+func runStats(conn db.Conn, dk docker.Client) {
+	var prevCPU cpuTimes
+	for range time.Tick(statsInterval) {
+		self, err := conn.MinionSelf()
+		if err != nil || self.Role != db.Worker {
+			continue
+		}
+
+		var stats []db.Stats
+		machine, cpu, err := machineStats(prevCPU)
+		if err != nil {
+			log.WithError(err).Warning("Failed to sample machine stats.")
+		} else {
+			stats = append(stats, machine)
+		}
+		prevCPU = cpu
+
+		stats = append(stats, containerStats(conn, dk, self.PrivateIP)...)
+
+		now := time.Now()
+		for i := range stats {
+			stats[i].Minion = self.PrivateIP
+			stats[i].Time = now
+		}
+
+		conn.Txn(db.StatsTable).Run(func(view db.Database) error {
+			updateStats(view, stats)
+			return nil
+		})
+	}
+}
+
+// containerStats samples the containers running on the minion with IP `myIP`.
+// Containers that fail to be sampled, usually because they just exited, are skipped.
+func containerStats(conn db.Conn, dk docker.Client, myIP string) []db.Stats {
+	dbcs := conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.Minion == myIP && dbc.DockerID != ""
+	})
+
+	var stats []db.Stats
+	for _, dbc := range dbcs {
+		sample, err := dk.Stats(dbc.DockerID)
+		if err != nil {
+			log.WithError(err).WithField("container", dbc.StitchID).Debug(
+				"Failed to sample container stats.")
+			continue
+		}
+
+		stats = append(stats, db.Stats{
+			StitchID:    dbc.StitchID,
+			CPUPercent:  sample.CPUPercent,
+			MemoryUsage: sample.MemoryUsage,
+			MemoryLimit: sample.MemoryLimit,
+			NetworkRx:   sample.NetworkRx,
+			NetworkTx:   sample.NetworkTx,
+		})
+	}
+	return stats
+}
+
+// machineStats samples the load and memory usage of the machine, and its CPU usage
+// since `prev`.  It returns the current CPU times to be passed in next time.
+func machineStats(prev cpuTimes) (db.Stats, cpuTimes, error) {
+	var stats db.Stats
+
+	loadavg, err := util.ReadFile("/proc/loadavg")
+	if err != nil {
+		return stats, prev, err
+	}
+
+	fields := strings.Fields(loadavg)
+	if len(fields) < 3 {
+		return stats, prev, fmt.Errorf("malformed /proc/loadavg: %s", loadavg)
+	}
+	for i, load := range []*float64{&stats.Load1, &stats.Load5, &stats.Load15} {
+		if *load, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return stats, prev, err
+		}
+	}
+
+	meminfo, err := util.ReadFile("/proc/meminfo")
+	if err != nil {
+		return stats, prev, err
+	}
+
+	mem := map[string]uint64{}
+	for _, line := range strings.Split(meminfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Values are in kB.
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err == nil {
+			mem[strings.TrimSuffix(fields[0], ":")] = kb * 1024
+		}
+	}
+	stats.MemoryLimit = mem["MemTotal"]
+	if available, ok := mem["MemAvailable"]; ok && available <= mem["MemTotal"] {
+		stats.MemoryUsage = mem["MemTotal"] - available
+	}
+
+	cpu, err := readCPUTimes()
+	if err != nil {
+		return stats, prev, err
+	}
+
+	if prev.total != 0 && cpu.total > prev.total {
+		busy := (cpu.total - prev.total) - (cpu.idle - prev.idle)
+		stats.CPUPercent = float64(busy) / float64(cpu.total-prev.total) *
+			float64(cpu.cores) * 100
+	}
+	return stats, cpu, nil
+}
+
+func readCPUTimes() (cpuTimes, error) {
+	stat, err := util.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+
+	var cpu cpuTimes
+	for _, line := range strings.Split(stat, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu"):
+			continue
+		case fields[0] != "cpu":
+			cpu.cores++
+			continue
+		}
+
+		// The aggregate line starts with user, nice, system, idle, iowait, irq,
+		// softirq, and steal time.  The guest times that follow are already
+		// included in the user times.
+		times := fields[1:]
+		if len(times) > 8 {
+			times = times[:8]
+		}
+
+		for i, field := range times {
+			jiffies, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return cpuTimes{}, fmt.Errorf("malformed /proc/stat: %s", line)
+			}
+
+			cpu.total += jiffies
+			if i == 3 || i == 4 {
+				cpu.idle += jiffies
+			}
+		}
+	}
+
+	if cpu.total == 0 {
+		return cpuTimes{}, errors.New("no CPU times in /proc/stat")
+	}
+	return cpu, nil
+}
+
+// updateStats replaces the Stats table with `stats`.
+func updateStats(view db.Database, stats []db.Stats) {
+	key := func(iface interface{}) interface{} {
+		return iface.(db.Stats).StitchID
+	}
+
+	pairs, dbStats, newStats := join.HashJoin(
+		db.StatsSlice(view.SelectFromStats(nil)), db.StatsSlice(stats), key, key)
+
+	for _, s := range dbStats {
+		view.Remove(s.(db.Stats))
+	}
+
+	for _, s := range newStats {
+		pairs = append(pairs, join.Pair{L: view.InsertStats(), R: s})
+	}
+
+	for _, pair := range pairs {
+		s := pair.R.(db.Stats)
+		s.ID = pair.L.(db.Stats).ID
+		view.Commit(s)
+	}
+}
@@ -0,0 +1,131 @@
+package minion
+
+import (
+	"sort"
+	"testing"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/minion/docker"
+	"github.com/quilt/quilt/util"
+)
+
+func TestMachineStats(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	_, _, err := machineStats(cpuTimes{})
+	assert.Error(t, err)
+
+	util.WriteFile("/proc/loadavg", []byte("0.50 1.25 2.00 1/100 1234\n"), 0644)
+	util.WriteFile("/proc/meminfo", []byte("MemTotal:       1000 kB\n"+
+		"MemFree:         100 kB\nMemAvailable:    400 kB\n"), 0644)
+	util.WriteFile("/proc/stat", []byte(
+		"cpu  100 0 100 700 100 0 0 0 50 0\n"+
+			"cpu0 50 0 50 350 50 0 0 0 25 0\n"+
+			"cpu1 50 0 50 350 50 0 0 0 25 0\n"+
+			"intr 12345\n"), 0644)
+
+	stats, cpu, err := machineStats(cpuTimes{})
+	assert.NoError(t, err)
+	assert.Equal(t, cpuTimes{total: 1000, idle: 800, cores: 2}, cpu)
+
+	// Without a previous sample, the CPU usage is unknown.
+	assert.Equal(t, db.Stats{
+		MemoryUsage: 600 * 1024,
+		MemoryLimit: 1000 * 1024,
+		Load1:       0.5,
+		Load5:       1.25,
+		Load15:      2,
+	}, stats)
+
+	util.WriteFile("/proc/stat", []byte(
+		"cpu  200 0 200 1300 300 0 0 0 50 0\ncpu0\ncpu1\n"), 0644)
+	stats, cpu, err = machineStats(cpu)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuTimes{total: 2000, idle: 1600, cores: 2}, cpu)
+
+	// 200 of the 1000 jiffies were busy, across 2 cores.
+	assert.Equal(t, 40.0, stats.CPUPercent)
+
+	util.WriteFile("/proc/stat", []byte("cpu  a b c\n"), 0644)
+	_, prev, err := machineStats(cpu)
+	assert.EqualError(t, err, "malformed /proc/stat: cpu  a b c")
+	assert.Equal(t, cpu, prev)
+
+	util.WriteFile("/proc/loadavg", []byte("0.50\n"), 0644)
+	_, _, err = machineStats(cpu)
+	assert.EqualError(t, err, "malformed /proc/loadavg: 0.50\n")
+}
+
+func TestContainerStats(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	sample := &dkc.Stats{}
+	sample.MemoryStats.Usage = 10
+	sample.MemoryStats.Limit = 100
+	sample.Networks = map[string]dkc.NetworkStats{
+		"eth0": {RxBytes: 1, TxBytes: 2},
+	}
+	md.ContainerStats["dockerA"] = sample
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range []db.Container{
+			{StitchID: "a", DockerID: "dockerA", Minion: "1.1.1.1"},
+			{StitchID: "b", DockerID: "dockerB", Minion: "1.1.1.1"},
+			{StitchID: "c", DockerID: "dockerC", Minion: "2.2.2.2"},
+			{StitchID: "d", Minion: "1.1.1.1"},
+		} {
+			c := view.InsertContainer()
+			dbc.ID = c.ID
+			view.Commit(dbc)
+		}
+		return nil
+	})
+
+	// Container b has exited, so it can't be sampled.
+	assert.Equal(t, []db.Stats{{
+		StitchID:    "a",
+		MemoryUsage: 10,
+		MemoryLimit: 100,
+		NetworkRx:   1,
+		NetworkTx:   2,
+	}}, containerStats(conn, dk, "1.1.1.1"))
+}
+
+func TestUpdateStats(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	update := func(stats ...db.Stats) []db.Stats {
+		conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+			updateStats(view, stats)
+			return nil
+		})
+
+		rows := db.StatsSlice(conn.SelectFromStats(nil))
+		for i := range rows {
+			rows[i].ID = 0
+		}
+		return rows
+	}
+
+	assert.Equal(t, []db.Stats{{Load1: 1}, {StitchID: "a", CPUPercent: 1}},
+		sortedStats(update(db.Stats{Load1: 1},
+			db.Stats{StitchID: "a", CPUPercent: 1})))
+
+	assert.Equal(t, []db.Stats{{Load1: 2}, {StitchID: "b", CPUPercent: 2}},
+		sortedStats(update(db.Stats{Load1: 2},
+			db.Stats{StitchID: "b", CPUPercent: 2})))
+
+	assert.Empty(t, update())
+}
+
+func sortedStats(stats []db.Stats) []db.Stats {
+	sort.Sort(db.StatsSlice(stats))
+	return stats
+}
@@ -0,0 +1,253 @@
+package supervisor
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/join"
+	"github.com/quilt/quilt/minion/ipdef"
+	"github.com/vishvananda/netlink"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The reqid marks the xfrm states and policies managed by Quilt, so that IPsec
+// configuration installed by anybody else is left alone.
+const ipsecReqid = 0x71756c74
+
+const ipsecAlgo = "rfc4106(gcm(aes))"
+const ipsecICVLen = 128
+
+// Only the overlay's tunnels are encrypted, so that workers that fail to agree on
+// keys can still reach each other's other services, such as etcd and the minion API.
+// These are the transport protocol and destination port of each tunneling protocol.
+var tunnelSelectors = map[string]struct {
+	proto netlink.Proto
+	port  int
+}{
+	"stt":    {syscall.IPPROTO_TCP, 7471},
+	"geneve": {syscall.IPPROTO_UDP, 6081},
+}
+
+// runIPsecOnce installs transport mode ESP between this worker and every other
+// worker in the cluster when the leader has published an overlay key, and removes
+// it otherwise.  Each direction between a pair of workers gets its own key and SPI,
+// derived from the master key and the endpoints' addresses, so that every worker
+// computes the same security associations without further coordination.
+func runIPsecOnce(conn db.Conn) {
+	var self db.Minion
+	var peers []string
+	var key string
+	conn.Txn(db.MinionTable, db.EtcdTable).Run(func(view db.Database) error {
+		self, _ = view.MinionSelf()
+		if etcdRows := view.SelectFromEtcd(nil); len(etcdRows) == 1 {
+			key = etcdRows[0].OverlayKey
+		}
+
+		for _, m := range view.SelectFromMinion(nil) {
+			if !m.Self && m.Role == db.Worker && m.PrivateIP != "" {
+				peers = append(peers, m.PrivateIP)
+			}
+		}
+		return nil
+	})
+
+	var states []netlink.XfrmState
+	var policies []netlink.XfrmPolicy
+	if self.Role == db.Worker && self.PrivateIP != "" && key != "" {
+		master, err := hex.DecodeString(key)
+		if err != nil || len(master) != db.OverlayKeyLen {
+			log.Error("Malformed overlay key.")
+			return
+		}
+		states, policies = ipsecConfig(self.PrivateIP, peers, master)
+	}
+
+	if err := syncXfrmStates(states); err != nil {
+		log.WithError(err).Error("Failed to sync IPsec security associations.")
+	}
+
+	if err := syncXfrmPolicies(policies); err != nil {
+		log.WithError(err).Error("Failed to sync IPsec policies.")
+	}
+}
+
+func ipsecConfig(selfIP string, peerIPs []string, master []byte) (
+	[]netlink.XfrmState, []netlink.XfrmPolicy) {
+
+	self := net.ParseIP(selfIP).To4()
+	if self == nil {
+		return nil, nil
+	}
+
+	var states []netlink.XfrmState
+	var policies []netlink.XfrmPolicy
+	for _, peerIP := range peerIPs {
+		peer := net.ParseIP(peerIP).To4()
+		if peer == nil || peer.Equal(self) {
+			continue
+		}
+
+		states = append(states, xfrmState(self, peer, master),
+			xfrmState(peer, self, master))
+		policies = append(policies, xfrmPolicy(self, peer, netlink.XFRM_DIR_OUT),
+			xfrmPolicy(peer, self, netlink.XFRM_DIR_IN))
+	}
+	return states, policies
+}
+
+func xfrmState(src, dst net.IP, master []byte) netlink.XfrmState {
+	mac := hmac.New(sha512.New, master)
+	mac.Write([]byte(fmt.Sprintf("%s>%s", src, dst)))
+	sum := mac.Sum(nil)
+
+	// SPIs below 256 are reserved.
+	spi := binary.BigEndian.Uint32(sum[db.OverlayKeyLen:]) | 0x100
+	return netlink.XfrmState{
+		Src:   src,
+		Dst:   dst,
+		Proto: netlink.XFRM_PROTO_ESP,
+		Mode:  netlink.XFRM_MODE_TRANSPORT,
+		Spi:   int(spi),
+		Reqid: ipsecReqid,
+		Aead: &netlink.XfrmStateAlgo{
+			Name:   ipsecAlgo,
+			Key:    sum[:db.OverlayKeyLen],
+			ICVLen: ipsecICVLen,
+		},
+	}
+}
+
+func xfrmPolicy(src, dst net.IP, dir netlink.Dir) netlink.XfrmPolicy {
+	tunnel := tunnelSelectors[ipdef.TunnelProtocol]
+	return netlink.XfrmPolicy{
+		Src:     &net.IPNet{IP: src, Mask: net.CIDRMask(32, 32)},
+		Dst:     &net.IPNet{IP: dst, Mask: net.CIDRMask(32, 32)},
+		Proto:   tunnel.proto,
+		DstPort: tunnel.port,
+		Dir:     dir,
+		Tmpls: []netlink.XfrmPolicyTmpl{{
+			Src:   src,
+			Dst:   dst,
+			Proto: netlink.XFRM_PROTO_ESP,
+			Mode:  netlink.XFRM_MODE_TRANSPORT,
+			Reqid: ipsecReqid,
+		}},
+	}
+}
+
+func syncXfrmStates(target []netlink.XfrmState) error {
+	allStates, err := xfrmStateList(netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("failed to list states: %s", err)
+	}
+
+	var current []netlink.XfrmState
+	for _, state := range allStates {
+		if state.Reqid == ipsecReqid {
+			current = append(current, state)
+		}
+	}
+
+	key := func(val interface{}) interface{} {
+		state := val.(netlink.XfrmState)
+		return struct {
+			src, dst string
+			spi      int
+		}{state.Src.String(), state.Dst.String(), state.Spi}
+	}
+
+	_, dels, adds := join.HashJoin(xfrmStateSlice(current),
+		xfrmStateSlice(target), key, key)
+
+	for _, iface := range dels {
+		state := iface.(netlink.XfrmState)
+		if err := xfrmStateDel(&state); err != nil {
+			return fmt.Errorf("failed to delete state %s: %s", state, err)
+		}
+	}
+
+	for _, iface := range adds {
+		state := iface.(netlink.XfrmState)
+		if err := xfrmStateAdd(&state); err != nil {
+			return fmt.Errorf("failed to add state %s: %s", state, err)
+		}
+	}
+	return nil
+}
+
+func syncXfrmPolicies(target []netlink.XfrmPolicy) error {
+	allPolicies, err := xfrmPolicyList(netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("failed to list policies: %s", err)
+	}
+
+	var current []netlink.XfrmPolicy
+	for _, policy := range allPolicies {
+		if len(policy.Tmpls) > 0 && policy.Tmpls[0].Reqid == ipsecReqid {
+			current = append(current, policy)
+		}
+	}
+
+	key := func(val interface{}) interface{} {
+		policy := val.(netlink.XfrmPolicy)
+		return struct {
+			src, dst string
+			proto    netlink.Proto
+			port     int
+			dir      netlink.Dir
+		}{policy.Src.String(), policy.Dst.String(), policy.Proto,
+			policy.DstPort, policy.Dir}
+	}
+
+	_, dels, adds := join.HashJoin(xfrmPolicySlice(current),
+		xfrmPolicySlice(target), key, key)
+
+	for _, iface := range dels {
+		policy := iface.(netlink.XfrmPolicy)
+		if err := xfrmPolicyDel(&policy); err != nil {
+			return fmt.Errorf("failed to delete policy %s: %s", policy, err)
+		}
+	}
+
+	for _, iface := range adds {
+		policy := iface.(netlink.XfrmPolicy)
+		if err := xfrmPolicyAdd(&policy); err != nil {
+			return fmt.Errorf("failed to add policy %s: %s", policy, err)
+		}
+	}
+	return nil
+}
+
+type xfrmStateSlice []netlink.XfrmState
+
+func (s xfrmStateSlice) Get(i int) interface{} {
+	return s[i]
+}
+
+func (s xfrmStateSlice) Len() int {
+	return len(s)
+}
+
+type xfrmPolicySlice []netlink.XfrmPolicy
+
+func (s xfrmPolicySlice) Get(i int) interface{} {
+	return s[i]
+}
+
+func (s xfrmPolicySlice) Len() int {
+	return len(s)
+}
+
+var xfrmStateList = netlink.XfrmStateList
+var xfrmStateAdd = netlink.XfrmStateAdd
+var xfrmStateDel = netlink.XfrmStateDel
+var xfrmPolicyList = netlink.XfrmPolicyList
+var xfrmPolicyAdd = netlink.XfrmPolicyAdd
+var xfrmPolicyDel = netlink.XfrmPolicyDel
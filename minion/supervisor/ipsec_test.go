@@ -0,0 +1,191 @@
+package supervisor
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/quilt/quilt/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+type fakeXfrm struct {
+	states   []netlink.XfrmState
+	policies []netlink.XfrmPolicy
+}
+
+func newFakeXfrm() *fakeXfrm {
+	fx := &fakeXfrm{}
+	xfrmStateList = func(int) ([]netlink.XfrmState, error) {
+		return fx.states, nil
+	}
+	xfrmStateAdd = func(state *netlink.XfrmState) error {
+		fx.states = append(fx.states, *state)
+		return nil
+	}
+	xfrmStateDel = func(state *netlink.XfrmState) error {
+		for i, s := range fx.states {
+			if s.Dst.Equal(state.Dst) && s.Spi == state.Spi {
+				fx.states = append(fx.states[:i], fx.states[i+1:]...)
+				return nil
+			}
+		}
+		return errors.New("no such state")
+	}
+	xfrmPolicyList = func(int) ([]netlink.XfrmPolicy, error) {
+		return fx.policies, nil
+	}
+	xfrmPolicyAdd = func(policy *netlink.XfrmPolicy) error {
+		fx.policies = append(fx.policies, *policy)
+		return nil
+	}
+	xfrmPolicyDel = func(policy *netlink.XfrmPolicy) error {
+		for i, p := range fx.policies {
+			if p.Src.String() == policy.Src.String() &&
+				p.Dst.String() == policy.Dst.String() &&
+				p.Dir == policy.Dir {
+				fx.policies = append(fx.policies[:i], fx.policies[i+1:]...)
+				return nil
+			}
+		}
+		return errors.New("no such policy")
+	}
+	return fx
+}
+
+func TestRunIPsecOnce(t *testing.T) {
+	fx := newFakeXfrm()
+	conn := db.New()
+
+	// Configuration we don't own must be left alone.
+	foreign := netlink.XfrmState{Src: net.IP{9, 9, 9, 9}, Dst: net.IP{1, 1, 1, 1},
+		Spi: 5, Reqid: 1}
+	fx.states = append(fx.states, foreign)
+
+	key := hex.EncodeToString([]byte(strings.Repeat("k", db.OverlayKeyLen)))
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		self.Role = db.Worker
+		self.PrivateIP = "10.1.0.1"
+		view.Commit(self)
+
+		for _, ip := range []string{"10.1.0.2", "10.1.0.3"} {
+			m := view.InsertMinion()
+			m.Role = db.Worker
+			m.PrivateIP = ip
+			view.Commit(m)
+		}
+
+		master := view.InsertMinion()
+		master.Role = db.Master
+		master.PrivateIP = "10.1.0.4"
+		view.Commit(master)
+
+		etcd := view.InsertEtcd()
+		view.Commit(etcd)
+		return nil
+	})
+
+	// Without a key, nothing is configured.
+	runIPsecOnce(conn)
+	assert.Equal(t, []netlink.XfrmState{foreign}, fx.states)
+	assert.Empty(t, fx.policies)
+
+	setOverlayKey(conn, key)
+	runIPsecOnce(conn)
+	assert.Len(t, fx.states, 5)
+	assert.Len(t, fx.policies, 4)
+
+	pairs := map[string]netlink.Dir{}
+	for _, p := range fx.policies {
+		pairs[p.Src.String()+">"+p.Dst.String()] = p.Dir
+		assert.Equal(t, ipsecReqid, p.Tmpls[0].Reqid)
+
+		// Only STT tunnels are encrypted.
+		assert.Equal(t, netlink.Proto(syscall.IPPROTO_TCP), p.Proto)
+		assert.Equal(t, 7471, p.DstPort)
+	}
+	assert.Equal(t, map[string]netlink.Dir{
+		"10.1.0.1/32>10.1.0.2/32": netlink.XFRM_DIR_OUT,
+		"10.1.0.2/32>10.1.0.1/32": netlink.XFRM_DIR_IN,
+		"10.1.0.1/32>10.1.0.3/32": netlink.XFRM_DIR_OUT,
+		"10.1.0.3/32>10.1.0.1/32": netlink.XFRM_DIR_IN,
+	}, pairs)
+
+	// Running again is a no-op.
+	states := append([]netlink.XfrmState{}, fx.states...)
+	runIPsecOnce(conn)
+	assert.Equal(t, states, fx.states)
+
+	// A new key replaces the security associations, but not the policies.
+	setOverlayKey(conn, hex.EncodeToString([]byte(strings.Repeat("j",
+		db.OverlayKeyLen))))
+	runIPsecOnce(conn)
+	assert.Len(t, fx.states, 5)
+	assert.Len(t, fx.policies, 4)
+	for _, s := range states[1:] {
+		assert.NotContains(t, fx.states, s)
+	}
+
+	// A malformed key leaves the configuration as is.
+	setOverlayKey(conn, "zz")
+	runIPsecOnce(conn)
+	assert.Len(t, fx.states, 5)
+
+	setOverlayKey(conn, "")
+	runIPsecOnce(conn)
+	assert.Equal(t, []netlink.XfrmState{foreign}, fx.states)
+	assert.Empty(t, fx.policies)
+}
+
+func TestXfrmState(t *testing.T) {
+	t.Parallel()
+
+	a := net.IP{10, 1, 0, 1}
+	b := net.IP{10, 1, 0, 2}
+	master := []byte(strings.Repeat("k", db.OverlayKeyLen))
+
+	ab := xfrmState(a, b, master)
+	ba := xfrmState(b, a, master)
+
+	// Both workers must derive identical security associations.
+	assert.Equal(t, ab, xfrmState(a, b, master))
+
+	// But each direction must use its own key and SPI.
+	assert.NotEqual(t, ab.Aead.Key, ba.Aead.Key)
+	assert.NotEqual(t, ab.Spi, ba.Spi)
+
+	assert.Len(t, ab.Aead.Key, db.OverlayKeyLen)
+	assert.True(t, ab.Spi >= 0x100)
+	assert.Equal(t, ipsecAlgo, ab.Aead.Name)
+	assert.Equal(t, netlink.XFRM_MODE_TRANSPORT, ab.Mode)
+}
+
+func TestIPsecConfig(t *testing.T) {
+	t.Parallel()
+
+	master := []byte(strings.Repeat("k", db.OverlayKeyLen))
+
+	states, policies := ipsecConfig("bad", []string{"10.1.0.2"}, master)
+	assert.Empty(t, states)
+	assert.Empty(t, policies)
+
+	states, policies = ipsecConfig("10.1.0.1",
+		[]string{"10.1.0.1", "bad", "10.1.0.2"}, master)
+	assert.Len(t, states, 2)
+	assert.Len(t, policies, 2)
+}
+
+func setOverlayKey(conn db.Conn, key string) {
+	conn.Txn(db.EtcdTable).Run(func(view db.Database) error {
+		etcd := view.SelectFromEtcd(nil)[0]
+		etcd.OverlayKey = key
+		view.Commit(etcd)
+		return nil
+	})
+}
@@ -35,10 +35,6 @@ const (
 
 const ovsImage = "quilt/ovs"
 
-// The tunneling protocol to use between machines.
-// "stt" and "geneve" are supported.
-const tunnelingProtocol = "stt"
-
 var images = map[string]string{
 	Etcd:          "quay.io/coreos/etcd:v3.0.2",
 	Ovncontroller: ovsImage,
@@ -62,6 +58,9 @@ type supervisor struct {
 	provider string
 	region   string
 	size     string
+
+	// The MTU quilt-int was configured with, or 0 if it hasn't been.
+	mtu int
 }
 
 // Run blocks implementing the supervisor module.
@@ -85,6 +84,7 @@ func (sv *supervisor) runSystem() {
 	for range sv.conn.Trigger(db.MinionTable, db.EtcdTable).C {
 		loopLog.LogStart()
 		sv.runSystemOnce()
+		runIPsecOnce(sv.conn)
 		loopLog.LogEnd()
 	}
 }
@@ -95,6 +95,8 @@ func (sv *supervisor) runSystemOnce() {
 		return
 	}
 
+	sv.updateMTU()
+
 	var etcdRow db.Etcd
 	if etcdRows := sv.conn.SelectFromEtcd(nil); len(etcdRows) == 1 {
 		etcdRow = etcdRows[0]
@@ -114,6 +116,7 @@ func (sv *supervisor) runSystemOnce() {
 	if minion.Role != sv.role {
 		sv.SetInit(false)
 		sv.RemoveAll()
+		sv.mtu = 0
 	}
 
 	switch minion.Role {
@@ -153,19 +156,23 @@ func (sv *supervisor) updateWorker(IP string, leaderIP string, etcdIPs []string)
 	}
 
 	gwMac := ipdef.IPToMac(ipdef.GatewayIP)
+	mtu := ipdef.MTU()
 	err := execRun("ovs-vsctl", "set", "Open_vSwitch", ".",
 		fmt.Sprintf("external_ids:ovn-remote=\"tcp:%s:6640\"", leaderIP),
 		fmt.Sprintf("external_ids:ovn-encap-ip=%s", IP),
-		fmt.Sprintf("external_ids:ovn-encap-type=\"%s\"", tunnelingProtocol),
+		fmt.Sprintf("external_ids:ovn-encap-type=\"%s\"", ipdef.TunnelProtocol),
 		fmt.Sprintf("external_ids:api_server=\"http://%s:9000\"", leaderIP),
 		fmt.Sprintf("external_ids:system-id=\"%s\"", IP),
 		"--", "add-br", "quilt-int",
 		"--", "set", "bridge", "quilt-int", "fail_mode=secure",
-		fmt.Sprintf("other_config:hwaddr=\"%s\"", gwMac))
+		fmt.Sprintf("other_config:hwaddr=\"%s\"", gwMac),
+		"--", "set", "interface", "quilt-int",
+		fmt.Sprintf("mtu_request=%d", mtu))
 	if err != nil {
 		log.WithError(err).Warnf("Failed to exec in %s.", Ovsvswitchd)
 		return
 	}
+	sv.mtu = mtu
 
 	ip := net.IPNet{IP: ipdef.GatewayIP, Mask: ipdef.QuiltSubnet.Mask}
 	if err := cfgGateway("quilt-int", ip); err != nil {
@@ -173,6 +180,14 @@ func (sv *supervisor) updateWorker(IP string, leaderIP string, etcdIPs []string)
 		return
 	}
 
+	if ipdef.GatewayIP6 != nil {
+		ip6 := net.IPNet{IP: ipdef.GatewayIP6, Mask: ipdef.QuiltSubnet6.Mask}
+		if err := cfgGateway("quilt-int", ip6); err != nil {
+			log.WithError(err).Error("Failed to configure quilt-int IPv6.")
+			return
+		}
+	}
+
 	/* The ovn controller doesn't support reconfiguring ovn-remote mid-run.
 	 * So, we need to restart the container when the leader changes. */
 	sv.Remove(Ovncontroller)
@@ -180,6 +195,23 @@ func (sv *supervisor) updateWorker(IP string, leaderIP string, etcdIPs []string)
 	sv.SetInit(true)
 }
 
+// updateMTU reconfigures quilt-int when the MTU of the container network changes
+// after it was created.  Containers keep the MTU they were started with.
+func (sv *supervisor) updateMTU() {
+	mtu := ipdef.MTU()
+	if sv.mtu == 0 || sv.mtu == mtu {
+		return
+	}
+
+	err := execRun("ovs-vsctl", "set", "interface", "quilt-int",
+		fmt.Sprintf("mtu_request=%d", mtu))
+	if err != nil {
+		log.WithError(err).Warn("Failed to update the MTU of quilt-int.")
+		return
+	}
+	sv.mtu = mtu
+}
+
 func (sv *supervisor) updateMaster(IP string, etcdIPs []string, leader bool) {
 	if sv.IP != IP || !reflect.DeepEqual(sv.etcdIPs, etcdIPs) {
 		sv.Remove(Etcd)
@@ -187,6 +187,71 @@ func TestWorker(t *testing.T) {
 	}
 }
 
+func TestWorkerMTU(t *testing.T) {
+	defer ipdef.SetMTU(ipdef.MTU())
+	ipdef.SetMTU(1400)
+
+	ctx := initTest()
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m, _ := view.MinionSelf()
+		e := view.SelectFromEtcd(nil)[0]
+		m.Role = db.Worker
+		m.PrivateIP = "1.2.3.4"
+		e.EtcdIPs = []string{"1.2.3.4"}
+		e.LeaderIP = "5.6.7.8"
+		view.Commit(m)
+		view.Commit(e)
+		return nil
+	})
+	ctx.run()
+	assert.Equal(t, ovsExecArgs("1.2.3.4", "5.6.7.8"), ctx.execs)
+
+	// A new MTU is applied to quilt-int without recreating it.
+	ipdef.SetMTU(1363)
+	ctx.conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		m, _ := view.MinionSelf()
+		m.MTU = 1363
+		view.Commit(m)
+		return nil
+	})
+	ctx.run()
+	assert.Equal(t, [][]string{{"ovs-vsctl", "set", "interface", "quilt-int",
+		"mtu_request=1363"}}, ctx.execs)
+
+	ctx.conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		m, _ := view.MinionSelf()
+		m.Spec = "{}"
+		view.Commit(m)
+		return nil
+	})
+	ctx.run()
+	assert.Empty(t, ctx.execs)
+}
+
+func TestWorker6(t *testing.T) {
+	assert.NoError(t, ipdef.SetSubnets("172.16.0.0/12", "fd00::/64"))
+	defer ipdef.SetSubnets("", "")
+
+	ctx := initTest()
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m, _ := view.MinionSelf()
+		e := view.SelectFromEtcd(nil)[0]
+		m.Role = db.Worker
+		m.PrivateIP = "1.2.3.4"
+		e.EtcdIPs = []string{"1.2.3.4"}
+		e.LeaderIP = "5.6.7.8"
+		view.Commit(m)
+		view.Commit(e)
+		return nil
+	})
+	ctx.run()
+
+	assert.Len(t, ctx.execs, 3)
+	assert.Contains(t, ctx.execs[0], "other_config:hwaddr=\"02:00:ac:10:00:01\"")
+	assert.Equal(t, []string{"cfgGateway", "172.16.0.1/12"}, ctx.execs[1])
+	assert.Equal(t, []string{"cfgGateway", "fd00::1/64"}, ctx.execs[2])
+}
+
 func TestChange(t *testing.T) {
 	ctx := initTest()
 	ip := "1.2.3.4"
@@ -507,6 +572,7 @@ func ovsExecArgs(ip, leader string) [][]string {
 		"--", "add-br", "quilt-int",
 		"--", "set", "bridge", "quilt-int", "fail_mode=secure",
 		"other_config:hwaddr=\"02:00:0a:00:00:01\"",
+		"--", "set", "interface", "quilt-int", "mtu_request=1400",
 	}
 	gateway := []string{"cfgGateway", "10.0.0.1/8"}
 	return [][]string{vsctl, gateway}
@@ -13,7 +13,6 @@ import (
 	"github.com/quilt/quilt/api/client"
 	"github.com/quilt/quilt/api/client/getter"
 	"github.com/quilt/quilt/db"
-	"github.com/quilt/quilt/join"
 )
 
 // anyIPAllowed is used to indicate that any non-error response is okay for an external
@@ -23,11 +22,9 @@ const anyIPAllowed = "0.0.0.0"
 var externalHostnames = []string{"google.com", "facebook.com", "en.wikipedia.org"}
 
 type testResult struct {
-	container        db.Container
-	pingUnauthorized []string
-	pingUnreachable  []string
-	dnsIncorrect     []string
-	dnsNotFound      []string
+	container    db.Container
+	dnsIncorrect []string
+	dnsNotFound  []string
 }
 
 func main() {
@@ -54,23 +51,17 @@ func main() {
 		log.WithError(err).Fatal("FAILED, couldn't query containers")
 	}
 
-	var failed bool
+	// Reachability is checked by `quilt netcheck`, which compares the results of
+	// pings between all containers against the connection policy.
+	netcheck, err := exec.Command("quilt", "netcheck").CombinedOutput()
+	fmt.Print(string(netcheck))
+	failed := err != nil
+	if failed {
+		fmt.Println("FAILED, network reachability differs from the policy")
+	}
+
 	for _, res := range runTests(tester, containers) {
 		fmt.Println(res.container)
-		if len(res.pingUnauthorized) != 0 {
-			failed = true
-			fmt.Println(".. FAILED, could ping unauthorized containers")
-			for _, unauthorized := range res.pingUnauthorized {
-				fmt.Printf(".... %s\n", unauthorized)
-			}
-		}
-		if len(res.pingUnreachable) != 0 {
-			failed = true
-			fmt.Println(".. FAILED, couldn't ping authorized containers")
-			for _, unreachable := range res.pingUnreachable {
-				fmt.Printf(".... %s\n", unreachable)
-			}
-		}
 		if len(res.dnsIncorrect) != 0 {
 			failed = true
 			fmt.Println(".. FAILED, hostnames resolved incorrectly")
@@ -140,11 +131,7 @@ func runTests(tester networkTester, containers []db.Container) []testResult {
 }
 
 type networkTester struct {
-	labelMap      map[string]db.Label
-	connectionMap map[string][]string
 	hostnameIPMap map[string]string
-	allIPs        []string
-	allHostnames  []string
 }
 
 func newNetworkTester(clnt client.Client) (networkTester, error) {
@@ -158,49 +145,14 @@ func newNetworkTester(clnt client.Client) (networkTester, error) {
 		hostnameIPMap[host] = anyIPAllowed
 	}
 
-	allIPsSet := make(map[string]struct{})
-	labelMap := make(map[string]db.Label)
 	for _, label := range labels {
-		labelMap[label.Label] = label
-
-		for _, ip := range append(label.ContainerIPs, label.IP) {
-			allIPsSet[ip] = struct{}{}
-		}
-
 		hostnameIPMap[label.Label+".q"] = label.IP
 		for i, ip := range label.ContainerIPs {
 			hostnameIPMap[fmt.Sprintf("%d.%s.q", i+1, label.Label)] = ip
 		}
 	}
 
-	var allIPs []string
-	for ip := range allIPsSet {
-		allIPs = append(allIPs, ip)
-	}
-
-	connections, err := clnt.QueryConnections()
-	if err != nil {
-		return networkTester{}, err
-	}
-
-	connectionMap := make(map[string][]string)
-	for _, conn := range connections {
-		connectionMap[conn.From] = append(connectionMap[conn.From], conn.To)
-		// Connections are bi-directional.
-		connectionMap[conn.To] = append(connectionMap[conn.To], conn.From)
-	}
-
-	return networkTester{
-		labelMap:      labelMap,
-		connectionMap: connectionMap,
-		hostnameIPMap: hostnameIPMap,
-		allIPs:        allIPs,
-	}, nil
-}
-
-type pingResult struct {
-	target    string
-	reachable bool
+	return networkTester{hostnameIPMap: hostnameIPMap}, nil
 }
 
 // We have to limit our parallelization because each `quilt exec` creates a new SSH login
@@ -208,43 +160,6 @@ type pingResult struct {
 // on the remote machine: https://github.com/systemd/systemd/issues/2925.
 const concurrencyLimit = 10
 
-func (tester networkTester) pingAll(container db.Container) []pingResult {
-	pingResultsChan := make(chan pingResult, len(tester.allIPs))
-
-	// Create worker threads.
-	pingRequests := make(chan string, concurrencyLimit)
-	var wg sync.WaitGroup
-	for i := 0; i < concurrencyLimit; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for ip := range pingRequests {
-				_, reachable := ping(container.StitchID, ip)
-				pingResultsChan <- pingResult{
-					target:    ip,
-					reachable: reachable,
-				}
-			}
-		}()
-	}
-
-	// Feed worker threads.
-	for _, ip := range tester.allIPs {
-		pingRequests <- ip
-	}
-	close(pingRequests)
-	wg.Wait()
-	close(pingResultsChan)
-
-	// Collect results.
-	var pingResults []pingResult
-	for res := range pingResultsChan {
-		pingResults = append(pingResults, res)
-	}
-
-	return pingResults
-}
-
 type lookupResult struct {
 	hostname string
 	ip       string
@@ -288,41 +203,6 @@ func (tester networkTester) lookupAll(container db.Container) []lookupResult {
 }
 
 func (tester networkTester) test(container db.Container) testResult {
-	// We should be able to ping ourselves.
-	expReachable := map[string]struct{}{
-		container.IP: {},
-	}
-	for _, label := range container.Labels {
-		for _, toLabelName := range tester.connectionMap[label] {
-			toLabel := tester.labelMap[toLabelName]
-			for _, ip := range append(toLabel.ContainerIPs, toLabel.IP) {
-				expReachable[ip] = struct{}{}
-			}
-		}
-	}
-
-	var expPings []pingResult
-	for _, ip := range tester.allIPs {
-		_, reachable := expReachable[ip]
-		expPings = append(expPings, pingResult{
-			target:    ip,
-			reachable: reachable,
-		})
-	}
-	pingResults := tester.pingAll(container)
-	_, failures, _ := join.HashJoin(pingSlice(expPings), pingSlice(pingResults),
-		nil, nil)
-
-	var pingUnreachable, pingUnauthorized []string
-	for _, badIntf := range failures {
-		bad := badIntf.(pingResult)
-		if bad.reachable {
-			pingUnreachable = append(pingUnreachable, bad.target)
-		} else {
-			pingUnauthorized = append(pingUnauthorized, bad.target)
-		}
-	}
-
 	lookupResults := tester.lookupAll(container)
 
 	var dnsIncorrect, dnsNotFound []string
@@ -342,23 +222,12 @@ func (tester networkTester) test(container db.Container) testResult {
 	}
 
 	return testResult{
-		container:        container,
-		pingUnreachable:  pingUnreachable,
-		pingUnauthorized: pingUnauthorized,
-		dnsIncorrect:     dnsIncorrect,
-		dnsNotFound:      dnsNotFound,
+		container:    container,
+		dnsIncorrect: dnsIncorrect,
+		dnsNotFound:  dnsNotFound,
 	}
 }
 
-// ping `target` from within container `id` with 3 packets, with a timeout of
-// 1 second for each packet.
-func ping(id string, target string) (string, bool) {
-	outBytes, err := exec.Command(
-		"quilt", "ssh", id, "ping", "-c", "3", "-W", "1", target).
-		CombinedOutput()
-	return string(outBytes), err == nil
-}
-
 func lookup(id string, hostname string) (string, error) {
 	outBytes, err := exec.Command(
 		"quilt", "ssh", id, "getent", "hosts", hostname).
@@ -375,13 +244,3 @@ func lookup(id string, hostname string) (string, error) {
 
 	return fields[0], nil
 }
-
-type pingSlice []pingResult
-
-func (ps pingSlice) Get(ii int) interface{} {
-	return ps[ii]
-}
-
-func (ps pingSlice) Len() int {
-	return len(ps)
-}
@@ -40,7 +40,7 @@ func TestMachineOutput(t *testing.T) {
 	}}
 
 	var b bytes.Buffer
-	writeMachines(&b, machines)
+	writeMachines(&b, machines, false)
 	result := string(b.Bytes())
 
 	/* By replacing space with underscore, we make the spaces explicit and whitespace
@@ -98,7 +98,7 @@ func TestContainerOutput(t *testing.T) {
 	}
 
 	var b bytes.Buffer
-	writeContainers(&b, containers, machines, connections)
+	writeContainers(&b, containers, machines, connections, false)
 	result := string(b.Bytes())
 
 	/* By replacing space with underscore, we make the spaces explicit and whitespace
@@ -137,7 +137,7 @@ ________________________________________________________________________________
 	connections = []db.Connection{}
 
 	var c bytes.Buffer
-	writeContainers(&c, containers, machines, connections)
+	writeContainers(&c, containers, machines, connections, false)
 	result = string(c.Bytes())
 	expected = `CONTAINER____MACHINE____COMMAND_________LABELS____STATUS___` +
 		`__CREATED___________________PUBLIC_IP
@@ -165,7 +165,7 @@ ________________________________________________________________________________
 	connections = []db.Connection{}
 
 	var d bytes.Buffer
-	writeContainers(&d, containers, machines, connections)
+	writeContainers(&d, containers, machines, connections, false)
 	result = string(d.Bytes())
 	expected = `CONTAINER____MACHINE____COMMAND_________LABELS____STATUS___` +
 		`__CREATED______________PUBLIC_IP
@@ -177,6 +177,34 @@ ________________________________________________________________________________
 	assert.Equal(t, expected, result)
 }
 
+func TestRolloutOutput(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	writeRollouts(&b, []db.Container{
+		{StitchID: "1", Labels: []string{"web"}},
+	})
+	assert.Empty(t, b.String())
+
+	writeRollouts(&b, []db.Container{
+		{StitchID: "1", Labels: []string{"web"}},
+		{StitchID: "2", Labels: []string{"web"}, Outdated: true},
+		{StitchID: "3", Labels: []string{"web", "db"}, Outdated: true},
+		{StitchID: "4", Labels: []string{"queue"}},
+	})
+	exp := `
+UPDATING    UPDATED    OUTDATED
+db          0          1
+web         1          2
+`
+	assert.Equal(t, exp, b.String())
+
+	b.Reset()
+	writeContainers(&b, []db.Container{{StitchID: "1", Image: "image",
+		Status: "running", Outdated: true}}, nil, nil, false)
+	assert.Contains(t, b.String(), "running (outdated)")
+}
+
 func TestContainerStr(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "", containerStr("", nil))
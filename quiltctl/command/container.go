@@ -22,6 +22,7 @@ import (
 // Container contains the options for querying containers.
 type Container struct {
 	common       *commonFlags
+	output       *outputFlags
 	clientGetter client.Getter
 }
 
@@ -30,16 +31,21 @@ func NewContainerCommand() *Container {
 	return &Container{
 		clientGetter: getter.New(),
 		common:       &commonFlags{},
+		output:       &outputFlags{},
 	}
 }
 
 // InstallFlags sets up parsing for command line flags
 func (cCmd *Container) InstallFlags(flags *flag.FlagSet) {
 	cCmd.common.InstallFlags(flags)
+	cCmd.output.InstallFlags(flags)
+	cCmd.output.installContainerFilters(flags)
 	flags.Usage = func() {
-		fmt.Println("usage: quilt container [-H=<daemon_host>]")
+		fmt.Println("usage: quilt container [-H=<daemon_host>] [-o=<format>] " +
+			"[-format=<template>] [-label=<label>] [-status=<status>]")
 		fmt.Println("`container` displays the status of quilt-managed " +
 			"Docker containers.")
+		fmt.Println(outputUsage)
 
 		flags.PrintDefaults()
 	}
@@ -47,7 +53,7 @@ func (cCmd *Container) InstallFlags(flags *flag.FlagSet) {
 
 // Parse parses the command line arguments for the container command.
 func (cCmd *Container) Parse(args []string) error {
-	return nil
+	return cCmd.output.parse()
 }
 
 // Run retrieves and prints the requested containers.
@@ -84,16 +90,35 @@ func (cCmd *Container) Run() int {
 		return 1
 	}
 
-	writeContainers(os.Stdout, containers, machines, connections)
+	containers = cCmd.output.filterContainers(containers)
+	if cCmd.output.structured() {
+		if err := cCmd.output.write(os.Stdout, containers); err != nil {
+			log.WithError(err).Error("Unable to write containers.")
+			return 1
+		}
+		return 0
+	}
+
+	writeContainers(os.Stdout, containers, machines, connections,
+		cCmd.output.wide())
+	writeRollouts(os.Stdout, containers)
 	return 0
 }
 
+// writeContainers writes a table of `containers`, grouped by machine.  Wide tables
+// include full IDs and additional columns.
 func writeContainers(fd io.Writer, containers []db.Container, machines []db.Machine,
-	connections []db.Connection) {
+	connections []db.Connection, wide bool) {
 	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
 	defer w.Flush()
-	fmt.Fprintln(w, "CONTAINER\tMACHINE\tCOMMAND\tLABELS"+
-		"\tSTATUS\tCREATED\tPUBLIC IP")
+
+	header := "CONTAINER\tMACHINE\tCOMMAND\tLABELS\tSTATUS\tCREATED\tPUBLIC IP"
+	blankRow := "\t\t\t\t\t\t"
+	if wide {
+		header += "\tIP\tDOCKER ID"
+		blankRow += "\t\t"
+	}
+	fmt.Fprintln(w, header)
 
 	labelPublicPortMap := map[string]string{}
 	for _, c := range connections {
@@ -133,7 +158,7 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 			// Insert a blank line between each machine.
 			// Need to print tabs in a blank line; otherwise, spacing will
 			// change in subsequent lines.
-			fmt.Fprintf(w, "\t\t\t\t\t\t\n")
+			fmt.Fprintln(w, blankRow)
 		}
 
 		dbcs := machineDBC[machineID]
@@ -148,9 +173,9 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 
 			container := containerStr(dbc.Image, dbc.Command)
 			labels := strings.Join(dbc.Labels, ", ")
-			status := dbc.Status
-			if dbc.Status == "" && dbc.Minion != "" {
-				status = "scheduled"
+			status := containerStatus(dbc)
+			if dbc.Outdated {
+				status += " (outdated)"
 			}
 			created := ""
 			if !dbc.Created.IsZero() {
@@ -162,11 +187,57 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 			publicIP := publicIPStr(idMachineMap[machineID].PublicIP,
 				publicPorts)
 
-			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
-				util.ShortUUID(dbc.StitchID), util.ShortUUID(machineID),
-				container, labels, status, created, publicIP)
+			displayID := util.ShortUUID(dbc.StitchID)
+			displayMachineID := util.ShortUUID(machineID)
+			if wide {
+				displayID = dbc.StitchID
+				displayMachineID = machineID
+			}
+
+			row := fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v",
+				displayID, displayMachineID, container, labels, status,
+				created, publicIP)
+			if wide {
+				row += fmt.Sprintf("\t%v\t%v", dbc.IP,
+					util.ShortUUID(dbc.DockerID))
+			}
+			fmt.Fprintln(w, row)
+		}
+	}
+}
+
+// writeRollouts prints the progress of the rolling updates of labels that still
+// have outdated containers.
+func writeRollouts(fd io.Writer, containers []db.Container) {
+	updated := map[string]int{}
+	outdated := map[string]int{}
+	for _, dbc := range containers {
+		for _, label := range dbc.Labels {
+			if dbc.Outdated {
+				outdated[label]++
+			} else {
+				updated[label]++
+			}
 		}
 	}
+
+	if len(outdated) == 0 {
+		return
+	}
+
+	var labels []string
+	for label := range outdated {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	fmt.Fprintln(fd)
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "UPDATING\tUPDATED\tOUTDATED")
+	for _, label := range labels {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", label, updated[label], outdated[label])
+	}
 }
 
 func containerStr(image string, args []string) string {
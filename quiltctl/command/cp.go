@@ -0,0 +1,451 @@
+package command
+
+import (
+	"archive/tar"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/pkg/sftp"
+	"github.com/spf13/afero"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/quiltctl/ssh"
+	"github.com/quilt/quilt/util"
+)
+
+// Cp contains the options for copying files to and from containers and machines.
+type Cp struct {
+	privateKey string
+	src, dst   string
+
+	common       *commonFlags
+	clientGetter client.Getter
+	sshGetter    ssh.Getter
+
+	// localFs is the file system used for paths without a machine or container.
+	localFs afero.Fs
+
+	// machineFs opens the file system of the machine at the given host.
+	machineFs func(cCmd *Cp, host string) (fileSystem, error)
+}
+
+// NewCpCommand creates a new Cp command instance.
+func NewCpCommand() *Cp {
+	return &Cp{
+		clientGetter: getter.New(),
+		sshGetter:    ssh.New,
+		common:       &commonFlags{},
+		localFs:      util.AppFs,
+		machineFs:    sftpMachineFs,
+	}
+}
+
+var cpUsage = `usage: quilt cp [-H=<daemon_host>] [-i=<private_key>] <src> <dst>
+
+Copy files and directories between the local machine, containers, and machines.
+Remote paths are written as <id>:<absolute path>, where id is either a container
+or machine ID.  Containers are reached through the Quilt API, while machines are
+reached over SFTP.  If dst ends in a slash, the source is copied into it.
+
+To copy a local config file into container 8879fd2dbcee:
+quilt cp ./nginx.conf 8879fd2dbcee:/etc/nginx/nginx.conf
+
+To copy a directory out of machine 09ed35808a0b:
+quilt cp 09ed35808a0b:/var/log/ ./logs
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (cCmd *Cp) InstallFlags(flags *flag.FlagSet) {
+	cCmd.common.InstallFlags(flags)
+	flags.StringVar(&cCmd.privateKey, "i", "",
+		"the private key to use to connect to machines")
+
+	flags.Usage = func() {
+		fmt.Println(cpUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the cp command.
+func (cCmd *Cp) Parse(args []string) error {
+	if len(args) != 2 {
+		return errors.New("must specify a source and a destination")
+	}
+
+	cCmd.src = args[0]
+	cCmd.dst = args[1]
+	return nil
+}
+
+// Run copies the source to the destination.
+func (cCmd *Cp) Run() int {
+	if err := cCmd.run(); err != nil {
+		log.WithError(err).Error("Failed to copy")
+		return 1
+	}
+	return 0
+}
+
+func (cCmd *Cp) run() error {
+	srcID, srcPath := parseCpPath(cCmd.src)
+	dstID, dstPath := parseCpPath(cCmd.dst)
+	if srcID == "" && dstID == "" {
+		return errors.New("either the source or destination must be a " +
+			"container or machine")
+	}
+
+	if strings.HasSuffix(dstPath, "/") {
+		dstPath = path.Join(dstPath, path.Base(srcPath))
+	}
+
+	c, err := cCmd.clientGetter.Client(cCmd.common.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	src, err := cCmd.endpoint(c, srcID)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := cCmd.endpoint(c, dstID)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	return copyTar(src, path.Clean(srcPath), dst, path.Clean(dstPath))
+}
+
+// parseCpPath splits an argument of the form <id>:<path> into its parts.  Local
+// paths, which may themselves contain colons after a slash, have an empty ID.
+func parseCpPath(arg string) (id, p string) {
+	i := strings.Index(arg, ":")
+	if i <= 0 || strings.Contains(arg[:i], "/") {
+		return "", arg
+	}
+	return arg[:i], arg[i+1:]
+}
+
+// endpoint resolves `id` to the container or machine it refers to.
+func (cCmd *Cp) endpoint(c client.Client, id string) (cpEndpoint, error) {
+	if id == "" {
+		return fsEndpoint{aferoFs{cCmd.localFs}}, nil
+	}
+
+	mach, machErr := getMachine(c, id)
+	_, cont, contErr := getContainer(c, cCmd.clientGetter, id)
+
+	resolvedMachine := machErr == nil
+	resolvedContainer := contErr == nil
+	switch {
+	case !resolvedMachine && !resolvedContainer:
+		return nil, fmt.Errorf("failed to resolve %s: %s, and %s",
+			id, machErr, contErr)
+	case resolvedMachine && resolvedContainer:
+		return nil, fmt.Errorf("ambiguous ID %s: matches machine %s and "+
+			"container %s", id, mach.StitchID, cont.StitchID)
+	case resolvedMachine:
+		fs, err := cCmd.machineFs(cCmd, mach.PublicIP)
+		if err != nil {
+			return nil, err
+		}
+		return fsEndpoint{fs}, nil
+	}
+
+	if cont.DockerID == "" {
+		return nil, fmt.Errorf("container %s is not running", cont.StitchID)
+	}
+
+	containerClient, err := cCmd.clientGetter.ContainerClient(c, cont.StitchID)
+	if err != nil {
+		return nil, err
+	}
+	return containerEndpoint{containerClient, cont.StitchID}, nil
+}
+
+// copyTar streams `srcPath` from `src` into `dstPath` on `dst` as a tar archive.
+func copyTar(src cpEndpoint, srcPath string, dst cpEndpoint, dstPath string) error {
+	archiveReader, archiveWriter := io.Pipe()
+	renamedReader, renamedWriter := io.Pipe()
+
+	downloadErr := make(chan error, 1)
+	go func() {
+		err := src.download(srcPath, archiveWriter)
+		archiveWriter.CloseWithError(err)
+		downloadErr <- err
+	}()
+
+	go func() {
+		err := renameTar(archiveReader, renamedWriter, path.Base(dstPath))
+		renamedWriter.CloseWithError(err)
+	}()
+
+	uploadErr := dst.upload(path.Dir(dstPath), renamedReader)
+
+	// Unblock the goroutines if the upload stopped reading early.
+	renamedReader.CloseWithError(io.ErrClosedPipe)
+	archiveReader.CloseWithError(io.ErrClosedPipe)
+
+	if err := <-downloadErr; err != nil && err != io.ErrClosedPipe {
+		return err
+	}
+	return uploadErr
+}
+
+// renameTar copies the archive in `r` to `w`, renaming its root entry to `name`.
+func renameTar(r io.Reader, w io.Writer, name string) error {
+	in := tar.NewReader(r)
+	out := tar.NewWriter(w)
+	for {
+		header, err := in.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		rest := ""
+		if i := strings.Index(header.Name, "/"); i >= 0 {
+			rest = header.Name[i:]
+		}
+		header.Name = name + rest
+
+		if err := out.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+// A cpEndpoint is a location that files can be copied to and from.
+type cpEndpoint interface {
+	// download writes a tar archive of `path`, rooted at its base name.
+	download(path string, archive io.Writer) error
+
+	// upload extracts a tar archive into the directory `dir`.
+	upload(dir string, archive io.Reader) error
+
+	Close() error
+}
+
+type containerEndpoint struct {
+	client.Client
+	stitchID string
+}
+
+func (ce containerEndpoint) download(path string, archive io.Writer) error {
+	return ce.Download(ce.stitchID, path, archive)
+}
+
+func (ce containerEndpoint) upload(dir string, archive io.Reader) error {
+	return ce.Upload(ce.stitchID, dir, archive)
+}
+
+type fsEndpoint struct {
+	fileSystem
+}
+
+func (fe fsEndpoint) download(p string, archive io.Writer) error {
+	info, err := fe.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(archive)
+	if err := writeTar(fe, p, path.Base(p), info, tw); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// writeTar adds the file or directory at `p` to `tw` under the name `name`.
+func writeTar(fs fileSystem, p, name string, info os.FileInfo, tw *tar.Writer) error {
+	isDir := info.IsDir()
+	if !isDir && info.Mode()&os.ModeType != 0 {
+		log.WithField("path", p).Warn("Skipping file that isn't regular")
+		return nil
+	}
+
+	header := &tar.Header{
+		Name:     name,
+		Mode:     int64(info.Mode().Perm()),
+		ModTime:  info.ModTime(),
+		Typeflag: tar.TypeReg,
+		Size:     info.Size(),
+	}
+	if isDir {
+		header.Name += "/"
+		header.Typeflag = tar.TypeDir
+		header.Size = 0
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !isDir {
+		f, err := fs.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	}
+
+	children, err := fs.ReadDir(p)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		err := writeTar(fs, path.Join(p, child.Name()),
+			path.Join(name, child.Name()), child, tw)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fe fsEndpoint) upload(dir string, archive io.Reader) error {
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		target := path.Join(dir, header.Name)
+		if !strings.HasPrefix(target+"/", path.Clean(dir)+"/") {
+			return fmt.Errorf("archive entry %s escapes %s",
+				header.Name, dir)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if info, err := fe.Stat(target); err == nil && info.IsDir() {
+				continue
+			}
+			if err := fe.Mkdir(target); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := fe.writeFile(target, tr); err != nil {
+				return err
+			}
+		default:
+			log.WithField("path", header.Name).Warn(
+				"Skipping file that isn't regular")
+			continue
+		}
+
+		if err := fe.Chmod(target, header.FileInfo().Mode().Perm()); err != nil {
+			return err
+		}
+	}
+}
+
+func (fe fsEndpoint) writeFile(p string, contents io.Reader) error {
+	f, err := fe.Create(p)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, contents); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fileSystem is the subset of file operations needed to copy to and from the
+// local machine and remote machines.
+type fileSystem interface {
+	Stat(path string) (os.FileInfo, error)
+	ReadDir(path string) ([]os.FileInfo, error)
+	Open(path string) (io.ReadCloser, error)
+	Create(path string) (io.WriteCloser, error)
+	Mkdir(path string) error
+	Chmod(path string, mode os.FileMode) error
+	Close() error
+}
+
+type aferoFs struct {
+	fs afero.Fs
+}
+
+func (a aferoFs) Stat(p string) (os.FileInfo, error) {
+	return a.fs.Stat(p)
+}
+
+func (a aferoFs) ReadDir(p string) ([]os.FileInfo, error) {
+	return afero.ReadDir(a.fs, p)
+}
+
+func (a aferoFs) Open(p string) (io.ReadCloser, error) {
+	return a.fs.Open(p)
+}
+
+func (a aferoFs) Create(p string) (io.WriteCloser, error) {
+	return a.fs.Create(p)
+}
+
+func (a aferoFs) Mkdir(p string) error {
+	return a.fs.Mkdir(p, 0755)
+}
+
+func (a aferoFs) Chmod(p string, mode os.FileMode) error {
+	return a.fs.Chmod(p, mode)
+}
+
+func (a aferoFs) Close() error {
+	return nil
+}
+
+type sftpFs struct {
+	*sftp.Client
+	sshClient ssh.Client
+}
+
+func sftpMachineFs(cCmd *Cp, host string) (fileSystem, error) {
+	sshClient, err := cCmd.sshGetter(host, cCmd.privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sftpClient, err := sshClient.SFTP()
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	return sftpFs{sftpClient, sshClient}, nil
+}
+
+func (s sftpFs) Open(p string) (io.ReadCloser, error) {
+	return s.Client.Open(p)
+}
+
+func (s sftpFs) Create(p string) (io.WriteCloser, error) {
+	return s.Client.Create(p)
+}
+
+func (s sftpFs) Close() error {
+	s.Client.Close()
+	return s.sshClient.Close()
+}
@@ -0,0 +1,220 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestCpFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewCpCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "-i", "key", "a", "1:/b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "key", cmd.privateKey)
+	assert.Equal(t, "a", cmd.src)
+	assert.Equal(t, "1:/b", cmd.dst)
+
+	for _, args := range []string{"", "a", "a b c"} {
+		err := parseHelper(NewCpCommand(), strings.Fields(args))
+		assert.EqualError(t, err, "must specify a source and a destination")
+	}
+}
+
+func TestParseCpPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		arg, id, path string
+	}{
+		{"1234:/etc/hosts", "1234", "/etc/hosts"},
+		{"1234:", "1234", ""},
+		{"/etc/hosts", "", "/etc/hosts"},
+		{"./a:b", "", "./a:b"},
+		{":/etc", "", ":/etc"},
+	}
+	for _, test := range tests {
+		id, path := parseCpPath(test.arg)
+		assert.Equal(t, test.id, id, test.arg)
+		assert.Equal(t, test.path, path, test.arg)
+	}
+}
+
+func TestCpLocalToContainer(t *testing.T) {
+	t.Parallel()
+
+	localFs := afero.NewMemMapFs()
+	afero.WriteFile(localFs, "/src/dir/a", []byte("a"), 0644)
+	afero.WriteFile(localFs, "/src/dir/sub/b", []byte("b"), 0600)
+
+	c := newCpClient()
+	cmd := newTestCp(c, localFs, afero.NewMemMapFs())
+	assert.NoError(t, parseHelper(cmd, []string{"/src/dir", "12:/dst/"}))
+	assert.Equal(t, 0, cmd.Run())
+
+	files := readTar(t, c.Uploads["1234:/dst"])
+	assert.Equal(t, map[string]string{
+		"dir/":      "",
+		"dir/a":     "a",
+		"dir/sub/":  "",
+		"dir/sub/b": "b",
+	}, files)
+
+	// Copying to an explicit path renames the root of the archive.
+	cmd = newTestCp(c, localFs, afero.NewMemMapFs())
+	assert.NoError(t, parseHelper(cmd, []string{"/src/dir/a", "12:/etc/c"}))
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, map[string]string{"c": "a"}, readTar(t, c.Uploads["1234:/etc"]))
+}
+
+func TestCpContainerToMachine(t *testing.T) {
+	t.Parallel()
+
+	c := newCpClient()
+	c.Downloads = map[string]string{
+		"1234:/etc/conf": makeTar(t, map[string]string{
+			"conf/":  "",
+			"conf/a": "contents",
+		}),
+	}
+
+	machineFs := afero.NewMemMapFs()
+	machineFs.Mkdir("/tmp", 0755)
+	cmd := newTestCp(c, afero.NewMemMapFs(), machineFs)
+	assert.NoError(t, parseHelper(cmd, []string{"12:/etc/conf", "09:/tmp/new"}))
+	assert.Equal(t, 0, cmd.Run())
+
+	contents, err := afero.ReadFile(machineFs, "/tmp/new/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "contents", string(contents))
+
+	info, err := machineFs.Stat("/tmp/new")
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestCpErrors(t *testing.T) {
+	t.Parallel()
+
+	run := func(c *clientMock.Client, src, dst string) int {
+		cmd := newTestCp(c, afero.NewMemMapFs(), afero.NewMemMapFs())
+		assert.NoError(t, parseHelper(cmd, []string{src, dst}))
+		return cmd.Run()
+	}
+
+	// Neither endpoint is remote.
+	assert.Equal(t, 1, run(newCpClient(), "a", "b"))
+
+	// The ID resolves to nothing.
+	assert.Equal(t, 1, run(newCpClient(), "a", "56:/b"))
+
+	// The ID resolves to both a machine and a container.
+	c := newCpClient()
+	c.MachineReturn = append(c.MachineReturn, db.Machine{StitchID: "12ab"})
+	assert.Equal(t, 1, run(c, "12:/a", "b"))
+
+	// The container isn't running yet.
+	c = newCpClient()
+	c.ContainerReturn[0].DockerID = ""
+	assert.Equal(t, 1, run(c, "12:/a", "b"))
+
+	// The local source doesn't exist.
+	assert.Equal(t, 1, run(newCpClient(), "/missing", "12:/b"))
+
+	// The upload fails.
+	localFs := afero.NewMemMapFs()
+	afero.WriteFile(localFs, "/a", []byte("a"), 0644)
+	c = newCpClient()
+	c.CopyErr = errors.New("copy error")
+	cmd := newTestCp(c, localFs, afero.NewMemMapFs())
+	assert.NoError(t, parseHelper(cmd, []string{"/a", "12:/b"}))
+	assert.Equal(t, 1, cmd.Run())
+
+	// The archive tries to escape the destination directory.
+	c = newCpClient()
+	c.Downloads = map[string]string{
+		"1234:/a": makeTar(t, map[string]string{"a/../../../etc/passwd": "x"}),
+	}
+	assert.Equal(t, 1, run(c, "12:/a", "09:/tmp/a"))
+}
+
+func newCpClient() *clientMock.Client {
+	return &clientMock.Client{
+		MachineReturn: []db.Machine{{StitchID: "09ed", PublicIP: "host"}},
+		ContainerReturn: []db.Container{
+			{StitchID: "1234", DockerID: "docker"},
+		},
+	}
+}
+
+func newTestCp(c *clientMock.Client, localFs, machineFs afero.Fs) *Cp {
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+	mockGetter.On("ContainerClient", mock.Anything, mock.Anything).Return(c, nil)
+
+	cmd := NewCpCommand()
+	cmd.clientGetter = mockGetter
+	cmd.localFs = localFs
+	cmd.machineFs = func(_ *Cp, host string) (fileSystem, error) {
+		if host != "host" {
+			return nil, errors.New("unknown host")
+		}
+		return aferoFs{machineFs}, nil
+	}
+	return cmd
+}
+
+func makeTar(t *testing.T, files map[string]string) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg,
+			Size: int64(len(files[name]))}
+		if strings.HasSuffix(name, "/") {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+		}
+		assert.NoError(t, tw.WriteHeader(header))
+		_, err := io.WriteString(tw, files[name])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf.String()
+}
+
+func readTar(t *testing.T, archive string) map[string]string {
+	files := map[string]string{}
+	tr := tar.NewReader(strings.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		assert.NoError(t, err)
+
+		contents, err := ioutil.ReadAll(tr)
+		assert.NoError(t, err)
+		files[header.Name] = string(contents)
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"fmt"
 
 	"github.com/quilt/quilt/api/server"
+	"github.com/quilt/quilt/auth"
 	"github.com/quilt/quilt/cluster"
 	"github.com/quilt/quilt/db"
 	"github.com/quilt/quilt/engine"
+	"github.com/quilt/quilt/metrics"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 // Daemon contains the options for running the Quilt daemon.
 type Daemon struct {
+	metricsAddr string
+
 	common *commonFlags
 }
 
@@ -25,8 +31,13 @@ func NewDaemonCommand() *Daemon {
 // InstallFlags sets up parsing for command line flags
 func (dCmd *Daemon) InstallFlags(flags *flag.FlagSet) {
 	dCmd.common.InstallFlags(flags)
+	flags.StringVar(&dCmd.metricsAddr, "metrics", metrics.DefaultDaemonAddress,
+		"the address on which to serve Prometheus metrics at /metrics, "+
+			"or empty to disable them")
+
 	flags.Usage = func() {
-		fmt.Println("usage: quilt daemon [-H=<daemon_host>]")
+		fmt.Println("usage: quilt daemon [-H=<daemon_host>] " +
+			"[-metrics=<metrics_address>]")
 		fmt.Println("`daemon` starts the quilt daemon, which listens for" +
 			"quilt API requests")
 
@@ -41,9 +52,20 @@ func (dCmd *Daemon) Parse(args []string) error {
 
 // Run starts the daemon.
 func (dCmd *Daemon) Run() int {
+	// The credentials are installed on the machines the daemon boots, so they must
+	// exist before any are booted.
+	creds, err := auth.LoadOrCreate(auth.DefaultDir)
+	if err != nil {
+		log.WithError(err).Error("Failed to load TLS credentials.")
+		return 1
+	}
+
 	conn := db.New()
 	go engine.Run(conn)
-	go server.Run(conn, dCmd.common.host)
+	go server.Run(conn, dCmd.common.host, creds)
+	if dCmd.metricsAddr != "" {
+		go metrics.Run(dCmd.metricsAddr)
+	}
 	cluster.Run(conn)
 	return 0
 }
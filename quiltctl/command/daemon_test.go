@@ -0,0 +1,26 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/metrics"
+)
+
+func TestDaemonFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewDaemonCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.Equal(t, metrics.DefaultDaemonAddress, cmd.metricsAddr)
+
+	cmd = NewDaemonCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-H", "IP", "-metrics", ":8080"}))
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, ":8080", cmd.metricsAddr)
+
+	cmd = NewDaemonCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-metrics="}))
+	assert.Empty(t, cmd.metricsAddr)
+}
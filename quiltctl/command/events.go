@@ -0,0 +1,121 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	units "github.com/docker/go-units"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+)
+
+// Events contains the options for listing the events recorded by the cluster.
+type Events struct {
+	follow bool
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewEventsCommand creates a new Events command instance.
+func NewEventsCommand() *Events {
+	return &Events{
+		common:       &commonFlags{},
+		clientGetter: getter.New(),
+	}
+}
+
+var eventsUsage = `usage: quilt events [-H=<daemon_host>] [-f]
+
+List the notable transitions of the cluster, such as machines booting, minions
+connecting, containers being placed, and images failing to pull, oldest first.
+Repeated events are listed once, with the number of times they occurred.
+
+To list the events as they happen:
+quilt events -f
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (eCmd *Events) InstallFlags(flags *flag.FlagSet) {
+	eCmd.common.InstallFlags(flags)
+	flags.BoolVar(&eCmd.follow, "f", false, "follow new and repeated events")
+
+	flags.Usage = func() {
+		fmt.Println(eventsUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the events command.
+func (eCmd *Events) Parse(args []string) error {
+	return nil
+}
+
+// Run lists the events, and then follows them if requested.
+func (eCmd *Events) Run() int {
+	c, err := eCmd.clientGetter.Client(eCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	header := true
+	err = c.Events(eCmd.follow, func(events []db.Event) error {
+		writeEvents(os.Stdout, events, header)
+		header = false
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Unable to query events.")
+		return 1
+	}
+	return 0
+}
+
+func writeEvents(fd io.Writer, events []db.Event, header bool) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+
+	if header {
+		fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tSOURCE\tCOUNT"+
+			"\tMESSAGE")
+	}
+
+	for _, e := range events {
+		lastSeen := ""
+		if !e.LastSeen.IsZero() {
+			duration := units.HumanDuration(time.Since(e.LastSeen.Local()))
+			lastSeen = fmt.Sprintf("%s ago", duration)
+		}
+
+		source := e.Source
+		if e.Machine != "" {
+			source = fmt.Sprintf("%s, %s", e.Source,
+				util.ShortUUID(e.Machine))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", lastSeen, e.Type,
+			e.Reason, shortObject(e.Object), source, e.Count, e.Message)
+	}
+}
+
+// shortObject abbreviates the IDs of the machines and containers events are about,
+// as `quilt ps` does.
+func shortObject(object string) string {
+	parts := strings.SplitN(object, "/", 2)
+	if len(parts) != 2 || (parts[0] != "machine" && parts[0] != "container") {
+		return object
+	}
+	return parts[0] + "/" + util.ShortUUID(parts[1])
+}
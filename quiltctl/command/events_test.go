@@ -0,0 +1,105 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestEventsFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewEventsCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-H", "IP", "-f"}))
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.True(t, cmd.follow)
+
+	cmd = NewEventsCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.False(t, cmd.follow)
+}
+
+func TestEventsRun(t *testing.T) {
+	t.Parallel()
+
+	c := &clientMock.Client{EventsReturn: [][]db.Event{
+		{{Reason: "Booted"}},
+		{{Reason: "Connected"}},
+	}}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+
+	cmd := &Events{follow: true, common: &commonFlags{}, clientGetter: mockGetter}
+	assert.Equal(t, 0, cmd.Run())
+	assert.True(t, c.EventsFollow)
+
+	c.EventsErr = errors.New("err")
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(nil, errors.New("err"))
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
+
+func TestEventsOutput(t *testing.T) {
+	t.Parallel()
+
+	events := []db.Event{
+		{
+			Type:     db.EventNormal,
+			Reason:   "Booted",
+			Object:   "machine/0123456789abcdef",
+			Message:  "Amazon m4.large in us-west-1 is up at 8.8.8.8",
+			Source:   "cluster",
+			Count:    1,
+			LastSeen: time.Now().Add(-time.Hour),
+		},
+		{
+			Type:    db.EventWarning,
+			Reason:  "FailedPull",
+			Object:  "container/fedcba9876543210",
+			Message: "failed to pull nginx: not found",
+			Source:  "scheduler",
+			Machine: "0123456789abcdef",
+			Count:   3,
+		},
+	}
+
+	var b bytes.Buffer
+	writeEvents(&b, events, true)
+
+	exp := `LAST SEEN            TYPE       REASON        ` +
+		`OBJECT                    SOURCE                     COUNT    MESSAGE
+About an hour ago    Normal     Booted        machine/0123456789ab      ` +
+		`cluster                    1        ` +
+		`Amazon m4.large in us-west-1 is up at 8.8.8.8
+                     Warning    FailedPull    container/fedcba987654    ` +
+		`scheduler, 0123456789ab    3        failed to pull nginx: not found
+`
+	assert.Equal(t, exp, b.String())
+
+	b.Reset()
+	writeEvents(&b, events[1:], false)
+	assert.Equal(t, "    Warning    FailedPull    container/fedcba987654    "+
+		"scheduler, 0123456789ab    3    failed to pull nginx: not found\n",
+		b.String())
+}
+
+func TestShortObject(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "machine/0123456789ab", shortObject("machine/0123456789abcdef"))
+	assert.Equal(t, "container/a", shortObject("container/a"))
+	assert.Equal(t, "provider/Amazon-us-west-1",
+		shortObject("provider/Amazon-us-west-1"))
+	assert.Equal(t, "minion/10.0.0.1", shortObject("minion/10.0.0.1"))
+	assert.Equal(t, "scheduler", shortObject("scheduler"))
+}
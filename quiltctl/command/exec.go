@@ -0,0 +1,160 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/api/util"
+)
+
+// Exec contains the options for running commands in containers.
+type Exec struct {
+	target      string
+	cmd         []string
+	interactive bool
+	allocatePTY bool
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewExecCommand creates a new Exec command instance.
+func NewExecCommand() *Exec {
+	return &Exec{
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+var execUsage = `usage: quilt exec [-H=<daemon_host>] [-i] [-t] <container> <command>...
+
+Run a command in a container.  Unlike "quilt ssh", the command is run through the
+Quilt API on the container's machine, so no SSH key is required.
+
+To open a shell in container 8879fd2dbcee:
+quilt exec -i -t 8879fd2dbcee sh
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (eCmd *Exec) InstallFlags(flags *flag.FlagSet) {
+	eCmd.common.InstallFlags(flags)
+	flags.BoolVar(&eCmd.interactive, "i", false,
+		"keep stdin attached to the command")
+	flags.BoolVar(&eCmd.allocatePTY, "t", false, "allocate a pseudo-terminal")
+
+	flags.Usage = func() {
+		fmt.Println(execUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the exec command.
+func (eCmd *Exec) Parse(args []string) error {
+	if len(args) < 2 {
+		return errors.New("must specify a container and a command")
+	}
+
+	eCmd.target = args[0]
+	eCmd.cmd = args[1:]
+	return nil
+}
+
+// Run executes the command in the container.
+func (eCmd *Exec) Run() int {
+	if eCmd.allocatePTY && !isTerminal() {
+		log.Error("Cannot allocate pseudo-terminal without a terminal")
+		return 1
+	}
+
+	c, err := eCmd.clientGetter.Client(eCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	containerClient, err := eCmd.clientGetter.ContainerClient(c, eCmd.target)
+	if err != nil {
+		log.WithError(err).Error("Unable to connect to the container's machine.")
+		return 1
+	}
+	defer containerClient.Close()
+
+	// The target may only be a prefix of the container's ID.
+	container, err := util.GetContainer(containerClient, eCmd.target)
+	if err != nil {
+		log.WithError(err).Error("Unable to find the container.")
+		return 1
+	}
+
+	opts := client.ExecOptions{
+		Cmd:    eCmd.cmd,
+		Tty:    eCmd.allocatePTY,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if eCmd.interactive {
+		opts.Stdin = os.Stdin
+	}
+
+	if eCmd.allocatePTY {
+		resize, restore, err := makeRawTerminal()
+		if err != nil {
+			log.WithError(err).Error("Unable to set up the terminal.")
+			return 1
+		}
+		defer restore()
+		opts.Resize = resize
+	}
+
+	code, err := containerClient.Exec(container.StitchID, opts)
+	if err != nil {
+		log.WithError(err).Error("Error running command")
+		return 1
+	}
+	return code
+}
+
+// makeRawTerminal puts the local terminal in raw mode, so that the remote terminal
+// handles all input, and reports the local terminal's size each time it changes.
+// It's stored in a variable so that it can be mocked out by the unit tests.
+var makeRawTerminal = func() (resize <-chan client.TTYSize, restore func(),
+	err error) {
+
+	fd := int(os.Stdin.Fd())
+	originalState, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sizes := make(chan client.TTYSize, 1)
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	winch <- syscall.SIGWINCH // Send the initial size.
+
+	go func() {
+		for range winch {
+			width, height, err := terminal.GetSize(fd)
+			if err != nil {
+				log.WithError(err).Warn("Error getting terminal window size")
+				continue
+			}
+			sizes <- client.TTYSize{Height: height, Width: width}
+		}
+	}()
+
+	restore = func() {
+		signal.Stop(winch)
+		terminal.Restore(fd, originalState)
+	}
+	return sizes, restore, nil
+}
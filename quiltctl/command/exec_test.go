@@ -0,0 +1,92 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/quilt/quilt/api/client"
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestExecFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewExecCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "-i", "-t", "1", "sh", "-c", "ls"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "1", cmd.target)
+	assert.Equal(t, []string{"sh", "-c", "ls"}, cmd.cmd)
+	assert.True(t, cmd.interactive)
+	assert.True(t, cmd.allocatePTY)
+
+	for _, args := range []string{"", "1"} {
+		err := parseHelper(NewExecCommand(), strings.Fields(args))
+		assert.EqualError(t, err, "must specify a container and a command")
+	}
+}
+
+func TestExecRun(t *testing.T) {
+	oldIsTerminal := isTerminal
+	oldMakeRawTerminal := makeRawTerminal
+	defer func() {
+		isTerminal = oldIsTerminal
+		makeRawTerminal = oldMakeRawTerminal
+	}()
+
+	var restored bool
+	resize := make(chan client.TTYSize)
+	makeRawTerminal = func() (<-chan client.TTYSize, func(), error) {
+		return resize, func() { restored = true }, nil
+	}
+
+	containerClient := &clientMock.Client{
+		ContainerReturn: []db.Container{{StitchID: "1234"}},
+		ExecReturn:      3,
+	}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(&clientMock.Client{}, nil)
+	mockGetter.On("ContainerClient", mock.Anything, "12").
+		Return(containerClient, nil)
+
+	run := func(args ...string) int {
+		cmd := NewExecCommand()
+		cmd.clientGetter = mockGetter
+		assert.NoError(t, parseHelper(cmd, args))
+		return cmd.Run()
+	}
+
+	// The exit code of the command is passed through.
+	assert.Equal(t, 3, run("12", "ls", "-l"))
+	assert.Equal(t, "1234", containerClient.ExecContainer)
+	assert.Equal(t, []string{"ls", "-l"}, containerClient.ExecArg.Cmd)
+	assert.Nil(t, containerClient.ExecArg.Stdin)
+	assert.False(t, containerClient.ExecArg.Tty)
+
+	isTerminal = func() bool { return true }
+	assert.Equal(t, 3, run("-i", "-t", "12", "sh"))
+	assert.Equal(t, os.Stdin, containerClient.ExecArg.Stdin)
+	assert.True(t, containerClient.ExecArg.Tty)
+	assert.True(t, restored)
+
+	isTerminal = func() bool { return false }
+	assert.Equal(t, 1, run("-t", "12", "sh"))
+
+	containerClient.ExecErr = errors.New("err")
+	assert.Equal(t, 1, run("12", "ls"))
+
+	containerClient.ContainerReturn = nil
+	assert.Equal(t, 1, run("12", "ls"))
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(&clientMock.Client{}, nil)
+	mockGetter.On("ContainerClient", mock.Anything, mock.Anything).
+		Return(nil, errors.New("err"))
+	assert.Equal(t, 1, run("12", "ls"))
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	units "github.com/docker/go-units"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+)
+
+// History contains the options for listing the previous deployments.
+type History struct {
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewHistoryCommand creates a new History command instance.
+func NewHistoryCommand() *History {
+	return &History{
+		common:       &commonFlags{},
+		clientGetter: getter.New(),
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (hCmd *History) InstallFlags(flags *flag.FlagSet) {
+	hCmd.common.InstallFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("usage: quilt history [-H=<daemon_host>]")
+		fmt.Println("`history` lists the specs recently deployed to the " +
+			"Quilt daemon.  Any of them can be redeployed with " +
+			"`quilt rollback`.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the history command.
+func (hCmd *History) Parse(args []string) error {
+	return nil
+}
+
+// Run lists the deployment history.
+func (hCmd *History) Run() int {
+	c, err := hCmd.clientGetter.Client(hCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	revisions, err := c.QueryHistory()
+	if err != nil {
+		log.WithError(err).Error("Unable to query deployment history.")
+		return 1
+	}
+
+	writeHistory(os.Stdout, revisions)
+	return 0
+}
+
+func writeHistory(fd io.Writer, revisions []db.Revision) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "REVISION\tDEPLOYED\tAUTHOR\tHASH")
+	for _, rev := range revisions {
+		deployed := ""
+		if !rev.Time.IsZero() {
+			duration := units.HumanDuration(time.Since(rev.Time.Local()))
+			deployed = fmt.Sprintf("%s ago", duration)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", rev.Number, deployed, rev.Author,
+			util.ShortUUID(rev.Hash))
+	}
+}
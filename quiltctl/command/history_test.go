@@ -0,0 +1,63 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestHistoryRun(t *testing.T) {
+	t.Parallel()
+
+	c := &clientMock.Client{HistoryReturn: []db.Revision{{Number: 1}}}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+
+	cmd := NewHistoryCommand()
+	cmd.clientGetter = mockGetter
+	assert.NoError(t, parseHelper(cmd, []string{"-H", "IP"}))
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, 0, cmd.Run())
+
+	c.HistoryErr = errors.New("err")
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(nil, errors.New("err"))
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
+
+func TestHistoryOutput(t *testing.T) {
+	t.Parallel()
+
+	revisions := []db.Revision{
+		{
+			Number: 1,
+			Hash:   "0123456789abcdef",
+			Author: "laptop",
+		},
+		{
+			Number: 2,
+			Hash:   "fedcba9876543210",
+			Author: "desktop",
+			Time:   time.Now().Add(-time.Hour),
+		},
+	}
+
+	var b bytes.Buffer
+	writeHistory(&b, revisions)
+
+	exp := `REVISION    DEPLOYED             AUTHOR     HASH
+1                                laptop     0123456789ab
+2           About an hour ago    desktop    fedcba987654
+`
+	assert.Equal(t, exp, b.String())
+}
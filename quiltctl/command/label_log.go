@@ -0,0 +1,220 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/fatih/color"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+)
+
+// The colors used to tell replicas apart, in order.
+var replicaColors = []color.Attribute{color.FgCyan, color.FgYellow, color.FgGreen,
+	color.FgMagenta, color.FgBlue, color.FgRed}
+
+// A logLine is a line of the logs of one of the replicas of a label.
+type logLine struct {
+	time   time.Time
+	prefix string
+	text   string
+}
+
+// labelContainers returns the containers with the given label, sorted by ID.
+func labelContainers(c client.Client, clientGetter client.Getter, label string) (
+	[]db.Container, error) {
+
+	leaderClient, err := clientGetter.LeaderClient(c)
+	if err != nil {
+		return nil, err
+	}
+	defer leaderClient.Close()
+
+	containers, err := leaderClient.QueryContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	var labelled []db.Container
+	for _, dbc := range containers {
+		for _, l := range dbc.Labels {
+			if l == label {
+				labelled = append(labelled, dbc)
+				break
+			}
+		}
+	}
+	sort.Sort(db.ContainerSlice(labelled))
+	return labelled, nil
+}
+
+// labelLogs writes the logs of all of `containers` to `out`, prefixing each line
+// with the replica it came from.  The logs are ordered by their timestamps, except
+// when following them, in which case lines are written as they arrive.
+func (lCmd *Log) labelLogs(c client.Client, containers []db.Container,
+	out io.Writer) int {
+
+	var lock sync.Mutex
+	var lines []logLine
+	handle := func(line logLine) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if lCmd.shouldTail {
+			fmt.Fprintln(out, lCmd.formatLine(line))
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	var since time.Time
+	if lCmd.sinceTimestamp != "" {
+		var err error
+		if since, err = parseSince(lCmd.sinceTimestamp); err != nil {
+			log.Error(err)
+			return 1
+		}
+	}
+
+	var wg sync.WaitGroup
+	failed := false
+	for i, dbc := range containers {
+		wg.Add(1)
+		go func(i int, dbc db.Container) {
+			defer wg.Done()
+			prefix := replicaPrefix(i, dbc.StitchID)
+			err := lCmd.replicaLogs(c, dbc, since, prefix, handle)
+			if err != nil {
+				log.WithError(err).WithField("container", dbc.StitchID).
+					Error("Failed to fetch logs")
+
+				lock.Lock()
+				failed = true
+				lock.Unlock()
+			}
+		}(i, dbc)
+	}
+	wg.Wait()
+
+	sort.Stable(logLineSlice(lines))
+	for _, line := range lines {
+		fmt.Fprintln(out, lCmd.formatLine(line))
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// replicaLogs passes each line of the logs of `dbc` to `handle`.
+func (lCmd *Log) replicaLogs(c client.Client, dbc db.Container, since time.Time,
+	prefix string, handle func(logLine)) error {
+
+	containerClient, err := lCmd.clientGetter.ContainerClient(c, dbc.StitchID)
+	if err != nil {
+		return err
+	}
+	defer containerClient.Close()
+
+	// Timestamps are always requested so that the replicas can be interleaved,
+	// but are only shown if asked for.
+	handleText := func(text string) {
+		line := parseLogLine(text)
+		line.prefix = prefix
+		handle(line)
+	}
+	stdout := &lineWriter{handle: handleText}
+	stderr := &lineWriter{handle: handleText}
+	defer stdout.Flush()
+	defer stderr.Flush()
+
+	return containerClient.Logs(dbc.StitchID, client.LogsOptions{
+		Since:      since,
+		Tail:       lCmd.tail,
+		Follow:     lCmd.shouldTail,
+		Timestamps: true,
+		Stdout:     stdout,
+		Stderr:     stderr,
+	})
+}
+
+func (lCmd *Log) formatLine(line logLine) string {
+	if lCmd.showTimestamps && !line.time.IsZero() {
+		return fmt.Sprintf("%s %s %s", line.prefix,
+			line.time.Format(time.RFC3339Nano), line.text)
+	}
+	return fmt.Sprintf("%s %s", line.prefix, line.text)
+}
+
+func replicaPrefix(i int, stitchID string) string {
+	c := color.New(replicaColors[i%len(replicaColors)])
+	return c.SprintFunc()(util.ShortUUID(stitchID) + " |")
+}
+
+// parseLogLine splits the timestamp added by Docker from a line of logs.
+func parseLogLine(text string) logLine {
+	fields := strings.SplitN(text, " ", 2)
+	if len(fields) == 2 {
+		if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+			return logLine{time: t, text: fields[1]}
+		}
+	}
+	return logLine{text: text}
+}
+
+// A lineWriter passes each complete line written to it to `handle`.
+type lineWriter struct {
+	sync.Mutex
+	buf    bytes.Buffer
+	handle func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf.Next(i + 1))
+		w.handle(strings.TrimSuffix(line, "\n"))
+	}
+	return len(p), nil
+}
+
+// Flush passes any trailing partial line to `handle`.
+func (w *lineWriter) Flush() {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.buf.Len() > 0 {
+		w.handle(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+type logLineSlice []logLine
+
+func (lines logLineSlice) Len() int {
+	return len(lines)
+}
+
+func (lines logLineSlice) Less(i, j int) bool {
+	return lines[i].time.Before(lines[j].time)
+}
+
+func (lines logLineSlice) Swap(i, j int) {
+	lines[i], lines[j] = lines[j], lines[i]
+}
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestLabelLogs(t *testing.T) {
+	t.Parallel()
+
+	leader := &mocks.Client{ContainerReturn: []db.Container{
+		{StitchID: "2", Labels: []string{"web"}},
+		{StitchID: "1", Labels: []string{"web", "other"}},
+		{StitchID: "3", Labels: []string{"db"}},
+	}}
+	replica1 := &mocks.Client{LogsReturn: "2017-01-01T00:00:01Z first\n" +
+		"2017-01-01T00:00:03Z third\n"}
+	replica2 := &mocks.Client{LogsReturn: "2017-01-01T00:00:02Z second\n" +
+		"no timestamp"}
+	mockGetter := new(mocks.Getter)
+	mockGetter.On("LeaderClient", mock.Anything).Return(leader, nil)
+	mockGetter.On("ContainerClient", mock.Anything, "1").Return(replica1, nil)
+	mockGetter.On("ContainerClient", mock.Anything, "2").Return(replica2, nil)
+
+	containers, err := labelContainers(nil, mockGetter, "web")
+	assert.NoError(t, err)
+	assert.Len(t, containers, 2)
+	assert.Equal(t, "1", containers[0].StitchID)
+	assert.Equal(t, "2", containers[1].StitchID)
+
+	cmd := Log{clientGetter: mockGetter, tail: "10"}
+	var out bytes.Buffer
+	assert.Equal(t, 0, cmd.labelLogs(nil, containers, &out))
+
+	p1, p2 := replicaPrefix(0, "1"), replicaPrefix(1, "2")
+	exp := p2 + " no timestamp\n" +
+		p1 + " first\n" +
+		p2 + " second\n" +
+		p1 + " third\n"
+	assert.Equal(t, exp, out.String())
+	assert.True(t, replica1.LogsArg.Timestamps)
+	assert.Equal(t, "10", replica1.LogsArg.Tail)
+
+	cmd.showTimestamps = true
+	out.Reset()
+	assert.Equal(t, 0, cmd.labelLogs(nil, containers[:1], &out))
+	assert.Equal(t, p1+" 2017-01-01T00:00:01Z first\n"+
+		p1+" 2017-01-01T00:00:03Z third\n", out.String())
+
+	replica2.LogsErr = errors.New("err")
+	out.Reset()
+	assert.Equal(t, 1, cmd.labelLogs(nil, containers, &out))
+	assert.Contains(t, out.String(), "first")
+}
+
+func TestParseLogLine(t *testing.T) {
+	t.Parallel()
+
+	line := parseLogLine("2017-01-01T00:00:01.5Z hello world")
+	assert.Equal(t, "hello world", line.text)
+	assert.Equal(t, time.Date(2017, 1, 1, 0, 0, 1, 5e8, time.UTC), line.time)
+
+	line = parseLogLine("hello world")
+	assert.Equal(t, "hello world", line.text)
+	assert.True(t, line.time.IsZero())
+}
+
+func TestLineWriter(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	w := &lineWriter{handle: func(line string) {
+		lines = append(lines, line)
+	}}
+
+	w.Write([]byte("a\nb"))
+	assert.Equal(t, []string{"a"}, lines)
+
+	w.Write([]byte("c\nd\n\ne"))
+	assert.Equal(t, []string{"a", "bc", "d", ""}, lines)
+
+	w.Flush()
+	assert.Equal(t, []string{"a", "bc", "d", "", "e"}, lines)
+}
+
+func TestLogLabelTarget(t *testing.T) {
+	t.Parallel()
+
+	leader := &mocks.Client{ContainerReturn: []db.Container{
+		{StitchID: "1", Labels: []string{"web"}},
+	}}
+	replica := &mocks.Client{LogsReturn: "logs\n"}
+	mockGetter := new(mocks.Getter)
+	mockGetter.On("Client", mock.Anything).Return(&mocks.Client{}, nil)
+	mockGetter.On("LeaderClient", mock.Anything).Return(leader, nil)
+	mockGetter.On("ContainerClient", mock.Anything, "web").Return(leader, nil)
+	mockGetter.On("ContainerClient", mock.Anything, "1").Return(replica, nil)
+
+	cmd := Log{common: &commonFlags{}, clientGetter: mockGetter, target: "web",
+		shouldTail: true}
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, "1", replica.LogsContainer)
+	assert.True(t, replica.LogsArg.Follow)
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/quilt/quilt/api"
 	"github.com/quilt/quilt/api/client"
 	"github.com/quilt/quilt/api/client/getter"
 	"github.com/quilt/quilt/quiltctl/ssh"
@@ -19,6 +22,7 @@ type Log struct {
 	sinceTimestamp string
 	showTimestamps bool
 	shouldTail     bool
+	tail           string
 
 	target string
 
@@ -36,16 +40,25 @@ func NewLogCommand() *Log {
 	}
 }
 
-var logsUsage = `usage: quilt logs [-H=<daemon_host>] [-i=<private_key>] <stitch_id>
+var logsUsage = `usage: quilt logs [-H=<daemon_host>] [-i=<private_key>] <target>
 
 Fetch the logs of a container or machine minion.
 Either a container or machine ID can be supplied.
+The logs are fetched over SSH, or through the Quilt API on the machine if an SSH
+connection can't be made.
+
+If the target is a label, the logs of all of its containers are fetched through the
+Quilt API, and interleaved by their timestamps, with each line prefixed by the ID
+of its container.  When following them, lines are shown as they arrive.
 
 To get the logs of container 8879fd2dbcee with a specific private key:
 quilt logs -i ~/.ssh/quilt 8879fd2dbcee
 
 To follow the logs of the minion on machine 09ed35808a0b:
 quilt logs -f 09ed35808a0b
+
+To follow the logs of all of the containers of the "web" label:
+quilt logs -f web
 `
 
 // InstallFlags sets up parsing for command line flags.
@@ -57,6 +70,8 @@ func (lCmd *Log) InstallFlags(flags *flag.FlagSet) {
 	flags.StringVar(&lCmd.sinceTimestamp, "since", "", "show logs since timestamp")
 	flags.BoolVar(&lCmd.shouldTail, "f", false, "follow log output")
 	flags.BoolVar(&lCmd.showTimestamps, "t", false, "show timestamps")
+	flags.StringVar(&lCmd.tail, "tail", "",
+		"the number of lines to show from the end of the logs")
 
 	flags.Usage = func() {
 		fmt.Println(logsUsage)
@@ -91,10 +106,15 @@ func (lCmd *Log) Run() int {
 
 	switch {
 	case !resolvedMachine && !resolvedContainer:
+		containers, err := labelContainers(c, lCmd.clientGetter, lCmd.target)
+		if err == nil && len(containers) > 0 {
+			return lCmd.labelLogs(c, containers, os.Stdout)
+		}
+
 		log.WithFields(log.Fields{
 			"machine error":   machErr.Error(),
 			"container error": contErr.Error(),
-		}).Error("Failed to resolve target machine or container")
+		}).Error("Failed to resolve target machine, container or label")
 		return 1
 	case resolvedMachine && resolvedContainer:
 		log.WithFields(log.Fields{
@@ -119,6 +139,9 @@ func (lCmd *Log) Run() int {
 	if lCmd.shouldTail {
 		cmd = append(cmd, "--follow")
 	}
+	if lCmd.tail != "" {
+		cmd = append(cmd, fmt.Sprintf("--tail=%s", lCmd.tail))
+	}
 
 	host := contHost
 	if resolvedMachine {
@@ -130,8 +153,9 @@ func (lCmd *Log) Run() int {
 
 	sshClient, err := lCmd.sshGetter(host, lCmd.privateKey)
 	if err != nil {
-		log.WithError(err).Info("Error opening SSH connection")
-		return 1
+		log.WithError(err).Debug(
+			"Error opening SSH connection, so fetching logs over the API")
+		return lCmd.apiLogs(host, cont.StitchID)
 	}
 	defer sshClient.Close()
 
@@ -142,3 +166,53 @@ func (lCmd *Log) Run() int {
 
 	return 0
 }
+
+// apiLogs fetches the logs of `container` through the Quilt API on `host`, or of
+// the minion itself if `container` is empty.
+func (lCmd *Log) apiLogs(host, container string) int {
+	opts := client.LogsOptions{
+		Tail:       lCmd.tail,
+		Follow:     lCmd.shouldTail,
+		Timestamps: lCmd.showTimestamps,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+	}
+
+	if lCmd.sinceTimestamp != "" {
+		since, err := parseSince(lCmd.sinceTimestamp)
+		if err != nil {
+			log.Error(err)
+			return 1
+		}
+		opts.Since = since
+	}
+
+	c, err := lCmd.clientGetter.Client(api.RemoteAddress(host))
+	if err != nil {
+		log.WithError(err).Error("Error connecting to the Quilt API")
+		return 1
+	}
+	defer c.Close()
+
+	if err := c.Logs(container, opts); err != nil {
+		log.WithError(err).Error("Error fetching logs")
+		return 1
+	}
+	return 0
+}
+
+// parseSince parses either a timestamp, or a duration relative to now, as accepted
+// by `docker logs --since`.
+func parseSince(since string) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05",
+		"2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("malformed since timestamp: %s", since)
+}
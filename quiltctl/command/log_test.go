@@ -3,6 +3,7 @@ package command
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -22,6 +23,7 @@ func checkLogParsing(t *testing.T, args []string, exp Log, expErr error) {
 	assert.Equal(t, exp.sinceTimestamp, logsCmd.sinceTimestamp)
 	assert.Equal(t, exp.showTimestamps, logsCmd.showTimestamps)
 	assert.Equal(t, exp.shouldTail, logsCmd.shouldTail)
+	assert.Equal(t, exp.tail, logsCmd.tail)
 }
 
 func TestLogFlags(t *testing.T) {
@@ -46,6 +48,10 @@ func TestLogFlags(t *testing.T) {
 		target:         "1",
 		sinceTimestamp: "07/27/2016",
 	}, nil)
+	checkLogParsing(t, []string{"-tail", "10", "1"}, Log{
+		target: "1",
+		tail:   "10",
+	}, nil)
 	checkLogParsing(t, []string{}, Log{},
 		errors.New("must specify a target container or machine"))
 }
@@ -102,6 +108,15 @@ func TestLog(t *testing.T) {
 			expHost:       "container",
 			expSSHCommand: "docker logs --since=2006-01-02T15:04:05 foo",
 		},
+		// Tail lines flag
+		{
+			cmd: Log{
+				target: targetContainer,
+				tail:   "10",
+			},
+			expHost:       "container",
+			expSSHCommand: "docker logs --tail=10 foo",
+		},
 	}
 
 	mockLocalClient := &mocks.Client{
@@ -171,6 +186,7 @@ func TestLogNoMatch(t *testing.T) {
 	mockClientGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockClientGetter.On("ContainerClient", mock.Anything, mock.Anything).
 		Return(mockClient, nil)
+	mockClientGetter.On("LeaderClient", mock.Anything).Return(mockClient, nil)
 
 	testCmd := Log{
 		common:       &commonFlags{},
@@ -197,3 +213,68 @@ func TestLogScheduledContainer(t *testing.T) {
 	}
 	assert.Equal(t, 1, testCmd.Run())
 }
+
+func TestLogAPIFallback(t *testing.T) {
+	t.Parallel()
+
+	mockLocalClient := &mocks.Client{
+		MachineReturn: []db.Machine{{StitchID: "a", PublicIP: "machine"}},
+	}
+	mockContainerClient := &mocks.Client{
+		ContainerReturn: []db.Container{{StitchID: "1", DockerID: "foo"}},
+		HostReturn:      "container",
+	}
+	mockMachineClient := &mocks.Client{}
+	mockGetter := new(mocks.Getter)
+	mockGetter.On("Client", "tcp://container:9000").Return(mockContainerClient, nil)
+	mockGetter.On("Client", "tcp://machine:9000").Return(mockMachineClient, nil)
+	mockGetter.On("Client", mock.Anything).Return(mockLocalClient, nil)
+	mockGetter.On("ContainerClient", mock.Anything, mock.Anything).Return(
+		mockContainerClient, nil)
+
+	run := func(cmd Log) int {
+		cmd.sshGetter = func(host, key string) (ssh.Client, error) {
+			return nil, errors.New("no key")
+		}
+		cmd.clientGetter = mockGetter
+		cmd.common = &commonFlags{}
+		return cmd.Run()
+	}
+
+	assert.Equal(t, 0, run(Log{target: "1", shouldTail: true, tail: "5",
+		sinceTimestamp: "2006-01-02T15:04:05"}))
+	assert.Equal(t, "1", mockContainerClient.LogsContainer)
+	assert.True(t, mockContainerClient.LogsArg.Follow)
+	assert.Equal(t, "5", mockContainerClient.LogsArg.Tail)
+	assert.Equal(t, time.Date(2006, 1, 2, 15, 4, 5, 0, time.Local),
+		mockContainerClient.LogsArg.Since)
+
+	// The logs of the minion are fetched when targeting a machine.
+	assert.Equal(t, 0, run(Log{target: "a", showTimestamps: true}))
+	assert.Equal(t, "", mockMachineClient.LogsContainer)
+	assert.True(t, mockMachineClient.LogsArg.Timestamps)
+
+	assert.Equal(t, 1, run(Log{target: "a", sinceTimestamp: "yesterday"}))
+
+	mockMachineClient.LogsErr = errors.New("err")
+	assert.Equal(t, 1, run(Log{target: "a"}))
+}
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	since, err := parseSince("1h")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), since, time.Minute)
+
+	since, err = parseSince("2016-07-27")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2016, 7, 27, 0, 0, 0, 0, time.Local), since)
+
+	since, err = parseSince("2016-07-27T10:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2016, 7, 27, 10, 0, 0, 0, time.UTC), since.UTC())
+
+	_, err = parseSince("07/27/2016")
+	assert.EqualError(t, err, "malformed since timestamp: 07/27/2016")
+}
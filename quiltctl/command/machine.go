@@ -18,6 +18,7 @@ import (
 // Machine contains the options for querying machines.
 type Machine struct {
 	common       *commonFlags
+	output       *outputFlags
 	clientGetter client.Getter
 }
 
@@ -25,6 +26,7 @@ type Machine struct {
 func NewMachineCommand() *Machine {
 	return &Machine{
 		common:       &commonFlags{},
+		output:       &outputFlags{},
 		clientGetter: getter.New(),
 	}
 }
@@ -32,16 +34,20 @@ func NewMachineCommand() *Machine {
 // InstallFlags sets up parsing for command line flags
 func (mCmd *Machine) InstallFlags(flags *flag.FlagSet) {
 	mCmd.common.InstallFlags(flags)
+	mCmd.output.InstallFlags(flags)
+	mCmd.output.installMachineFilters(flags)
 	flags.Usage = func() {
-		fmt.Println("usage: quilt machine [-H=<daemon_host>]")
+		fmt.Println("usage: quilt machine [-H=<daemon_host>] [-o=<format>] " +
+			"[-format=<template>] [-role=<role>]")
 		fmt.Println("`machine` displays the status of quilt-managed machines.")
+		fmt.Println(outputUsage)
 		flags.PrintDefaults()
 	}
 }
 
 // Parse parses the command line arguments for the machine command.
 func (mCmd *Machine) Parse(args []string) error {
-	return nil
+	return mCmd.output.parse()
 }
 
 // Run retrieves and prints the requested machines.
@@ -59,18 +65,42 @@ func (mCmd *Machine) Run() int {
 		return 1
 	}
 
-	writeMachines(os.Stdout, machines)
+	machines = mCmd.output.filterMachines(machines)
+	if mCmd.output.structured() {
+		if err := mCmd.output.write(os.Stdout, machines); err != nil {
+			log.WithError(err).Error("Unable to write machines.")
+			return 1
+		}
+		return 0
+	}
+
+	writeMachines(os.Stdout, machines, mCmd.output.wide())
 	return 0
 }
 
-func writeMachines(fd io.Writer, machines []db.Machine) {
+// writeMachines writes a table of `machines`.  Wide tables include full IDs and
+// additional columns.
+func writeMachines(fd io.Writer, machines []db.Machine, wide bool) {
 	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
 	defer w.Flush()
-	fmt.Fprintln(w, "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tPUBLIC IP\tCONNECTED")
+
+	header := "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tPUBLIC IP\tCONNECTED"
+	if wide {
+		header += "\tPRIVATE IP\tCLOUD ID"
+	}
+	fmt.Fprintln(w, header)
 
 	for _, m := range db.SortMachines(machines) {
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
-			util.ShortUUID(m.StitchID), m.Role, m.Provider, m.Region, m.Size,
-			m.PublicIP, m.Connected)
+		id := util.ShortUUID(m.StitchID)
+		if wide {
+			id = m.StitchID
+		}
+
+		row := fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v", id, m.Role,
+			m.Provider, m.Region, m.Size, m.PublicIP, m.Connected)
+		if wide {
+			row += fmt.Sprintf("\t%v\t%v", m.PrivateIP, m.CloudID)
+		}
+		fmt.Fprintln(w, row)
 	}
 }
@@ -0,0 +1,331 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/quiltctl/ssh"
+	"github.com/quilt/quilt/stitch"
+	"github.com/quilt/quilt/util"
+)
+
+// NetCheck contains the options for verifying that the network matches the
+// connection policy.
+type NetCheck struct {
+	privateKey string
+
+	common       *commonFlags
+	clientGetter client.Getter
+	sshGetter    ssh.Getter
+}
+
+// NewNetCheckCommand creates a new NetCheck command instance.
+func NewNetCheckCommand() *NetCheck {
+	return &NetCheck{
+		clientGetter: getter.New(),
+		sshGetter:    ssh.New,
+		common:       &commonFlags{},
+	}
+}
+
+var netCheckUsage = `usage: quilt netcheck [-H=<daemon_host>] [-i=<private_key>]
+
+Ping every label and container from within every container, and report each
+pair whose reachability differs from the connection policy.  The exit status is
+non-zero if any pair differs.
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (nCmd *NetCheck) InstallFlags(flags *flag.FlagSet) {
+	nCmd.common.InstallFlags(flags)
+	flags.StringVar(&nCmd.privateKey, "i", "",
+		"the private key to use to connect to the hosts")
+
+	flags.Usage = func() {
+		fmt.Println(netCheckUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the netcheck command.
+func (nCmd *NetCheck) Parse(args []string) error {
+	if len(args) != 0 {
+		return errors.New("netcheck takes no arguments")
+	}
+	return nil
+}
+
+// Run probes the network and prints the pairs that violate the policy.
+func (nCmd *NetCheck) Run() int {
+	localClient, err := nCmd.clientGetter.Client(nCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer localClient.Close()
+
+	leader, err := nCmd.clientGetter.LeaderClient(localClient)
+	if err != nil {
+		log.WithError(err).Error("Error connecting to leader.")
+		return 1
+	}
+	defer leader.Close()
+
+	machines, err := localClient.QueryMachines()
+	if err != nil {
+		log.WithError(err).Error("Unable to query machines.")
+		return 1
+	}
+
+	containers, err := leader.QueryContainers()
+	if err != nil {
+		log.WithError(err).Error("Unable to query containers.")
+		return 1
+	}
+
+	labels, err := leader.QueryLabels()
+	if err != nil {
+		log.WithError(err).Error("Unable to query labels.")
+		return 1
+	}
+
+	connections, err := leader.QueryConnections()
+	if err != nil {
+		log.WithError(err).Error("Unable to query connections.")
+		return 1
+	}
+
+	probes := planProbes(containers, labels, connections)
+	if len(probes) == 0 {
+		fmt.Println("No running containers to check.")
+		return 0
+	}
+
+	hosts := map[string]string{}
+	for _, m := range machines {
+		hosts[m.PrivateIP] = m.PublicIP
+	}
+
+	results := nCmd.runProbes(hosts, probes)
+	if !writeNetCheck(os.Stdout, results) {
+		return 1
+	}
+	return 0
+}
+
+// A probe pings a target IP from within a container.
+type probe struct {
+	container db.Container
+	target    string // A description of what owns the IP.
+	ip        string
+	expected  bool // Whether the policy allows the container to reach the IP.
+
+	reachable bool
+	err       error
+}
+
+// planProbes decides which IPs each running container should ping, and whether
+// the connection policy allows the pings to succeed.
+func planProbes(containers []db.Container, labels []db.Label,
+	connections []db.Connection) []probe {
+
+	// Connections are tested in both directions because the replies to pings
+	// sent over a connection must be allowed back.
+	connected := map[[2]string]bool{}
+	for _, c := range connections {
+		connected[[2]string{c.From, c.To}] = true
+		connected[[2]string{c.To, c.From}] = true
+	}
+
+	type target struct {
+		name   string
+		labels []string
+	}
+
+	targets := map[string]target{}
+	for _, label := range labels {
+		if label.IP == "" || label.Label == stitch.PublicInternetLabel {
+			continue
+		}
+		targets[label.IP] = target{label.Label, []string{label.Label}}
+	}
+
+	var running []db.Container
+	for _, dbc := range containers {
+		if dbc.IP == "" || dbc.DockerID == "" || dbc.Minion == "" {
+			continue
+		}
+		running = append(running, dbc)
+
+		name := fmt.Sprintf("%s (%s)", util.ShortUUID(dbc.StitchID),
+			strings.Join(dbc.Labels, ", "))
+		targets[dbc.IP] = target{name, dbc.Labels}
+	}
+
+	var ips []string
+	for ip := range targets {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	var probes []probe
+	for _, dbc := range running {
+		for _, ip := range ips {
+			if ip == dbc.IP {
+				continue
+			}
+
+			var expected bool
+			for _, from := range dbc.Labels {
+				for _, to := range targets[ip].labels {
+					expected = expected || connected[[2]string{from, to}]
+				}
+			}
+
+			probes = append(probes, probe{
+				container: dbc,
+				target:    targets[ip].name,
+				ip:        ip,
+				expected:  expected,
+			})
+		}
+	}
+	return probes
+}
+
+// We have to limit our parallelization because each ping creates a new SSH session,
+// and creating many quickly breaks systemd-logind on the remote machine:
+// https://github.com/systemd/systemd/issues/2925.
+const netCheckConcurrency = 10
+
+// runProbes runs the probes over one SSH connection per host.  `hosts` maps the
+// private IPs of the machines to their public IPs.
+func (nCmd *NetCheck) runProbes(hosts map[string]string, probes []probe) []probe {
+	hostProbes := map[string][]int{}
+	for i, p := range probes {
+		hostProbes[p.container.Minion] = append(hostProbes[p.container.Minion], i)
+	}
+
+	var wg sync.WaitGroup
+	for minion, indices := range hostProbes {
+		sshClient, err := nCmd.sshGetter(hosts[minion], nCmd.privateKey)
+		if err != nil {
+			for _, i := range indices {
+				probes[i].err = fmt.Errorf("ssh %s: %s", hosts[minion], err)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(sshClient ssh.Client, indices []int) {
+			defer wg.Done()
+			defer sshClient.Close()
+
+			requests := make(chan int)
+			var workers sync.WaitGroup
+			for i := 0; i < netCheckConcurrency; i++ {
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					for i := range requests {
+						p := &probes[i]
+						p.reachable, p.err = ping(sshClient,
+							p.container.DockerID, p.ip)
+					}
+				}()
+			}
+
+			for _, i := range indices {
+				requests <- i
+			}
+			close(requests)
+			workers.Wait()
+		}(sshClient, indices)
+	}
+	wg.Wait()
+	return probes
+}
+
+// ping `ip` from within the container with 3 packets, with a timeout of 1 second
+// for each packet.
+func ping(c ssh.Client, dockerID, ip string) (bool, error) {
+	err := c.Run(false, fmt.Sprintf("docker exec %s ping -c 3 -W 1 %s "+
+		">/dev/null 2>&1", dockerID, ip))
+	if err == nil {
+		return true, nil
+	}
+
+	if _, ok := err.(exitError); ok {
+		return false, nil
+	}
+	return false, err
+}
+
+// writeNetCheck prints the probes that disagree with the policy, and returns
+// whether they all agreed.
+func writeNetCheck(fd io.Writer, probes []probe) bool {
+	var failed []probe
+	for _, p := range probes {
+		if p.err != nil || p.reachable != p.expected {
+			failed = append(failed, p)
+		}
+	}
+
+	if len(failed) == 0 {
+		fmt.Fprintf(fd, "All %d probes matched the connection policy.\n",
+			len(probes))
+		return true
+	}
+
+	sort.Sort(probeSlice(failed))
+
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "CONTAINER\tLABELS\tTARGET\tTARGET IP\tPROBLEM")
+
+	for _, p := range failed {
+		var problem string
+		switch {
+		case p.err != nil:
+			problem = fmt.Sprintf("probe failed: %s", p.err)
+		case p.expected:
+			problem = "allowed but blocked"
+		default:
+			problem = "blocked but reachable"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			util.ShortUUID(p.container.StitchID),
+			strings.Join(p.container.Labels, ", "), p.target, p.ip, problem)
+	}
+	return false
+}
+
+type probeSlice []probe
+
+func (ps probeSlice) Len() int {
+	return len(ps)
+}
+
+func (ps probeSlice) Swap(i, j int) {
+	ps[i], ps[j] = ps[j], ps[i]
+}
+
+func (ps probeSlice) Less(i, j int) bool {
+	if ps[i].container.StitchID != ps[j].container.StitchID {
+		return ps[i].container.StitchID < ps[j].container.StitchID
+	}
+	return ps[i].ip < ps[j].ip
+}
@@ -0,0 +1,169 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/quiltctl/ssh"
+)
+
+func TestNetCheckFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewNetCheckCommand()
+	err := parseHelper(cmd, []string{"-i", "key", "-H", "host"})
+	assert.NoError(t, err)
+	assert.Equal(t, "key", cmd.privateKey)
+	assert.Equal(t, "host", cmd.common.host)
+
+	err = parseHelper(NewNetCheckCommand(), []string{"arg"})
+	assert.EqualError(t, err, "netcheck takes no arguments")
+}
+
+var netCheckContainers = []db.Container{
+	{StitchID: "1", DockerID: "d1", IP: "10.0.0.1", Minion: "p1",
+		Labels: []string{"web"}},
+	{StitchID: "2", DockerID: "d2", IP: "10.0.0.2", Minion: "p2",
+		Labels: []string{"db"}},
+	{StitchID: "3", DockerID: "d3", IP: "10.0.0.3", Minion: "p2",
+		Labels: []string{"other"}},
+	{StitchID: "4", Labels: []string{"web"}},
+}
+
+var netCheckLabels = []db.Label{
+	{Label: "web", IP: "10.1.0.1"},
+	{Label: "db", IP: "10.1.0.2"},
+	{Label: "other", IP: "10.1.0.3"},
+	{Label: "public"},
+}
+
+var netCheckConnections = []db.Connection{
+	{From: "web", To: "db", MinPort: 5432, MaxPort: 5432},
+	{From: "public", To: "web", MinPort: 80, MaxPort: 80},
+}
+
+func TestPlanProbes(t *testing.T) {
+	t.Parallel()
+
+	probes := planProbes(netCheckContainers, netCheckLabels, netCheckConnections)
+
+	// Each of the 3 running containers pings the 5 IPs other than its own.
+	assert.Len(t, probes, 15)
+
+	expected := map[[2]string]bool{}
+	for _, p := range probes {
+		if p.expected {
+			expected[[2]string{p.container.StitchID, p.ip}] = true
+		}
+	}
+	assert.Equal(t, map[[2]string]bool{
+		{"1", "10.0.0.2"}: true,
+		{"1", "10.1.0.2"}: true,
+		{"2", "10.0.0.1"}: true,
+		{"2", "10.1.0.1"}: true,
+	}, expected)
+
+	for _, p := range probes {
+		if p.ip == "10.0.0.2" {
+			assert.Equal(t, "2 (db)", p.target)
+		} else if p.ip == "10.1.0.2" {
+			assert.Equal(t, "db", p.target)
+		}
+	}
+}
+
+func TestWriteNetCheck(t *testing.T) {
+	t.Parallel()
+
+	dbc := db.Container{StitchID: "1", Labels: []string{"web"}}
+	probes := []probe{
+		{container: dbc, target: "db", ip: "10.1.0.2", expected: true,
+			reachable: true},
+		{container: dbc, target: "other", ip: "10.1.0.3", expected: false,
+			reachable: true},
+		{container: dbc, target: "2 (db)", ip: "10.0.0.2", expected: true},
+		{container: dbc, target: "3 (x)", ip: "10.0.0.3",
+			err: errors.New("ssh")},
+	}
+
+	var b bytes.Buffer
+	assert.False(t, writeNetCheck(&b, probes))
+
+	exp := "CONTAINER    LABELS    TARGET    TARGET IP    PROBLEM\n" +
+		"1            web       2 (db)    10.0.0.2     allowed but blocked\n" +
+		"1            web       3 (x)     10.0.0.3     probe failed: ssh\n" +
+		"1            web       other     10.1.0.3     blocked but reachable\n"
+	assert.Equal(t, exp, b.String())
+
+	b.Reset()
+	assert.True(t, writeNetCheck(&b, probes[:1]))
+	assert.Equal(t, "All 1 probes matched the connection policy.\n", b.String())
+}
+
+func TestNetCheckRun(t *testing.T) {
+	t.Parallel()
+
+	c := &mocks.Client{
+		MachineReturn: []db.Machine{
+			{PublicIP: "h1", PrivateIP: "p1"},
+			{PublicIP: "h2", PrivateIP: "p2"},
+		},
+		ContainerReturn:  netCheckContainers,
+		LabelReturn:      netCheckLabels,
+		ConnectionReturn: netCheckConnections,
+	}
+
+	mockGetter := new(mocks.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+	mockGetter.On("LeaderClient", mock.Anything).Return(c, nil)
+
+	// The network behaves exactly as the policy requires.
+	allowed := map[string]bool{
+		"d1 10.0.0.2": true, "d1 10.1.0.2": true,
+		"d2 10.0.0.1": true, "d2 10.1.0.1": true,
+	}
+	sshClient := func(extra string) *ssh.MockClient {
+		mockSSH := new(ssh.MockClient)
+		mockSSH.On("Run", false, mock.Anything).Return(
+			func(_ bool, cmd string) error {
+				fields := strings.Fields(cmd)
+				probe := fields[2] + " " + fields[8]
+				if allowed[probe] || probe == extra {
+					return nil
+				}
+				return mockExitError(1)
+			})
+		mockSSH.On("Close").Return(nil)
+		return mockSSH
+	}
+
+	cmd := NewNetCheckCommand()
+	cmd.clientGetter = mockGetter
+	cmd.sshGetter = func(host, key string) (ssh.Client, error) {
+		return sshClient(""), nil
+	}
+	assert.Equal(t, 0, cmd.Run())
+
+	// Container 3 can reach a label it isn't connected to.
+	cmd.sshGetter = func(host, key string) (ssh.Client, error) {
+		return sshClient("d3 10.1.0.1"), nil
+	}
+	assert.Equal(t, 1, cmd.Run())
+
+	// Hosts that can't be reached fail their probes.
+	cmd.sshGetter = func(host, key string) (ssh.Client, error) {
+		return nil, errors.New("unreachable")
+	}
+	assert.Equal(t, 1, cmd.Run())
+
+	c.LabelReturn = nil
+	c.ContainerReturn = nil
+	assert.Equal(t, 0, cmd.Run())
+}
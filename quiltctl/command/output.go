@@ -0,0 +1,273 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/quilt/quilt/db"
+)
+
+// outputFlags contains the options shared by commands that print machines and
+// containers, which control how their rows are filtered and formatted.
+type outputFlags struct {
+	output string
+	format string
+
+	label  string
+	role   string
+	status string
+
+	template *template.Template
+}
+
+const outputUsage = `The -o flag selects the output format: "json", "yaml", or "wide",
+which adds columns to the default table.  Alternatively, -format accepts a Go
+template that is executed for each row, e.g. -format '{{.StitchID}} {{.PublicIP}}'.`
+
+// InstallFlags sets up parsing for the output format flags.
+func (of *outputFlags) InstallFlags(flags *flag.FlagSet) {
+	flags.StringVar(&of.output, "o", "", "the output format: json, yaml, or wide")
+	flags.StringVar(&of.format, "format", "",
+		"a Go template used to format each row")
+}
+
+// installMachineFilters sets up parsing for the flags that filter machines.
+func (of *outputFlags) installMachineFilters(flags *flag.FlagSet) {
+	flags.StringVar(&of.role, "role", "", "only show machines with this role")
+}
+
+// installContainerFilters sets up parsing for the flags that filter containers.
+func (of *outputFlags) installContainerFilters(flags *flag.FlagSet) {
+	flags.StringVar(&of.label, "label", "", "only show containers with this label")
+	flags.StringVar(&of.status, "status", "",
+		"only show containers with this status")
+}
+
+// parse validates the output flags, and compiles the format template.
+func (of *outputFlags) parse() error {
+	switch of.output {
+	case "", "json", "yaml", "wide":
+	default:
+		return fmt.Errorf("unknown output format: %s", of.output)
+	}
+
+	if of.format == "" {
+		return nil
+	}
+
+	if of.output != "" {
+		return errors.New("-o and -format cannot be used together")
+	}
+
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			js, err := json.Marshal(v)
+			return string(js), err
+		},
+	}).Parse(of.format)
+	if err != nil {
+		return fmt.Errorf("malformed format: %s", err)
+	}
+	of.template = tmpl
+	return nil
+}
+
+// structured returns whether the output should be written by `write` rather than
+// as a table.
+func (of outputFlags) structured() bool {
+	return of.output == "json" || of.output == "yaml" || of.template != nil
+}
+
+// wide returns whether tables should include extra columns.
+func (of outputFlags) wide() bool {
+	return of.output == "wide"
+}
+
+// write writes `data` to `fd` as JSON, YAML, or with the format template.  If
+// `data` is a slice, the template is executed for each of its elements.
+func (of outputFlags) write(fd io.Writer, data interface{}) error {
+	switch {
+	case of.template != nil:
+		return writeTemplate(fd, of.template, data)
+	case of.output == "yaml":
+		return writeYAML(fd, data)
+	default:
+		js, err := json.MarshalIndent(data, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(fd, "%s\n", js)
+		return err
+	}
+}
+
+func writeTemplate(fd io.Writer, tmpl *template.Template, data interface{}) error {
+	rows := []interface{}{data}
+	if value := reflect.ValueOf(data); value.Kind() == reflect.Slice {
+		rows = nil
+		for i := 0; i < value.Len(); i++ {
+			rows = append(rows, value.Index(i).Interface())
+		}
+	}
+
+	for _, row := range rows {
+		if err := tmpl.Execute(fd, row); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterMachines returns the machines that match the filter flags, sorted.
+func (of outputFlags) filterMachines(machines []db.Machine) []db.Machine {
+	filtered := []db.Machine{}
+	for _, m := range db.SortMachines(machines) {
+		if of.role == "" || strings.EqualFold(string(m.Role), of.role) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// filterContainers returns the containers that match the filter flags, sorted.
+func (of outputFlags) filterContainers(containers []db.Container) []db.Container {
+	filtered := []db.Container{}
+	for _, dbc := range containers {
+		status := containerStatus(dbc)
+		if of.status != "" && !strings.EqualFold(status, of.status) {
+			continue
+		}
+		if of.label != "" && !hasLabel(dbc, of.label) {
+			continue
+		}
+		filtered = append(filtered, dbc)
+	}
+	sort.Sort(db.ContainerSlice(filtered))
+	return filtered
+}
+
+// containerStatus returns the status of `dbc` as displayed to users.
+func containerStatus(dbc db.Container) string {
+	if dbc.Status == "" && dbc.Minion != "" {
+		return "scheduled"
+	}
+	return dbc.Status
+}
+
+func hasLabel(dbc db.Container, label string) bool {
+	for _, l := range dbc.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// writeYAML writes `data` as YAML.  It's converted by way of its JSON encoding, so
+// that the field names and omitted fields match the JSON output.
+func writeYAML(fd io.Writer, data interface{}) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeYAMLValue(&buf, value, 0)
+	_, err = buf.WriteTo(fd)
+	return err
+}
+
+// writeYAMLValue writes `value`, which must have been decoded from JSON, with each
+// line indented by `indent` spaces.  Map keys are written in sorted order.
+func writeYAMLValue(buf *bytes.Buffer, value interface{}, indent int) {
+	prefix := strings.Repeat(" ", indent)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			buf.WriteString(prefix + "{}\n")
+			return
+		}
+
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			buf.WriteString(prefix + yamlString(key) + ":")
+			if isYAMLCollection(v[key]) {
+				buf.WriteString("\n")
+				writeYAMLValue(buf, v[key], indent+2)
+			} else {
+				buf.WriteString(" ")
+				writeYAMLValue(buf, v[key], 0)
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			buf.WriteString(prefix + "[]\n")
+			return
+		}
+
+		for _, elem := range v {
+			// Nested collections start on the same line as their dash.
+			var elemBuf bytes.Buffer
+			writeYAMLValue(&elemBuf, elem, indent+2)
+			buf.WriteString(prefix + "- ")
+			buf.Write(elemBuf.Bytes()[indent+2:])
+		}
+	case string:
+		buf.WriteString(prefix + yamlString(v) + "\n")
+	case nil:
+		buf.WriteString(prefix + "null\n")
+	default:
+		buf.WriteString(fmt.Sprintf("%s%v\n", prefix, v))
+	}
+}
+
+func isYAMLCollection(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+var plainYAMLString = regexp.MustCompile(
+	`^[a-zA-Z_/]([a-zA-Z0-9_./ -]*[a-zA-Z0-9_./-])?$`)
+
+// yamlString quotes `s` unless it can be safely written as a plain YAML scalar.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+		return strconv.Quote(s)
+	}
+
+	if plainYAMLString.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
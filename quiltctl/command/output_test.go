@@ -0,0 +1,216 @@
+package command
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quilt/quilt/db"
+)
+
+func TestOutputFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewPsCommand()
+	err := parseHelper(cmd, []string{"-o", "json", "-role", "worker",
+		"-label", "web", "-status", "running"})
+	assert.NoError(t, err)
+	assert.Equal(t, "json", cmd.output.output)
+	assert.Equal(t, "worker", cmd.output.role)
+	assert.Equal(t, "web", cmd.output.label)
+	assert.Equal(t, "running", cmd.output.status)
+	assert.True(t, cmd.output.structured())
+
+	cmd = NewPsCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-o", "wide"}))
+	assert.True(t, cmd.output.wide())
+	assert.False(t, cmd.output.structured())
+
+	machineCmd := NewMachineCommand()
+	assert.NoError(t, parseHelper(machineCmd, []string{"-format", "{{.Role}}"}))
+	assert.NotNil(t, machineCmd.output.template)
+	assert.True(t, machineCmd.output.structured())
+
+	err = parseHelper(NewMachineCommand(), []string{"-o", "xml"})
+	assert.EqualError(t, err, "unknown output format: xml")
+
+	err = parseHelper(NewContainerCommand(),
+		[]string{"-o", "json", "-format", "{{.}}"})
+	assert.EqualError(t, err, "-o and -format cannot be used together")
+
+	err = parseHelper(NewContainerCommand(), []string{"-format", "{{.Image"})
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "malformed format: "))
+
+	// Commands only accept the filters that apply to their rows.
+	machineFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+	NewMachineCommand().InstallFlags(machineFlags)
+	assert.NotNil(t, machineFlags.Lookup("role"))
+	assert.Nil(t, machineFlags.Lookup("label"))
+
+	containerFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+	NewContainerCommand().InstallFlags(containerFlags)
+	assert.NotNil(t, containerFlags.Lookup("label"))
+	assert.Nil(t, containerFlags.Lookup("role"))
+}
+
+func TestOutputWrite(t *testing.T) {
+	t.Parallel()
+
+	machines := []db.Machine{
+		{StitchID: "1", Role: db.Master, PublicIP: "8.8.8.8"},
+		{StitchID: "2", Role: db.Worker},
+	}
+
+	write := func(args []string, data interface{}) string {
+		cmd := NewMachineCommand()
+		assert.NoError(t, parseHelper(cmd, args))
+
+		var buf bytes.Buffer
+		assert.NoError(t, cmd.output.write(&buf, data))
+		return buf.String()
+	}
+
+	assert.Equal(t, "1 8.8.8.8\n2 \n",
+		write([]string{"-format", "{{.StitchID}} {{.PublicIP}}"}, machines))
+	assert.Equal(t, "[\"a\"]\n",
+		write([]string{"-format", "{{json .Labels}}"},
+			db.Container{Labels: []string{"a"}}))
+
+	assert.Equal(t, `[
+    {
+        "StitchID": "1",
+        "Labels": [
+            "a"
+        ],
+        "Created": "0001-01-01T00:00:00Z"
+    }
+]
+`, write([]string{"-o", "json"},
+		[]db.Container{{StitchID: "1", Labels: []string{"a"}}}))
+
+	assert.Equal(t, `- Created: "0001-01-01T00:00:00Z"
+  Env:
+    KEY: "true"
+  Labels:
+    - a
+    - b c
+  StitchID: "1"
+`, write([]string{"-o", "yaml"}, []db.Container{{
+		StitchID: "1",
+		Labels:   []string{"a", "b c"},
+		Env:      map[string]string{"KEY": "true"},
+	}}))
+}
+
+func TestWriteYAML(t *testing.T) {
+	t.Parallel()
+
+	toYAML := func(data interface{}) string {
+		var buf bytes.Buffer
+		assert.NoError(t, writeYAML(&buf, data))
+		return buf.String()
+	}
+
+	assert.Equal(t, "[]\n", toYAML([]string{}))
+	assert.Equal(t, "{}\n", toYAML(map[string]int{}))
+	assert.Equal(t, "null\n", toYAML(nil))
+	assert.Equal(t, "a: 1\nb: false\nc: null\nd: []\n",
+		toYAML(map[string]interface{}{"a": 1, "b": false, "c": nil, "d": []int{}}))
+	assert.Equal(t, "- - 1\n  - 2\n- - 3\n", toYAML([][]int{{1, 2}, {3}}))
+	assert.Equal(t, "outer:\n  - inner: x\n    other: z\n",
+		toYAML(map[string]interface{}{
+			"outer": []map[string]string{{"inner": "x", "other": "z"}},
+		}))
+
+	for _, s := range []string{"", "yes", "No", "null", "1.5", "a: b", "- a",
+		"#", "a ", "'"} {
+		assert.True(t, strings.HasPrefix(yamlString(s), `"`), s)
+	}
+	for _, s := range []string{"Master", "us-west-1", "/etc/hosts", "m4.large",
+		"b c"} {
+		assert.Equal(t, s, yamlString(s))
+	}
+}
+
+func TestFilterRows(t *testing.T) {
+	t.Parallel()
+
+	machines := []db.Machine{
+		{StitchID: "2", Role: db.Worker},
+		{StitchID: "1", Role: db.Master},
+		{StitchID: "3", Role: db.Worker},
+	}
+
+	of := outputFlags{}
+	assert.Len(t, of.filterMachines(machines), 3)
+	assert.Equal(t, []db.Machine{}, of.filterMachines(nil))
+
+	of.role = "worker"
+	assert.Equal(t, []db.Machine{
+		{StitchID: "2", Role: db.Worker},
+		{StitchID: "3", Role: db.Worker},
+	}, of.filterMachines(machines))
+
+	containers := []db.Container{
+		{StitchID: "3", Labels: []string{"web"}, Status: "running"},
+		{StitchID: "1", Labels: []string{"db"}, Status: "running"},
+		{StitchID: "2", Labels: []string{"web", "db"}, Minion: "1.2.3.4"},
+		{StitchID: "4", Labels: []string{"web"}},
+	}
+
+	ids := func(containers []db.Container) (ids []string) {
+		for _, dbc := range containers {
+			ids = append(ids, dbc.StitchID)
+		}
+		return ids
+	}
+
+	of = outputFlags{}
+	assert.Equal(t, []string{"1", "2", "3", "4"},
+		ids(of.filterContainers(containers)))
+
+	of.label = "web"
+	assert.Equal(t, []string{"2", "3", "4"}, ids(of.filterContainers(containers)))
+
+	of.status = "Running"
+	assert.Equal(t, []string{"3"}, ids(of.filterContainers(containers)))
+
+	of = outputFlags{status: "scheduled"}
+	assert.Equal(t, []string{"2"}, ids(of.filterContainers(containers)))
+}
+
+func TestWideTables(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	writeMachines(&b, []db.Machine{{
+		StitchID:  "0123456789abcdef",
+		Role:      db.Worker,
+		PrivateIP: "10.0.0.1",
+		CloudID:   "i-1234",
+	}}, true)
+	lines := strings.Split(b.String(), "\n")
+	assert.Equal(t, []string{"MACHINE", "ROLE", "PROVIDER", "REGION", "SIZE",
+		"PUBLIC", "IP", "CONNECTED", "PRIVATE", "IP", "CLOUD", "ID"},
+		strings.Fields(lines[0]))
+	assert.Equal(t, []string{"0123456789abcdef", "Worker", "false", "10.0.0.1",
+		"i-1234"}, strings.Fields(lines[1]))
+
+	b.Reset()
+	writeContainers(&b, []db.Container{{
+		StitchID: "0123456789abcdef",
+		IP:       "10.1.0.2",
+		DockerID: "fedcba9876543210",
+		Status:   "running",
+	}}, nil, nil, true)
+	lines = strings.Split(b.String(), "\n")
+	assert.Equal(t, []string{"CONTAINER", "MACHINE", "COMMAND", "LABELS", "STATUS",
+		"CREATED", "PUBLIC", "IP", "IP", "DOCKER", "ID"},
+		strings.Fields(lines[0]))
+	assert.Equal(t, []string{"0123456789abcdef", "running", "10.1.0.2",
+		"fedcba987654"}, strings.Fields(lines[1]))
+}
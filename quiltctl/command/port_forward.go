@@ -0,0 +1,228 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/quiltctl/ssh"
+)
+
+// PortForward contains the options for forwarding local ports to containers.
+type PortForward struct {
+	privateKey string
+	target     string
+	localPort  int
+	remotePort int
+
+	common       *commonFlags
+	clientGetter client.Getter
+	sshGetter    ssh.Getter
+}
+
+// NewPortForwardCommand creates a new PortForward command instance.
+func NewPortForwardCommand() *PortForward {
+	return &PortForward{
+		clientGetter: getter.New(),
+		sshGetter:    ssh.New,
+		common:       &commonFlags{},
+	}
+}
+
+var portForwardUsage = `usage: quilt port-forward [-H=<daemon_host>] ` +
+	`[-i=<private_key>] <id_or_label> <local_port>:<remote_port>
+
+Forward a local port to a port on a container, even if the port isn't public.
+Connections are tunneled over SSH to the container's machine, and then to the
+container's Quilt IP.  If a label is given, the first of its containers is used.
+The tunnel stays open until interrupted.
+
+To reach port 28017 of container 8879fd2dbcee on localhost:8080:
+quilt port-forward 8879fd2dbcee 8080:28017
+
+To reach port 80 of a container with the label "web" on localhost:80:
+quilt port-forward web 80
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (pfCmd *PortForward) InstallFlags(flags *flag.FlagSet) {
+	pfCmd.common.InstallFlags(flags)
+	flags.StringVar(&pfCmd.privateKey, "i", "",
+		"the private key to use to connect to the host")
+
+	flags.Usage = func() {
+		fmt.Println(portForwardUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the port-forward command.
+func (pfCmd *PortForward) Parse(args []string) error {
+	if len(args) != 2 {
+		return errors.New("must specify a target and a port mapping")
+	}
+
+	local, remote, err := parsePortMapping(args[1])
+	if err != nil {
+		return err
+	}
+
+	pfCmd.target = args[0]
+	pfCmd.localPort = local
+	pfCmd.remotePort = remote
+	return nil
+}
+
+// parsePortMapping parses a mapping of the form <local>:<remote>, or a single port
+// that is used for both.
+func parsePortMapping(mapping string) (local, remote int, err error) {
+	parts := strings.Split(mapping, ":")
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("malformed port mapping: %s", mapping)
+	}
+
+	var ports []int
+	for _, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil || port <= 0 || port > 65535 {
+			return 0, 0, fmt.Errorf("malformed port mapping: %s", mapping)
+		}
+		ports = append(ports, port)
+	}
+	return ports[0], ports[1], nil
+}
+
+// Run forwards the local port until interrupted.
+func (pfCmd *PortForward) Run() int {
+	c, err := pfCmd.clientGetter.Client(pfCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	host, cont, err := pfCmd.resolve(c)
+	if err != nil {
+		log.WithError(err).Error("Failed to resolve target container")
+		return 1
+	}
+
+	if cont.IP == "" {
+		log.Errorf("Container %s doesn't have an IP yet", cont.StitchID)
+		return 1
+	}
+
+	sshClient, err := pfCmd.sshGetter(host, pfCmd.privateKey)
+	if err != nil {
+		log.WithError(err).Error("Failed to setup SSH connection")
+		return 1
+	}
+	defer sshClient.Close()
+
+	listener, err := listen("tcp", fmt.Sprintf("localhost:%d", pfCmd.localPort))
+	if err != nil {
+		log.WithError(err).Error("Failed to listen on the local port")
+		return 1
+	}
+
+	stop := interrupted()
+	go func() {
+		<-stop
+		listener.Close()
+	}()
+
+	remoteAddr := fmt.Sprintf("%s:%d", cont.IP, pfCmd.remotePort)
+	fmt.Printf("Forwarding localhost:%d to %s:%d\n", pfCmd.localPort,
+		cont.StitchID, pfCmd.remotePort)
+	forwardConnections(listener, func() (net.Conn, error) {
+		return sshClient.Dial("tcp", remoteAddr)
+	})
+	return 0
+}
+
+// resolve finds the container to forward to, and the public IP of its machine.
+// The target is first treated as a container ID, and then as a label.
+func (pfCmd *PortForward) resolve(c client.Client) (string, db.Container, error) {
+	host, cont, contErr := getContainer(c, pfCmd.clientGetter, pfCmd.target)
+	if contErr == nil {
+		return host, cont, nil
+	}
+
+	containers, err := labelContainers(c, pfCmd.clientGetter, pfCmd.target)
+	if err != nil {
+		return "", db.Container{}, err
+	}
+
+	for _, dbc := range containers {
+		if dbc.Minion != "" {
+			return getContainer(c, pfCmd.clientGetter, dbc.StitchID)
+		}
+	}
+
+	if len(containers) > 0 {
+		return "", db.Container{}, fmt.Errorf(
+			"no containers with label %s have been scheduled", pfCmd.target)
+	}
+	return "", db.Container{}, contErr
+}
+
+// forwardConnections proxies every connection accepted by `listener` to a
+// connection created by `dial`.  It returns once the listener is closed.
+func forwardConnections(listener net.Listener, dial func() (net.Conn, error)) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			remote, err := dial()
+			if err != nil {
+				log.WithError(err).Error(
+					"Failed to connect to the container")
+				local.Close()
+				return
+			}
+			proxy(local, remote)
+		}()
+	}
+}
+
+// proxy copies data between `a` and `b` until either side closes.
+func proxy(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+
+	a.Close()
+	b.Close()
+}
+
+var listen = net.Listen
+
+var interrupted = func() <-chan os.Signal {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	return sigs
+}
@@ -0,0 +1,170 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/quiltctl/ssh"
+)
+
+func TestPortForwardFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewPortForwardCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "-i", "key", "1234", "8080:80"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "key", cmd.privateKey)
+	assert.Equal(t, "1234", cmd.target)
+	assert.Equal(t, 8080, cmd.localPort)
+	assert.Equal(t, 80, cmd.remotePort)
+
+	cmd = NewPortForwardCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"web", "80"}))
+	assert.Equal(t, 80, cmd.localPort)
+	assert.Equal(t, 80, cmd.remotePort)
+
+	for _, args := range []string{"", "1234", "1234 80 81"} {
+		err := parseHelper(NewPortForwardCommand(), strings.Fields(args))
+		assert.EqualError(t, err, "must specify a target and a port mapping")
+	}
+
+	for _, mapping := range []string{"a", "80:", "1:2:3", "0:80", "80:70000"} {
+		err := parseHelper(NewPortForwardCommand(), []string{"1234", mapping})
+		assert.EqualError(t, err, "malformed port mapping: "+mapping)
+	}
+}
+
+func TestPortForwardRun(t *testing.T) {
+	oldListen := listen
+	oldInterrupted := interrupted
+	defer func() {
+		listen = oldListen
+		interrupted = oldInterrupted
+	}()
+
+	listeners := make(chan net.Listener, 1)
+	listen = func(network, addr string) (net.Listener, error) {
+		assert.Equal(t, "localhost:8080", addr)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		listeners <- listener
+		return listener, err
+	}
+
+	stop := make(chan os.Signal, 1)
+	interrupted = func() <-chan os.Signal { return stop }
+
+	// The container echoes each line it receives in upper case.
+	containerConn, tunnelConn := net.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(containerConn)
+		for scanner.Scan() {
+			fmt.Fprintln(containerConn, strings.ToUpper(scanner.Text()))
+		}
+	}()
+
+	sshClient := new(ssh.MockClient)
+	sshClient.On("Dial", "tcp", "10.0.0.2:80").Return(tunnelConn, nil)
+	sshClient.On("Close").Return(nil)
+
+	cmd := newTestPortForward(newPortForwardClient(), sshClient)
+	assert.NoError(t, parseHelper(cmd, []string{"12", "8080:80"}))
+
+	exitCode := make(chan int)
+	go func() { exitCode <- cmd.Run() }()
+
+	listener := <-listeners
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+
+	fmt.Fprintln(conn, "ping")
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "PING\n", reply)
+	conn.Close()
+
+	stop <- os.Interrupt
+	assert.Equal(t, 0, <-exitCode)
+	sshClient.AssertExpectations(t)
+}
+
+func TestPortForwardErrors(t *testing.T) {
+	t.Parallel()
+
+	run := func(c *clientMock.Client, sshClient ssh.Client, target string) int {
+		cmd := newTestPortForward(c, sshClient)
+		assert.NoError(t, parseHelper(cmd, []string{target, "80"}))
+		return cmd.Run()
+	}
+
+	// Neither a container nor a label.
+	assert.Equal(t, 1, run(newPortForwardClient(), nil, "56"))
+
+	// The label's containers haven't been scheduled.
+	c := newPortForwardClient()
+	c.ContainerReturn[0].Minion = ""
+	assert.Equal(t, 1, run(c, nil, "web"))
+
+	// The container doesn't have an IP.
+	c = newPortForwardClient()
+	c.ContainerReturn[0].IP = ""
+	assert.Equal(t, 1, run(c, nil, "12"))
+
+	// The SSH connection fails.
+	assert.Equal(t, 1, run(newPortForwardClient(), nil, "12"))
+}
+
+func TestPortForwardResolveLabel(t *testing.T) {
+	t.Parallel()
+
+	c := newPortForwardClient()
+	c.ContainerReturn = append([]db.Container{
+		{StitchID: "0123", Labels: []string{"web"}},
+	}, c.ContainerReturn...)
+
+	cmd := newTestPortForward(c, nil)
+	cmd.target = "web"
+	host, cont, err := cmd.resolve(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "host", host)
+	assert.Equal(t, "1234", cont.StitchID)
+}
+
+func newPortForwardClient() *clientMock.Client {
+	return &clientMock.Client{
+		HostReturn: "host",
+		ContainerReturn: []db.Container{{
+			StitchID: "1234",
+			IP:       "10.0.0.2",
+			Minion:   "10.0.0.1",
+			Labels:   []string{"web"},
+		}},
+	}
+}
+
+func newTestPortForward(c *clientMock.Client, sshClient ssh.Client) *PortForward {
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+	mockGetter.On("ContainerClient", mock.Anything, mock.Anything).Return(c, nil)
+	mockGetter.On("LeaderClient", mock.Anything).Return(c, nil)
+
+	cmd := NewPortForwardCommand()
+	cmd.clientGetter = mockGetter
+	cmd.sshGetter = func(host, key string) (ssh.Client, error) {
+		if sshClient == nil || host != "host" {
+			return nil, errors.New("ssh error")
+		}
+		return sshClient, nil
+	}
+	return cmd
+}
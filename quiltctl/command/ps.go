@@ -16,6 +16,7 @@ import (
 // Ps contains the options for querying machines and containers.
 type Ps struct {
 	common       *commonFlags
+	output       *outputFlags
 	clientGetter client.Getter
 }
 
@@ -23,6 +24,7 @@ type Ps struct {
 func NewPsCommand() *Ps {
 	return &Ps{
 		common:       &commonFlags{},
+		output:       &outputFlags{},
 		clientGetter: getter.New(),
 	}
 }
@@ -30,10 +32,18 @@ func NewPsCommand() *Ps {
 // InstallFlags sets up parsing for command line flags
 func (pCmd *Ps) InstallFlags(flags *flag.FlagSet) {
 	pCmd.common.InstallFlags(flags)
+	pCmd.output.InstallFlags(flags)
+	pCmd.output.installMachineFilters(flags)
+	pCmd.output.installContainerFilters(flags)
 	flags.Usage = func() {
-		fmt.Println("usage: quilt ps [-H=<daemon_host>]")
+		fmt.Println("usage: quilt ps [-H=<daemon_host>] [-o=<format>] " +
+			"[-format=<template>] [-role=<role>] [-label=<label>] " +
+			"[-status=<status>]")
 		fmt.Println("`ps` displays the status of quilt-managed " +
 			"machines and containers.")
+		fmt.Println(outputUsage)
+		fmt.Println("For ps, the template is executed once, with the fields " +
+			"Machines and Containers.")
 
 		flags.PrintDefaults()
 	}
@@ -41,7 +51,7 @@ func (pCmd *Ps) InstallFlags(flags *flag.FlagSet) {
 
 // Parse parses the command line arguments for the ps command.
 func (pCmd *Ps) Parse(args []string) error {
-	return nil
+	return pCmd.output.parse()
 }
 
 // Run retrieves and prints all machines and containers.
@@ -69,6 +79,7 @@ func (pCmd *Ps) run() error {
 	machineErr := make(chan error)
 
 	go func() {
+		var err error
 		machines, err = localClient.QueryMachines()
 		machineErr <- err
 	}()
@@ -78,11 +89,13 @@ func (pCmd *Ps) run() error {
 		defer leaderClient.Close()
 
 		go func() {
+			var err error
 			connections, err = leaderClient.QueryConnections()
 			connectionErr <- err
 		}()
 
 		go func() {
+			var err error
 			containers, err = leaderClient.QueryContainers()
 			containerErr <- err
 		}()
@@ -92,12 +105,15 @@ func (pCmd *Ps) run() error {
 		return fmt.Errorf("unable to query machines: %s", err)
 	}
 
-	writeMachines(os.Stdout, machines)
-	fmt.Println()
+	if !pCmd.output.structured() {
+		writeMachines(os.Stdout, pCmd.output.filterMachines(machines),
+			pCmd.output.wide())
+		fmt.Println()
+	}
 
 	if leadErr != nil {
 		log.WithError(leadErr).Debug("unable to connect to a cluster leader")
-		return nil
+		return pCmd.writeStructured(machines, nil)
 	}
 	if err := <-connectionErr; err != nil {
 		return fmt.Errorf("unable to query connections: %s", err)
@@ -109,11 +125,39 @@ func (pCmd *Ps) run() error {
 	workerContainers := pCmd.queryWorkers(machines)
 	containers = updateContainers(containers, workerContainers)
 
-	writeContainers(os.Stdout, containers, machines, connections)
+	if pCmd.output.structured() {
+		return pCmd.writeStructured(machines, containers)
+	}
+
+	containers = pCmd.output.filterContainers(containers)
+	writeContainers(os.Stdout, containers, machines, connections,
+		pCmd.output.wide())
+	writeRollouts(os.Stdout, containers)
 
 	return nil
 }
 
+// psOutput is written by `ps` when a structured output format is requested.
+type psOutput struct {
+	Machines   []db.Machine
+	Containers []db.Container
+}
+
+// writeStructured writes the filtered machines and containers with the requested
+// output format, if any.
+func (pCmd *Ps) writeStructured(machines []db.Machine,
+	containers []db.Container) error {
+
+	if !pCmd.output.structured() {
+		return nil
+	}
+
+	return pCmd.output.write(os.Stdout, psOutput{
+		Machines:   pCmd.output.filterMachines(machines),
+		Containers: pCmd.output.filterContainers(containers),
+	})
+}
+
 // queryWorkers gets a client for all connected worker machines (have a PublicIP
 // and are role Worker) and returns a list of db.Container on these machines.
 // If there is an error querying any machine, we skip it and attempt to return
@@ -38,7 +38,7 @@ func TestPsErrors(t *testing.T) {
 	mockGetter = new(clientMock.Getter)
 	mockGetter.On("Client", mock.Anything).Return(nil, mockErr)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.EqualError(t, cmd.run(), "error connecting to quilt daemon: error")
 	mockGetter.AssertExpectations(t)
 
@@ -48,7 +48,7 @@ func TestPsErrors(t *testing.T) {
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockGetter.On("LeaderClient", mock.Anything).Return(nil, mockErr)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.EqualError(t, cmd.run(), "unable to query machines: error")
 	mockGetter.AssertExpectations(t)
 
@@ -58,7 +58,7 @@ func TestPsErrors(t *testing.T) {
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockGetter.On("LeaderClient", mock.Anything).Return(nil, mockErr)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.NoError(t, cmd.run())
 	mockGetter.AssertExpectations(t)
 
@@ -69,7 +69,7 @@ func TestPsErrors(t *testing.T) {
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockGetter.On("LeaderClient", mock.Anything).Return(mockLeaderClient, nil)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.EqualError(t, cmd.run(), "unable to query containers: error")
 	mockGetter.AssertExpectations(t)
 
@@ -80,7 +80,7 @@ func TestPsErrors(t *testing.T) {
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockGetter.On("LeaderClient", mock.Anything).Return(mockLeaderClient, nil)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.EqualError(t, cmd.run(), "unable to query connections: error")
 	mockGetter.AssertExpectations(t)
 
@@ -99,7 +99,7 @@ func TestPsErrors(t *testing.T) {
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockGetter.On("LeaderClient", mock.Anything).Return(mockLeaderClient, nil)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.Equal(t, 0, cmd.Run())
 	mockGetter.AssertExpectations(t)
 }
@@ -114,7 +114,7 @@ func TestPsSuccess(t *testing.T) {
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 	mockGetter.On("LeaderClient", mock.Anything).Return(mockLeaderClient, nil)
 
-	cmd := &Ps{&commonFlags{}, mockGetter}
+	cmd := &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	assert.Equal(t, 0, cmd.Run())
 	mockGetter.AssertExpectations(t)
 }
@@ -141,7 +141,7 @@ func TestQueryWorkersSuccess(t *testing.T) {
 	}
 	mockGetter.On("Client", mock.Anything).Return(mockClient, nil)
 
-	cmd := &Ps{&commonFlags{}, mockGetter}
+	cmd := &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	result := cmd.queryWorkers(machines)
 	assert.Equal(t, containers, result)
 	mockGetter.AssertExpectations(t)
@@ -177,7 +177,7 @@ func TestQueryWorkersFailure(t *testing.T) {
 	mockGetter.On("Client", api.RemoteAddress("1.2.3.4")).Return(nil, mockErr)
 	mockGetter.On("Client", api.RemoteAddress("5.6.7.8")).Return(mockClient, nil)
 
-	cmd := &Ps{&commonFlags{}, mockGetter}
+	cmd := &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	result := cmd.queryWorkers(machines)
 	assert.Equal(t, containers, result)
 	mockGetter.AssertExpectations(t)
@@ -191,7 +191,7 @@ func TestQueryWorkersFailure(t *testing.T) {
 	mockGetter.On("Client", api.RemoteAddress("1.2.3.4")).Return(failingClient, nil)
 	mockGetter.On("Client", api.RemoteAddress("5.6.7.8")).Return(mockClient, nil)
 
-	cmd = &Ps{&commonFlags{}, mockGetter}
+	cmd = &Ps{&commonFlags{}, &outputFlags{}, mockGetter}
 	result = cmd.queryWorkers(machines)
 	assert.Equal(t, containers, result)
 	mockGetter.AssertExpectations(t)
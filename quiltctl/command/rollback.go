@@ -0,0 +1,114 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+)
+
+// Rollback contains the options for redeploying a previous spec.
+type Rollback struct {
+	revision int
+	force    bool
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewRollbackCommand creates a new Rollback command instance.
+func NewRollbackCommand() *Rollback {
+	return &Rollback{
+		common:       &commonFlags{},
+		clientGetter: getter.New(),
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (rCmd *Rollback) InstallFlags(flags *flag.FlagSet) {
+	rCmd.common.InstallFlags(flags)
+	flags.BoolVar(&rCmd.force, "f", false, "deploy without confirming changes")
+
+	flags.Usage = func() {
+		fmt.Println("usage: quilt rollback [-H=<daemon_host>] [-f] <revision>")
+		fmt.Println("`rollback` redeploys the spec of a revision listed by " +
+			"`quilt history`.  As with `quilt run`, confirmation is " +
+			"required unless the `-f` flag is given.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the rollback command.
+func (rCmd *Rollback) Parse(args []string) error {
+	if len(args) != 1 {
+		return errors.New("must specify a revision")
+	}
+
+	revision, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("malformed revision: %s", args[0])
+	}
+	rCmd.revision = revision
+	return nil
+}
+
+// Run redeploys the spec of the chosen revision.
+func (rCmd *Rollback) Run() int {
+	c, err := rCmd.clientGetter.Client(rCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	revisions, err := c.QueryHistory()
+	if err != nil {
+		log.WithError(err).Error("Unable to query deployment history.")
+		return 1
+	}
+
+	var rev *db.Revision
+	for i := range revisions {
+		if revisions[i].Number == rCmd.revision {
+			rev = &revisions[i]
+		}
+	}
+	if rev == nil {
+		log.Errorf("Revision %d not found.", rCmd.revision)
+		return 1
+	}
+
+	curr, err := getCurrentDeployment(c)
+	if err != nil && err != errNoCluster {
+		log.WithError(err).Error("Unable to get current deployment.")
+		return 1
+	}
+
+	if !rCmd.force && err != errNoCluster {
+		shouldDeploy, err := confirmDeployment(curr.String(), rev.Spec,
+			"Continue with rollback?")
+		if err != nil {
+			log.Error(err)
+			return 1
+		}
+
+		if !shouldDeploy {
+			fmt.Println("Rollback aborted by user.")
+			return 0
+		}
+	}
+
+	if err := c.Deploy(rev.Spec); err != nil {
+		log.WithError(err).Error("Error while rolling back.")
+		return 1
+	}
+
+	fmt.Printf("Rolled back to revision %d.\n", rev.Number)
+	return 0
+}
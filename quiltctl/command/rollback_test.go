@@ -0,0 +1,109 @@
+package command
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestRollbackFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewRollbackCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-H", "IP", "-f", "3"}))
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, 3, cmd.revision)
+	assert.True(t, cmd.force)
+
+	for args, exp := range map[string]string{
+		"":    "must specify a revision",
+		"1 2": "must specify a revision",
+		"a":   "malformed revision: a",
+	} {
+		err := parseHelper(NewRollbackCommand(), strings.Fields(args))
+		assert.EqualError(t, err, exp)
+	}
+}
+
+func TestRollbackRun(t *testing.T) {
+	oldConfirm := confirm
+	defer func() {
+		confirm = oldConfirm
+	}()
+
+	var prompted bool
+	var confirmResp bool
+	confirm = func(in io.Reader, prompt string) (bool, error) {
+		prompted = true
+		return confirmResp, nil
+	}
+
+	newClient := func() *clientMock.Client {
+		return &clientMock.Client{
+			ClusterReturn: []db.Cluster{{Spec: `{"new":"spec"}`}},
+			HistoryReturn: []db.Revision{
+				{Number: 1, Spec: `{"old":"spec"}`},
+				{Number: 2, Spec: `{"new":"spec"}`},
+			},
+		}
+	}
+	run := func(c *clientMock.Client, args ...string) int {
+		mockGetter := new(clientMock.Getter)
+		mockGetter.On("Client", mock.Anything).Return(c, nil)
+
+		cmd := NewRollbackCommand()
+		cmd.clientGetter = mockGetter
+		assert.NoError(t, parseHelper(cmd, args))
+		return cmd.Run()
+	}
+
+	// The user confirms the rollback.
+	c := newClient()
+	confirmResp = true
+	assert.Equal(t, 0, run(c, "1"))
+	assert.True(t, prompted)
+	assert.Equal(t, `{"old":"spec"}`, c.DeployArg)
+
+	// The user aborts the rollback.
+	c = newClient()
+	prompted, confirmResp = false, false
+	assert.Equal(t, 0, run(c, "1"))
+	assert.True(t, prompted)
+	assert.Empty(t, c.DeployArg)
+
+	// Confirmation is skipped with -f.
+	c = newClient()
+	prompted = false
+	assert.Equal(t, 0, run(c, "-f", "1"))
+	assert.False(t, prompted)
+	assert.Equal(t, `{"old":"spec"}`, c.DeployArg)
+
+	// There's no confirmation without a current deployment.
+	c = newClient()
+	c.ClusterReturn = nil
+	prompted = false
+	assert.Equal(t, 0, run(c, "1"))
+	assert.False(t, prompted)
+	assert.Equal(t, `{"old":"spec"}`, c.DeployArg)
+
+	// Unknown revisions.
+	c = newClient()
+	assert.Equal(t, 1, run(c, "-f", "3"))
+	assert.Empty(t, c.DeployArg)
+
+	// Errors from the daemon.
+	c = newClient()
+	c.HistoryErr = errors.New("err")
+	assert.Equal(t, 1, run(c, "-f", "1"))
+
+	c = newClient()
+	c.DeployErr = errors.New("err")
+	assert.Equal(t, 1, run(c, "-f", "1"))
+}
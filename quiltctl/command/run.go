@@ -110,20 +110,10 @@ func (rCmd *Run) Run() int {
 	}
 
 	if !rCmd.force && err != errNoCluster {
-		diff, err := diffDeployment(curr.String(), deployment)
+		shouldDeploy, err := confirmDeployment(curr.String(), deployment,
+			"Continue with deployment?")
 		if err != nil {
-			log.WithError(err).Error("Unable to diff deployments.")
-			return 1
-		}
-
-		if diff == "" {
-			fmt.Println("No change.")
-		} else {
-			fmt.Println(colorizeDiff(diff))
-		}
-		shouldDeploy, err := confirm(os.Stdin, "Continue with deployment?")
-		if err != nil {
-			log.WithError(err).Error("Unable to get user response.")
+			log.Error(err)
 			return 1
 		}
 
@@ -158,6 +148,27 @@ func getCurrentDeployment(c client.Client) (stitch.Stitch, error) {
 	}
 }
 
+// confirmDeployment shows the changes that deploying `proposed` would make to the
+// `current` deployment, and asks the user whether to go ahead with them.
+func confirmDeployment(current, proposed, prompt string) (bool, error) {
+	diff, err := diffDeployment(current, proposed)
+	if err != nil {
+		return false, fmt.Errorf("unable to diff deployments: %s", err)
+	}
+
+	if diff == "" {
+		fmt.Println("No change.")
+	} else {
+		fmt.Println(colorizeDiff(diff))
+	}
+
+	shouldDeploy, err := confirm(os.Stdin, prompt)
+	if err != nil {
+		return false, fmt.Errorf("unable to get user response: %s", err)
+	}
+	return shouldDeploy, nil
+}
+
 func diffDeployment(currRaw, newRaw string) (string, error) {
 	curr, err := prettifyJSON(currRaw)
 	if err != nil {
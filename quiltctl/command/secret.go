@@ -0,0 +1,146 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+)
+
+// Secret contains the options for managing the secrets stored by the daemon.
+type Secret struct {
+	action string
+	name   string
+	value  *string // Read from stdin if nil.
+
+	stdin        io.Reader
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewSecretCommand creates a new Secret command instance.
+func NewSecretCommand() *Secret {
+	return &Secret{
+		stdin:        os.Stdin,
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+var secretUsage = `usage: quilt secret [-H=<daemon_host>] set <name> [<value>]
+       quilt secret [-H=<daemon_host>] list
+       quilt secret [-H=<daemon_host>] rm <name>
+
+Manage the secrets that containers reference with secret("name") in their
+environment.  Secrets are only sent to the workers that run containers using them.
+The daemon keeps them in memory, so they must be set again after it restarts.  If
+the value isn't given to "set", it's read from stdin, which keeps it out of the
+shell history.
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (sCmd *Secret) InstallFlags(flags *flag.FlagSet) {
+	sCmd.common.InstallFlags(flags)
+	flags.Usage = func() {
+		fmt.Println(secretUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the secret command.
+func (sCmd *Secret) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify an action")
+	}
+
+	sCmd.action = args[0]
+	args = args[1:]
+	switch sCmd.action {
+	case "set":
+		if len(args) != 1 && len(args) != 2 {
+			return errors.New("set takes a name, and optionally a value")
+		}
+		if len(args) == 2 {
+			sCmd.value = &args[1]
+		}
+	case "rm":
+		if len(args) != 1 {
+			return errors.New("rm takes a name")
+		}
+	case "list":
+		if len(args) != 0 {
+			return errors.New("list takes no arguments")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action: %s", sCmd.action)
+	}
+
+	sCmd.name = args[0]
+	return nil
+}
+
+// Run performs the secret action.
+func (sCmd *Secret) Run() int {
+	c, err := sCmd.clientGetter.Client(sCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	switch sCmd.action {
+	case "set":
+		err = sCmd.set(c)
+	case "rm":
+		err = c.RemoveSecret(sCmd.name)
+	case "list":
+		var secrets []db.Secret
+		if secrets, err = c.QuerySecrets(); err == nil {
+			writeSecrets(os.Stdout, secrets)
+		}
+	}
+
+	if err != nil {
+		log.WithError(err).Errorf("Failed to %s secret.", sCmd.action)
+		return 1
+	}
+	return 0
+}
+
+func (sCmd *Secret) set(c client.Client) error {
+	if sCmd.value != nil {
+		return c.SetSecret(sCmd.name, *sCmd.value)
+	}
+
+	value, err := ioutil.ReadAll(sCmd.stdin)
+	if err != nil {
+		return err
+	}
+
+	// Trailing newlines are almost always an artifact of how the value was
+	// piped in, e.g. by echo.
+	return c.SetSecret(sCmd.name, strings.TrimRight(string(value), "\r\n"))
+}
+
+func writeSecrets(fd io.Writer, secrets []db.Secret) {
+	var names []string
+	for _, secret := range secrets {
+		names = append(names, secret.Name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(fd, name)
+	}
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestSecretFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewSecretCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-H", "IP", "set", "a", "b"}))
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "set", cmd.action)
+	assert.Equal(t, "a", cmd.name)
+	assert.Equal(t, "b", *cmd.value)
+
+	cmd = NewSecretCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"set", "a"}))
+	assert.Nil(t, cmd.value)
+
+	cmd = NewSecretCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"rm", "a"}))
+	assert.Equal(t, "rm", cmd.action)
+	assert.Equal(t, "a", cmd.name)
+
+	cmd = NewSecretCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"list"}))
+	assert.Equal(t, "list", cmd.action)
+
+	for args, exp := range map[string]string{
+		"":          "must specify an action",
+		"get a":     "unknown action: get",
+		"set":       "set takes a name, and optionally a value",
+		"set a b c": "set takes a name, and optionally a value",
+		"rm":        "rm takes a name",
+		"list a":    "list takes no arguments",
+	} {
+		err := parseHelper(NewSecretCommand(), strings.Fields(args))
+		assert.EqualError(t, err, exp)
+	}
+}
+
+func TestSecretRun(t *testing.T) {
+	t.Parallel()
+
+	c := &clientMock.Client{}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+
+	cmd := NewSecretCommand()
+	cmd.clientGetter = mockGetter
+	assert.NoError(t, parseHelper(cmd, []string{"set", "a", "1"}))
+	assert.Equal(t, 0, cmd.Run())
+
+	cmd = NewSecretCommand()
+	cmd.clientGetter = mockGetter
+	cmd.stdin = strings.NewReader("from stdin\n")
+	assert.NoError(t, parseHelper(cmd, []string{"set", "b"}))
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, map[string]string{"a": "1", "b": "from stdin"}, c.Secrets)
+
+	cmd = NewSecretCommand()
+	cmd.clientGetter = mockGetter
+	assert.NoError(t, parseHelper(cmd, []string{"rm", "a"}))
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, map[string]string{"b": "from stdin"}, c.Secrets)
+
+	cmd = NewSecretCommand()
+	cmd.clientGetter = mockGetter
+	assert.NoError(t, parseHelper(cmd, []string{"list"}))
+	assert.Equal(t, 0, cmd.Run())
+
+	c.SecretErr = errors.New("err")
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(nil, errors.New("err"))
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
+
+func TestSecretOutput(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	writeSecrets(&b, []db.Secret{{Name: "b"}, {Name: "a"}})
+	assert.Equal(t, "a\nb\n", b.String())
+}
@@ -0,0 +1,277 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	units "github.com/docker/go-units"
+
+	"github.com/quilt/quilt/api"
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/util"
+)
+
+// Top contains the options for displaying the resource usage of the cluster.
+type Top struct {
+	sortBy     string
+	delay      time.Duration
+	iterations int
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewTopCommand creates a new Top command instance.
+func NewTopCommand() *Top {
+	return &Top{
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+var topUsage = `usage: quilt top [-H=<daemon_host>] [-sort=<cpu|mem|net>] ` +
+	`[-d=<delay>] [-n=<iterations>]
+
+Display the CPU, memory, and network usage of each worker machine and container,
+refreshing until interrupted.  Each minion samples its usage every 10 seconds.
+CPU usage is relative to a single core, so it may exceed 100%.
+
+To print the containers using the most memory once:
+quilt top -sort mem -n 1
+`
+
+// InstallFlags sets up parsing for command line flags.
+func (tCmd *Top) InstallFlags(flags *flag.FlagSet) {
+	tCmd.common.InstallFlags(flags)
+	flags.StringVar(&tCmd.sortBy, "sort", "cpu",
+		"the usage to sort by: cpu, mem, or net")
+	flags.DurationVar(&tCmd.delay, "d", 5*time.Second, "the delay between refreshes")
+	flags.IntVar(&tCmd.iterations, "n", 0,
+		"the number of refreshes before exiting, or 0 to refresh forever")
+
+	flags.Usage = func() {
+		fmt.Println(topUsage)
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the top command.
+func (tCmd *Top) Parse(args []string) error {
+	switch tCmd.sortBy {
+	case "cpu", "mem", "net":
+	default:
+		return fmt.Errorf("unknown sort key: %s", tCmd.sortBy)
+	}
+
+	if tCmd.iterations < 0 {
+		return errors.New("the number of refreshes must not be negative")
+	}
+	return nil
+}
+
+// Run displays the resource usage of the cluster.
+func (tCmd *Top) Run() int {
+	c, err := tCmd.clientGetter.Client(tCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	for i := 0; tCmd.iterations == 0 || i < tCmd.iterations; i++ {
+		if i > 0 {
+			time.Sleep(tCmd.delay)
+		}
+
+		machines, containers, err := tCmd.queryUsage(c)
+		if err != nil {
+			log.WithError(err).Error("Unable to query machines.")
+			return 1
+		}
+
+		sortTopRows(machines, tCmd.sortBy)
+		sortTopRows(containers, tCmd.sortBy)
+
+		if isTerminal() {
+			// Clear the screen, as `top` does.
+			fmt.Print("\033[H\033[2J")
+		}
+		writeTop(os.Stdout, machines, containers)
+	}
+	return 0
+}
+
+// A topRow is the latest resource usage of a machine or container.
+type topRow struct {
+	id      string
+	machine string
+	image   string
+	stats   db.Stats
+}
+
+// queryUsage collects the usage of each worker machine, and its containers.
+// Workers that can't be reached are skipped.
+func (tCmd *Top) queryUsage(c client.Client) (machines, containers []topRow,
+	err error) {
+
+	dbMachines, err := c.QueryMachines()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, m := range dbMachines {
+		if m.PublicIP == "" || m.Role != db.Worker {
+			continue
+		}
+
+		wg.Add(1)
+		go func(m db.Machine) {
+			defer wg.Done()
+
+			machine, machineContainers, err := tCmd.queryWorker(m)
+			if err != nil {
+				log.WithError(err).WithField("machine", m.StitchID).Warn(
+					"Unable to query the machine's usage.")
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			machines = append(machines, machine)
+			containers = append(containers, machineContainers...)
+		}(m)
+	}
+	wg.Wait()
+
+	return machines, containers, nil
+}
+
+func (tCmd *Top) queryWorker(m db.Machine) (machine topRow, containers []topRow,
+	err error) {
+
+	workerClient, err := tCmd.clientGetter.Client(api.RemoteAddress(m.PublicIP))
+	if err != nil {
+		return topRow{}, nil, err
+	}
+	defer workerClient.Close()
+
+	stats, err := workerClient.QueryStats()
+	if err != nil {
+		return topRow{}, nil, err
+	}
+
+	dbcs, err := workerClient.QueryContainers()
+	if err != nil {
+		return topRow{}, nil, err
+	}
+
+	images := map[string]string{}
+	for _, dbc := range dbcs {
+		images[dbc.StitchID] = dbc.Image
+	}
+
+	machine = topRow{id: m.StitchID, machine: m.StitchID}
+	for _, s := range stats {
+		if s.StitchID == "" {
+			machine.stats = s
+			continue
+		}
+
+		containers = append(containers, topRow{
+			id:      s.StitchID,
+			machine: m.StitchID,
+			image:   images[s.StitchID],
+			stats:   s,
+		})
+	}
+	return machine, containers, nil
+}
+
+func writeTop(fd io.Writer, machines, containers []topRow) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "MACHINE\tCPU %\tMEM USAGE / LIMIT\tMEM %\tLOAD AVERAGE")
+	for _, m := range machines {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%s\t%.2f %.2f %.2f\n",
+			util.ShortUUID(m.id), m.stats.CPUPercent, memoryStr(m.stats),
+			memoryPercentStr(m.stats), m.stats.Load1, m.stats.Load5,
+			m.stats.Load15)
+	}
+	w.Flush()
+
+	fmt.Fprintln(fd)
+	w = tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tMACHINE\tIMAGE\tCPU %\tMEM USAGE / LIMIT\tMEM %"+
+		"\tNET RX / TX")
+	for _, c := range containers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.1f%%\t%s\t%s\t%s / %s\n",
+			util.ShortUUID(c.id), util.ShortUUID(c.machine), c.image,
+			c.stats.CPUPercent, memoryStr(c.stats),
+			memoryPercentStr(c.stats),
+			units.HumanSize(float64(c.stats.NetworkRx)),
+			units.HumanSize(float64(c.stats.NetworkTx)))
+	}
+	w.Flush()
+}
+
+func memoryStr(stats db.Stats) string {
+	return fmt.Sprintf("%s / %s", units.BytesSize(float64(stats.MemoryUsage)),
+		units.BytesSize(float64(stats.MemoryLimit)))
+}
+
+func memoryPercentStr(stats db.Stats) string {
+	if stats.MemoryLimit == 0 {
+		return "-"
+	}
+	percent := float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	return fmt.Sprintf("%.1f%%", percent)
+}
+
+// sortTopRows sorts `rows` by decreasing usage of the resource `key`, and then by
+// ID.
+func sortTopRows(rows []topRow, key string) {
+	sort.Sort(topRowSlice{rows, key})
+}
+
+type topRowSlice struct {
+	rows []topRow
+	key  string
+}
+
+func (trs topRowSlice) usage(i int) float64 {
+	stats := trs.rows[i].stats
+	switch trs.key {
+	case "mem":
+		return float64(stats.MemoryUsage)
+	case "net":
+		return float64(stats.NetworkRx + stats.NetworkTx)
+	default:
+		return stats.CPUPercent
+	}
+}
+
+func (trs topRowSlice) Len() int {
+	return len(trs.rows)
+}
+
+func (trs topRowSlice) Less(i, j int) bool {
+	if usageI, usageJ := trs.usage(i), trs.usage(j); usageI != usageJ {
+		return usageI > usageJ
+	}
+	return trs.rows[i].id < trs.rows[j].id
+}
+
+func (trs topRowSlice) Swap(i, j int) {
+	trs.rows[i], trs.rows[j] = trs.rows[j], trs.rows[i]
+}
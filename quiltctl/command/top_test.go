@@ -0,0 +1,178 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/quilt/quilt/api"
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestTopFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewTopCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "-sort", "mem", "-d", "1s",
+		"-n", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "mem", cmd.sortBy)
+	assert.Equal(t, time.Second, cmd.delay)
+	assert.Equal(t, 3, cmd.iterations)
+
+	cmd = NewTopCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.Equal(t, "cpu", cmd.sortBy)
+	assert.Equal(t, 0, cmd.iterations)
+
+	err = parseHelper(NewTopCommand(), []string{"-sort", "disk"})
+	assert.EqualError(t, err, "unknown sort key: disk")
+
+	err = parseHelper(NewTopCommand(), []string{"-n", "-1"})
+	assert.EqualError(t, err, "the number of refreshes must not be negative")
+}
+
+func TestTopRun(t *testing.T) {
+	t.Parallel()
+
+	mockErr := errors.New("error")
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(nil, mockErr)
+	cmd := &Top{iterations: 1, common: &commonFlags{}, clientGetter: mockGetter}
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(
+		&clientMock.Client{MachineErr: mockErr}, nil)
+	cmd = &Top{iterations: 1, common: &commonFlags{}, clientGetter: mockGetter}
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(&clientMock.Client{}, nil)
+	cmd = &Top{iterations: 1, common: &commonFlags{}, clientGetter: mockGetter}
+	assert.Equal(t, 0, cmd.Run())
+}
+
+func TestTopQueryUsage(t *testing.T) {
+	t.Parallel()
+
+	mockErr := errors.New("error")
+	daemonClient := &clientMock.Client{
+		MachineReturn: []db.Machine{
+			{StitchID: "master", Role: db.Master, PublicIP: "1.1.1.1"},
+			{StitchID: "booting", Role: db.Worker},
+			{StitchID: "worker", Role: db.Worker, PublicIP: "2.2.2.2"},
+			{StitchID: "failing", Role: db.Worker, PublicIP: "3.3.3.3"},
+		},
+	}
+	workerClient := &clientMock.Client{
+		StatsReturn: []db.Stats{
+			{Minion: "10.0.0.2", Load1: 1},
+			{StitchID: "a", Minion: "10.0.0.2", CPUPercent: 50},
+			{StitchID: "b", Minion: "10.0.0.2", CPUPercent: 10},
+		},
+		ContainerReturn: []db.Container{
+			{StitchID: "a", Image: "nginx"},
+			{StitchID: "b", Image: "redis"},
+		},
+	}
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", api.RemoteAddress("2.2.2.2")).Return(workerClient, nil)
+	mockGetter.On("Client", api.RemoteAddress("3.3.3.3")).Return(
+		&clientMock.Client{StatsErr: mockErr}, nil)
+
+	cmd := &Top{common: &commonFlags{}, clientGetter: mockGetter}
+	machines, containers, err := cmd.queryUsage(daemonClient)
+	assert.NoError(t, err)
+	mockGetter.AssertExpectations(t)
+
+	assert.Equal(t, []topRow{{
+		id:      "worker",
+		machine: "worker",
+		stats:   workerClient.StatsReturn[0],
+	}}, machines)
+
+	sortTopRows(containers, "cpu")
+	assert.Equal(t, []topRow{
+		{id: "a", machine: "worker", image: "nginx",
+			stats: workerClient.StatsReturn[1]},
+		{id: "b", machine: "worker", image: "redis",
+			stats: workerClient.StatsReturn[2]},
+	}, containers)
+
+	_, _, err = cmd.queryUsage(&clientMock.Client{MachineErr: mockErr})
+	assert.EqualError(t, err, "error")
+}
+
+func TestSortTopRows(t *testing.T) {
+	t.Parallel()
+
+	rows := []topRow{
+		{id: "a", stats: db.Stats{CPUPercent: 1, MemoryUsage: 30, NetworkRx: 1}},
+		{id: "b", stats: db.Stats{CPUPercent: 3, MemoryUsage: 10, NetworkTx: 5}},
+		{id: "c", stats: db.Stats{CPUPercent: 2, MemoryUsage: 20}},
+		{id: "d", stats: db.Stats{CPUPercent: 2, MemoryUsage: 20}},
+	}
+
+	ids := func(key string) []string {
+		sortTopRows(rows, key)
+		var result []string
+		for _, row := range rows {
+			result = append(result, row.id)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"b", "c", "d", "a"}, ids("cpu"))
+	assert.Equal(t, []string{"a", "c", "d", "b"}, ids("mem"))
+	assert.Equal(t, []string{"b", "a", "c", "d"}, ids("net"))
+}
+
+func TestWriteTop(t *testing.T) {
+	t.Parallel()
+
+	machines := []topRow{{
+		id:      "0123456789abcdef",
+		machine: "0123456789abcdef",
+		stats: db.Stats{
+			CPUPercent:  12.34,
+			MemoryUsage: 1024 * 1024 * 1024,
+			MemoryLimit: 4 * 1024 * 1024 * 1024,
+			Load1:       0.5,
+			Load5:       0.25,
+			Load15:      0.125,
+		},
+	}}
+	containers := []topRow{{
+		id:      "fedcba9876543210",
+		machine: "0123456789abcdef",
+		image:   "nginx",
+		stats: db.Stats{
+			CPUPercent:  150,
+			MemoryUsage: 512 * 1024 * 1024,
+			NetworkRx:   2000,
+			NetworkTx:   3000000,
+		},
+	}}
+
+	var buf bytes.Buffer
+	writeTop(&buf, machines, containers)
+
+	exp := `MACHINE         CPU %    MEM USAGE / LIMIT    MEM %    LOAD AVERAGE
+0123456789ab    12.3%    1 GiB / 4 GiB        25.0%    0.50 0.25 0.12
+
+CONTAINER       MACHINE         IMAGE    CPU %     MEM USAGE / LIMIT    MEM %    ` +
+		`NET RX / TX
+fedcba987654    0123456789ab    nginx    150.0%    512 MiB / 0 B        -        ` +
+		`2 kB / 3 MB
+`
+	assert.Equal(t, exp, buf.String())
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	units "github.com/docker/go-units"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/db"
+	"github.com/quilt/quilt/stitch"
+)
+
+// Traffic contains the options for querying the traffic sent over connections.
+type Traffic struct {
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewTrafficCommand creates a new Traffic command instance.
+func NewTrafficCommand() *Traffic {
+	return &Traffic{
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+// InstallFlags sets up parsing for command line flags
+func (tCmd *Traffic) InstallFlags(flags *flag.FlagSet) {
+	tCmd.common.InstallFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("usage: quilt traffic [-H=<daemon_host>]")
+		fmt.Println("`traffic` displays the number of packets and bytes " +
+			"sent over each connection, and sent and received by each " +
+			"container.")
+
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the traffic command.
+func (tCmd *Traffic) Parse(args []string) error {
+	return nil
+}
+
+// Run retrieves and prints the traffic sent over each connection.
+func (tCmd *Traffic) Run() int {
+	localClient, err := tCmd.clientGetter.Client(tCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer localClient.Close()
+
+	c, err := tCmd.clientGetter.LeaderClient(localClient)
+	if err != nil {
+		log.WithError(err).Error("Error connecting to leader.")
+		return 1
+	}
+	defer c.Close()
+
+	connections, err := c.QueryConnections()
+	if err != nil {
+		log.WithError(err).Error("Unable to query connections.")
+		return 1
+	}
+
+	traffic, err := c.QueryTraffic()
+	if err != nil {
+		log.WithError(err).Error("Unable to query traffic.")
+		return 1
+	}
+
+	ports, err := c.QueryPortTraffic()
+	if err != nil {
+		log.WithError(err).Error("Unable to query container traffic.")
+		return 1
+	}
+
+	writeTraffic(os.Stdout, connections, traffic)
+	fmt.Println()
+	writePortTraffic(os.Stdout, ports)
+	return 0
+}
+
+type trafficRow struct {
+	conn    db.Connection
+	traffic *db.Traffic
+}
+
+type trafficRows []trafficRow
+
+func (rows trafficRows) Len() int {
+	return len(rows)
+}
+
+func (rows trafficRows) Swap(i, j int) {
+	rows[i], rows[j] = rows[j], rows[i]
+}
+
+// Connections with the most traffic come first.  Connections that aren't measured,
+// such as those to and from the public internet, come last.
+func (rows trafficRows) Less(i, j int) bool {
+	l, r := rows[i], rows[j]
+	switch {
+	case l.traffic == nil && r.traffic != nil:
+		return false
+	case l.traffic != nil && r.traffic == nil:
+		return true
+	case l.traffic != nil && l.traffic.Bytes != r.traffic.Bytes:
+		return l.traffic.Bytes > r.traffic.Bytes
+	case l.conn.From != r.conn.From:
+		return l.conn.From < r.conn.From
+	case l.conn.To != r.conn.To:
+		return l.conn.To < r.conn.To
+	default:
+		return l.conn.MinPort < r.conn.MinPort
+	}
+}
+
+func writeTraffic(fd io.Writer, connections []db.Connection, traffic []db.Traffic) {
+	counted := map[db.Traffic]db.Traffic{}
+	for _, t := range traffic {
+		counted[db.Traffic{From: t.From, To: t.To, MinPort: t.MinPort,
+			MaxPort: t.MaxPort}] = t
+	}
+
+	var rows trafficRows
+	for _, c := range connections {
+		key := db.Traffic{From: c.From, To: c.To, MinPort: c.MinPort,
+			MaxPort: c.MaxPort}
+		row := trafficRow{conn: c}
+		if t, ok := counted[key]; ok {
+			row.traffic = &t
+		} else if c.From != stitch.PublicInternetLabel &&
+			c.To != stitch.PublicInternetLabel {
+			row.traffic = &key
+		}
+		rows = append(rows, row)
+	}
+	sort.Sort(rows)
+
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "FROM\tTO\tPORTS\tPACKETS\tBYTES")
+
+	for _, row := range rows {
+		packets, bytes := "-", "-"
+		if row.traffic != nil {
+			packets = fmt.Sprintf("%d", row.traffic.Packets)
+			bytes = units.HumanSize(float64(row.traffic.Bytes))
+		}
+
+		ports := fmt.Sprintf("%d", row.conn.MinPort)
+		if row.conn.MinPort != row.conn.MaxPort {
+			ports = fmt.Sprintf("%d-%d", row.conn.MinPort, row.conn.MaxPort)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.conn.From, row.conn.To,
+			ports, packets, bytes)
+	}
+}
+
+func writePortTraffic(fd io.Writer, ports []db.PortTraffic) {
+	sort.Sort(db.PortTrafficSlice(ports))
+
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "CONTAINER\tMACHINE\tRX PACKETS\tRX BYTES\tTX PACKETS"+
+		"\tTX BYTES")
+
+	for _, p := range ports {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\n", p.StitchID, p.Minion,
+			p.RxPackets, units.HumanSize(float64(p.RxBytes)),
+			p.TxPackets, units.HumanSize(float64(p.TxBytes)))
+	}
+}
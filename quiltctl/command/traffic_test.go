@@ -0,0 +1,118 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+	"github.com/quilt/quilt/db"
+)
+
+func TestTrafficFlags(t *testing.T) {
+	t.Parallel()
+
+	expHost := "IP"
+
+	cmd := NewTrafficCommand()
+	err := parseHelper(cmd, []string{"-H", expHost})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expHost, cmd.common.host)
+}
+
+func TestTrafficOutput(t *testing.T) {
+	t.Parallel()
+
+	connections := []db.Connection{
+		{From: "public", To: "web", MinPort: 80, MaxPort: 80},
+		{From: "web", To: "db", MinPort: 5432, MaxPort: 5432},
+		{From: "web", To: "db", MinPort: 1000, MaxPort: 1010},
+		{From: "web", To: "cache", MinPort: 6379, MaxPort: 6379},
+		{From: "web", To: "idle", MinPort: 22, MaxPort: 22},
+	}
+	traffic := []db.Traffic{
+		{From: "web", To: "db", MinPort: 5432, MaxPort: 5432,
+			Packets: 10, Bytes: 2000},
+		{From: "web", To: "db", MinPort: 1000, MaxPort: 1010,
+			Packets: 5, Bytes: 500},
+		{From: "web", To: "cache", MinPort: 6379, MaxPort: 6379,
+			Packets: 20, Bytes: 3000},
+		{From: "stale", To: "web", Packets: 1, Bytes: 1},
+	}
+
+	var b bytes.Buffer
+	writeTraffic(&b, connections, traffic)
+
+	exp := `FROM      TO       PORTS        PACKETS    BYTES
+web       cache    6379         20         3 kB
+web       db       5432         10         2 kB
+web       db       1000-1010    5          500 B
+web       idle     22           0          0 B
+public    web      80           -          -
+`
+	assert.Equal(t, exp, b.String())
+}
+
+func TestPortTrafficOutput(t *testing.T) {
+	t.Parallel()
+
+	ports := []db.PortTraffic{
+		{StitchID: "2", Minion: "10.0.0.2", RxPackets: 1, RxBytes: 100,
+			TxPackets: 2, TxBytes: 2000},
+		{StitchID: "1", Minion: "10.0.0.1", RxPackets: 3, RxBytes: 3000},
+	}
+
+	var b bytes.Buffer
+	writePortTraffic(&b, ports)
+
+	exp := `CONTAINER    MACHINE     RX PACKETS    RX BYTES    TX PACKETS    TX BYTES
+1            10.0.0.1    3             3 kB        0             0 B
+2            10.0.0.2    1             100 B       2             2 kB
+`
+	assert.Equal(t, exp, b.String())
+}
+
+func TestTrafficRun(t *testing.T) {
+	t.Parallel()
+
+	c := &clientMock.Client{
+		ConnectionReturn: []db.Connection{
+			{From: "a", To: "b", MinPort: 80, MaxPort: 80}},
+		TrafficReturn: []db.Traffic{
+			{From: "a", To: "b", Packets: 1, Bytes: 1}},
+	}
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+	mockGetter.On("LeaderClient", mock.Anything).Return(c, nil)
+
+	cmd := NewTrafficCommand()
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 0, cmd.Run())
+
+	c.PortTrafficErr = errors.New("ports")
+	assert.Equal(t, 1, cmd.Run())
+
+	c.TrafficErr = errors.New("traffic")
+	assert.Equal(t, 1, cmd.Run())
+
+	c.ConnectionErr = errors.New("connection")
+	assert.Equal(t, 1, cmd.Run())
+}
+
+func TestTrafficNoLeader(t *testing.T) {
+	t.Parallel()
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(&clientMock.Client{}, nil)
+	mockGetter.On("LeaderClient", mock.Anything).Return(nil,
+		errors.New("no leader"))
+
+	cmd := NewTrafficCommand()
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
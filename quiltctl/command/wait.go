@@ -0,0 +1,122 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/quilt/quilt/api/client"
+	"github.com/quilt/quilt/api/client/getter"
+	"github.com/quilt/quilt/util"
+)
+
+// How often the daemon and the leader are polled while waiting.
+const waitInterval = 5 * time.Second
+
+// Wait contains the options for waiting until a deployment converges.
+type Wait struct {
+	timeout time.Duration
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewWaitCommand creates a new Wait command instance.
+func NewWaitCommand() *Wait {
+	return &Wait{
+		common:       &commonFlags{},
+		clientGetter: getter.New(),
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (wCmd *Wait) InstallFlags(flags *flag.FlagSet) {
+	wCmd.common.InstallFlags(flags)
+	flags.DurationVar(&wCmd.timeout, "timeout", 10*time.Minute,
+		"how long to wait before giving up")
+
+	flags.Usage = func() {
+		fmt.Println("usage: quilt wait [-H=<daemon_host>] [-timeout=<duration>]")
+		fmt.Println("`wait` blocks until the cluster has converged to the " +
+			"deployed stitch: all machines are booted and connected, and " +
+			"all containers are scheduled, running and have IPs.  It " +
+			"prints what it's still waiting on as that changes, and exits " +
+			"with a non-zero status if the timeout expires first.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the wait command.
+func (wCmd *Wait) Parse(args []string) error {
+	if len(args) != 0 {
+		return errors.New("wait takes no arguments")
+	}
+	return nil
+}
+
+// Run waits for the deployment to converge.
+func (wCmd *Wait) Run() int {
+	c, err := wCmd.clientGetter.Client(wCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	var pending []string
+	converged := func() bool {
+		newPending := wCmd.pending(c)
+		if len(newPending) != 0 && !reflect.DeepEqual(pending, newPending) {
+			fmt.Println("Waiting on:")
+			for _, p := range newPending {
+				fmt.Printf("\t%s\n", p)
+			}
+		}
+		pending = newPending
+		return len(pending) == 0
+	}
+
+	if err := util.WaitFor(converged, waitInterval, wCmd.timeout); err != nil {
+		log.Errorf("Timed out after %s waiting for the deployment to converge.",
+			wCmd.timeout)
+		return 1
+	}
+
+	fmt.Println("Deployment converged.")
+	return 0
+}
+
+// pending returns what the daemon and the lead minion are still waiting on.  The
+// leader is only consulted once the machines have converged, as it can't be
+// reached before then.
+func (wCmd *Wait) pending(c client.Client) []string {
+	status, err := c.QueryStatus()
+	if err != nil {
+		return []string{fmt.Sprintf("unable to query the daemon: %s", err)}
+	}
+	if len(status.Pending) != 0 {
+		return status.Pending
+	}
+
+	leader, err := wCmd.clientGetter.LeaderClient(c)
+	if err != nil {
+		return []string{fmt.Sprintf("unable to connect to the leader: %s", err)}
+	}
+	defer leader.Close()
+
+	leaderStatus, err := leader.QueryStatus()
+	if err != nil {
+		return []string{fmt.Sprintf("unable to query the leader: %s", err)}
+	}
+
+	pending := leaderStatus.Pending
+	if leaderStatus.SpecHash != status.SpecHash {
+		pending = append([]string{"the minions haven't received the " +
+			"latest deployment"}, pending...)
+	}
+	return pending
+}
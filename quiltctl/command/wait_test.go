@@ -0,0 +1,99 @@
+package command
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/quilt/quilt/api/client"
+	clientMock "github.com/quilt/quilt/api/client/mocks"
+)
+
+func TestWaitFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewWaitCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"-H", "IP", "-timeout", "1m"}))
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, time.Minute, cmd.timeout)
+
+	cmd = NewWaitCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.Equal(t, 10*time.Minute, cmd.timeout)
+
+	assert.EqualError(t, parseHelper(NewWaitCommand(), []string{"a"}),
+		"wait takes no arguments")
+}
+
+func TestWaitPending(t *testing.T) {
+	t.Parallel()
+
+	daemon := &clientMock.Client{StatusReturn: client.Status{
+		SpecHash: "hash",
+		Pending:  []string{"machine 1 (Master): booting"},
+	}}
+	leader := &clientMock.Client{StatusReturn: client.Status{
+		SpecHash: "hash",
+		Pending:  []string{"container 1 (a): not running"},
+	}}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("LeaderClient", mock.Anything).Return(leader, nil)
+
+	cmd := NewWaitCommand()
+	cmd.clientGetter = mockGetter
+
+	// The leader isn't consulted until the machines have converged.
+	assert.Equal(t, []string{"machine 1 (Master): booting"}, cmd.pending(daemon))
+
+	daemon.StatusReturn.Pending = nil
+	assert.Equal(t, []string{"container 1 (a): not running"}, cmd.pending(daemon))
+
+	leader.StatusReturn.SpecHash = "old"
+	assert.Equal(t, []string{
+		"the minions haven't received the latest deployment",
+		"container 1 (a): not running"}, cmd.pending(daemon))
+
+	leader.StatusReturn = client.Status{SpecHash: "hash"}
+	assert.Empty(t, cmd.pending(daemon))
+
+	leader.StatusErr = errors.New("err")
+	assert.Equal(t, []string{"unable to query the leader: err"},
+		cmd.pending(daemon))
+
+	daemon.StatusErr = errors.New("err")
+	assert.Equal(t, []string{"unable to query the daemon: err"},
+		cmd.pending(daemon))
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("LeaderClient", mock.Anything).Return(nil, errors.New("err"))
+	cmd.clientGetter = mockGetter
+	daemon.StatusErr = nil
+	assert.Equal(t, []string{"unable to connect to the leader: err"},
+		cmd.pending(daemon))
+}
+
+func TestWaitRun(t *testing.T) {
+	t.Parallel()
+
+	daemon := &clientMock.Client{StatusReturn: client.Status{SpecHash: "hash"}}
+	leader := &clientMock.Client{StatusReturn: client.Status{SpecHash: "hash"}}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(daemon, nil)
+	mockGetter.On("LeaderClient", mock.Anything).Return(leader, nil)
+
+	cmd := NewWaitCommand()
+	cmd.clientGetter = mockGetter
+	assert.NoError(t, parseHelper(cmd, []string{"-timeout", "0s"}))
+	assert.Equal(t, 0, cmd.Run())
+
+	leader.StatusReturn.Pending = []string{"container 1 (a): not scheduled"}
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(nil, errors.New("err"))
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
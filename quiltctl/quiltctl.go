@@ -10,17 +10,28 @@ import (
 )
 
 var commands = map[string]command.SubCommand{
-	"containers": command.NewContainerCommand(),
-	"daemon":     command.NewDaemonCommand(),
-	"get":        &command.Get{},
-	"inspect":    &command.Inspect{},
-	"logs":       command.NewLogCommand(),
-	"machines":   command.NewMachineCommand(),
-	"minion":     &command.Minion{},
-	"ps":         command.NewPsCommand(),
-	"run":        command.NewRunCommand(),
-	"ssh":        command.NewSSHCommand(),
-	"stop":       command.NewStopCommand(),
+	"containers":   command.NewContainerCommand(),
+	"cp":           command.NewCpCommand(),
+	"daemon":       command.NewDaemonCommand(),
+	"events":       command.NewEventsCommand(),
+	"exec":         command.NewExecCommand(),
+	"get":          &command.Get{},
+	"history":      command.NewHistoryCommand(),
+	"inspect":      &command.Inspect{},
+	"logs":         command.NewLogCommand(),
+	"netcheck":     command.NewNetCheckCommand(),
+	"machines":     command.NewMachineCommand(),
+	"minion":       &command.Minion{},
+	"port-forward": command.NewPortForwardCommand(),
+	"ps":           command.NewPsCommand(),
+	"rollback":     command.NewRollbackCommand(),
+	"run":          command.NewRunCommand(),
+	"secret":       command.NewSecretCommand(),
+	"ssh":          command.NewSSHCommand(),
+	"stop":         command.NewStopCommand(),
+	"top":          command.NewTopCommand(),
+	"traffic":      command.NewTrafficCommand(),
+	"wait":         command.NewWaitCommand(),
 }
 
 // Run parses and runs the quiltctl subcommand given the command line arguments.
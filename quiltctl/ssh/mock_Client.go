@@ -1,6 +1,8 @@
 package ssh
 
 import mock "github.com/stretchr/testify/mock"
+import sftp "github.com/pkg/sftp"
+import net "net"
 
 // MockClient is an autogenerated mock type for the Client type
 type MockClient struct {
@@ -21,6 +23,29 @@ func (_m *MockClient) Close() error {
 	return r0
 }
 
+// Dial provides a mock function with given fields: network, addr
+func (_m *MockClient) Dial(network string, addr string) (net.Conn, error) {
+	ret := _m.Called(network, addr)
+
+	var r0 net.Conn
+	if rf, ok := ret.Get(0).(func(string, string) net.Conn); ok {
+		r0 = rf(network, addr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(net.Conn)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(network, addr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Run provides a mock function with given fields: _a0, _a1
 func (_m *MockClient) Run(_a0 bool, _a1 string) error {
 	ret := _m.Called(_a0, _a1)
@@ -35,6 +60,29 @@ func (_m *MockClient) Run(_a0 bool, _a1 string) error {
 	return r0
 }
 
+// SFTP provides a mock function with given fields:
+func (_m *MockClient) SFTP() (*sftp.Client, error) {
+	ret := _m.Called()
+
+	var r0 *sftp.Client
+	if rf, ok := ret.Get(0).(func() *sftp.Client); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*sftp.Client)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Shell provides a mock function with given fields:
 func (_m *MockClient) Shell() error {
 	ret := _m.Called()
@@ -13,6 +13,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/terminal"
@@ -124,6 +125,11 @@ func (c NativeClient) Shell() error {
 	return s.Wait()
 }
 
+// SFTP starts an SFTP session over the SSH connection.
+func (c NativeClient) SFTP() (*sftp.Client, error) {
+	return sftp.NewClient(c.Client)
+}
+
 // pty encapsulates pseudo-terminal operations.
 type pty struct {
 	session        *ssh.Session
@@ -1,5 +1,11 @@
 package ssh
 
+import (
+	"net"
+
+	"github.com/pkg/sftp"
+)
+
 //go:generate mockery -name=Client -inpkg
 
 // Client is an SSH client used for `quilt` commands.
@@ -12,6 +18,12 @@ type Client interface {
 
 	// Shell creates a login shell.
 	Shell() error
+
+	// Dial opens a connection to `addr` tunneled through the SSH connection.
+	Dial(network, addr string) (net.Conn, error)
+
+	// SFTP starts an SFTP session over the SSH connection.
+	SFTP() (*sftp.Client, error)
 }
 
 // Getter is used to retrieve a Client.
@@ -18,6 +18,11 @@ function Deployment(deploymentOpts) {
     this.maxPrice = deploymentOpts.maxPrice || 0;
     this.namespace = deploymentOpts.namespace || "default-namespace";
     this.adminACL = deploymentOpts.adminACL || [];
+    this.dnsForwarders = deploymentOpts.dnsForwarders || [];
+    this.subnet = deploymentOpts.subnet || "";
+    this.ipv6Subnet = deploymentOpts.ipv6Subnet || "";
+    this.encryptOverlay = deploymentOpts.encryptOverlay || false;
+    this.firewallBackend = deploymentOpts.firewallBackend || "";
 
     this.machines = [];
     this.containers = {};
@@ -96,11 +101,15 @@ Deployment.prototype.toQuiltRepresentation = function() {
             containerMap[container.id] = container;
         });
 
-        services.push({
+        var label = {
             name: service.name,
             ids: ids,
             annotations: service.annotations
-        });
+        };
+        if (service.updateStrategy) {
+            label.updateStrategy = service.updateStrategy;
+        }
+        services.push(label);
     });
 
     var containers = [];
@@ -118,7 +127,12 @@ Deployment.prototype.toQuiltRepresentation = function() {
 
         namespace: this.namespace,
         adminACL: this.adminACL,
-        maxPrice: this.maxPrice
+        maxPrice: this.maxPrice,
+        dnsForwarders: this.dnsForwarders,
+        subnet: this.subnet,
+        ipv6Subnet: this.ipv6Subnet,
+        encryptOverlay: this.encryptOverlay,
+        firewallBackend: this.firewallBackend
     };
 };
 
@@ -208,6 +222,40 @@ Service.prototype.annotate = function(annotation) {
     this.annotations.push(annotation);
 };
 
+// Replace the service's containers gradually when their definitions change,
+// rather than all at once.  "strategy.maxUnavailable" is the number of containers
+// that may be unavailable during the update, and "strategy.maxSurge" the number of
+// containers that may be booted beyond those deployed.  Both default to 1.  If
+// "strategy.minReadySeconds" is set, new containers must have been running that
+// long before the update proceeds.
+//
+// Containers aren't health checked: one counts as available once its process has
+// been running for "strategy.minReadySeconds", whether or not it's serving yet.
+// Set it to at least the time the containers take to start up, or an update may
+// stop the old containers before the new ones are ready to take their place.
+Service.prototype.setUpdateStrategy = function(strategy) {
+    var result = {
+        maxUnavailable: 1,
+        maxSurge: 1,
+        minReadySeconds: 0
+    };
+    Object.keys(strategy).forEach(function(key) {
+        var val = strategy[key];
+        if (!result.hasOwnProperty(key)) {
+            throw "unknown update strategy option: " + key;
+        }
+        if (typeof val !== "number" || val % 1 !== 0 || val < 0) {
+            throw "update strategy options must be non-negative integers";
+        }
+        result[key] = val;
+    });
+
+    if (result.maxUnavailable === 0 && result.maxSurge === 0) {
+        throw "maxUnavailable and maxSurge can't both be 0";
+    }
+    this.updateStrategy = result;
+};
+
 Service.prototype.canReach = function(target) {
     if (target === publicInternet) {
         return reachable(this.name, publicInternetLabel);
@@ -232,12 +280,19 @@ Service.prototype.deploy = function(deployment) {
     deployment.services.push(this);
 };
 
-Service.prototype.connect = function(range, to) {
+// Allow traffic from the service to "to" on "range".  The optional "opts.bandwidth"
+// limits the rate, in kbit/s, at which each container of "to" receives traffic from
+// this service.
+Service.prototype.connect = function(range, to, opts) {
     range = boxRange(range);
+    opts = opts || {};
     if (to === publicInternet) {
+        if (opts.bandwidth) {
+            throw "connections to the public internet cannot be rate limited";
+        }
         return this.connectToPublic(range);
     }
-    this.connections.push(new Connection(range, to));
+    this.connections.push(new Connection(range, to, opts.bandwidth));
 };
 
 // publicInternet is an object that looks like another service that can be
@@ -283,7 +338,8 @@ Service.prototype.getQuiltConnections = function() {
             from: that.name,
             to: conn.to.name,
             minPort: conn.minPort,
-            maxPort: conn.maxPort
+            maxPort: conn.maxPort,
+            bandwidth: conn.bandwidth
         });
     });
 
@@ -415,6 +471,21 @@ function Container(image, command) {
 Container.prototype.clone = function() {
     var cloned = new Container(this.image, _.clone(this.command));
     cloned.env = _.clone(this.env);
+    if (this.secrets) {
+        cloned.secrets = _.clone(this.secrets);
+    }
+    if (this.files) {
+        cloned.files = _.clone(this.files);
+    }
+    if (this.secretFiles) {
+        cloned.secretFiles = _.clone(this.secretFiles);
+    }
+    if (this.bandwidth) {
+        cloned.bandwidth = this.bandwidth;
+    }
+    if (this.stopTimeout) {
+        cloned.stopTimeout = this.stopTimeout;
+    }
     return cloned;
 };
 
@@ -428,13 +499,98 @@ Container.prototype.replicate = function(n) {
     return res;
 };
 
+// Set the environment variable "key" to "val", which is either a string or a
+// reference to a secret.
 Container.prototype.setEnv = function(key, val) {
+    if (val instanceof Secret) {
+        delete this.env[key];
+        this.secrets = this.secrets || {};
+        this.secrets[key] = val.name;
+        return;
+    }
+
     this.env[key] = val;
+    if (this.secrets) {
+        delete this.secrets[key];
+        if (_.isEmpty(this.secrets)) {
+            delete this.secrets;
+        }
+    }
 };
 
 Container.prototype.withEnv = function(env) {
     var cloned = this.clone();
-    cloned.env = env;
+    cloned.env = {};
+    delete cloned.secrets;
+    Object.keys(env).forEach(function(key) {
+        cloned.setEnv(key, env[key]);
+    });
+    return cloned;
+};
+
+// A reference to the secret "name", as set with "quilt secret set".  Secrets may
+// be used as the values of environment variables or the contents of files.  They're
+// delivered to every worker, as any of them may run the containers using them, but
+// are only injected into those containers.
+function Secret(name) {
+    if (typeof name !== "string" || name === "") {
+        throw "secret names must be non-empty strings";
+    }
+    this.name = name;
+}
+
+function secret(name) {
+    return new Secret(name);
+}
+
+// Write files into the container before it starts.  "files" maps absolute paths to
+// their contents, which are either strings or references to secrets, e.g.
+// {"/etc/haproxy.cfg": config, "/etc/ssl/key.pem": secret("key")}.  The container is
+// restarted when the contents change.
+Container.prototype.withFiles = function(files) {
+    var cloned = this.clone();
+    Object.keys(files).forEach(function(path) {
+        if (path.charAt(0) !== "/") {
+            throw "file paths must be absolute: " + path;
+        }
+
+        var contents = files[path];
+        if (contents instanceof Secret) {
+            if (cloned.files) {
+                delete cloned.files[path];
+            }
+            cloned.secretFiles = cloned.secretFiles || {};
+            cloned.secretFiles[path] = contents.name;
+            return;
+        }
+
+        if (typeof contents !== "string") {
+            throw "file contents must be strings or secrets: " + path;
+        }
+        if (cloned.secretFiles) {
+            delete cloned.secretFiles[path];
+        }
+        cloned.files = cloned.files || {};
+        cloned.files[path] = contents;
+    });
+    return cloned;
+};
+
+// Limit the traffic the container sends to "bandwidth" kbit/s.
+Container.prototype.withBandwidth = function(bandwidth) {
+    var cloned = this.clone();
+    cloned.bandwidth = bandwidth;
+    return cloned;
+};
+
+// Give the container "seconds" to exit after it's sent SIGTERM when it's stopped,
+// before it's killed.  By default, containers have 10 seconds.
+Container.prototype.withStopTimeout = function(seconds) {
+    if (typeof seconds !== "number" || seconds % 1 !== 0 || seconds <= 0) {
+        throw "stop timeouts must be positive integers";
+    }
+    var cloned = this.clone();
+    cloned.stopTimeout = seconds;
     return cloned;
 };
 
@@ -488,10 +644,11 @@ function MachineRule(exclusive, optionalArgs) {
     }
 }
 
-function Connection(ports, to) {
+function Connection(ports, to, bandwidth) {
     this.minPort = ports.min;
     this.maxPort = ports.max;
     this.to = to;
+    this.bandwidth = bandwidth || 0;
 }
 
 function Range(min, max) {
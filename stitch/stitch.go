@@ -28,9 +28,36 @@ type Stitch struct {
 	MaxPrice  float64  `json:",omitempty"`
 	Namespace string   `json:",omitempty"`
 
+	// DNSForwarders are the upstream servers that resolve non-Quilt hostnames
+	// on behalf of containers.
+	DNSForwarders []string `json:",omitempty"`
+
+	// Subnet is the IPv4 subnet containers are addressed from, and IPv6Subnet
+	// enables dual stack container addressing if set.  Neither may change once
+	// the deployment is running.
+	Subnet     string `json:",omitempty"`
+	IPv6Subnet string `json:",omitempty"`
+
+	// EncryptOverlay enables IPsec encryption of the tunnels that carry
+	// container traffic between workers.  Workers size the container network's
+	// MTU for it when they boot, so enabling it on running machines leaves their
+	// largest packets to be fragmented.
+	EncryptOverlay bool `json:",omitempty"`
+
+	// FirewallBackend selects how workers install NAT rules, either "iptables"
+	// (the default) or "nftables", which requires nf_tables support in the
+	// workers' kernels.
+	FirewallBackend string `json:",omitempty"`
+
 	Invariants []invariant `json:",omitempty"`
 }
 
+// The firewall backends a deployment may select.
+const (
+	IPTablesBackend = "iptables"
+	NFTablesBackend = "nftables"
+)
+
 // A Placement constraint guides where containers may be scheduled, either relative to
 // the labels of other containers, or the machine the container will run on.
 type Placement struct {
@@ -54,6 +81,26 @@ type Container struct {
 	Image   string            `json:",omitempty"`
 	Command []string          `json:",omitempty"`
 	Env     map[string]string `json:",omitempty"`
+
+	// Secrets maps environment variables to the names of the secrets they're set
+	// to, which are stored in the daemon rather than the deployment.
+	Secrets map[string]string `json:",omitempty"`
+
+	// Files maps absolute paths to the contents of the files that are written
+	// into the container before it starts.
+	Files map[string]string `json:",omitempty"`
+
+	// SecretFiles maps absolute paths to the names of the secrets whose values are
+	// written to them, like Files.
+	SecretFiles map[string]string `json:",omitempty"`
+
+	// Bandwidth limits the traffic the container sends, in kbit/s.  Zero means
+	// unlimited.
+	Bandwidth int `json:",omitempty"`
+
+	// StopTimeout is the number of seconds the container has to exit after
+	// it's sent SIGTERM, before it's killed.  Zero means the default.
+	StopTimeout int `json:",omitempty"`
 }
 
 // A Label represents a logical group of containers.
@@ -61,15 +108,36 @@ type Label struct {
 	Name        string   `json:",omitempty"`
 	IDs         []string `json:",omitempty"`
 	Annotations []string `json:",omitempty"`
+
+	// UpdateStrategy is nil if the label's containers are replaced all at once.
+	UpdateStrategy *UpdateStrategy `json:",omitempty"`
+}
+
+// An UpdateStrategy limits how quickly the containers of a label are replaced when
+// their definitions change.
+type UpdateStrategy struct {
+	// The number of containers that may be unavailable during the update.
+	MaxUnavailable int
+
+	// The number of containers that may be booted beyond the label's size.
+	MaxSurge int
+
+	// The number of seconds a new container must have been running before it's
+	// considered available.  It's the only readiness check, as containers aren't
+	// health checked.
+	MinReadySeconds int
 }
 
 // A Connection allows containers implementing the From label to speak to containers
-// implementing the To label in ports in the range [MinPort, MaxPort]
+// implementing the To label in ports in the range [MinPort, MaxPort].  If Bandwidth is
+// set, each container implementing To receives at most Bandwidth kbit/s from the
+// containers implementing From.
 type Connection struct {
-	From    string `json:",omitempty"`
-	To      string `json:",omitempty"`
-	MinPort int    `json:",omitempty"`
-	MaxPort int    `json:",omitempty"`
+	From      string `json:",omitempty"`
+	To        string `json:",omitempty"`
+	MinPort   int    `json:",omitempty"`
+	MaxPort   int    `json:",omitempty"`
+	Bandwidth int    `json:",omitempty"`
 }
 
 // A ConnectionSlice allows for slices of Collections to be used in joins
@@ -162,6 +230,17 @@ func New(filename string, specStr string, getter ImportGetter) (Stitch, error) {
 	}
 	spec.createPortRules()
 
+	if err := spec.checkSubnets(); err != nil {
+		return Stitch{}, err
+	}
+
+	switch spec.FirewallBackend {
+	case "", IPTablesBackend, NFTablesBackend:
+	default:
+		return Stitch{}, fmt.Errorf("unknown firewall backend: %s",
+			spec.FirewallBackend)
+	}
+
 	if len(spec.Invariants) == 0 {
 		return spec, nil
 	}
@@ -247,6 +326,22 @@ func (stitch *Stitch) createPortRules() {
 	}
 }
 
+// checkSubnets verifies that the container subnets are usable by the minions.
+func (stitch Stitch) checkSubnets() error {
+	if stitch.Subnet != "" {
+		if _, err := util.ParseSubnet(stitch.Subnet, false); err != nil {
+			return err
+		}
+	}
+
+	if stitch.IPv6Subnet != "" {
+		if _, err := util.ParseSubnet(stitch.IPv6Subnet, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // String returns the Stitch in its deployment representation.
 func (stitch Stitch) String() string {
 	jsonBytes, err := json.Marshal(stitch)
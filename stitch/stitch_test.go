@@ -92,6 +92,42 @@ func TestMachine(t *testing.T) {
 		})
 }
 
+func TestSecretName(t *testing.T) {
+	t.Parallel()
+
+	checkError(t, `secret("")`, "secret names must be non-empty strings")
+}
+
+func TestFiles(t *testing.T) {
+	t.Parallel()
+
+	checkError(t, `new Container("image").withFiles({"etc/a.cfg": "a"})`,
+		"file paths must be absolute: etc/a.cfg")
+	checkError(t, `new Container("image").withFiles({"/etc/a.cfg": 1})`,
+		"file contents must be strings or secrets: /etc/a.cfg")
+}
+
+func TestStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, timeout := range []string{"0", "-1", "1.5", `"10"`} {
+		checkError(t, `new Container("image").withStopTimeout(`+timeout+`)`,
+			"stop timeouts must be positive integers")
+	}
+}
+
+func TestUpdateStrategy(t *testing.T) {
+	t.Parallel()
+
+	checkError(t, `new Service("foo", []).setUpdateStrategy({max: 1})`,
+		"unknown update strategy option: max")
+	checkError(t, `new Service("foo", []).setUpdateStrategy({maxSurge: -1})`,
+		"update strategy options must be non-negative integers")
+	checkError(t, `new Service("foo", []).setUpdateStrategy(
+		{maxSurge: 0, maxUnavailable: 0})`,
+		"maxUnavailable and maxSurge can't both be 0")
+}
+
 func TestContainer(t *testing.T) {
 	t.Parallel()
 
@@ -145,6 +181,84 @@ func TestContainer(t *testing.T) {
 			},
 		})
 
+	checkContainers(t, `deployment.deploy(new Service("foo", [
+	new Container("image").withBandwidth(1000)
+	]));`,
+		map[string]Container{
+			"a767f90e748ba01ff277500533c159c0d2975b24": {
+				ID:        "a767f90e748ba01ff277500533c159c0d2975b24",
+				Image:     "image",
+				Command:   []string{},
+				Env:       map[string]string{},
+				Bandwidth: 1000,
+			},
+		})
+
+	checkContainers(t, `deployment.deploy(new Service("foo", [
+	new Container("image").withStopTimeout(30)
+	]));`,
+		map[string]Container{
+			"ac3f94425b6dde51608c11f5ffb46ea23c948dda": {
+				ID:          "ac3f94425b6dde51608c11f5ffb46ea23c948dda",
+				Image:       "image",
+				Command:     []string{},
+				Env:         map[string]string{},
+				StopTimeout: 30,
+			},
+		})
+
+	checkContainers(t, `var c = new Container("image").withEnv({
+		"user": "admin", "pass": secret("db-pass")});
+	c.setEnv("key", secret("api-key"));
+	c.setEnv("user", secret("db-user"));
+	c.setEnv("key", "plain");
+	deployment.deploy(new Service("foo", [c]));`,
+		map[string]Container{
+			"a1094c7104f8a9ecfa79cf6a525102ca8a918100": {
+				ID:      "a1094c7104f8a9ecfa79cf6a525102ca8a918100",
+				Image:   "image",
+				Command: []string{},
+				Env:     map[string]string{"key": "plain"},
+				Secrets: map[string]string{"pass": "db-pass", "user": "db-user"},
+			},
+		})
+
+	checkContainers(t, `deployment.deploy(new Service("foo", [
+	new Container("image").withFiles({"/etc/a.cfg": "a"})
+		.withFiles({"/etc/b.cfg": "b"})
+	]));`,
+		map[string]Container{
+			"821629eed9c4975785f41679068641906e5b8449": {
+				ID:      "821629eed9c4975785f41679068641906e5b8449",
+				Image:   "image",
+				Command: []string{},
+				Env:     map[string]string{},
+				Files: map[string]string{
+					"/etc/a.cfg": "a",
+					"/etc/b.cfg": "b",
+				},
+			},
+		})
+
+	checkContainers(t, `deployment.deploy(new Service("foo", [
+	new Container("image").withFiles({"/etc/a.cfg": "a", "/etc/b.pem": "b"})
+		.withFiles({"/etc/b.pem": secret("key"), "/etc/c.pem": secret("c")})
+		.withFiles({"/etc/c.pem": "c"})
+	]));`,
+		map[string]Container{
+			"e548c529697326c9bc32a4c0be0ac7b1d0fd2df0": {
+				ID:      "e548c529697326c9bc32a4c0be0ac7b1d0fd2df0",
+				Image:   "image",
+				Command: []string{},
+				Env:     map[string]string{},
+				Files: map[string]string{
+					"/etc/a.cfg": "a",
+					"/etc/c.pem": "c",
+				},
+				SecretFiles: map[string]string{"/etc/b.pem": "key"},
+			},
+		})
+
 	checkContainers(t, `deployment.deploy(
 		new Service("foo", new Container("image", ["arg"]).replicate(2))
 	);`,
@@ -290,6 +404,22 @@ func TestLabel(t *testing.T) {
 			},
 		})
 
+	checkLabels(t, `var foo = new Service("foo", []);
+	foo.setUpdateStrategy({maxSurge: 2, minReadySeconds: 10});
+	deployment.deploy(foo);`,
+		map[string]Label{
+			"foo": {
+				Name:        "foo",
+				IDs:         []string{},
+				Annotations: []string{},
+				UpdateStrategy: &UpdateStrategy{
+					MaxUnavailable:  1,
+					MaxSurge:        2,
+					MinReadySeconds: 10,
+				},
+			},
+		})
+
 	expHostname := "foo.q"
 	checkJavascript(t, `(function() {
 		var foo = new Service("foo", []);
@@ -361,6 +491,19 @@ func TestConnect(t *testing.T) {
 			},
 		})
 
+	checkConnections(t, pre+`foo.connect(80, bar, {bandwidth: 500});`,
+		[]Connection{
+			{
+				From:      "foo",
+				To:        "bar",
+				MinPort:   80,
+				MaxPort:   80,
+				Bandwidth: 500,
+			},
+		})
+
+	checkError(t, pre+`foo.connect(80, publicInternet, {bandwidth: 500});`,
+		"connections to the public internet cannot be rate limited")
 	checkError(t, pre+`foo.connect(new PortRange(80, 81), publicInternet);`,
 		"public internet cannot connect on port ranges")
 	checkError(t, pre+`publicInternet.connect(new PortRange(80, 81), foo);`,
@@ -457,6 +600,18 @@ func TestQuery(t *testing.T) {
 	adminACLChecker := queryChecker(func(handle Stitch) interface{} {
 		return handle.AdminACL
 	})
+	dnsForwardersChecker := queryChecker(func(handle Stitch) interface{} {
+		return handle.DNSForwarders
+	})
+	subnetChecker := queryChecker(func(handle Stitch) interface{} {
+		return []string{handle.Subnet, handle.IPv6Subnet}
+	})
+	encryptOverlayChecker := queryChecker(func(handle Stitch) interface{} {
+		return handle.EncryptOverlay
+	})
+	firewallBackendChecker := queryChecker(func(handle Stitch) interface{} {
+		return handle.FirewallBackend
+	})
 
 	namespaceChecker(t, `createDeployment({namespace: "myNamespace"});`,
 		"myNamespace")
@@ -465,6 +620,26 @@ func TestQuery(t *testing.T) {
 	maxPriceChecker(t, ``, 0.0)
 	adminACLChecker(t, `createDeployment({adminACL: ["local"]});`, []string{"local"})
 	adminACLChecker(t, ``, []string{})
+	dnsForwardersChecker(t, `createDeployment({dnsForwarders: ["8.8.8.8"]});`,
+		[]string{"8.8.8.8"})
+	dnsForwardersChecker(t, ``, []string{})
+	subnetChecker(t, `createDeployment({subnet: "172.16.0.0/12",
+		ipv6Subnet: "fd00::/64"});`, []string{"172.16.0.0/12", "fd00::/64"})
+	subnetChecker(t, ``, []string{"", ""})
+	encryptOverlayChecker(t, `createDeployment({encryptOverlay: true});`, true)
+	encryptOverlayChecker(t, ``, false)
+	firewallBackendChecker(t, `createDeployment({firewallBackend: "nftables"});`,
+		"nftables")
+	firewallBackendChecker(t, ``, "")
+
+	checkError(t, `createDeployment({firewallBackend: "pf"});`,
+		"unknown firewall backend: pf")
+	checkError(t, `createDeployment({subnet: "fd00::/64"});`,
+		"not an IPv4 subnet: fd00::/64")
+	checkError(t, `createDeployment({subnet: "10.0.0.0/30"});`,
+		"subnet must be a /24 or larger: 10.0.0.0/30")
+	checkError(t, `createDeployment({ipv6Subnet: "10.0.0.0/8"});`,
+		"not an IPv6 subnet: 10.0.0.0/8")
 }
 
 func TestMarshal(t *testing.T) {
@@ -6,9 +6,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/quilt/quilt/metrics"
+
 	log "github.com/Sirupsen/logrus"
 )
 
+var loopDuration = metrics.NewHistogram("quilt_loop_duration_seconds",
+	"How long each iteration of the daemon's and minion's loops takes.",
+	metrics.DefBuckets, "loop")
+
 // Formatter implements the log formatter for Quilt.
 type Formatter struct{}
 
@@ -52,9 +58,11 @@ func (ltl *EventTimer) LogStart() {
 		ltl.eventName, ltl.lastStart.Sub(ltl.lastEnd))
 }
 
-// LogEnd logs the end of a loop and how long it took to run.
+// LogEnd logs the end of a loop and how long it took to run, and records the
+// duration in the loop duration metric.
 func (ltl *EventTimer) LogEnd() {
 	ltl.lastEnd = time.Now()
-	log.Debugf("%s event ended. It took %v", ltl.eventName,
-		ltl.lastEnd.Sub(ltl.lastStart))
+	duration := ltl.lastEnd.Sub(ltl.lastStart)
+	log.Debugf("%s event ended. It took %v", ltl.eventName, duration)
+	loopDuration.Observe(duration.Seconds(), ltl.eventName)
 }
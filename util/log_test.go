@@ -0,0 +1,17 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestEventTimer(t *testing.T) {
+	timer := NewEventTimer("TestLoop")
+	for i := 0; i < 2; i++ {
+		timer.LogStart()
+		timer.LogEnd()
+	}
+
+	if count := loopDuration.Count("TestLoop"); count != 2 {
+		t.Errorf("Recorded %d loop durations, expected 2", count)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"archive/tar"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -149,3 +151,26 @@ func WaitFor(pred func() bool, interval time.Duration, timeout time.Duration) er
 		Sleep(interval)
 	}
 }
+
+// ParseSubnet parses a container subnet in CIDR notation.  IPv4 subnets must have
+// room for at least 254 containers.  Container addresses are allocated from the
+// last 32 bits of IPv6 subnets, so they must be a /96 or larger.
+func ParseSubnet(cidr string, ipv6 bool) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subnet: %s", cidr)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	switch {
+	case !ipv6 && bits != 32:
+		return nil, fmt.Errorf("not an IPv4 subnet: %s", cidr)
+	case !ipv6 && ones > 24:
+		return nil, fmt.Errorf("subnet must be a /24 or larger: %s", cidr)
+	case ipv6 && bits != 128:
+		return nil, fmt.Errorf("not an IPv6 subnet: %s", cidr)
+	case ipv6 && ones > 96:
+		return nil, fmt.Errorf("subnet must be a /96 or larger: %s", cidr)
+	}
+	return ipNet, nil
+}
@@ -65,3 +65,34 @@ func TestWaitFor(t *testing.T) {
 		t.Errorf("Expected waitFor to timeout")
 	}
 }
+
+func TestParseSubnet(t *testing.T) {
+	ipNet, err := ParseSubnet("192.168.1.7/24", false)
+	if err != nil || ipNet.String() != "192.168.1.0/24" {
+		t.Errorf("Parsed %v, %v, expected 192.168.1.0/24", ipNet, err)
+	}
+
+	ipNet, err = ParseSubnet("fd00::/96", true)
+	if err != nil || ipNet.String() != "fd00::/96" {
+		t.Errorf("Parsed %v, %v, expected fd00::/96", ipNet, err)
+	}
+
+	for _, test := range []struct {
+		cidr string
+		ipv6 bool
+		err  string
+	}{
+		{"junk", false, "invalid subnet: junk"},
+		{"192.168.1.0/25", false,
+			"subnet must be a /24 or larger: 192.168.1.0/25"},
+		{"fd00::/64", false, "not an IPv4 subnet: fd00::/64"},
+		{"10.0.0.0/8", true, "not an IPv6 subnet: 10.0.0.0/8"},
+		{"fd00::/112", true, "subnet must be a /96 or larger: fd00::/112"},
+	} {
+		_, err := ParseSubnet(test.cidr, test.ipv6)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Parsing %s gave error %v, expected %s",
+				test.cidr, err, test.err)
+		}
+	}
+}